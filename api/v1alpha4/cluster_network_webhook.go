@@ -0,0 +1,231 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha4
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const clusterNetworkWebhookPath = "/validate-cluster-x-k8s-io-v1alpha4-cluster-network"
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-cluster-x-k8s-io-v1alpha4-cluster-network,mutating=false,failurePolicy=fail,matchPolicy=Equivalent,groups=cluster.x-k8s.io,resources=clusters,versions=v1alpha4,name=validation-network.cluster.cluster.x-k8s.io,sideEffects=None,admissionReviewVersions=v1beta1
+
+// ClusterNetworkValidator validates the network configuration of the Clusters in a group, i.e. of the Clusters
+// matching the Selector across all namespaces. Within a group:
+// - the pods and services CIDR blocks of a Cluster must not overlap with each other or with the ones of other Clusters.
+// - the ControlPlaneEndpoint of a Cluster must not be used by other Clusters.
+// - the pods and services CIDR blocks and the ControlPlaneEndpoint are immutable once set.
+// Only creates and the updates changing the network configuration are validated, and Clusters being deleted are
+// never validated. If the Selector is nil or empty, the validation is disabled.
+// +kubebuilder:object:generate=false
+type ClusterNetworkValidator struct {
+	Client   client.Reader
+	Selector labels.Selector
+
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &ClusterNetworkValidator{}
+var _ admission.DecoderInjector = &ClusterNetworkValidator{}
+
+// SetupWebhookWithManager registers the ClusterNetworkValidator webhook with the manager's webhook server.
+func (v *ClusterNetworkValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(clusterNetworkWebhookPath, &webhook.Admission{Handler: v})
+	return nil
+}
+
+// InjectDecoder implements admission.DecoderInjector.
+func (v *ClusterNetworkValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle implements admission.Handler.
+func (v *ClusterNetworkValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if v.Selector == nil || v.Selector.Empty() {
+		return admission.Allowed("")
+	}
+
+	cluster := &Cluster{}
+	if err := v.decoder.Decode(req, cluster); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	// Clusters being deleted are not validated, so that updates removing finalizers are never blocked.
+	if !v.Selector.Matches(labels.Set(cluster.Labels)) || !cluster.DeletionTimestamp.IsZero() {
+		return admission.Allowed("")
+	}
+
+	var old *Cluster
+	if req.Operation == admissionv1.Update {
+		old = &Cluster{}
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		// Updates not changing the network configuration of a Cluster already in the group, e.g. to finalizers or
+		// other fields, are not validated; a Cluster whose labels move it into the group is validated against it.
+		if v.Selector.Matches(labels.Set(old.Labels)) &&
+			reflect.DeepEqual(cluster.Spec.ClusterNetwork, old.Spec.ClusterNetwork) && cluster.Spec.ControlPlaneEndpoint == old.Spec.ControlPlaneEndpoint {
+			return admission.Allowed("")
+		}
+	}
+
+	clusters := &ClusterList{}
+	if err := v.Client.List(ctx, clusters, client.MatchingLabelsSelector{Selector: v.Selector}); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	if allErrs := validateClusterNetworkGroup(cluster, old, clusters.Items); len(allErrs) > 0 {
+		status := apierrors.NewInvalid(GroupVersion.WithKind("Cluster").GroupKind(), cluster.Name, allErrs).ErrStatus
+		return admission.Response{
+			AdmissionResponse: admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result:  &status,
+			},
+		}
+	}
+	return admission.Allowed("")
+}
+
+// clusterCIDR is a parsed CIDR block of a Cluster, together with the path of the field defining it.
+type clusterCIDR struct {
+	path  *field.Path
+	value string
+	ipNet *net.IPNet
+}
+
+// validateClusterNetworkGroup validates the network configuration of a Cluster against the other Clusters in its group.
+func validateClusterNetworkGroup(cluster, old *Cluster, group []Cluster) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if old != nil {
+		allErrs = append(allErrs, validateClusterNetworkImmutability(cluster, old)...)
+	}
+
+	cidrs, errs := parseClusterCIDRs(cluster)
+	allErrs = append(allErrs, errs...)
+
+	// The CIDR blocks of the Cluster must not overlap with each other.
+	for i := range cidrs {
+		for j := i + 1; j < len(cidrs); j++ {
+			if cidrsOverlap(cidrs[i].ipNet, cidrs[j].ipNet) {
+				allErrs = append(allErrs, field.Invalid(cidrs[j].path, cidrs[j].value, fmt.Sprintf("overlaps with %s %s", cidrs[i].path, cidrs[i].value)))
+			}
+		}
+	}
+
+	for i := range group {
+		other := &group[i]
+		if other.Namespace == cluster.Namespace && other.Name == cluster.Name {
+			continue
+		}
+
+		// The CIDR blocks of the Cluster must not overlap with the ones of other Clusters in the group.
+		otherCIDRs, _ := parseClusterCIDRs(other)
+		for _, c := range cidrs {
+			for _, o := range otherCIDRs {
+				if cidrsOverlap(c.ipNet, o.ipNet) {
+					allErrs = append(allErrs, field.Invalid(c.path, c.value, fmt.Sprintf("overlaps with %s of Cluster %s/%s", o.value, other.Namespace, other.Name)))
+				}
+			}
+		}
+
+		// The ControlPlaneEndpoint of the Cluster must not be used by other Clusters in the group.
+		if cluster.Spec.ControlPlaneEndpoint.IsValid() && cluster.Spec.ControlPlaneEndpoint == other.Spec.ControlPlaneEndpoint {
+			allErrs = append(allErrs, field.Duplicate(field.NewPath("spec", "controlPlaneEndpoint"),
+				fmt.Sprintf("%s is already used by Cluster %s/%s", cluster.Spec.ControlPlaneEndpoint.String(), other.Namespace, other.Name)))
+		}
+	}
+
+	return allErrs
+}
+
+// validateClusterNetworkImmutability validates that the CIDR blocks and the ControlPlaneEndpoint are not changed once set.
+func validateClusterNetworkImmutability(cluster, old *Cluster) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if old.Spec.ControlPlaneEndpoint.IsValid() && cluster.Spec.ControlPlaneEndpoint != old.Spec.ControlPlaneEndpoint {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "controlPlaneEndpoint"), cluster.Spec.ControlPlaneEndpoint.String(), "field is immutable"))
+	}
+
+	oldServices, oldPods := networkRanges(old)
+	newServices, newPods := networkRanges(cluster)
+	if len(oldServices) > 0 && !reflect.DeepEqual(oldServices, newServices) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "clusterNetwork", "services", "cidrBlocks"), newServices, "field is immutable"))
+	}
+	if len(oldPods) > 0 && !reflect.DeepEqual(oldPods, newPods) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "clusterNetwork", "pods", "cidrBlocks"), newPods, "field is immutable"))
+	}
+
+	return allErrs
+}
+
+// networkRanges returns the services and pods CIDR blocks of the Cluster.
+func networkRanges(cluster *Cluster) (services, pods []string) {
+	if cluster.Spec.ClusterNetwork == nil {
+		return nil, nil
+	}
+	if cluster.Spec.ClusterNetwork.Services != nil {
+		services = cluster.Spec.ClusterNetwork.Services.CIDRBlocks
+	}
+	if cluster.Spec.ClusterNetwork.Pods != nil {
+		pods = cluster.Spec.ClusterNetwork.Pods.CIDRBlocks
+	}
+	return services, pods
+}
+
+// parseClusterCIDRs parses the services and pods CIDR blocks of the Cluster.
+func parseClusterCIDRs(cluster *Cluster) ([]clusterCIDR, field.ErrorList) {
+	var cidrs []clusterCIDR
+	var allErrs field.ErrorList
+
+	services, pods := networkRanges(cluster)
+	for _, r := range []struct {
+		path   *field.Path
+		blocks []string
+	}{
+		{path: field.NewPath("spec", "clusterNetwork", "services", "cidrBlocks"), blocks: services},
+		{path: field.NewPath("spec", "clusterNetwork", "pods", "cidrBlocks"), blocks: pods},
+	} {
+		for i, block := range r.blocks {
+			_, ipNet, err := net.ParseCIDR(block)
+			if err != nil {
+				allErrs = append(allErrs, field.Invalid(r.path.Index(i), block, "must be a valid CIDR block"))
+				continue
+			}
+			cidrs = append(cidrs, clusterCIDR{path: r.path.Index(i), value: block, ipNet: ipNet})
+		}
+	}
+	return cidrs, allErrs
+}
+
+// cidrsOverlap returns true if the two CIDR blocks overlap.
+func cidrsOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha4
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func newNetworkCluster(name string, groupLabels map[string]string, services, pods []string, endpoint APIEndpoint) *Cluster {
+	c := &Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    groupLabels,
+		},
+		Spec: ClusterSpec{
+			ControlPlaneEndpoint: endpoint,
+		},
+	}
+	if services != nil || pods != nil {
+		c.Spec.ClusterNetwork = &ClusterNetwork{
+			Services: &NetworkRanges{CIDRBlocks: services},
+			Pods:     &NetworkRanges{CIDRBlocks: pods},
+		}
+	}
+	return c
+}
+
+func TestValidateClusterNetworkGroup(t *testing.T) {
+	endpoint := APIEndpoint{Host: "10.0.0.1", Port: 6443}
+	other := newNetworkCluster("other", nil, []string{"10.96.0.0/12"}, []string{"192.168.0.0/16"}, endpoint)
+
+	tests := []struct {
+		name      string
+		cluster   *Cluster
+		old       *Cluster
+		group     []Cluster
+		expectErr bool
+	}{
+		{
+			name:    "should succeed without cluster network",
+			cluster: newNetworkCluster("foo", nil, nil, nil, APIEndpoint{}),
+			group:   []Cluster{*other},
+		},
+		{
+			name:    "should succeed with non overlapping CIDR blocks and a different endpoint",
+			cluster: newNetworkCluster("foo", nil, []string{"172.16.0.0/16"}, []string{"10.244.0.0/16"}, APIEndpoint{Host: "10.0.0.2", Port: 6443}),
+			group:   []Cluster{*other},
+		},
+		{
+			name:      "should fail with invalid CIDR blocks",
+			cluster:   newNetworkCluster("foo", nil, []string{"not-a-cidr"}, nil, APIEndpoint{}),
+			expectErr: true,
+		},
+		{
+			name:      "should fail if pods and services CIDR blocks overlap",
+			cluster:   newNetworkCluster("foo", nil, []string{"10.0.0.0/8"}, []string{"10.244.0.0/16"}, APIEndpoint{}),
+			expectErr: true,
+		},
+		{
+			name:      "should fail if CIDR blocks overlap with another cluster in the group",
+			cluster:   newNetworkCluster("foo", nil, []string{"172.16.0.0/16"}, []string{"192.168.1.0/24"}, APIEndpoint{}),
+			group:     []Cluster{*other},
+			expectErr: true,
+		},
+		{
+			name:      "should fail if the control plane endpoint is used by another cluster in the group",
+			cluster:   newNetworkCluster("foo", nil, nil, nil, endpoint),
+			group:     []Cluster{*other},
+			expectErr: true,
+		},
+		{
+			name:    "should ignore the cluster itself in the group",
+			cluster: other.DeepCopy(),
+			group:   []Cluster{*other},
+		},
+		{
+			name:    "should succeed setting the control plane endpoint on update",
+			cluster: newNetworkCluster("foo", nil, nil, nil, APIEndpoint{Host: "10.0.0.2", Port: 6443}),
+			old:     newNetworkCluster("foo", nil, nil, nil, APIEndpoint{}),
+		},
+		{
+			name:      "should fail changing the control plane endpoint once set",
+			cluster:   newNetworkCluster("foo", nil, nil, nil, APIEndpoint{Host: "10.0.0.3", Port: 6443}),
+			old:       newNetworkCluster("foo", nil, nil, nil, APIEndpoint{Host: "10.0.0.2", Port: 6443}),
+			expectErr: true,
+		},
+		{
+			name:      "should fail changing the CIDR blocks once set",
+			cluster:   newNetworkCluster("foo", nil, []string{"172.16.0.0/16"}, []string{"10.244.0.0/16"}, APIEndpoint{}),
+			old:       newNetworkCluster("foo", nil, []string{"172.17.0.0/16"}, []string{"10.244.0.0/16"}, APIEndpoint{}),
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			errs := validateClusterNetworkGroup(tt.cluster, tt.old, tt.group)
+			if tt.expectErr {
+				g.Expect(errs).NotTo(BeEmpty())
+			} else {
+				g.Expect(errs).To(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestClusterNetworkValidatorHandle(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(AddToScheme(scheme)).To(Succeed())
+	decoder, err := admission.NewDecoder(scheme)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	groupLabels := map[string]string{"network-group": "prod"}
+	existing := newNetworkCluster("existing", groupLabels, []string{"10.96.0.0/12"}, []string{"192.168.0.0/16"}, APIEndpoint{})
+	outsideGroup := newNetworkCluster("outside", nil, []string{"10.128.0.0/12"}, []string{"172.16.0.0/16"}, APIEndpoint{})
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing, outsideGroup).Build()
+
+	createRequest := func(c *Cluster) admission.Request {
+		raw, err := json.Marshal(c)
+		g.Expect(err).NotTo(HaveOccurred())
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+	}
+
+	selector, err := labels.Parse("network-group=prod")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	v := &ClusterNetworkValidator{Client: fakeClient, Selector: selector}
+	g.Expect(v.InjectDecoder(decoder)).To(Succeed())
+
+	// Overlapping with a cluster in the group is rejected.
+	resp := v.Handle(context.Background(), createRequest(newNetworkCluster("new", groupLabels, []string{"10.100.0.0/16"}, []string{"10.244.0.0/16"}, APIEndpoint{})))
+	g.Expect(resp.Allowed).To(BeFalse())
+
+	// Overlapping with a cluster outside of the group is allowed.
+	resp = v.Handle(context.Background(), createRequest(newNetworkCluster("new", groupLabels, []string{"10.200.0.0/16"}, []string{"172.16.0.0/16"}, APIEndpoint{})))
+	g.Expect(resp.Allowed).To(BeTrue())
+
+	// Clusters not matching the selector are not validated.
+	resp = v.Handle(context.Background(), createRequest(newNetworkCluster("new", nil, []string{"10.100.0.0/16"}, []string{"10.244.0.0/16"}, APIEndpoint{})))
+	g.Expect(resp.Allowed).To(BeTrue())
+
+	updateRequest := func(c, old *Cluster) admission.Request {
+		raw, err := json.Marshal(c)
+		g.Expect(err).NotTo(HaveOccurred())
+		oldRaw, err := json.Marshal(old)
+		g.Expect(err).NotTo(HaveOccurred())
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Update,
+			Object:    runtime.RawExtension{Raw: raw},
+			OldObject: runtime.RawExtension{Raw: oldRaw},
+		}}
+	}
+
+	// Updates not changing the network configuration of a Cluster in the group are not validated, e.g. for Clusters
+	// created before the validation was enabled.
+	overlapping := newNetworkCluster("overlapping", groupLabels, []string{"10.100.0.0/16"}, []string{"10.244.0.0/16"}, APIEndpoint{})
+	updated := overlapping.DeepCopy()
+	updated.Finalizers = []string{ClusterFinalizer}
+	resp = v.Handle(context.Background(), updateRequest(updated, overlapping))
+	g.Expect(resp.Allowed).To(BeTrue())
+
+	// Updates changing the network configuration are validated.
+	updated = overlapping.DeepCopy()
+	updated.Spec.ControlPlaneEndpoint = APIEndpoint{Host: "10.0.0.1", Port: 6443}
+	resp = v.Handle(context.Background(), updateRequest(updated, overlapping))
+	g.Expect(resp.Allowed).To(BeFalse())
+
+	// Updates moving a Cluster into the group are validated, even if they change only its labels.
+	outside := overlapping.DeepCopy()
+	outside.Labels = nil
+	resp = v.Handle(context.Background(), updateRequest(overlapping, outside))
+	g.Expect(resp.Allowed).To(BeFalse())
+
+	joining := newNetworkCluster("joining", nil, []string{"10.200.0.0/16"}, []string{"172.16.0.0/16"}, APIEndpoint{})
+	updated = joining.DeepCopy()
+	updated.Labels = groupLabels
+	resp = v.Handle(context.Background(), updateRequest(updated, joining))
+	g.Expect(resp.Allowed).To(BeTrue())
+
+	// Clusters being deleted are not validated.
+	now := metav1.Now()
+	updated = overlapping.DeepCopy()
+	updated.DeletionTimestamp = &now
+	updated.Spec.ControlPlaneEndpoint = APIEndpoint{Host: "10.0.0.1", Port: 6443}
+	resp = v.Handle(context.Background(), updateRequest(updated, overlapping))
+	g.Expect(resp.Allowed).To(BeTrue())
+
+	// Without a selector the validation is disabled.
+	v.Selector = labels.Everything()
+	resp = v.Handle(context.Background(), createRequest(newNetworkCluster("new", groupLabels, []string{"10.100.0.0/16"}, []string{"10.244.0.0/16"}, APIEndpoint{})))
+	g.Expect(resp.Allowed).To(BeTrue())
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -57,10 +58,117 @@ type ClusterSpec struct {
 	// for provisioning infrastructure for a cluster in said provider.
 	// +optional
 	InfrastructureRef *corev1.ObjectReference `json:"infrastructureRef,omitempty"`
+
+	// MaintenanceWindows restricts when disruptive actions on the Cluster's machines, such as rollouts,
+	// remediation and scale down, are allowed. Disruptive actions are deferred until one of the windows is open.
+	// If empty, disruptive actions are always allowed.
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+
+	// DeletionPolicy defines how the Cluster and its descendants are deleted.
+	// +optional
+	DeletionPolicy *ClusterDeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Remediation defines the default remediation of the Cluster's unhealthy machines, used by the
+	// MachineHealthChecks of the Cluster that do not define a RemediationTemplate.
+	// +optional
+	Remediation *ClusterRemediation `json:"remediation,omitempty"`
 }
 
 // ANCHOR_END: ClusterSpec
 
+// ANCHOR: MaintenanceWindow
+
+// MaintenanceWindow defines a recurring time window in which disruptive actions are allowed.
+type MaintenanceWindow struct {
+	// Schedule is a cron expression in the standard five fields format (minute, hour, day of month,
+	// month, day of week) defining when the window opens, e.g. "0 2 * * 6" for every Saturday at 02:00.
+	// Schedules are evaluated in UTC.
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Duration is how long the window stays open.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// ANCHOR_END: MaintenanceWindow
+
+// ANCHOR: ClusterDeletionPolicy
+
+// ClusterDeletionOrder defines the order in which the Cluster's machines are deleted.
+type ClusterDeletionOrder string
+
+const (
+	// ParallelClusterDeletionOrder deletes the worker and the control plane machines at the same time.
+	// If the Cluster has a control plane provider, the control plane is deleted after the worker machines anyway.
+	ParallelClusterDeletionOrder = ClusterDeletionOrder("Parallel")
+
+	// WorkersFirstClusterDeletionOrder deletes the MachineDeployments, MachineSets, MachinePools and worker machines
+	// before the control plane machines or the control plane object.
+	WorkersFirstClusterDeletionOrder = ClusterDeletionOrder("WorkersFirst")
+)
+
+// ClusterPreDeleteCheck is a check run against the workload cluster before deleting the Cluster.
+type ClusterPreDeleteCheck string
+
+const (
+	// RetainedPersistentVolumesPreDeleteCheck blocks the deletion while PersistentVolumes with the
+	// Retain reclaim policy exist in the workload cluster.
+	RetainedPersistentVolumesPreDeleteCheck = ClusterPreDeleteCheck("RetainedPersistentVolumes")
+)
+
+// ClusterDeletionPolicy defines how the Cluster and its descendants are deleted.
+type ClusterDeletionPolicy struct {
+	// Order defines the order in which the Cluster's machines are deleted.
+	// Defaults to Parallel.
+	// +kubebuilder:validation:Enum=Parallel;WorkersFirst
+	// +optional
+	Order ClusterDeletionOrder `json:"order,omitempty"`
+
+	// PreDeleteChecks are the checks run against the workload cluster before deleting any of the Cluster's
+	// descendants; the deletion is blocked until all the checks pass.
+	// +optional
+	PreDeleteChecks []ClusterPreDeleteCheck `json:"preDeleteChecks,omitempty"`
+}
+
+// ANCHOR_END: ClusterDeletionPolicy
+
+// ANCHOR: ClusterRemediation
+
+// ClusterRemediation defines the default remediation of the Cluster's unhealthy machines.
+type ClusterRemediation struct {
+	// Steps is the escalation chain applied to an unhealthy machine: the steps are applied in order,
+	// moving to the next step once all the attempts of the current one did not make the machine healthy.
+	// A single step with a TemplateRef defines a default remediation template for the Cluster.
+	// +kubebuilder:validation:MinItems=1
+	Steps []RemediationStep `json:"steps"`
+}
+
+// RemediationStep defines a step of the remediation escalation chain.
+type RemediationStep struct {
+	// TemplateRef is a reference to a remediation template provided by an infrastructure provider,
+	// e.g. to reboot the machine. If not set, the machine is remediated by its owner, e.g. it is replaced
+	// by its MachineSet; as the machine is deleted, only the last step can be remediated by the owner.
+	// +optional
+	TemplateRef *corev1.ObjectReference `json:"templateRef,omitempty"`
+
+	// MaxAttempts is the number of remediation attempts of this step before moving to the next one.
+	// Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxAttempts *int32 `json:"maxAttempts,omitempty"`
+
+	// Timeout is how long an attempt is given to make the machine healthy before it is considered failed.
+	// Defaults to 10m.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// DefaultRemediationStepTimeout is the default timeout of a remediation attempt of a RemediationStep.
+const DefaultRemediationStepTimeout = 10 * time.Minute
+
+// ANCHOR_END: ClusterRemediation
+
 // ANCHOR: ClusterNetwork
 
 // ClusterNetwork specifies the different networking
@@ -219,6 +327,117 @@ func (c *Cluster) SetConditions(conditions Conditions) {
 	c.Status.Conditions = conditions
 }
 
+// GetIPFamily returns a ClusterIPFamily from the configuration provided.
+// Clusters without pods and services CIDR blocks default to IPv4.
+func (c *Cluster) GetIPFamily() (ClusterIPFamily, error) {
+	var podCIDRs, serviceCIDRs []string
+	if c.Spec.ClusterNetwork != nil {
+		if c.Spec.ClusterNetwork.Pods != nil {
+			podCIDRs = c.Spec.ClusterNetwork.Pods.CIDRBlocks
+		}
+		if c.Spec.ClusterNetwork.Services != nil {
+			serviceCIDRs = c.Spec.ClusterNetwork.Services.CIDRBlocks
+		}
+	}
+	if len(podCIDRs) == 0 && len(serviceCIDRs) == 0 {
+		return IPv4IPFamily, nil
+	}
+
+	podsIPFamily, err := ipFamilyForCIDRStrings(podCIDRs)
+	if err != nil {
+		return InvalidIPFamily, fmt.Errorf("pods: %s", err)
+	}
+	if len(serviceCIDRs) == 0 {
+		return podsIPFamily, nil
+	}
+
+	servicesIPFamily, err := ipFamilyForCIDRStrings(serviceCIDRs)
+	if err != nil {
+		return InvalidIPFamily, fmt.Errorf("services: %s", err)
+	}
+	if len(podCIDRs) == 0 {
+		return servicesIPFamily, nil
+	}
+
+	if podsIPFamily == DualStackIPFamily {
+		return DualStackIPFamily, nil
+	} else if podsIPFamily != servicesIPFamily {
+		return InvalidIPFamily, fmt.Errorf("pods and services IP family mismatch")
+	}
+
+	return podsIPFamily, nil
+}
+
+// GetPrimaryIPFamily returns the IP family of the first pods CIDR block, or of the first services CIDR block
+// if no pods CIDR blocks are defined; this is the family Kubernetes components prefer on dual-stack Clusters.
+// Clusters without pods and services CIDR blocks default to IPv4.
+func (c *Cluster) GetPrimaryIPFamily() (ClusterIPFamily, error) {
+	var cidrs []string
+	if c.Spec.ClusterNetwork != nil {
+		if c.Spec.ClusterNetwork.Pods != nil {
+			cidrs = append(cidrs, c.Spec.ClusterNetwork.Pods.CIDRBlocks...)
+		}
+		if c.Spec.ClusterNetwork.Services != nil {
+			cidrs = append(cidrs, c.Spec.ClusterNetwork.Services.CIDRBlocks...)
+		}
+	}
+	if len(cidrs) == 0 {
+		return IPv4IPFamily, nil
+	}
+	return ipFamilyForCIDRStrings(cidrs[:1])
+}
+
+func ipFamilyForCIDRStrings(cidrs []string) (ClusterIPFamily, error) {
+	if len(cidrs) > 2 {
+		return InvalidIPFamily, fmt.Errorf("too many CIDRs specified")
+	}
+	var foundIPv4 bool
+	var foundIPv6 bool
+	for _, cidr := range cidrs {
+		ip, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return InvalidIPFamily, fmt.Errorf("could not parse CIDR: %s", err)
+		}
+		if ip.To4() != nil {
+			foundIPv4 = true
+		} else {
+			foundIPv6 = true
+		}
+	}
+	switch {
+	case foundIPv4 && foundIPv6:
+		return DualStackIPFamily, nil
+	case foundIPv4:
+		return IPv4IPFamily, nil
+	case foundIPv6:
+		return IPv6IPFamily, nil
+	default:
+		return InvalidIPFamily, nil
+	}
+}
+
+// ClusterIPFamily defines the IP family of a Cluster.
+type ClusterIPFamily int
+
+const (
+	// InvalidIPFamily is the IP family of a Cluster whose CIDR blocks cannot be parsed,
+	// or do not define a valid combination of IP families.
+	InvalidIPFamily ClusterIPFamily = iota
+
+	// IPv4IPFamily is the IP family of a Cluster whose CIDR blocks are all IPv4.
+	IPv4IPFamily
+
+	// IPv6IPFamily is the IP family of a Cluster whose CIDR blocks are all IPv6.
+	IPv6IPFamily
+
+	// DualStackIPFamily is the IP family of a Cluster defining both IPv4 and IPv6 CIDR blocks.
+	DualStackIPFamily
+)
+
+func (f ClusterIPFamily) String() string {
+	return [...]string{"InvalidIPFamily", "IPv4IPFamily", "IPv6IPFamily", "DualStackIPFamily"}[f]
+}
+
 // +kubebuilder:object:root=true
 
 // ClusterList contains a list of Cluster
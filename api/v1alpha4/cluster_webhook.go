@@ -17,9 +17,15 @@ limitations under the License.
 package v1alpha4
 
 import (
+	"fmt"
+	"net"
+
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/cluster-api/util/cron"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
@@ -30,7 +36,7 @@ func (c *Cluster) SetupWebhookWithManager(mgr ctrl.Manager) error {
 		Complete()
 }
 
-// +kubebuilder:webhook:verbs=create;update,path=/validate-cluster-x-k8s-io-v1alpha4-cluster,mutating=false,failurePolicy=fail,matchPolicy=Equivalent,groups=cluster.x-k8s.io,resources=clusters,versions=v1alpha4,name=validation.cluster.cluster.x-k8s.io,sideEffects=None,admissionReviewVersions=v1beta1
+// +kubebuilder:webhook:verbs=create;update;delete,path=/validate-cluster-x-k8s-io-v1alpha4-cluster,mutating=false,failurePolicy=fail,matchPolicy=Equivalent,groups=cluster.x-k8s.io,resources=clusters,versions=v1alpha4,name=validation.cluster.cluster.x-k8s.io,sideEffects=None,admissionReviewVersions=v1beta1
 // +kubebuilder:webhook:verbs=create;update,path=/mutate-cluster-x-k8s-io-v1alpha4-cluster,mutating=true,failurePolicy=fail,matchPolicy=Equivalent,groups=cluster.x-k8s.io,resources=clusters,versions=v1alpha4,name=default.cluster.cluster.x-k8s.io,sideEffects=None,admissionReviewVersions=v1beta1
 
 var _ webhook.Defaulter = &Cluster{}
@@ -44,6 +50,25 @@ func (c *Cluster) Default() {
 	if c.Spec.ControlPlaneRef != nil && len(c.Spec.ControlPlaneRef.Namespace) == 0 {
 		c.Spec.ControlPlaneRef.Namespace = c.Namespace
 	}
+
+	if c.Spec.DeletionPolicy != nil && c.Spec.DeletionPolicy.Order == "" {
+		c.Spec.DeletionPolicy.Order = ParallelClusterDeletionOrder
+	}
+
+	if c.Spec.Remediation != nil {
+		for i := range c.Spec.Remediation.Steps {
+			step := &c.Spec.Remediation.Steps[i]
+			if step.TemplateRef != nil && len(step.TemplateRef.Namespace) == 0 {
+				step.TemplateRef.Namespace = c.Namespace
+			}
+			if step.MaxAttempts == nil {
+				step.MaxAttempts = pointer.Int32Ptr(1)
+			}
+			if step.Timeout == nil {
+				step.Timeout = &metav1.Duration{Duration: DefaultRemediationStepTimeout}
+			}
+		}
+	}
 }
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
@@ -58,6 +83,13 @@ func (c *Cluster) ValidateUpdate(old runtime.Object) error {
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
 func (c *Cluster) ValidateDelete() error {
+	if _, ok := c.Annotations[PreventDeleteAnnotation]; ok {
+		return apierrors.NewForbidden(
+			GroupVersion.WithResource("clusters").GroupResource(),
+			c.Name,
+			fmt.Errorf("cluster is protected from deletion, remove the %q annotation before deleting it", PreventDeleteAnnotation),
+		)
+	}
 	return nil
 }
 
@@ -87,8 +119,99 @@ func (c *Cluster) validate() error {
 
 	}
 
+	allErrs = append(allErrs, c.validateClusterNetwork()...)
+
+	for i, w := range c.Spec.MaintenanceWindows {
+		path := field.NewPath("spec", "maintenanceWindows").Index(i)
+		if _, err := cron.Parse(w.Schedule); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("schedule"), w.Schedule, err.Error()))
+		}
+		if w.Duration.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("duration"), w.Duration.Duration.String(), "must be greater than zero"))
+		}
+	}
+
+	if c.Spec.Remediation != nil {
+		allErrs = append(allErrs, c.validateRemediation(field.NewPath("spec", "remediation"))...)
+	}
+
 	if len(allErrs) == 0 {
 		return nil
 	}
 	return apierrors.NewInvalid(GroupVersion.WithKind("Cluster").GroupKind(), c.Name, allErrs)
 }
+
+// validateRemediation validates the steps of the remediation escalation chain.
+func (c *Cluster) validateRemediation(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	steps := c.Spec.Remediation.Steps
+	if len(steps) == 0 {
+		allErrs = append(allErrs, field.Required(path.Child("steps"), "at least one step must be defined"))
+	}
+	for i, step := range steps {
+		stepPath := path.Child("steps").Index(i)
+		if step.TemplateRef == nil && i < len(steps)-1 {
+			allErrs = append(allErrs, field.Invalid(stepPath.Child("templateRef"), step.TemplateRef, "only the last step can be remediated by the machine owner"))
+		}
+		if step.TemplateRef != nil && step.TemplateRef.Namespace != c.Namespace {
+			allErrs = append(allErrs, field.Invalid(stepPath.Child("templateRef", "namespace"), step.TemplateRef.Namespace, "must match metadata.namespace"))
+		}
+		if step.MaxAttempts != nil && *step.MaxAttempts < 1 {
+			allErrs = append(allErrs, field.Invalid(stepPath.Child("maxAttempts"), *step.MaxAttempts, "must be greater than or equal to 1"))
+		}
+		if step.Timeout != nil && step.Timeout.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(stepPath.Child("timeout"), step.Timeout.Duration.String(), "must be greater than zero"))
+		}
+	}
+	return allErrs
+}
+
+// validateClusterNetwork validates the IP families of the pods and services CIDR blocks;
+// each of them must be either a single IPv4 or IPv6 CIDR block, or a pair of IPv4 and IPv6 CIDR blocks
+// for dual-stack Clusters, and the pods and services IP families must match.
+func (c *Cluster) validateClusterNetwork() field.ErrorList {
+	var allErrs field.ErrorList
+	if c.Spec.ClusterNetwork == nil {
+		return allErrs
+	}
+
+	path := field.NewPath("spec", "clusterNetwork")
+	if c.Spec.ClusterNetwork.Pods != nil {
+		allErrs = append(allErrs, validateNetworkRanges(path.Child("pods", "cidrBlocks"), c.Spec.ClusterNetwork.Pods.CIDRBlocks)...)
+	}
+	if c.Spec.ClusterNetwork.Services != nil {
+		allErrs = append(allErrs, validateNetworkRanges(path.Child("services", "cidrBlocks"), c.Spec.ClusterNetwork.Services.CIDRBlocks)...)
+	}
+	if len(allErrs) > 0 {
+		return allErrs
+	}
+
+	if _, err := c.GetIPFamily(); err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("services", "cidrBlocks"), c.Spec.ClusterNetwork.Services.String(), err.Error()))
+	}
+	return allErrs
+}
+
+func validateNetworkRanges(path *field.Path, cidrBlocks []string) field.ErrorList {
+	var allErrs field.ErrorList
+	if len(cidrBlocks) > 2 {
+		return append(allErrs, field.TooMany(path, len(cidrBlocks), 2))
+	}
+
+	ipv4Count := 0
+	for i, cidr := range cidrBlocks {
+		ip, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Index(i), cidr, "must be a valid CIDR block"))
+			continue
+		}
+		if ip.To4() != nil {
+			ipv4Count++
+		}
+	}
+	if len(allErrs) == 0 && len(cidrBlocks) == 2 && ipv4Count != 1 {
+		allErrs = append(allErrs, field.Invalid(path, cidrBlocks, "must contain one IPv4 and one IPv6 CIDR block for dual-stack"))
+	}
+	return allErrs
+}
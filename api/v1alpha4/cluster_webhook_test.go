@@ -18,10 +18,12 @@ package v1alpha4
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -35,12 +37,37 @@ func TestClusterDefault(t *testing.T) {
 		Spec: ClusterSpec{
 			InfrastructureRef: &corev1.ObjectReference{},
 			ControlPlaneRef:   &corev1.ObjectReference{},
+			DeletionPolicy:    &ClusterDeletionPolicy{},
+			Remediation: &ClusterRemediation{
+				Steps: []RemediationStep{{TemplateRef: &corev1.ObjectReference{}}},
+			},
 		},
 	}
 	c.Default()
 
 	g.Expect(c.Spec.InfrastructureRef.Namespace).To(Equal(c.Namespace))
 	g.Expect(c.Spec.ControlPlaneRef.Namespace).To(Equal(c.Namespace))
+	g.Expect(c.Spec.DeletionPolicy.Order).To(Equal(ParallelClusterDeletionOrder))
+	g.Expect(c.Spec.Remediation.Steps[0].TemplateRef.Namespace).To(Equal(c.Namespace))
+	g.Expect(*c.Spec.Remediation.Steps[0].MaxAttempts).To(Equal(int32(1)))
+	g.Expect(c.Spec.Remediation.Steps[0].Timeout.Duration).To(Equal(10 * time.Minute))
+}
+
+func TestClusterPreventDelete(t *testing.T) {
+	g := NewWithT(t)
+
+	c := &Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster",
+			Namespace: "foo",
+		},
+	}
+	g.Expect(c.ValidateDelete()).To(Succeed())
+
+	c.Annotations = map[string]string{PreventDeleteAnnotation: ""}
+	err := c.ValidateDelete()
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(apierrors.IsForbidden(err)).To(BeTrue())
 }
 
 func TestClusterValidation(t *testing.T) {
@@ -63,6 +90,47 @@ func TestClusterValidation(t *testing.T) {
 	invalidCPNamespace := valid.DeepCopy()
 	invalidCPNamespace.Spec.InfrastructureRef.Namespace = "baz"
 
+	validMaintenanceWindows := valid.DeepCopy()
+	validMaintenanceWindows.Spec.MaintenanceWindows = []MaintenanceWindow{
+		{Schedule: "0 2 * * 6", Duration: metav1.Duration{Duration: 4 * time.Hour}},
+	}
+
+	invalidMaintenanceSchedule := valid.DeepCopy()
+	invalidMaintenanceSchedule.Spec.MaintenanceWindows = []MaintenanceWindow{
+		{Schedule: "0 2 * *", Duration: metav1.Duration{Duration: 4 * time.Hour}},
+	}
+
+	invalidMaintenanceDuration := valid.DeepCopy()
+	invalidMaintenanceDuration.Spec.MaintenanceWindows = []MaintenanceWindow{
+		{Schedule: "0 2 * * 6"},
+	}
+
+	validRemediation := valid.DeepCopy()
+	validRemediation.Spec.Remediation = &ClusterRemediation{
+		Steps: []RemediationStep{
+			{TemplateRef: &corev1.ObjectReference{Namespace: valid.Namespace}},
+			{},
+		},
+	}
+
+	invalidRemediationOwnerStep := valid.DeepCopy()
+	invalidRemediationOwnerStep.Spec.Remediation = &ClusterRemediation{
+		Steps: []RemediationStep{
+			{},
+			{TemplateRef: &corev1.ObjectReference{Namespace: valid.Namespace}},
+		},
+	}
+
+	invalidRemediationNamespace := valid.DeepCopy()
+	invalidRemediationNamespace.Spec.Remediation = &ClusterRemediation{
+		Steps: []RemediationStep{
+			{TemplateRef: &corev1.ObjectReference{Namespace: "baz"}},
+		},
+	}
+
+	invalidRemediationSteps := valid.DeepCopy()
+	invalidRemediationSteps.Spec.Remediation = &ClusterRemediation{}
+
 	tests := []struct {
 		name      string
 		expectErr bool
@@ -83,6 +151,41 @@ func TestClusterValidation(t *testing.T) {
 			expectErr: false,
 			c:         valid,
 		},
+		{
+			name:      "should succeed when maintenance windows are valid",
+			expectErr: false,
+			c:         validMaintenanceWindows,
+		},
+		{
+			name:      "should return error when a maintenance window schedule is invalid",
+			expectErr: true,
+			c:         invalidMaintenanceSchedule,
+		},
+		{
+			name:      "should return error when a maintenance window duration is not set",
+			expectErr: true,
+			c:         invalidMaintenanceDuration,
+		},
+		{
+			name:      "should succeed when the remediation escalation chain is valid",
+			expectErr: false,
+			c:         validRemediation,
+		},
+		{
+			name:      "should return error when a remediation step other than the last one is remediated by the owner",
+			expectErr: true,
+			c:         invalidRemediationOwnerStep,
+		},
+		{
+			name:      "should return error when cluster namespace and remediation template namespace mismatch",
+			expectErr: true,
+			c:         invalidRemediationNamespace,
+		},
+		{
+			name:      "should return error when no remediation step is defined",
+			expectErr: true,
+			c:         invalidRemediationSteps,
+		},
 	}
 
 	for _, tt := range tests {
@@ -99,3 +202,128 @@ func TestClusterValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestClusterNetworkValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		services  []string
+		pods      []string
+		expectErr bool
+	}{
+		{
+			name:     "should succeed with IPv4 CIDR blocks",
+			services: []string{"10.96.0.0/12"},
+			pods:     []string{"192.168.0.0/16"},
+		},
+		{
+			name:     "should succeed with IPv6 CIDR blocks",
+			services: []string{"fd00:100:64::/108"},
+			pods:     []string{"fd00:100:96::/48"},
+		},
+		{
+			name:     "should succeed with dual-stack CIDR blocks",
+			services: []string{"10.96.0.0/12", "fd00:100:64::/108"},
+			pods:     []string{"fd00:100:96::/48", "192.168.0.0/16"},
+		},
+		{
+			name:     "should succeed with dual-stack pods and single-stack services",
+			services: []string{"10.96.0.0/12"},
+			pods:     []string{"192.168.0.0/16", "fd00:100:96::/48"},
+		},
+		{
+			name:      "should return error with invalid CIDR blocks",
+			pods:      []string{"192.168.0.0"},
+			expectErr: true,
+		},
+		{
+			name:      "should return error with more than two CIDR blocks",
+			pods:      []string{"192.168.0.0/16", "fd00:100:96::/48", "10.0.0.0/16"},
+			expectErr: true,
+		},
+		{
+			name:      "should return error with two CIDR blocks of the same family",
+			pods:      []string{"192.168.0.0/16", "10.0.0.0/16"},
+			expectErr: true,
+		},
+		{
+			name:      "should return error when pods and services IP families mismatch",
+			services:  []string{"fd00:100:64::/108"},
+			pods:      []string{"192.168.0.0/16"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := &Cluster{
+				Spec: ClusterSpec{
+					ClusterNetwork: &ClusterNetwork{
+						Services: &NetworkRanges{CIDRBlocks: tt.services},
+						Pods:     &NetworkRanges{CIDRBlocks: tt.pods},
+					},
+				},
+			}
+			if tt.expectErr {
+				g.Expect(c.ValidateCreate()).NotTo(Succeed())
+			} else {
+				g.Expect(c.ValidateCreate()).To(Succeed())
+			}
+		})
+	}
+}
+
+func TestClusterGetIPFamily(t *testing.T) {
+	tests := []struct {
+		name           string
+		clusterNetwork *ClusterNetwork
+		expectFamily   ClusterIPFamily
+		expectPrimary  ClusterIPFamily
+	}{
+		{
+			name:          "defaults to IPv4 without cluster network",
+			expectFamily:  IPv4IPFamily,
+			expectPrimary: IPv4IPFamily,
+		},
+		{
+			name: "IPv6 services only",
+			clusterNetwork: &ClusterNetwork{
+				Services: &NetworkRanges{CIDRBlocks: []string{"fd00:100:64::/108"}},
+			},
+			expectFamily:  IPv6IPFamily,
+			expectPrimary: IPv6IPFamily,
+		},
+		{
+			name: "dual-stack with IPv6 primary",
+			clusterNetwork: &ClusterNetwork{
+				Services: &NetworkRanges{CIDRBlocks: []string{"fd00:100:64::/108", "10.96.0.0/12"}},
+				Pods:     &NetworkRanges{CIDRBlocks: []string{"fd00:100:96::/48", "192.168.0.0/16"}},
+			},
+			expectFamily:  DualStackIPFamily,
+			expectPrimary: IPv6IPFamily,
+		},
+		{
+			name: "dual-stack with IPv4 primary",
+			clusterNetwork: &ClusterNetwork{
+				Pods: &NetworkRanges{CIDRBlocks: []string{"192.168.0.0/16", "fd00:100:96::/48"}},
+			},
+			expectFamily:  DualStackIPFamily,
+			expectPrimary: IPv4IPFamily,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := &Cluster{Spec: ClusterSpec{ClusterNetwork: tt.clusterNetwork}}
+			family, err := c.GetIPFamily()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(family).To(Equal(tt.expectFamily))
+			primary, err := c.GetPrimaryIPFamily()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(primary).To(Equal(tt.expectPrimary))
+		})
+	}
+}
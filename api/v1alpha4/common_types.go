@@ -53,6 +53,12 @@ const (
 	// on the reconciled object.
 	PausedAnnotation = "cluster.x-k8s.io/paused"
 
+	// PauseReasonAnnotationPrefix is the prefix of the annotations that can be applied to a Cluster to pause it
+	// together with all its associated objects, recording why; e.g. `paused.cluster.x-k8s.io/etcd-restore: "<message>"`.
+	// The Cluster stays paused as long as at least one of these annotations exists, so different actors can
+	// pause it independently by adding and removing their own annotation.
+	PauseReasonAnnotationPrefix = "paused.cluster.x-k8s.io"
+
 	// WatchLabel is a label othat can be applied to any Cluster API object.
 	//
 	// Controllers which allow for selective reconciliation may check this label and proceed
@@ -74,6 +80,35 @@ const (
 	// MachineSkipRemediationAnnotation is the annotation used to mark the machines that should not be considered for remediation by MachineHealthCheck reconciler.
 	MachineSkipRemediationAnnotation = "cluster.x-k8s.io/skip-remediation"
 
+	// RemediationStepAnnotation is the annotation set on the machines being remediated by the Cluster's remediation
+	// escalation chain, recording the index of the current step.
+	RemediationStepAnnotation = "cluster.x-k8s.io/remediation-step"
+
+	// RemediationAttemptsAnnotation is the annotation set on the machines being remediated by the Cluster's remediation
+	// escalation chain, recording the number of attempts of the current step.
+	RemediationAttemptsAnnotation = "cluster.x-k8s.io/remediation-attempts"
+
+	// RemediationLastAttemptAnnotation is the annotation set on the machines being remediated by the Cluster's remediation
+	// escalation chain, recording the time of the last attempt in RFC3339 format.
+	RemediationLastAttemptAnnotation = "cluster.x-k8s.io/remediation-last-attempt"
+
+	// MachineDeletionProtectionAnnotation is the annotation used to protect machines from deletion; protected machines
+	// are never deleted by scale down, rollouts or remediation, and deletion requests for them are rejected.
+	// The annotation must be removed before deleting the machine.
+	MachineDeletionProtectionAnnotation = "cluster.x-k8s.io/deletion-protection"
+
+	// PreventDeleteAnnotation is the annotation used to protect a Cluster from deletion; deletion requests for
+	// the Cluster are rejected, and the deletion of a Cluster already being deleted is blocked until the annotation is removed.
+	PreventDeleteAnnotation = "cluster.x-k8s.io/prevent-delete"
+
+	// AutoscalerMinSizeAnnotation is the annotation used by the cluster autoscaler to read the minimum size of
+	// a MachineDeployment or a MachineSet; it is kept in sync with spec.minReplicas, when defined.
+	AutoscalerMinSizeAnnotation = "cluster.x-k8s.io/cluster-api-autoscaler-node-group-min-size"
+
+	// AutoscalerMaxSizeAnnotation is the annotation used by the cluster autoscaler to read the maximum size of
+	// a MachineDeployment or a MachineSet; it is kept in sync with spec.maxReplicas, when defined.
+	AutoscalerMaxSizeAnnotation = "cluster.x-k8s.io/cluster-api-autoscaler-node-group-max-size"
+
 	// ClusterSecretType defines the type of secret created by core components
 	ClusterSecretType corev1.SecretType = "cluster.x-k8s.io/secret" //nolint:gosec
 
@@ -190,3 +225,19 @@ type ObjectMeta struct {
 	// +patchStrategy=merge
 	OwnerReferences []metav1.OwnerReference `json:"ownerReferences,omitempty" patchStrategy:"merge" patchMergeKey:"uid"`
 }
+
+// MachineCapacity describes the resources and the node properties of the Machines of a MachineDeployment
+// or a MachineSet; it is used by autoscalers to scale from zero, when there are no Nodes to read them from.
+type MachineCapacity struct {
+	// Resources is the amount of resources of each Machine, e.g. cpu, memory and nvidia.com/gpu.
+	// +optional
+	Resources corev1.ResourceList `json:"resources,omitempty"`
+
+	// Labels are the labels of the Node of each Machine.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Taints are the taints of the Node of each Machine.
+	// +optional
+	Taints []corev1.Taint `json:"taints,omitempty"`
+}
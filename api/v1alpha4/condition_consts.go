@@ -98,10 +98,24 @@ const (
 	// DrainingFailedReason (Severity=Warning) documents a machine node drain operation failed.
 	DrainingFailedReason = "DrainingFailed"
 
-	// PreDrainDeleteHookSucceededCondition reports a machine waiting for a PreDrainDeleteHook before being delete.
+	// VolumeDetachSucceededCondition provide evidence of the status of the wait for the node volumes to be detached,
+	// which happens after the node drain during the machine deletion process.
+	VolumeDetachSucceededCondition ConditionType = "VolumeDetachSucceeded"
+
+	// WaitingForVolumeDetachReason (Severity=Info) documents a machine node waiting for its volumes to be detached.
+	WaitingForVolumeDetachReason = "WaitingForVolumeDetach"
+
+	// VolumeDetachTimedOutReason (Severity=Warning) documents a machine node whose volumes have not been detached
+	// within the NodeVolumeDetachTimeout; the machine deletion proceeds regardless.
+	VolumeDetachTimedOutReason = "VolumeDetachTimedOut"
+
+	// PreDrainDeleteHookSucceededCondition reports a machine waiting for a PreDrainDeleteHook before being drained.
+	// The condition is False as long as at least one annotation with the PreDrainDeleteHookAnnotationPrefix is present.
 	PreDrainDeleteHookSucceededCondition ConditionType = "PreDrainDeleteHookSucceeded"
 
-	// PreTerminateDeleteHookSucceededCondition reports a machine waiting for a PreDrainDeleteHook before being delete.
+	// PreTerminateDeleteHookSucceededCondition reports a machine waiting for a PreTerminateDeleteHook before its
+	// infrastructure is deleted. The condition is False as long as at least one annotation with the
+	// PreTerminateDeleteHookAnnotationPrefix is present.
 	PreTerminateDeleteHookSucceededCondition ConditionType = "PreTerminateDeleteHookSucceeded"
 
 	// WaitingExternalHookReason (Severity=Info) provide evidence that we are waiting for an external hook to complete.
@@ -121,6 +135,16 @@ const (
 
 	// UnhealthyNodeConditionReason is the reason used when a machine's node has one of the MachineHealthCheck's unhealthy conditions.
 	UnhealthyNodeConditionReason = "UnhealthyNode"
+
+	// UnhealthyMachineConditionReason is the reason used when a machine has one of the MachineHealthCheck's unhealthy machine conditions.
+	UnhealthyMachineConditionReason = "UnhealthyMachineCondition"
+
+	// MachineProbeSucceededCondition is set on machines by the MachineHealthCheck controller when the MachineHealthCheck
+	// defines a machine probe, reporting the result of the latest probe execution.
+	MachineProbeSucceededCondition ConditionType = "MachineProbeSucceeded"
+
+	// MachineProbeFailedReason is the reason used when the MachineHealthCheck's machine probe fails for a machine.
+	MachineProbeFailedReason = "MachineProbeFailed"
 )
 
 const (
@@ -152,6 +176,16 @@ const (
 	ExternalRemediationRequestCreationFailed = "ExternalRemediationRequestCreationFailed"
 )
 
+// Conditions and condition Reasons for the Machine's readiness gates
+const (
+	// ReadinessGatesReadyCondition reports whether all the conditions in the Machine's readiness gates are True.
+	ReadinessGatesReadyCondition ConditionType = "ReadinessGatesReady"
+
+	// ReadinessGatesNotReadyReason (Severity=Info) documents a Machine with conditions in its readiness gates
+	// that are not reported yet or that are not True.
+	ReadinessGatesNotReadyReason = "ReadinessGatesNotReady"
+)
+
 // Conditions and condition Reasons for the Machine's Node object
 const (
 	// MachineNodeHealthyCondition provides info about the operational state of the Kubernetes node hosted on the machine by summarizing  node conditions.
@@ -183,4 +217,87 @@ const (
 	// TooManyUnhealthy is the reason used when too many Machines are unhealthy and the MachineHealthCheck is blocked
 	// from making any further remediations.
 	TooManyUnhealthyReason = "TooManyUnhealthy"
+
+	// RemediationRateLimitedReason is the reason used when the MachineHealthCheck is deferring remediations because
+	// of the remediation rate limit or of the backoff for remediations triggered repeatedly for the machines
+	// of the same owner and for the same reason.
+	RemediationRateLimitedReason = "RemediationRateLimited"
+)
+
+// Conditions and condition Reasons for the Cluster deletion
+
+const (
+	// DeletingCondition reports the progress of the Cluster deletion. It is set to True with the reason documenting
+	// the current deletion phase, or to False if the deletion is blocked.
+	DeletingCondition ConditionType = "Deleting"
+
+	// DeletionBlockedReason (Severity=Warning) documents a Cluster deletion being blocked by the prevent-delete annotation
+	// or by a failed pre-delete check.
+	DeletionBlockedReason = "DeletionBlocked"
+
+	// DeletingMachinesReason documents the deletion of the Cluster's MachineDeployments, MachineSets, MachinePools and Machines.
+	DeletingMachinesReason = "DeletingMachines"
+
+	// DeletingWorkersReason documents the deletion of the Cluster's MachineDeployments, MachineSets, MachinePools and
+	// worker machines, before deleting the control plane.
+	DeletingWorkersReason = "DeletingWorkers"
+
+	// DeletingControlPlaneReason documents the deletion of the Cluster's control plane.
+	DeletingControlPlaneReason = "DeletingControlPlane"
+
+	// DeletingInfrastructureReason documents the deletion of the Cluster's infrastructure.
+	DeletingInfrastructureReason = "DeletingInfrastructure"
+)
+
+// Conditions and condition Reasons for maintenance windows
+
+const (
+	// DisruptionAllowedCondition is set on objects performing disruptive actions on machines, such as rollouts,
+	// remediation and scale down, when the Cluster defines maintenance windows. It is set to False when a
+	// disruptive action is deferred because none of the Cluster's maintenance windows is open.
+	DisruptionAllowedCondition ConditionType = "DisruptionAllowed"
+
+	// OutsideMaintenanceWindowReason (Severity=Info) documents a disruptive action being deferred until the next
+	// maintenance window opens.
+	OutsideMaintenanceWindowReason = "OutsideMaintenanceWindow"
+)
+
+// Conditions and condition Reasons for the MachineSet, MachineDeployment and KubeadmControlPlane objects
+
+const (
+	// MachinesDeletableCondition documents whether the machines a MachineSet, MachineDeployment or KubeadmControlPlane
+	// needs to delete in order to scale down or to complete a rollout can be deleted.
+	MachinesDeletableCondition ConditionType = "MachinesDeletable"
+
+	// MachineDeletionProtectedReason (Severity=Warning) documents a MachineSet or KubeadmControlPlane scale down or a
+	// MachineDeployment rollout being stalled because the machines to be deleted are protected from deletion.
+	MachineDeletionProtectedReason = "MachineDeletionProtected"
+)
+
+// Conditions and condition Reasons for the MachineDeployment object
+
+const (
+	// MachineDeploymentProgressingCondition documents whether the rollout of a MachineDeployment makes progress
+	// within spec.progressDeadlineSeconds. Progress is not estimated while the MachineDeployment is paused or
+	// its rollout is deferred.
+	MachineDeploymentProgressingCondition ConditionType = "Progressing"
+
+	// ProgressDeadlineExceededReason (Severity=Warning) documents a MachineDeployment rollout that did not make
+	// progress within spec.progressDeadlineSeconds.
+	ProgressDeadlineExceededReason = "ProgressDeadlineExceeded"
+
+	// RolledBackReason (Severity=Warning) documents a MachineDeployment whose machine template has been
+	// automatically rolled back to the previous revision after a rollout exceeded spec.progressDeadlineSeconds.
+	RolledBackReason = "RolledBack"
+)
+
+// Conditions and condition Reasons for the Cluster pause
+
+const (
+	// PausedCondition is set to True on a Cluster when it is paused, either by spec.paused, by the paused annotation
+	// or by pause reason annotations; its message lists the reasons. The condition is removed when the Cluster is resumed.
+	PausedCondition ConditionType = "Paused"
+
+	// ClusterPausedReason documents a Cluster being paused.
+	ClusterPausedReason = "ClusterPaused"
 )
@@ -0,0 +1,182 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha4
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const machineScaleWebhookPath = "/validate-cluster-x-k8s-io-v1alpha4-scale"
+
+// +kubebuilder:webhook:verbs=update,path=/validate-cluster-x-k8s-io-v1alpha4-scale,mutating=false,failurePolicy=fail,matchPolicy=Equivalent,groups=cluster.x-k8s.io,resources=machinedeployments/scale;machinesets/scale,versions=v1alpha4,name=validation-scale.machine.cluster.x-k8s.io,sideEffects=None,admissionReviewVersions=v1beta1
+
+// MachineScaleValidator validates the requests to the scale subresource of MachineDeployments and MachineSets,
+// rejecting the ones setting replicas outside of the minReplicas and maxReplicas bounds.
+// +kubebuilder:object:generate=false
+type MachineScaleValidator struct {
+	Client client.Reader
+}
+
+var _ admission.Handler = &MachineScaleValidator{}
+
+// SetupWebhookWithManager registers the MachineScaleValidator webhook with the manager's webhook server.
+func (v *MachineScaleValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(machineScaleWebhookPath, &webhook.Admission{Handler: v})
+	return nil
+}
+
+// Handle implements admission.Handler.
+func (v *MachineScaleValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.SubResource != "scale" {
+		return admission.Allowed("")
+	}
+
+	scale := &autoscalingv1.Scale{}
+	if err := json.Unmarshal(req.Object.Raw, scale); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var kind string
+	var minReplicas, maxReplicas *int32
+	key := client.ObjectKey{Namespace: req.Namespace, Name: req.Name}
+	switch req.Resource.Resource {
+	case "machinedeployments":
+		md := &MachineDeployment{}
+		if err := v.Client.Get(ctx, key, md); err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		kind, minReplicas, maxReplicas = "MachineDeployment", md.Spec.MinReplicas, md.Spec.MaxReplicas
+	case "machinesets":
+		ms := &MachineSet{}
+		if err := v.Client.Get(ctx, key, ms); err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		kind, minReplicas, maxReplicas = "MachineSet", ms.Spec.MinReplicas, ms.Spec.MaxReplicas
+	default:
+		return admission.Allowed("")
+	}
+
+	if allErrs := validateReplicaBounds(&scale.Spec.Replicas, minReplicas, maxReplicas, field.NewPath("spec")); len(allErrs) > 0 {
+		status := apierrors.NewInvalid(GroupVersion.WithKind(kind).GroupKind(), req.Name, allErrs).ErrStatus
+		return admission.Response{
+			AdmissionResponse: admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result:  &status,
+			},
+		}
+	}
+	return admission.Allowed("")
+}
+
+// validateReplicaBounds validates minReplicas and maxReplicas, and that replicas are within them.
+func validateReplicaBounds(replicas, minReplicas, maxReplicas *int32, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if minReplicas != nil && *minReplicas < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("minReplicas"), *minReplicas, "must be greater than or equal to 0"))
+	}
+	if maxReplicas != nil && *maxReplicas < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxReplicas"), *maxReplicas, "must be greater than or equal to 0"))
+	}
+	if minReplicas != nil && maxReplicas != nil && *minReplicas > *maxReplicas {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxReplicas"), *maxReplicas, fmt.Sprintf("must be greater than or equal to minReplicas %d", *minReplicas)))
+	}
+	if len(allErrs) > 0 || replicas == nil {
+		return allErrs
+	}
+
+	if minReplicas != nil && *replicas < *minReplicas {
+		allErrs = append(allErrs, field.Invalid(path.Child("replicas"), *replicas, fmt.Sprintf("must be greater than or equal to minReplicas %d", *minReplicas)))
+	}
+	if maxReplicas != nil && *replicas > *maxReplicas {
+		allErrs = append(allErrs, field.Invalid(path.Child("replicas"), *replicas, fmt.Sprintf("must be less than or equal to maxReplicas %d", *maxReplicas)))
+	}
+	return allErrs
+}
+
+// validateMachineCapacity validates the resources, labels and taints of a MachineCapacity.
+func validateMachineCapacity(capacity *MachineCapacity, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if capacity == nil {
+		return allErrs
+	}
+
+	for name, quantity := range capacity.Resources {
+		if quantity.Sign() < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("resources").Key(string(name)), quantity.String(), "must be greater than or equal to 0"))
+		}
+	}
+
+	allErrs = append(allErrs, metav1validation.ValidateLabels(capacity.Labels, path.Child("labels"))...)
+
+	for i, taint := range capacity.Taints {
+		taintPath := path.Child("taints").Index(i)
+		for _, msg := range validation.IsQualifiedName(taint.Key) {
+			allErrs = append(allErrs, field.Invalid(taintPath.Child("key"), taint.Key, msg))
+		}
+		switch taint.Effect {
+		case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			allErrs = append(allErrs, field.NotSupported(taintPath.Child("effect"), taint.Effect,
+				[]string{string(corev1.TaintEffectNoSchedule), string(corev1.TaintEffectPreferNoSchedule), string(corev1.TaintEffectNoExecute)}))
+		}
+	}
+	return allErrs
+}
+
+// setAutoscalerAnnotations sets the annotations read by the cluster autoscaler from minReplicas and maxReplicas, when defined;
+// annotations set by the user are never removed, so that autoscaling configured through them keeps working.
+func setAutoscalerAnnotations(annotations map[string]string, minReplicas, maxReplicas *int32) map[string]string {
+	if minReplicas == nil && maxReplicas == nil {
+		return annotations
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if minReplicas != nil {
+		annotations[AutoscalerMinSizeAnnotation] = strconv.Itoa(int(*minReplicas))
+	}
+	if maxReplicas != nil {
+		annotations[AutoscalerMaxSizeAnnotation] = strconv.Itoa(int(*maxReplicas))
+	}
+	return annotations
+}
+
+// defaultReplicas returns the default number of replicas, i.e. minReplicas if defined, otherwise 1.
+func defaultReplicas(minReplicas *int32) *int32 {
+	if minReplicas != nil {
+		return pointer.Int32Ptr(*minReplicas)
+	}
+	return pointer.Int32Ptr(1)
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha4
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestValidateReplicaBounds(t *testing.T) {
+	tests := []struct {
+		name        string
+		replicas    *int32
+		minReplicas *int32
+		maxReplicas *int32
+		expectErr   bool
+	}{
+		{
+			name:     "should succeed without bounds",
+			replicas: pointer.Int32Ptr(5),
+		},
+		{
+			name:        "should succeed with replicas within the bounds",
+			replicas:    pointer.Int32Ptr(2),
+			minReplicas: pointer.Int32Ptr(0),
+			maxReplicas: pointer.Int32Ptr(3),
+		},
+		{
+			name:        "should succeed with nil replicas",
+			minReplicas: pointer.Int32Ptr(1),
+			maxReplicas: pointer.Int32Ptr(3),
+		},
+		{
+			name:        "should fail with negative minReplicas",
+			minReplicas: pointer.Int32Ptr(-1),
+			expectErr:   true,
+		},
+		{
+			name:        "should fail with minReplicas greater than maxReplicas",
+			minReplicas: pointer.Int32Ptr(3),
+			maxReplicas: pointer.Int32Ptr(1),
+			expectErr:   true,
+		},
+		{
+			name:        "should fail with replicas lower than minReplicas",
+			replicas:    pointer.Int32Ptr(0),
+			minReplicas: pointer.Int32Ptr(1),
+			expectErr:   true,
+		},
+		{
+			name:        "should fail with replicas greater than maxReplicas",
+			replicas:    pointer.Int32Ptr(4),
+			maxReplicas: pointer.Int32Ptr(3),
+			expectErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			errs := validateReplicaBounds(tt.replicas, tt.minReplicas, tt.maxReplicas, field.NewPath("spec"))
+			if tt.expectErr {
+				g.Expect(errs).NotTo(BeEmpty())
+			} else {
+				g.Expect(errs).To(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestValidateMachineCapacity(t *testing.T) {
+	tests := []struct {
+		name      string
+		capacity  *MachineCapacity
+		expectErr bool
+	}{
+		{
+			name: "should succeed without capacity",
+		},
+		{
+			name: "should succeed with a valid capacity",
+			capacity: &MachineCapacity{
+				Resources: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("16Gi"),
+				},
+				Labels: map[string]string{"node.kubernetes.io/instance-type": "large"},
+				Taints: []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}},
+			},
+		},
+		{
+			name: "should fail with negative resources",
+			capacity: &MachineCapacity{
+				Resources: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("-1")},
+			},
+			expectErr: true,
+		},
+		{
+			name: "should fail with invalid labels",
+			capacity: &MachineCapacity{
+				Labels: map[string]string{"not a valid key": "value"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "should fail with an invalid taint effect",
+			capacity: &MachineCapacity{
+				Taints: []corev1.Taint{{Key: "dedicated", Effect: "Invalid"}},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			errs := validateMachineCapacity(tt.capacity, field.NewPath("spec", "capacity"))
+			if tt.expectErr {
+				g.Expect(errs).NotTo(BeEmpty())
+			} else {
+				g.Expect(errs).To(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestSetAutoscalerAnnotations(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(setAutoscalerAnnotations(nil, nil, nil)).To(BeNil())
+
+	annotations := setAutoscalerAnnotations(map[string]string{"foo": "bar"}, pointer.Int32Ptr(1), pointer.Int32Ptr(5))
+	g.Expect(annotations).To(Equal(map[string]string{
+		"foo":                       "bar",
+		AutoscalerMinSizeAnnotation: "1",
+		AutoscalerMaxSizeAnnotation: "5",
+	}))
+
+	annotations = setAutoscalerAnnotations(annotations, pointer.Int32Ptr(2), nil)
+	g.Expect(annotations).To(Equal(map[string]string{
+		"foo":                       "bar",
+		AutoscalerMinSizeAnnotation: "2",
+		AutoscalerMaxSizeAnnotation: "5",
+	}))
+
+	// Annotations set by the user without the bounds are preserved.
+	annotations = setAutoscalerAnnotations(annotations, nil, nil)
+	g.Expect(annotations).To(Equal(map[string]string{
+		"foo":                       "bar",
+		AutoscalerMinSizeAnnotation: "2",
+		AutoscalerMaxSizeAnnotation: "5",
+	}))
+}
+
+func TestDefaultReplicasFromMinReplicas(t *testing.T) {
+	g := NewWithT(t)
+
+	md := &MachineDeployment{Spec: MachineDeploymentSpec{MinReplicas: pointer.Int32Ptr(3)}}
+	md.Default()
+	g.Expect(md.Spec.Replicas).To(Equal(pointer.Int32Ptr(3)))
+	g.Expect(md.ValidateCreate()).To(Succeed())
+
+	md = &MachineDeployment{Spec: MachineDeploymentSpec{MinReplicas: pointer.Int32Ptr(3), Replicas: pointer.Int32Ptr(1)}}
+	md.Default()
+	g.Expect(md.Spec.Replicas).To(Equal(pointer.Int32Ptr(1)))
+	g.Expect(md.ValidateCreate()).NotTo(Succeed())
+
+	ms := &MachineSet{Spec: MachineSetSpec{MinReplicas: pointer.Int32Ptr(3)}}
+	ms.Default()
+	g.Expect(ms.Spec.Replicas).To(Equal(pointer.Int32Ptr(3)))
+	g.Expect(ms.ValidateCreate()).To(Succeed())
+
+	ms = &MachineSet{}
+	ms.Default()
+	g.Expect(ms.Spec.Replicas).To(Equal(pointer.Int32Ptr(1)))
+}
+
+func TestMachineScaleValidatorHandle(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(AddToScheme(scheme)).To(Succeed())
+
+	md := &MachineDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "md", Namespace: "default"},
+		Spec: MachineDeploymentSpec{
+			MinReplicas: pointer.Int32Ptr(1),
+			MaxReplicas: pointer.Int32Ptr(3),
+		},
+	}
+	ms := &MachineSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "default"},
+	}
+
+	v := &MachineScaleValidator{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(md, ms).Build()}
+
+	scaleRequest := func(resource, name string, replicas int32) admission.Request {
+		raw, err := json.Marshal(&autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: replicas}})
+		g.Expect(err).NotTo(HaveOccurred())
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation:   admissionv1.Update,
+			Name:        name,
+			Namespace:   "default",
+			Resource:    metav1.GroupVersionResource{Group: GroupVersion.Group, Version: GroupVersion.Version, Resource: resource},
+			SubResource: "scale",
+			Object:      runtime.RawExtension{Raw: raw},
+		}}
+	}
+
+	g.Expect(v.Handle(context.Background(), scaleRequest("machinedeployments", "md", 2)).Allowed).To(BeTrue())
+	g.Expect(v.Handle(context.Background(), scaleRequest("machinedeployments", "md", 0)).Allowed).To(BeFalse())
+	g.Expect(v.Handle(context.Background(), scaleRequest("machinedeployments", "md", 4)).Allowed).To(BeFalse())
+
+	// Without bounds any number of replicas is allowed.
+	g.Expect(v.Handle(context.Background(), scaleRequest("machinesets", "ms", 10)).Allowed).To(BeTrue())
+}
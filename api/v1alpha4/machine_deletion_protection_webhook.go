@@ -0,0 +1,117 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha4
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const machineDeletionProtectionWebhookPath = "/validate-cluster-x-k8s-io-v1alpha4-machine-deletion-protection"
+
+// +kubebuilder:webhook:verbs=delete,path=/validate-cluster-x-k8s-io-v1alpha4-machine-deletion-protection,mutating=false,failurePolicy=fail,matchPolicy=Equivalent,groups=cluster.x-k8s.io,resources=machines,versions=v1alpha4,name=validation-deletion-protection.machine.cluster.x-k8s.io,sideEffects=None,admissionReviewVersions=v1beta1
+
+// MachineDeletionProtectionValidator rejects the deletion of Machines with the MachineDeletionProtectionAnnotation.
+// The deletion is allowed anyway if the Cluster or an owner of the Machine is being deleted or is gone, so that
+// protected Machines do not block the deletion of a Cluster or the garbage collection of their owners.
+// +kubebuilder:object:generate=false
+type MachineDeletionProtectionValidator struct {
+	Client client.Reader
+
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &MachineDeletionProtectionValidator{}
+var _ admission.DecoderInjector = &MachineDeletionProtectionValidator{}
+
+// SetupWebhookWithManager registers the MachineDeletionProtectionValidator webhook with the manager's webhook server.
+func (v *MachineDeletionProtectionValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(machineDeletionProtectionWebhookPath, &webhook.Admission{Handler: v})
+	return nil
+}
+
+// InjectDecoder implements admission.DecoderInjector.
+func (v *MachineDeletionProtectionValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle implements admission.Handler.
+func (v *MachineDeletionProtectionValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	m := &Machine{}
+	if err := v.decoder.DecodeRaw(req.OldObject, m); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if _, ok := m.Annotations[MachineDeletionProtectionAnnotation]; !ok || !m.DeletionTimestamp.IsZero() {
+		return admission.Allowed("")
+	}
+
+	// Allow the deletion if the Cluster is being deleted.
+	cluster := &Cluster{}
+	if err := v.Client.Get(ctx, client.ObjectKey{Namespace: m.Namespace, Name: m.Spec.ClusterName}, cluster); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		return admission.Allowed("")
+	}
+	if !cluster.DeletionTimestamp.IsZero() {
+		return admission.Allowed("")
+	}
+
+	// Allow the deletion if an owner is being deleted, e.g. in case of foreground deletion, or is gone, e.g. in case
+	// of background deletion by the garbage collector.
+	for _, ref := range m.OwnerReferences {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		owner := &unstructured.Unstructured{}
+		owner.SetGroupVersionKind(gv.WithKind(ref.Kind))
+		if err := v.Client.Get(ctx, client.ObjectKey{Namespace: m.Namespace, Name: ref.Name}, owner); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+			return admission.Allowed("")
+		}
+		if owner.GetUID() != ref.UID || owner.GetDeletionTimestamp() != nil {
+			return admission.Allowed("")
+		}
+	}
+
+	status := apierrors.NewForbidden(
+		GroupVersion.WithResource("machines").GroupResource(),
+		m.Name,
+		fmt.Errorf("machine is protected from deletion, remove the %q annotation before deleting it", MachineDeletionProtectionAnnotation),
+	).ErrStatus
+	return admission.Response{
+		AdmissionResponse: admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result:  &status,
+		},
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha4
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestMachineDeletionProtectionValidatorHandle(t *testing.T) {
+	deletionTimestamp := metav1.Now()
+
+	cluster := &Cluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"}}
+	deletingCluster := &Cluster{ObjectMeta: metav1.ObjectMeta{Name: "deleting-cluster", Namespace: "default", DeletionTimestamp: &deletionTimestamp, Finalizers: []string{ClusterFinalizer}}}
+	machineSet := &MachineSet{ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "default", UID: "ms-uid"}}
+	deletingMachineSet := &MachineSet{ObjectMeta: metav1.ObjectMeta{Name: "deleting-ms", Namespace: "default", UID: "deleting-ms-uid", DeletionTimestamp: &deletionTimestamp, Finalizers: []string{"test"}}}
+
+	ownerRef := func(ms *MachineSet) metav1.OwnerReference {
+		return metav1.OwnerReference{APIVersion: GroupVersion.String(), Kind: "MachineSet", Name: ms.Name, UID: ms.UID}
+	}
+
+	tests := []struct {
+		name        string
+		clusterName string
+		annotations map[string]string
+		owners      []metav1.OwnerReference
+		deleting    bool
+		expectAllow bool
+	}{
+		{
+			name:        "should allow deleting a machine without the deletion protection annotation",
+			clusterName: cluster.Name,
+			annotations: map[string]string{DeleteMachineAnnotation: ""},
+			expectAllow: true,
+		},
+		{
+			name:        "should reject deleting a machine with the deletion protection annotation",
+			clusterName: cluster.Name,
+			annotations: map[string]string{MachineDeletionProtectionAnnotation: ""},
+			expectAllow: false,
+		},
+		{
+			name:        "should reject deleting a machine with the deletion protection annotation and an owner not being deleted",
+			clusterName: cluster.Name,
+			annotations: map[string]string{MachineDeletionProtectionAnnotation: ""},
+			owners:      []metav1.OwnerReference{ownerRef(machineSet)},
+			expectAllow: false,
+		},
+		{
+			name:        "should allow deleting a machine with the deletion protection annotation already being deleted",
+			clusterName: cluster.Name,
+			annotations: map[string]string{MachineDeletionProtectionAnnotation: ""},
+			deleting:    true,
+			expectAllow: true,
+		},
+		{
+			name:        "should allow deleting a machine with the deletion protection annotation if the cluster is being deleted",
+			clusterName: deletingCluster.Name,
+			annotations: map[string]string{MachineDeletionProtectionAnnotation: ""},
+			expectAllow: true,
+		},
+		{
+			name:        "should allow deleting a machine with the deletion protection annotation if the cluster is gone",
+			clusterName: "gone",
+			annotations: map[string]string{MachineDeletionProtectionAnnotation: ""},
+			expectAllow: true,
+		},
+		{
+			name:        "should allow deleting a machine with the deletion protection annotation if an owner is being deleted",
+			clusterName: cluster.Name,
+			annotations: map[string]string{MachineDeletionProtectionAnnotation: ""},
+			owners:      []metav1.OwnerReference{ownerRef(deletingMachineSet)},
+			expectAllow: true,
+		},
+		{
+			name:        "should allow deleting a machine with the deletion protection annotation if an owner is gone",
+			clusterName: cluster.Name,
+			annotations: map[string]string{MachineDeletionProtectionAnnotation: ""},
+			owners: []metav1.OwnerReference{
+				{APIVersion: GroupVersion.String(), Kind: "MachineSet", Name: "gone", UID: "gone-uid"},
+			},
+			expectAllow: true,
+		},
+		{
+			name:        "should allow deleting a machine with the deletion protection annotation if an owner has been recreated",
+			clusterName: cluster.Name,
+			annotations: map[string]string{MachineDeletionProtectionAnnotation: ""},
+			owners: []metav1.OwnerReference{
+				{APIVersion: GroupVersion.String(), Kind: "MachineSet", Name: machineSet.Name, UID: "old-uid"},
+			},
+			expectAllow: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			scheme := runtime.NewScheme()
+			g.Expect(AddToScheme(scheme)).To(Succeed())
+			decoder, err := admission.NewDecoder(scheme)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			objs := []client.Object{cluster.DeepCopy(), deletingCluster.DeepCopy(), machineSet.DeepCopy(), deletingMachineSet.DeepCopy()}
+			v := &MachineDeletionProtectionValidator{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()}
+			g.Expect(v.InjectDecoder(decoder)).To(Succeed())
+
+			m := &Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "machine",
+					Namespace:       "default",
+					Annotations:     tt.annotations,
+					OwnerReferences: tt.owners,
+				},
+				Spec: MachineSpec{ClusterName: tt.clusterName},
+			}
+			if tt.deleting {
+				m.DeletionTimestamp = &deletionTimestamp
+			}
+			raw, err := json.Marshal(m)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			resp := v.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Delete,
+				OldObject: runtime.RawExtension{Raw: raw},
+			}})
+			g.Expect(resp.Allowed).To(Equal(tt.expectAllow))
+			if !tt.expectAllow {
+				g.Expect(resp.Result.Code).To(BeEquivalentTo(403))
+			}
+		})
+	}
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha4
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	capierrors "sigs.k8s.io/cluster-api/errors"
@@ -32,6 +34,9 @@ const (
 	// ExcludeNodeDrainingAnnotation annotation explicitly skips node draining if set
 	ExcludeNodeDrainingAnnotation = "machine.cluster.x-k8s.io/exclude-node-draining"
 
+	// ExcludeWaitForNodeVolumeDetachAnnotation annotation explicitly skips the waiting for node volume detaching if set
+	ExcludeWaitForNodeVolumeDetachAnnotation = "machine.cluster.x-k8s.io/exclude-wait-for-node-volume-detach"
+
 	// MachineSetLabelName is the label set on machines if they're controlled by MachineSet
 	MachineSetLabelName = "cluster.x-k8s.io/set-name"
 
@@ -51,6 +56,10 @@ const (
 	PreTerminateDeleteHookAnnotationPrefix = "pre-terminate.delete.hook.machine.cluster.x-k8s.io"
 )
 
+// DefaultNodeVolumeDetachTimeout is the time spent waiting for the volumes attached to the node of a Machine
+// to be detached when NodeVolumeDetachTimeout is not set.
+const DefaultNodeVolumeDetachTimeout = 10 * time.Minute
+
 // ANCHOR: MachineSpec
 
 // MachineSpec defines the desired state of Machine
@@ -95,10 +104,84 @@ type MachineSpec struct {
 	// NOTE: NodeDrainTimeout is different from `kubectl drain --timeout`
 	// +optional
 	NodeDrainTimeout *metav1.Duration `json:"nodeDrainTimeout,omitempty"`
+
+	// NodeVolumeDetachTimeout is the total amount of time that the controller will spend on waiting for all the volumes
+	// attached to the node to be detached, before deleting the infrastructure of the Machine.
+	// Defaults to 10 minutes when not set; 0 means that the volumes can be detached without any time limitations.
+	// +optional
+	NodeVolumeDetachTimeout *metav1.Duration `json:"nodeVolumeDetachTimeout,omitempty"`
+
+	// NodeDrainOptions configures how the node is drained before the Machine is deleted.
+	// If not set, all the pods are evicted from the node with their own termination grace period,
+	// pods using emptyDir volumes are evicted and DaemonSet-managed pods are left running.
+	// +optional
+	NodeDrainOptions *NodeDrainOptions `json:"nodeDrainOptions,omitempty"`
+
+	// ReadinessGates specifies additional conditions, set on the Machine by external controllers, to include when
+	// evaluating the Machine readiness. The Machine is ready, and it is counted as ready and available by MachineSets
+	// and MachineDeployments, only when its Node is ready and all the conditions in the readiness gates are True.
+	// +optional
+	ReadinessGates []MachineReadinessGate `json:"readinessGates,omitempty"`
 }
 
 // ANCHOR_END: MachineSpec
 
+// ANCHOR: MachineReadinessGate
+
+// MachineReadinessGate contains the type of a condition to include when evaluating the Machine readiness.
+type MachineReadinessGate struct {
+	// ConditionType refers to a condition in the Machine's condition list with matching type.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:MinLength=1
+	ConditionType ConditionType `json:"conditionType"`
+}
+
+// ANCHOR_END: MachineReadinessGate
+
+// ANCHOR: NodeDrainOptions
+
+// NodeDrainOptions defines the options used when draining the node of a Machine being deleted.
+type NodeDrainOptions struct {
+	// GracePeriodSeconds overrides the termination grace period of the pods evicted from the node.
+	// If not set or negative, the grace period defined in each pod is used.
+	// +optional
+	GracePeriodSeconds *int32 `json:"gracePeriodSeconds,omitempty"`
+
+	// SkipPodSelector is a label query over the pods that must not be evicted from the node.
+	// +optional
+	SkipPodSelector *metav1.LabelSelector `json:"skipPodSelector,omitempty"`
+
+	// SkipNamespaces is a list of namespaces whose pods must not be evicted from the node.
+	// +optional
+	SkipNamespaces []string `json:"skipNamespaces,omitempty"`
+
+	// DeleteEmptyDirData allows evicting pods using emptyDir volumes, losing the data stored in them.
+	// If set to false, pods using emptyDir volumes are not evicted from the node.
+	// Defaults to true.
+	// +optional
+	DeleteEmptyDirData *bool `json:"deleteEmptyDirData,omitempty"`
+
+	// DaemonSetPods defines how DaemonSet-managed pods are handled during the drain.
+	// Valid values are "Ignore" (the default), which leaves them running on the node,
+	// and "Evict", which evicts them once all the other pods have left the node.
+	// +kubebuilder:validation:Enum=Ignore;Evict
+	// +optional
+	DaemonSetPods DaemonSetPodsPolicy `json:"daemonSetPods,omitempty"`
+}
+
+// DaemonSetPodsPolicy defines how DaemonSet-managed pods are handled when draining a node.
+type DaemonSetPodsPolicy string
+
+const (
+	// IgnoreDaemonSetPodsPolicy leaves DaemonSet-managed pods running on the node.
+	IgnoreDaemonSetPodsPolicy DaemonSetPodsPolicy = "Ignore"
+
+	// EvictDaemonSetPodsPolicy evicts DaemonSet-managed pods once all the other pods have left the node.
+	EvictDaemonSetPodsPolicy DaemonSetPodsPolicy = "Evict"
+)
+
+// ANCHOR_END: NodeDrainOptions
+
 // ANCHOR: MachineStatus
 
 // MachineStatus defines the observed state of Machine
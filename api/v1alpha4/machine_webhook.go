@@ -22,6 +22,7 @@ import (
 	"strings"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -127,8 +128,53 @@ func (m *Machine) validate(old *Machine) error {
 		}
 	}
 
+	allErrs = append(allErrs, validateNodeDrainOptions(m.Spec.NodeDrainOptions, field.NewPath("spec", "nodeDrainOptions"))...)
+	allErrs = append(allErrs, validateReadinessGates(m.Spec.ReadinessGates, field.NewPath("spec", "readinessGates"))...)
+
 	if len(allErrs) == 0 {
 		return nil
 	}
 	return apierrors.NewInvalid(GroupVersion.WithKind("Machine").GroupKind(), m.Name, allErrs)
 }
+
+// validateNodeDrainOptions validates the NodeDrainOptions of a Machine or of a Machine template.
+func validateNodeDrainOptions(options *NodeDrainOptions, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if options == nil {
+		return allErrs
+	}
+
+	if options.SkipPodSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(options.SkipPodSelector); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("skipPodSelector"), options.SkipPodSelector, err.Error()))
+		}
+	}
+
+	return allErrs
+}
+
+// validateReadinessGates validates the ReadinessGates of a Machine or of a Machine template; the condition types
+// must be unique and must not be conditions managed by Cluster API.
+func validateReadinessGates(gates []MachineReadinessGate, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	seen := map[ConditionType]bool{}
+	for i, gate := range gates {
+		conditionPath := path.Index(i).Child("conditionType")
+		if gate.ConditionType == "" {
+			allErrs = append(allErrs, field.Required(conditionPath, "conditionType must be set"))
+			continue
+		}
+		if seen[gate.ConditionType] {
+			allErrs = append(allErrs, field.Duplicate(conditionPath, gate.ConditionType))
+		}
+		seen[gate.ConditionType] = true
+
+		switch gate.ConditionType {
+		case ReadyCondition, ReadinessGatesReadyCondition, InfrastructureReadyCondition, BootstrapReadyCondition,
+			MachineHealthCheckSuccededCondition, MachineOwnerRemediatedCondition, MachineNodeHealthyCondition:
+			allErrs = append(allErrs, field.Invalid(conditionPath, gate.ConditionType, "cannot be a condition managed by Cluster API"))
+		}
+	}
+	return allErrs
+}
@@ -246,3 +246,113 @@ func TestMachineVersionValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestMachineNodeDrainOptionsValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   *NodeDrainOptions
+		expectErr bool
+	}{
+		{
+			name:      "should succeed when node drain options are not set",
+			options:   nil,
+			expectErr: false,
+		},
+		{
+			name: "should succeed when given a valid skipPodSelector",
+			options: &NodeDrainOptions{
+				SkipPodSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": "storage"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "should return error when given an invalid skipPodSelector",
+			options: &NodeDrainOptions{
+				SkipPodSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "app", Operator: "Unknown"},
+					},
+				},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			m := &Machine{
+				Spec: MachineSpec{
+					Bootstrap:        Bootstrap{ConfigRef: nil, DataSecretName: pointer.StringPtr("test")},
+					NodeDrainOptions: tt.options,
+				},
+			}
+
+			if tt.expectErr {
+				g.Expect(m.ValidateCreate()).NotTo(Succeed())
+				g.Expect(m.ValidateUpdate(m)).NotTo(Succeed())
+			} else {
+				g.Expect(m.ValidateCreate()).To(Succeed())
+				g.Expect(m.ValidateUpdate(m)).To(Succeed())
+			}
+		})
+	}
+}
+
+func TestMachineReadinessGatesValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		gates     []MachineReadinessGate
+		expectErr bool
+	}{
+		{
+			name:      "should succeed when readiness gates are not set",
+			gates:     nil,
+			expectErr: false,
+		},
+		{
+			name:      "should succeed when given valid readiness gates",
+			gates:     []MachineReadinessGate{{ConditionType: "CNIReady"}, {ConditionType: "StorageAttached"}},
+			expectErr: false,
+		},
+		{
+			name:      "should return error when a condition type is empty",
+			gates:     []MachineReadinessGate{{ConditionType: ""}},
+			expectErr: true,
+		},
+		{
+			name:      "should return error when a condition type is duplicated",
+			gates:     []MachineReadinessGate{{ConditionType: "CNIReady"}, {ConditionType: "CNIReady"}},
+			expectErr: true,
+		},
+		{
+			name:      "should return error when a condition type is managed by Cluster API",
+			gates:     []MachineReadinessGate{{ConditionType: ReadyCondition}},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			m := &Machine{
+				Spec: MachineSpec{
+					Bootstrap:      Bootstrap{ConfigRef: nil, DataSecretName: pointer.StringPtr("test")},
+					ReadinessGates: tt.gates,
+				},
+			}
+
+			if tt.expectErr {
+				g.Expect(m.ValidateCreate()).NotTo(Succeed())
+				g.Expect(m.ValidateUpdate(m)).NotTo(Succeed())
+			} else {
+				g.Expect(m.ValidateCreate()).To(Succeed())
+				g.Expect(m.ValidateUpdate(m)).To(Succeed())
+			}
+		})
+	}
+}
@@ -50,12 +50,29 @@ type MachineDeploymentSpec struct {
 	// +kubebuilder:validation:MinLength=1
 	ClusterName string `json:"clusterName"`
 
-	// Number of desired machines. Defaults to 1.
+	// Number of desired machines. Defaults to minReplicas if set, otherwise to 1.
 	// This is a pointer to distinguish between explicit zero and not specified.
 	// +optional
-	// +kubebuilder:default=1
 	Replicas *int32 `json:"replicas,omitempty"`
 
+	// MinReplicas is the minimum number of replicas autoscalers can scale the MachineDeployment down to.
+	// When defined, replicas must not be lower than minReplicas.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the maximum number of replicas autoscalers can scale the MachineDeployment up to.
+	// When defined, replicas must not be greater than maxReplicas.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+
+	// Capacity describes the resources and the node properties of the Machines, used by autoscalers to scale
+	// the MachineDeployment from zero. Resources not defined here are read from the status.capacity field of the
+	// infrastructure template, when the infrastructure provider supports it.
+	// +optional
+	Capacity *MachineCapacity `json:"capacity,omitempty"`
+
 	// Label selector for machines. Existing MachineSets whose machines are
 	// selected by this will be the ones affected by this deployment.
 	// It must match the machine template's labels.
@@ -92,6 +109,12 @@ type MachineDeploymentSpec struct {
 	// reason will be surfaced in the deployment status. Note that progress will
 	// not be estimated during the time a deployment is paused. Defaults to 600s.
 	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
+	// AutoRollback enables the automatic rollback of the machine template to the one
+	// of the previous MachineSet revision when a rollout does not make progress within
+	// ProgressDeadlineSeconds. Defaults to false.
+	// +optional
+	AutoRollback bool `json:"autoRollback,omitempty"`
 }
 
 // ANCHOR_END: MachineDeploymentSpec
@@ -204,6 +227,21 @@ type MachineDeploymentStatus struct {
 	// Phase represents the current phase of a MachineDeployment (ScalingUp, ScalingDown, Running, Failed, or Unknown).
 	// +optional
 	Phase string `json:"phase,omitempty"`
+
+	// Conditions defines current service state of the MachineDeployment.
+	// +optional
+	Conditions Conditions `json:"conditions,omitempty"`
+
+	// Capacity is the capacity of the Machines of the MachineDeployment, computed from spec.capacity and
+	// from the status.capacity field of the infrastructure template.
+	// +optional
+	Capacity *MachineCapacity `json:"capacity,omitempty"`
+
+	// LastProgressTime is the last time the current rollout made progress, i.e. machines were
+	// created, became ready or available, or old machines were deleted. It is not set when no
+	// rollout is in progress or while the MachineDeployment is paused.
+	// +optional
+	LastProgressTime *metav1.Time `json:"lastProgressTime,omitempty"`
 }
 
 // ANCHOR_END: MachineDeploymentStatus
@@ -268,6 +306,14 @@ type MachineDeployment struct {
 	Status MachineDeploymentStatus `json:"status,omitempty"`
 }
 
+func (m *MachineDeployment) GetConditions() Conditions {
+	return m.Status.Conditions
+}
+
+func (m *MachineDeployment) SetConditions(conditions Conditions) {
+	m.Status.Conditions = conditions
+}
+
 // +kubebuilder:object:root=true
 
 // MachineDeploymentList contains a list of MachineDeployment
@@ -92,6 +92,18 @@ func (m *MachineDeployment) validate(old *MachineDeployment) error {
 		)
 	}
 
+	allErrs = append(allErrs, validateNodeDrainOptions(m.Spec.Template.Spec.NodeDrainOptions, field.NewPath("spec", "template", "spec", "nodeDrainOptions"))...)
+	allErrs = append(allErrs, validateReadinessGates(m.Spec.Template.Spec.ReadinessGates, field.NewPath("spec", "template", "spec", "readinessGates"))...)
+	allErrs = append(allErrs, validateReplicaBounds(m.Spec.Replicas, m.Spec.MinReplicas, m.Spec.MaxReplicas, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateMachineCapacity(m.Spec.Capacity, field.NewPath("spec", "capacity"))...)
+
+	if m.Spec.ProgressDeadlineSeconds != nil && m.Spec.MinReadySeconds != nil && *m.Spec.ProgressDeadlineSeconds <= *m.Spec.MinReadySeconds {
+		allErrs = append(
+			allErrs,
+			field.Invalid(field.NewPath("spec", "progressDeadlineSeconds"), *m.Spec.ProgressDeadlineSeconds, "must be greater than minReadySeconds"),
+		)
+	}
+
 	if len(allErrs) == 0 {
 		return nil
 	}
@@ -106,6 +118,11 @@ func PopulateDefaultsMachineDeployment(d *MachineDeployment) {
 		d.Labels = make(map[string]string)
 	}
 	d.Labels[ClusterLabelName] = d.Spec.ClusterName
+	d.Annotations = setAutoscalerAnnotations(d.Annotations, d.Spec.MinReplicas, d.Spec.MaxReplicas)
+
+	if d.Spec.Replicas == nil {
+		d.Spec.Replicas = defaultReplicas(d.Spec.MinReplicas)
+	}
 
 	if d.Spec.MinReadySeconds == nil {
 		d.Spec.MinReadySeconds = pointer.Int32Ptr(0)
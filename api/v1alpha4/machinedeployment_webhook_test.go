@@ -36,6 +36,7 @@ func TestMachineDeploymentDefault(t *testing.T) {
 	md.Default()
 
 	g.Expect(md.Labels[ClusterLabelName]).To(Equal(md.Spec.ClusterName))
+	g.Expect(md.Spec.Replicas).To(Equal(pointer.Int32Ptr(1)))
 	g.Expect(md.Spec.MinReadySeconds).To(Equal(pointer.Int32Ptr(0)))
 	g.Expect(md.Spec.RevisionHistoryLimit).To(Equal(pointer.Int32Ptr(1)))
 	g.Expect(md.Spec.ProgressDeadlineSeconds).To(Equal(pointer.Int32Ptr(600)))
@@ -176,3 +177,54 @@ func TestMachineDeploymentClusterNameImmutable(t *testing.T) {
 		})
 	}
 }
+
+func TestMachineDeploymentProgressDeadlineValidation(t *testing.T) {
+	tests := []struct {
+		name                    string
+		minReadySeconds         *int32
+		progressDeadlineSeconds *int32
+		expectErr               bool
+	}{
+		{
+			name:                    "should succeed with progressDeadlineSeconds greater than minReadySeconds",
+			minReadySeconds:         pointer.Int32Ptr(30),
+			progressDeadlineSeconds: pointer.Int32Ptr(600),
+		},
+		{
+			name:            "should succeed without progressDeadlineSeconds",
+			minReadySeconds: pointer.Int32Ptr(30),
+		},
+		{
+			name:                    "should fail with progressDeadlineSeconds equal to minReadySeconds",
+			minReadySeconds:         pointer.Int32Ptr(600),
+			progressDeadlineSeconds: pointer.Int32Ptr(600),
+			expectErr:               true,
+		},
+		{
+			name:                    "should fail with progressDeadlineSeconds lower than minReadySeconds",
+			minReadySeconds:         pointer.Int32Ptr(900),
+			progressDeadlineSeconds: pointer.Int32Ptr(600),
+			expectErr:               true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			md := &MachineDeployment{
+				Spec: MachineDeploymentSpec{
+					MinReadySeconds:         tt.minReadySeconds,
+					ProgressDeadlineSeconds: tt.progressDeadlineSeconds,
+					AutoRollback:            true,
+				},
+			}
+
+			if tt.expectErr {
+				g.Expect(md.ValidateCreate()).NotTo(Succeed())
+			} else {
+				g.Expect(md.ValidateCreate()).To(Succeed())
+			}
+		})
+	}
+}
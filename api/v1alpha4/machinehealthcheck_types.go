@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha4
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -40,11 +42,33 @@ type MachineHealthCheckSpec struct {
 	// +kubebuilder:validation:MinItems=1
 	UnhealthyConditions []UnhealthyCondition `json:"unhealthyConditions"`
 
+	// UnhealthyMachineConditions contains a list of the Machine conditions that determine
+	// whether a machine is considered unhealthy, e.g. an InfrastructureReady condition reporting
+	// False for too long. The conditions are combined in a logical OR with UnhealthyConditions,
+	// i.e. if any of the conditions is met, the machine is unhealthy.
+	// +optional
+	UnhealthyMachineConditions []UnhealthyMachineCondition `json:"unhealthyMachineConditions,omitempty"`
+
+	// MachineProbe defines a probe executed by the MachineHealthCheck controller from the management cluster
+	// against each machine, e.g. to detect machines whose workloads are unreachable while their node still
+	// reports Ready. If the probe fails for longer than its timeout, the machine is considered unhealthy.
+	// +optional
+	MachineProbe *MachineProbe `json:"machineProbe,omitempty"`
+
 	// Any further remediation is only allowed if at most "MaxUnhealthy" machines selected by
 	// "selector" are not healthy.
 	// +optional
 	MaxUnhealthy *intstr.IntOrString `json:"maxUnhealthy,omitempty"`
 
+	// Any further remediation is only allowed if the number of machines selected by "selector" as not healthy
+	// is within the range of "UnhealthyRange". Takes precedence over MaxUnhealthy.
+	// Eg. "[3-5]" - This means that remediation will be allowed only when:
+	// (a) there are at least 3 unhealthy machines (and)
+	// (b) there are at most 5 unhealthy machines
+	// +optional
+	// +kubebuilder:validation:Pattern=^\[[0-9]+-[0-9]+\]$
+	UnhealthyRange *string `json:"unhealthyRange,omitempty"`
+
 	// Machines older than this duration without a node will be considered to have
 	// failed and will be remediated.
 	// +optional
@@ -58,10 +82,48 @@ type MachineHealthCheckSpec struct {
 	// a controller that lives outside of Cluster API.
 	// +optional
 	RemediationTemplate *corev1.ObjectReference `json:"remediationTemplate,omitempty"`
+
+	// RemediationRateLimit limits how often the MachineHealthCheck triggers remediations,
+	// preventing remediation storms e.g. when every new machine fails for the same reason.
+	// +optional
+	RemediationRateLimit *RemediationRateLimit `json:"remediationRateLimit,omitempty"`
 }
 
 // ANCHOR_END: MachineHealthCHeckSpec
 
+// ANCHOR: RemediationRateLimit
+
+// RemediationRateLimit defines a remediation budget over time and a backoff for remediations
+// triggered repeatedly for the machines of the same owner and for the same reason.
+type RemediationRateLimit struct {
+	// MaxRemediations is the maximum number of remediations the MachineHealthCheck is allowed to
+	// trigger within the Window. If not set, the number of remediations is not limited.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxRemediations *int32 `json:"maxRemediations,omitempty"`
+
+	// Window is the sliding time window used to count remediations and to retain the
+	// remediation history. Defaults to 1h.
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+
+	// BackoffDelay is the delay applied before triggering a remediation for a machine with the same owner,
+	// e.g. the same MachineDeployment, and failing for the same reason as a previous remediation within the Window.
+	// The delay doubles for every further remediation with the same owner and reason. If not set, no backoff is applied.
+	// +optional
+	BackoffDelay *metav1.Duration `json:"backoffDelay,omitempty"`
+
+	// MaxBackoffDelay is the maximum delay applied by the backoff. Defaults to the Window.
+	// +optional
+	MaxBackoffDelay *metav1.Duration `json:"maxBackoffDelay,omitempty"`
+}
+
+// ANCHOR_END: RemediationRateLimit
+
+// DefaultRemediationWindow is the default time window used to count remediations and to retain
+// the remediation history of a MachineHealthCheck.
+const DefaultRemediationWindow = 1 * time.Hour
+
 // ANCHOR: UnhealthyCondition
 
 // UnhealthyCondition represents a Node condition type and value with a timeout
@@ -81,6 +143,93 @@ type UnhealthyCondition struct {
 
 // ANCHOR_END: UnhealthyCondition
 
+// ANCHOR: UnhealthyMachineCondition
+
+// UnhealthyMachineCondition represents a Machine condition type and value with a timeout
+// specified as a duration.  When the named condition has been in the given
+// status for at least the timeout value, a machine is considered unhealthy.
+type UnhealthyMachineCondition struct {
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:MinLength=1
+	Type ConditionType `json:"type"`
+
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:MinLength=1
+	Status corev1.ConditionStatus `json:"status"`
+
+	Timeout metav1.Duration `json:"timeout"`
+}
+
+// ANCHOR_END: UnhealthyMachineCondition
+
+// ANCHOR: MachineProbe
+
+// MachineProbe describes a health check executed against a machine from the management cluster.
+// Exactly one of HTTPGet, TCPSocket or KubeletHealthz must be specified.
+type MachineProbe struct {
+	// HTTPGet specifies an HTTP GET request against the machine address.
+	// The probe succeeds if the response status code is at least 200 and lower than 400.
+	// +optional
+	HTTPGet *HTTPGetMachineProbe `json:"httpGet,omitempty"`
+
+	// TCPSocket specifies a TCP connection against the machine address.
+	// +optional
+	TCPSocket *TCPSocketMachineProbe `json:"tcpSocket,omitempty"`
+
+	// KubeletHealthz specifies a request to the kubelet healthz endpoint of the machine's node,
+	// proxied through the workload cluster API server.
+	// +optional
+	KubeletHealthz *KubeletHealthzMachineProbe `json:"kubeletHealthz,omitempty"`
+
+	// AddressType is the type of the address from the machine status used by the HTTPGet and
+	// TCPSocket probes. Defaults to InternalIP.
+	// +optional
+	// +kubebuilder:validation:Enum=Hostname;ExternalIP;InternalIP;ExternalDNS;InternalDNS
+	AddressType MachineAddressType `json:"addressType,omitempty"`
+
+	// Period is how often the probe is executed. Defaults to 30s.
+	// +optional
+	Period *metav1.Duration `json:"period,omitempty"`
+
+	// ProbeTimeout is the timeout of a single probe execution. Defaults to 5s.
+	// +optional
+	ProbeTimeout *metav1.Duration `json:"probeTimeout,omitempty"`
+
+	// Timeout is how long the probe must be failing before the machine is considered unhealthy.
+	Timeout metav1.Duration `json:"timeout"`
+}
+
+// HTTPGetMachineProbe describes an HTTP GET request against a machine.
+type HTTPGetMachineProbe struct {
+	// Path to access on the HTTP server.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Port to access on the machine.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+
+	// Scheme to use for connecting to the machine, either HTTP or HTTPS. Defaults to HTTP.
+	// When using HTTPS, the certificate presented by the machine is not verified.
+	// +optional
+	// +kubebuilder:validation:Enum=HTTP;HTTPS
+	Scheme corev1.URIScheme `json:"scheme,omitempty"`
+}
+
+// TCPSocketMachineProbe describes a TCP connection against a machine.
+type TCPSocketMachineProbe struct {
+	// Port to connect to on the machine.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+}
+
+// KubeletHealthzMachineProbe describes a request to the kubelet healthz endpoint of a machine's node.
+type KubeletHealthzMachineProbe struct{}
+
+// ANCHOR_END: MachineProbe
+
 // ANCHOR: MachineHealthCheckStatus
 
 // MachineHealthCheckStatus defines the observed state of MachineHealthCheck
@@ -106,6 +255,16 @@ type MachineHealthCheckStatus struct {
 	// +optional
 	Targets []string `json:"targets,omitempty"`
 
+	// RemediationHistory lists the remediations triggered by the machine health check
+	// within the remediation window, oldest first.
+	// +optional
+	RemediationHistory []RemediationRecord `json:"remediationHistory,omitempty"`
+
+	// MachineRemediations reports the progress of the Cluster's remediation escalation chain
+	// for the machines being remediated.
+	// +optional
+	MachineRemediations []MachineRemediationStatus `json:"machineRemediations,omitempty"`
+
 	// Conditions defines current service state of the MachineHealthCheck.
 	// +optional
 	Conditions Conditions `json:"conditions,omitempty"`
@@ -113,6 +272,47 @@ type MachineHealthCheckStatus struct {
 
 // ANCHOR_END: MachineHealthCheckStatus
 
+// ANCHOR: RemediationRecord
+
+// RemediationRecord records a remediation triggered by a MachineHealthCheck.
+type RemediationRecord struct {
+	// MachineName is the name of the remediated machine.
+	MachineName string `json:"machineName"`
+
+	// Owner is the owner of the remediated machine, i.e. the kind and name of its MachineDeployment
+	// or of its controller, e.g. MachineSet/my-ms; it is the machine itself for machines without a controller.
+	// +optional
+	Owner string `json:"owner,omitempty"`
+
+	// Reason is the reason the machine has been considered unhealthy.
+	Reason string `json:"reason"`
+
+	// Timestamp is the time the remediation has been triggered.
+	Timestamp metav1.Time `json:"timestamp"`
+}
+
+// ANCHOR_END: RemediationRecord
+
+// ANCHOR: MachineRemediationStatus
+
+// MachineRemediationStatus reports the progress of the Cluster's remediation escalation chain for a machine.
+type MachineRemediationStatus struct {
+	// MachineName is the name of the machine being remediated.
+	MachineName string `json:"machineName"`
+
+	// Step is the index of the current step of the escalation chain.
+	Step int32 `json:"step"`
+
+	// Attempts is the number of attempts of the current step.
+	Attempts int32 `json:"attempts"`
+
+	// LastAttemptTime is the time of the last attempt.
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+}
+
+// ANCHOR_END: MachineRemediationStatus
+
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:path=machinehealthchecks,shortName=mhc;mhcs,scope=Namespaced,categories=cluster-api
 // +kubebuilder:storageversion
@@ -18,8 +18,11 @@ package v1alpha4
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -38,6 +41,9 @@ var (
 	defaultNodeStartupTimeout = metav1.Duration{Duration: 10 * time.Minute}
 	// Minimum time allowed for a node to start up
 	minNodeStartupTimeout = metav1.Duration{Duration: 30 * time.Second}
+	// Default period and timeout of the machine probe.
+	defaultMachineProbePeriod  = metav1.Duration{Duration: 30 * time.Second}
+	defaultMachineProbeTimeout = metav1.Duration{Duration: 5 * time.Second}
 )
 
 // SetMinNodeStartupTimeout allows users to optionally set a custom timeout
@@ -76,6 +82,25 @@ func (m *MachineHealthCheck) Default() {
 	if m.Spec.NodeStartupTimeout == nil {
 		m.Spec.NodeStartupTimeout = &defaultNodeStartupTimeout
 	}
+
+	if m.Spec.RemediationRateLimit != nil && m.Spec.RemediationRateLimit.Window == nil {
+		m.Spec.RemediationRateLimit.Window = &metav1.Duration{Duration: DefaultRemediationWindow}
+	}
+
+	if probe := m.Spec.MachineProbe; probe != nil {
+		if probe.AddressType == "" {
+			probe.AddressType = MachineInternalIP
+		}
+		if probe.Period == nil {
+			probe.Period = &defaultMachineProbePeriod
+		}
+		if probe.ProbeTimeout == nil {
+			probe.ProbeTimeout = &defaultMachineProbeTimeout
+		}
+		if probe.HTTPGet != nil && probe.HTTPGet.Scheme == "" {
+			probe.HTTPGet.Scheme = corev1.URISchemeHTTP
+		}
+	}
 }
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
@@ -153,8 +178,130 @@ func (m *MachineHealthCheck) validate(old *MachineHealthCheck) error {
 		}
 	}
 
+	if m.Spec.UnhealthyRange != nil {
+		if _, _, err := ParseUnhealthyRange(*m.Spec.UnhealthyRange); err != nil {
+			allErrs = append(
+				allErrs,
+				field.Invalid(field.NewPath("spec", "unhealthyRange"), *m.Spec.UnhealthyRange, err.Error()),
+			)
+		}
+	}
+
+	for i, c := range m.Spec.UnhealthyMachineConditions {
+		// Conditions managed by the MachineHealthCheck itself cannot be used to determine the health of a machine.
+		if c.Type == MachineHealthCheckSuccededCondition || c.Type == MachineOwnerRemediatedCondition || c.Type == MachineProbeSucceededCondition || c.Type == ReadyCondition {
+			allErrs = append(
+				allErrs,
+				field.Invalid(field.NewPath("spec", "unhealthyMachineConditions").Index(i).Child("type"), c.Type, "cannot be a condition managed by the MachineHealthCheck"),
+			)
+		}
+	}
+
+	if m.Spec.MachineProbe != nil {
+		allErrs = append(allErrs, validateMachineProbe(m.Spec.MachineProbe, field.NewPath("spec", "machineProbe"))...)
+	}
+
+	if m.Spec.RemediationRateLimit != nil {
+		allErrs = append(allErrs, validateRemediationRateLimit(m.Spec.RemediationRateLimit, field.NewPath("spec", "remediationRateLimit"))...)
+	}
+
 	if len(allErrs) == 0 {
 		return nil
 	}
 	return apierrors.NewInvalid(GroupVersion.WithKind("MachineHealthCheck").GroupKind(), m.Name, allErrs)
 }
+
+func validateMachineProbe(probe *MachineProbe, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	handlers := 0
+	if probe.HTTPGet != nil {
+		handlers++
+		if probe.HTTPGet.Port < 1 || probe.HTTPGet.Port > 65535 {
+			allErrs = append(allErrs, field.Invalid(path.Child("httpGet", "port"), probe.HTTPGet.Port, "must be between 1 and 65535"))
+		}
+		if probe.HTTPGet.Scheme != "" && probe.HTTPGet.Scheme != corev1.URISchemeHTTP && probe.HTTPGet.Scheme != corev1.URISchemeHTTPS {
+			allErrs = append(allErrs, field.NotSupported(path.Child("httpGet", "scheme"), probe.HTTPGet.Scheme, []string{string(corev1.URISchemeHTTP), string(corev1.URISchemeHTTPS)}))
+		}
+	}
+	if probe.TCPSocket != nil {
+		handlers++
+		if probe.TCPSocket.Port < 1 || probe.TCPSocket.Port > 65535 {
+			allErrs = append(allErrs, field.Invalid(path.Child("tcpSocket", "port"), probe.TCPSocket.Port, "must be between 1 and 65535"))
+		}
+	}
+	if probe.KubeletHealthz != nil {
+		handlers++
+	}
+	if handlers != 1 {
+		allErrs = append(allErrs, field.Invalid(path, handlers, "exactly one of httpGet, tcpSocket or kubeletHealthz must be specified"))
+	}
+
+	if probe.Period != nil && probe.Period.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("period"), probe.Period.Duration.String(), "must be greater than zero"))
+	}
+
+	if probe.ProbeTimeout != nil && probe.ProbeTimeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("probeTimeout"), probe.ProbeTimeout.Duration.String(), "must be greater than zero"))
+	}
+
+	if probe.Timeout.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("timeout"), probe.Timeout.Duration.String(), "must be greater than or equal to zero"))
+	}
+
+	return allErrs
+}
+
+func validateRemediationRateLimit(rateLimit *RemediationRateLimit, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if rateLimit.MaxRemediations != nil && *rateLimit.MaxRemediations < 1 {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxRemediations"), *rateLimit.MaxRemediations, "must be greater than zero"))
+	}
+
+	if rateLimit.Window != nil && rateLimit.Window.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("window"), rateLimit.Window.Duration.String(), "must be greater than zero"))
+	}
+
+	if rateLimit.BackoffDelay != nil && rateLimit.BackoffDelay.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("backoffDelay"), rateLimit.BackoffDelay.Duration.String(), "must be greater than zero"))
+	}
+
+	if rateLimit.MaxBackoffDelay != nil {
+		if rateLimit.BackoffDelay == nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("maxBackoffDelay"), "cannot be set without backoffDelay"))
+		} else if rateLimit.MaxBackoffDelay.Duration < rateLimit.BackoffDelay.Duration {
+			allErrs = append(allErrs, field.Invalid(path.Child("maxBackoffDelay"), rateLimit.MaxBackoffDelay.Duration.String(), "must be greater than or equal to backoffDelay"))
+		}
+	}
+
+	return allErrs
+}
+
+// ParseUnhealthyRange parses an unhealthy range in the "[min-max]" format, returning the min and max values.
+func ParseUnhealthyRange(unhealthyRange string) (int, int, error) {
+	if !strings.HasPrefix(unhealthyRange, "[") || !strings.HasSuffix(unhealthyRange, "]") {
+		return 0, 0, fmt.Errorf("must be in the \"[min-max]\" format")
+	}
+
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(unhealthyRange, "["), "]"), "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("must be in the \"[min-max]\" format")
+	}
+
+	min, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid min value %q: %v", parts[0], err)
+	}
+
+	max, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid max value %q: %v", parts[1], err)
+	}
+
+	if max < min {
+		return 0, 0, fmt.Errorf("max value %d cannot be less than min value %d", max, min)
+	}
+
+	return int(min), int(max), nil
+}
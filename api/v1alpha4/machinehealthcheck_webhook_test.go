@@ -22,6 +22,7 @@ import (
 
 	. "github.com/onsi/gomega"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
@@ -36,6 +37,40 @@ func TestMachineHealthCheckDefault(t *testing.T) {
 	g.Expect(mhc.Spec.MaxUnhealthy.String()).To(Equal("100%"))
 	g.Expect(mhc.Spec.NodeStartupTimeout).ToNot(BeNil())
 	g.Expect(*mhc.Spec.NodeStartupTimeout).To(Equal(metav1.Duration{Duration: 10 * time.Minute}))
+	g.Expect(mhc.Spec.RemediationRateLimit).To(BeNil())
+	g.Expect(mhc.Spec.MachineProbe).To(BeNil())
+}
+
+func TestMachineHealthCheckDefaultMachineProbe(t *testing.T) {
+	g := NewWithT(t)
+	mhc := &MachineHealthCheck{
+		Spec: MachineHealthCheckSpec{
+			MachineProbe: &MachineProbe{
+				HTTPGet: &HTTPGetMachineProbe{Port: 8080},
+			},
+		},
+	}
+
+	mhc.Default()
+
+	g.Expect(mhc.Spec.MachineProbe.AddressType).To(Equal(MachineInternalIP))
+	g.Expect(mhc.Spec.MachineProbe.HTTPGet.Scheme).To(Equal(corev1.URISchemeHTTP))
+	g.Expect(*mhc.Spec.MachineProbe.Period).To(Equal(metav1.Duration{Duration: 30 * time.Second}))
+	g.Expect(*mhc.Spec.MachineProbe.ProbeTimeout).To(Equal(metav1.Duration{Duration: 5 * time.Second}))
+}
+
+func TestMachineHealthCheckDefaultRemediationRateLimit(t *testing.T) {
+	g := NewWithT(t)
+	mhc := &MachineHealthCheck{
+		Spec: MachineHealthCheckSpec{
+			RemediationRateLimit: &RemediationRateLimit{},
+		},
+	}
+
+	mhc.Default()
+
+	g.Expect(mhc.Spec.RemediationRateLimit.Window).ToNot(BeNil())
+	g.Expect(*mhc.Spec.RemediationRateLimit.Window).To(Equal(metav1.Duration{Duration: 1 * time.Hour}))
 }
 
 func TestMachineHealthCheckLabelSelectorAsSelectorValidation(t *testing.T) {
@@ -253,6 +288,280 @@ func TestMachineHealthCheckMaxUnhealthy(t *testing.T) {
 	}
 }
 
+func TestMachineHealthCheckUnhealthyRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		expectErr bool
+	}{
+		{
+			name:      "when the range is valid",
+			value:     "[2-5]",
+			expectErr: false,
+		},
+		{
+			name:      "when min and max are equal",
+			value:     "[3-3]",
+			expectErr: false,
+		},
+		{
+			name:      "when max is less than min",
+			value:     "[5-2]",
+			expectErr: true,
+		},
+		{
+			name:      "when the brackets are missing",
+			value:     "2-5",
+			expectErr: true,
+		},
+		{
+			name:      "when the value is a random string",
+			value:     "[a-b]",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		g := NewWithT(t)
+
+		unhealthyRange := tt.value
+		mhc := &MachineHealthCheck{
+			Spec: MachineHealthCheckSpec{
+				UnhealthyRange: &unhealthyRange,
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+
+		if tt.expectErr {
+			g.Expect(mhc.ValidateCreate()).NotTo(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).NotTo(Succeed())
+		} else {
+			g.Expect(mhc.ValidateCreate()).To(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).To(Succeed())
+		}
+	}
+}
+
+func TestMachineHealthCheckUnhealthyMachineConditions(t *testing.T) {
+	tests := []struct {
+		name          string
+		conditionType ConditionType
+		expectErr     bool
+	}{
+		{
+			name:          "when the condition is reported by the infrastructure",
+			conditionType: InfrastructureReadyCondition,
+			expectErr:     false,
+		},
+		{
+			name:          "when the condition is managed by the MachineHealthCheck",
+			conditionType: MachineHealthCheckSuccededCondition,
+			expectErr:     true,
+		},
+		{
+			name:          "when the condition is the Ready summary",
+			conditionType: ReadyCondition,
+			expectErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		g := NewWithT(t)
+
+		mhc := &MachineHealthCheck{
+			Spec: MachineHealthCheckSpec{
+				UnhealthyMachineConditions: []UnhealthyMachineCondition{
+					{
+						Type:    tt.conditionType,
+						Status:  corev1.ConditionFalse,
+						Timeout: metav1.Duration{Duration: 5 * time.Minute},
+					},
+				},
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+
+		if tt.expectErr {
+			g.Expect(mhc.ValidateCreate()).NotTo(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).NotTo(Succeed())
+		} else {
+			g.Expect(mhc.ValidateCreate()).To(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).To(Succeed())
+		}
+	}
+}
+
+func TestMachineHealthCheckRemediationRateLimit(t *testing.T) {
+	maxRemediations := func(i int32) *int32 { return &i }
+	duration := func(d time.Duration) *metav1.Duration { return &metav1.Duration{Duration: d} }
+
+	tests := []struct {
+		name      string
+		rateLimit *RemediationRateLimit
+		expectErr bool
+	}{
+		{
+			name: "when the rate limit is valid",
+			rateLimit: &RemediationRateLimit{
+				MaxRemediations: maxRemediations(3),
+				Window:          duration(time.Hour),
+				BackoffDelay:    duration(time.Minute),
+				MaxBackoffDelay: duration(30 * time.Minute),
+			},
+			expectErr: false,
+		},
+		{
+			name: "when maxRemediations is zero",
+			rateLimit: &RemediationRateLimit{
+				MaxRemediations: maxRemediations(0),
+			},
+			expectErr: true,
+		},
+		{
+			name: "when the window is negative",
+			rateLimit: &RemediationRateLimit{
+				Window: duration(-time.Minute),
+			},
+			expectErr: true,
+		},
+		{
+			name: "when maxBackoffDelay is set without backoffDelay",
+			rateLimit: &RemediationRateLimit{
+				MaxBackoffDelay: duration(time.Minute),
+			},
+			expectErr: true,
+		},
+		{
+			name: "when maxBackoffDelay is less than backoffDelay",
+			rateLimit: &RemediationRateLimit{
+				BackoffDelay:    duration(time.Minute),
+				MaxBackoffDelay: duration(time.Second),
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		g := NewWithT(t)
+
+		mhc := &MachineHealthCheck{
+			Spec: MachineHealthCheckSpec{
+				RemediationRateLimit: tt.rateLimit,
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+
+		if tt.expectErr {
+			g.Expect(mhc.ValidateCreate()).NotTo(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).NotTo(Succeed())
+		} else {
+			g.Expect(mhc.ValidateCreate()).To(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).To(Succeed())
+		}
+	}
+}
+
+func TestMachineHealthCheckMachineProbe(t *testing.T) {
+	tests := []struct {
+		name      string
+		probe     *MachineProbe
+		expectErr bool
+	}{
+		{
+			name: "when the probe is an HTTP GET",
+			probe: &MachineProbe{
+				HTTPGet: &HTTPGetMachineProbe{Path: "/healthz", Port: 8080, Scheme: corev1.URISchemeHTTPS},
+				Timeout: metav1.Duration{Duration: 5 * time.Minute},
+			},
+			expectErr: false,
+		},
+		{
+			name: "when the probe is a kubelet healthz",
+			probe: &MachineProbe{
+				KubeletHealthz: &KubeletHealthzMachineProbe{},
+				Timeout:        metav1.Duration{Duration: 5 * time.Minute},
+			},
+			expectErr: false,
+		},
+		{
+			name: "when no probe handler is specified",
+			probe: &MachineProbe{
+				Timeout: metav1.Duration{Duration: 5 * time.Minute},
+			},
+			expectErr: true,
+		},
+		{
+			name: "when more than one probe handler is specified",
+			probe: &MachineProbe{
+				HTTPGet:   &HTTPGetMachineProbe{Port: 8080},
+				TCPSocket: &TCPSocketMachineProbe{Port: 22},
+				Timeout:   metav1.Duration{Duration: 5 * time.Minute},
+			},
+			expectErr: true,
+		},
+		{
+			name: "when the port is out of range",
+			probe: &MachineProbe{
+				TCPSocket: &TCPSocketMachineProbe{Port: 70000},
+				Timeout:   metav1.Duration{Duration: 5 * time.Minute},
+			},
+			expectErr: true,
+		},
+		{
+			name: "when the scheme is not supported",
+			probe: &MachineProbe{
+				HTTPGet: &HTTPGetMachineProbe{Port: 8080, Scheme: "FTP"},
+				Timeout: metav1.Duration{Duration: 5 * time.Minute},
+			},
+			expectErr: true,
+		},
+		{
+			name: "when the period is zero",
+			probe: &MachineProbe{
+				TCPSocket: &TCPSocketMachineProbe{Port: 22},
+				Period:    &metav1.Duration{},
+				Timeout:   metav1.Duration{Duration: 5 * time.Minute},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		g := NewWithT(t)
+
+		mhc := &MachineHealthCheck{
+			Spec: MachineHealthCheckSpec{
+				MachineProbe: tt.probe,
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+
+		if tt.expectErr {
+			g.Expect(mhc.ValidateCreate()).NotTo(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).NotTo(Succeed())
+		} else {
+			g.Expect(mhc.ValidateCreate()).To(Succeed())
+			g.Expect(mhc.ValidateUpdate(mhc)).To(Succeed())
+		}
+	}
+}
+
 func TestMachineHealthCheckSelectorValidation(t *testing.T) {
 	g := NewWithT(t)
 	mhc := &MachineHealthCheck{}
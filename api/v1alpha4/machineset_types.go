@@ -34,11 +34,28 @@ type MachineSetSpec struct {
 
 	// Replicas is the number of desired replicas.
 	// This is a pointer to distinguish between explicit zero and unspecified.
-	// Defaults to 1.
+	// Defaults to minReplicas if set, otherwise to 1.
 	// +optional
-	// +kubebuilder:default=1
 	Replicas *int32 `json:"replicas,omitempty"`
 
+	// MinReplicas is the minimum number of replicas autoscalers can scale the MachineSet down to.
+	// When defined, replicas must not be lower than minReplicas.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the maximum number of replicas autoscalers can scale the MachineSet up to.
+	// When defined, replicas must not be greater than maxReplicas.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+
+	// Capacity describes the resources and the node properties of the Machines, used by autoscalers to scale
+	// the MachineSet from zero. Resources not defined here are read from the status.capacity field of the
+	// infrastructure template, when the infrastructure provider supports it.
+	// +optional
+	Capacity *MachineCapacity `json:"capacity,omitempty"`
+
 	// MinReadySeconds is the minimum number of seconds for which a newly created machine should be ready.
 	// Defaults to 0 (machine will be considered available as soon as it is ready)
 	// +optional
@@ -157,6 +174,15 @@ type MachineSetStatus struct {
 	FailureReason *capierrors.MachineSetStatusError `json:"failureReason,omitempty"`
 	// +optional
 	FailureMessage *string `json:"failureMessage,omitempty"`
+
+	// Conditions defines current service state of the MachineSet.
+	// +optional
+	Conditions Conditions `json:"conditions,omitempty"`
+
+	// Capacity is the capacity of the Machines of the MachineSet, computed from spec.capacity and
+	// from the status.capacity field of the infrastructure template.
+	// +optional
+	Capacity *MachineCapacity `json:"capacity,omitempty"`
 }
 
 // ANCHOR_END: MachineSetStatus
@@ -202,6 +228,14 @@ type MachineSet struct {
 	Status MachineSetStatus `json:"status,omitempty"`
 }
 
+func (m *MachineSet) GetConditions() Conditions {
+	return m.Status.Conditions
+}
+
+func (m *MachineSet) SetConditions(conditions Conditions) {
+	m.Status.Conditions = conditions
+}
+
 // +kubebuilder:object:root=true
 
 // MachineSetList contains a list of MachineSet
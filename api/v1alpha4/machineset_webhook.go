@@ -46,6 +46,11 @@ func (m *MachineSet) Default() {
 		m.Labels = make(map[string]string)
 	}
 	m.Labels[ClusterLabelName] = m.Spec.ClusterName
+	m.Annotations = setAutoscalerAnnotations(m.Annotations, m.Spec.MinReplicas, m.Spec.MaxReplicas)
+
+	if m.Spec.Replicas == nil {
+		m.Spec.Replicas = defaultReplicas(m.Spec.MinReplicas)
+	}
 
 	if m.Spec.DeletePolicy == "" {
 		randomPolicy := string(RandomMachineSetDeletePolicy)
@@ -111,6 +116,11 @@ func (m *MachineSet) validate(old *MachineSet) error {
 		)
 	}
 
+	allErrs = append(allErrs, validateNodeDrainOptions(m.Spec.Template.Spec.NodeDrainOptions, field.NewPath("spec", "template", "spec", "nodeDrainOptions"))...)
+	allErrs = append(allErrs, validateReadinessGates(m.Spec.Template.Spec.ReadinessGates, field.NewPath("spec", "template", "spec", "readinessGates"))...)
+	allErrs = append(allErrs, validateReplicaBounds(m.Spec.Replicas, m.Spec.MinReplicas, m.Spec.MaxReplicas, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateMachineCapacity(m.Spec.Capacity, field.NewPath("spec", "capacity"))...)
+
 	if len(allErrs) == 0 {
 		return nil
 	}
@@ -20,8 +20,10 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/blang/semver"
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -30,6 +32,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1alpha4"
@@ -46,6 +49,7 @@ import (
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/cluster-api/util/predicates"
 	"sigs.k8s.io/cluster-api/util/secret"
+	"sigs.k8s.io/cluster-api/util/version"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -56,8 +60,17 @@ import (
 const (
 	// KubeadmConfigControllerName defines the controller used when creating clients
 	KubeadmConfigControllerName = "kubeadmconfig-controller"
+
+	// kubeletNodeIPArg is the kubelet flag defining the IP address of the node.
+	kubeletNodeIPArg = "node-ip"
+
+	// ipv6DualStackFeatureGate is the Kubernetes feature gate enabling dual-stack networking.
+	ipv6DualStackFeatureGate = "IPv6DualStack"
 )
 
+// ipv6DualStackDefaultVersion is the first Kubernetes version where the IPv6DualStack feature gate is enabled by default.
+var ipv6DualStackDefaultVersion = semver.MustParse("1.21.0")
+
 // InitLocker is a lock that is used around kubeadm init
 type InitLocker interface {
 	Lock(ctx context.Context, cluster *clusterv1.Cluster, machine *clusterv1.Machine) bool
@@ -227,6 +240,17 @@ func (r *KubeadmConfigReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	// Status is ready means a config has been generated.
 	case config.Status.Ready:
 		if config.Spec.JoinConfiguration != nil && config.Spec.JoinConfiguration.Discovery.BootstrapToken != nil {
+			if configOwner.IsMachinePool() {
+				// If the cluster certificate authorities changed since the bootstrap data has been generated, e.g. during
+				// a rotation of the certificate authorities, we regenerate it so the new machines of the pool can join.
+				refreshed, err := r.refreshCACertHashes(ctx, config, cluster)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				if refreshed {
+					return r.joinWorker(ctx, scope)
+				}
+			}
 			if !configOwner.IsInfrastructureReady() {
 				// If the BootstrapToken has been generated for a join and the infrastructure is not ready.
 				// This indicates the token in the join config has not been consumed and it may need a refresh.
@@ -317,6 +341,38 @@ func (r *KubeadmConfigReconciler) rotateMachinePoolBootstrapToken(ctx context.Co
 	}, nil
 }
 
+// refreshCACertHashes updates the CA certificate hashes pinned for the discovery of a joining node when they do not
+// match the certificates of the cluster certificate authority anymore, e.g. during a rotation of the certificate authorities;
+// it returns true if the hashes have been updated. Hashes not pinning any of the cluster certificate authorities are
+// provided by the user and are never changed.
+func (r *KubeadmConfigReconciler) refreshCACertHashes(ctx context.Context, config *bootstrapv1.KubeadmConfig, cluster *clusterv1.Cluster) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	pinned := sets.NewString(config.Spec.JoinConfiguration.Discovery.BootstrapToken.CACertHashes...)
+	if pinned.Len() == 0 {
+		return false, nil
+	}
+
+	certificates := secret.NewCertificatesForWorker(config.Spec.JoinConfiguration.CACertPath)
+	if err := certificates.Lookup(ctx, r.Client, util.ObjectKey(cluster)); err != nil {
+		return false, err
+	}
+	if err := certificates.EnsureAllExist(); err != nil {
+		return false, err
+	}
+	hashes, err := certificates.GetByPurpose(secret.ClusterCA).Hashes()
+	if err != nil {
+		return false, errors.Wrap(err, "unable to generate Cluster CA certificate hashes")
+	}
+	if pinned.Equal(sets.NewString(hashes...)) || !pinned.HasAny(hashes...) {
+		return false, nil
+	}
+
+	log.Info("Altering JoinConfiguration.Discovery.BootstrapToken.CACertHashes", "CACertHashes", hashes)
+	config.Spec.JoinConfiguration.Discovery.BootstrapToken.CACertHashes = hashes
+	return true, nil
+}
+
 func (r *KubeadmConfigReconciler) handleClusterNotInitialized(ctx context.Context, scope *Scope) (_ ctrl.Result, reterr error) {
 	// initialize the DataSecretAvailableCondition if missing.
 	// this is required in order to avoid the condition's LastTransitionTime to flicker in case of errors surfacing
@@ -373,7 +429,17 @@ func (r *KubeadmConfigReconciler) handleClusterNotInitialized(ctx context.Contex
 			},
 		}
 	}
-	initdata, err := kubeadmv1beta1.ConfigurationToYAML(scope.Config.Spec.InitConfiguration)
+	r.reconcileNodeRegistrationSettings(ctx, scope.Cluster, &scope.Config.Spec.InitConfiguration.NodeRegistration)
+
+	// SkipPhases is not part of the kubeadm v1beta1 configuration format, so it is passed to kubeadm init as a flag.
+	initConfiguration := scope.Config.Spec.InitConfiguration.DeepCopy()
+	skipPhasesFlag := ""
+	if len(initConfiguration.SkipPhases) > 0 {
+		skipPhasesFlag = fmt.Sprintf("--skip-phases=%s", strings.Join(initConfiguration.SkipPhases, ","))
+	}
+	initConfiguration.SkipPhases = nil
+
+	initdata, err := kubeadmv1beta1.ConfigurationToYAML(initConfiguration)
 	if err != nil {
 		scope.Error(err, "Failed to marshal init configuration")
 		return ctrl.Result{}, err
@@ -434,6 +500,7 @@ func (r *KubeadmConfigReconciler) handleClusterNotInitialized(ctx context.Contex
 		},
 		InitConfiguration:    initdata,
 		ClusterConfiguration: clusterdata,
+		KubeadmSkipPhases:    skipPhasesFlag,
 		Certificates:         certificates,
 	})
 	if err != nil {
@@ -473,6 +540,8 @@ func (r *KubeadmConfigReconciler) joinWorker(ctx context.Context, scope *Scope)
 		return res, nil
 	}
 
+	r.reconcileNodeRegistrationSettings(ctx, scope.Cluster, &scope.Config.Spec.JoinConfiguration.NodeRegistration)
+
 	joinData, err := kubeadmv1beta1.ConfigurationToYAML(scope.Config.Spec.JoinConfiguration)
 	if err != nil {
 		scope.Error(err, "Failed to marshal join configuration")
@@ -554,6 +623,8 @@ func (r *KubeadmConfigReconciler) joinControlplane(ctx context.Context, scope *S
 		return res, nil
 	}
 
+	r.reconcileNodeRegistrationSettings(ctx, scope.Cluster, &scope.Config.Spec.JoinConfiguration.NodeRegistration)
+
 	joinData, err := kubeadmv1beta1.ConfigurationToYAML(scope.Config.Spec.JoinConfiguration)
 	if err != nil {
 		scope.Error(err, "Failed to marshal join configuration")
@@ -829,6 +900,42 @@ func (r *KubeadmConfigReconciler) reconcileTopLevelObjectSettings(ctx context.Co
 		config.Spec.ClusterConfiguration.KubernetesVersion = *machine.Spec.Version
 		log.Info("Altering ClusterConfiguration", "KubernetesVersion", config.Spec.ClusterConfiguration.KubernetesVersion)
 	}
+
+	// If the Cluster is dual-stack and the IPv6DualStack feature gate is not defined in ClusterConfiguration,
+	// enable it for the Kubernetes versions where it is not enabled by default.
+	if ipFamily, err := cluster.GetIPFamily(); err == nil && ipFamily == clusterv1.DualStackIPFamily {
+		if _, ok := config.Spec.ClusterConfiguration.FeatureGates[ipv6DualStackFeatureGate]; !ok {
+			kubernetesVersion, err := version.ParseMajorMinorPatchTolerant(config.Spec.ClusterConfiguration.KubernetesVersion)
+			if err == nil && kubernetesVersion.LT(ipv6DualStackDefaultVersion) {
+				if config.Spec.ClusterConfiguration.FeatureGates == nil {
+					config.Spec.ClusterConfiguration.FeatureGates = map[string]bool{}
+				}
+				config.Spec.ClusterConfiguration.FeatureGates[ipv6DualStackFeatureGate] = true
+				log.Info("Altering ClusterConfiguration", "FeatureGates", config.Spec.ClusterConfiguration.FeatureGates)
+			}
+		}
+	}
+}
+
+// reconcileNodeRegistrationSettings injects into nodeRegistration values from the Cluster.
+// The implementation func respect user provided config values, but in case some of them are missing, values from the Cluster are used.
+func (r *KubeadmConfigReconciler) reconcileNodeRegistrationSettings(ctx context.Context, cluster *clusterv1.Cluster, nodeRegistration *kubeadmv1beta1.NodeRegistrationOptions) {
+	log := ctrl.LoggerFrom(ctx)
+
+	// If there is no node-ip defined in KubeletExtraArgs and the Cluster is IPv6 single-stack, make the kubelet
+	// pick the IPv6 address of the node, given that by default it picks the IPv4 one. This does not apply to
+	// dual-stack Clusters, where "::" would make the kubelet register the node with its IPv6 address only.
+	if _, ok := nodeRegistration.KubeletExtraArgs[kubeletNodeIPArg]; ok {
+		return
+	}
+	if ipFamily, err := cluster.GetIPFamily(); err != nil || ipFamily != clusterv1.IPv6IPFamily {
+		return
+	}
+	if nodeRegistration.KubeletExtraArgs == nil {
+		nodeRegistration.KubeletExtraArgs = map[string]string{}
+	}
+	nodeRegistration.KubeletExtraArgs[kubeletNodeIPArg] = "::"
+	log.Info("Altering NodeRegistration", "KubeletExtraArgs", nodeRegistration.KubeletExtraArgs)
 }
 
 // storeBootstrapData creates a new secret with the data passed in as input,
@@ -40,6 +40,7 @@ import (
 	"sigs.k8s.io/cluster-api/feature"
 	"sigs.k8s.io/cluster-api/test/helpers"
 	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/certs"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/secret"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -417,6 +418,49 @@ func TestKubeadmConfigReconciler_Reconcile_GenerateCloudConfigData(t *testing.T)
 	g.Expect(err).NotTo(HaveOccurred())
 }
 
+func TestKubeadmConfigReconciler_Reconcile_GenerateCloudConfigDataWithSkipPhases(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := newCluster("cluster")
+	cluster.Status.InfrastructureReady = true
+
+	controlPlaneInitMachine := newControlPlaneMachine(cluster, "control-plane-init-machine")
+	controlPlaneInitConfig := newControlPlaneInitKubeadmConfig(controlPlaneInitMachine, "control-plane-init-cfg")
+	controlPlaneInitConfig.Spec.InitConfiguration.SkipPhases = []string{"addon/coredns", "addon/kube-proxy"}
+
+	objects := []client.Object{
+		cluster,
+		controlPlaneInitMachine,
+		controlPlaneInitConfig,
+	}
+	objects = append(objects, createSecrets(t, cluster, controlPlaneInitConfig)...)
+
+	myclient := helpers.NewFakeClientWithScheme(setupScheme(), objects...)
+
+	k := &KubeadmConfigReconciler{
+		Client:          myclient,
+		KubeadmInitLock: &myInitLocker{},
+	}
+
+	request := ctrl.Request{
+		NamespacedName: client.ObjectKey{
+			Namespace: "default",
+			Name:      "control-plane-init-cfg",
+		},
+	}
+	_, err := k.Reconcile(ctx, request)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	cfg, err := getKubeadmConfig(myclient, "control-plane-init-cfg")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cfg.Status.DataSecretName).NotTo(BeNil())
+
+	secret := &corev1.Secret{}
+	g.Expect(myclient.Get(ctx, client.ObjectKey{Namespace: "default", Name: *cfg.Status.DataSecretName}, secret)).To(Succeed())
+	g.Expect(string(secret.Data["value"])).To(ContainSubstring("--skip-phases=addon/coredns,addon/kube-proxy"))
+	g.Expect(string(secret.Data["value"])).NotTo(ContainSubstring("skipPhases"))
+}
+
 // If a control plane has no JoinConfiguration, then we will create a default and no error will occur
 func TestKubeadmConfigReconciler_Reconcile_ErrorIfJoiningControlPlaneHasInvalidConfiguration(t *testing.T) {
 	g := NewWithT(t)
@@ -1023,6 +1067,66 @@ func TestBootstrapTokenRotationMachinePool(t *testing.T) {
 	g.Expect(foundNew).To(BeTrue())
 }
 
+// Ensure the CA certificate hashes of a join configuration follow a rotation of the cluster certificate authority.
+func TestRefreshCACertHashes(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := newCluster("cluster")
+	controlPlaneInitMachine := newControlPlaneMachine(cluster, "control-plane-init-machine")
+	initConfig := newControlPlaneInitKubeadmConfig(controlPlaneInitMachine, "control-plane-init-config")
+	objects := createSecrets(t, cluster, initConfig)
+	myclient := helpers.NewFakeClientWithScheme(setupScheme(), objects...)
+	k := &KubeadmConfigReconciler{
+		Client: myclient,
+	}
+
+	caSecret := &corev1.Secret{}
+	g.Expect(myclient.Get(ctx, client.ObjectKey{Namespace: "default", Name: secret.Name(cluster.Name, secret.ClusterCA)}, caSecret)).To(Succeed())
+	hashes, err := (&secret.Certificate{KeyPair: &certs.KeyPair{Cert: caSecret.Data[secret.TLSCrtDataName]}}).Hashes()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(hashes).To(HaveLen(1))
+
+	config := newWorkerPoolJoinKubeadmConfig(newWorkerMachinePool(cluster))
+	config.Spec.JoinConfiguration.Discovery.BootstrapToken = &kubeadmv1beta1.BootstrapTokenDiscovery{
+		CACertHashes: hashes,
+	}
+
+	// Hashes pinning the current certificate authority are kept.
+	refreshed, err := k.refreshCACertHashes(ctx, config, cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(refreshed).To(BeFalse())
+	g.Expect(config.Spec.JoinConfiguration.Discovery.BootstrapToken.CACertHashes).To(Equal(hashes))
+
+	// Trusting a new certificate authority adds its hash to the pinned ones.
+	g.Expect(secret.AddNextKeyPair(caSecret, secret.ClusterCA)).To(Succeed())
+	g.Expect(myclient.Update(ctx, caSecret)).To(Succeed())
+
+	refreshed, err = k.refreshCACertHashes(ctx, config, cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(refreshed).To(BeTrue())
+	g.Expect(config.Spec.JoinConfiguration.Discovery.BootstrapToken.CACertHashes).To(HaveLen(2))
+	g.Expect(config.Spec.JoinConfiguration.Discovery.BootstrapToken.CACertHashes).To(ContainElement(hashes[0]))
+
+	// Removing the previous certificate authority removes its hash from the pinned ones.
+	g.Expect(secret.PromoteNextKeyPair(caSecret)).To(Succeed())
+	g.Expect(secret.RemovePreviousKeyPairs(caSecret)).To(Succeed())
+	g.Expect(myclient.Update(ctx, caSecret)).To(Succeed())
+
+	refreshed, err = k.refreshCACertHashes(ctx, config, cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(refreshed).To(BeTrue())
+	g.Expect(config.Spec.JoinConfiguration.Discovery.BootstrapToken.CACertHashes).To(HaveLen(1))
+	g.Expect(config.Spec.JoinConfiguration.Discovery.BootstrapToken.CACertHashes).NotTo(ContainElement(hashes[0]))
+
+	// Hashes not pinning the cluster certificate authority are provided by the user and are never changed.
+	config.Spec.JoinConfiguration.Discovery.BootstrapToken.CACertHashes = []string{"sha256:user-provided"}
+
+	refreshed, err = k.refreshCACertHashes(ctx, config, cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(refreshed).To(BeFalse())
+	g.Expect(config.Spec.JoinConfiguration.Discovery.BootstrapToken.CACertHashes).To(ConsistOf("sha256:user-provided"))
+}
+
 // Ensure the discovery portion of the JoinConfiguration gets generated correctly.
 func TestKubeadmConfigReconciler_Reconcile_DiscoveryReconcileBehaviors(t *testing.T) {
 	k := &KubeadmConfigReconciler{
@@ -1300,6 +1404,84 @@ func TestKubeadmConfigReconciler_Reconcile_DynamicDefaultsForClusterConfiguratio
 	}
 }
 
+func TestKubeadmConfigReconciler_Reconcile_DynamicDefaultsForIPFamily(t *testing.T) {
+	k := &KubeadmConfigReconciler{}
+
+	newCluster := func(services, pods []string) *clusterv1.Cluster {
+		return &clusterv1.Cluster{
+			Spec: clusterv1.ClusterSpec{
+				ClusterNetwork: &clusterv1.ClusterNetwork{
+					Services: &clusterv1.NetworkRanges{CIDRBlocks: services},
+					Pods:     &clusterv1.NetworkRanges{CIDRBlocks: pods},
+				},
+			},
+		}
+	}
+
+	testcases := []struct {
+		name                   string
+		cluster                *clusterv1.Cluster
+		version                string
+		kubeletExtraArgs       map[string]string
+		expectedNodeIP         string
+		expectDualStackEnabled bool
+	}{
+		{
+			name:    "IPv4 clusters use kubelet defaults",
+			cluster: newCluster([]string{"10.96.0.0/12"}, []string{"192.168.0.0/16"}),
+			version: "v1.19.1",
+		},
+		{
+			name:           "IPv6 clusters make the kubelet pick the IPv6 node address",
+			cluster:        newCluster([]string{"fd00:100:64::/108"}, []string{"fd00:100:96::/48"}),
+			version:        "v1.19.1",
+			expectedNodeIP: "::",
+		},
+		{
+			name:             "node-ip defined in the config has precedence",
+			cluster:          newCluster([]string{"fd00:100:64::/108"}, []string{"fd00:100:96::/48"}),
+			version:          "v1.19.1",
+			kubeletExtraArgs: map[string]string{"node-ip": "fd00::10"},
+			expectedNodeIP:   "fd00::10",
+		},
+		{
+			name:                   "dual-stack clusters enable the IPv6DualStack feature gate before v1.21",
+			cluster:                newCluster([]string{"10.96.0.0/12", "fd00:100:64::/108"}, []string{"192.168.0.0/16", "fd00:100:96::/48"}),
+			version:                "v1.20.2",
+			expectDualStackEnabled: true,
+		},
+		{
+			name:    "dual-stack clusters with IPv6 primary use kubelet defaults",
+			cluster: newCluster([]string{"fd00:100:64::/108", "10.96.0.0/12"}, []string{"fd00:100:96::/48", "192.168.0.0/16"}),
+			version: "v1.21.1",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config := &bootstrapv1.KubeadmConfig{
+				Spec: bootstrapv1.KubeadmConfigSpec{
+					ClusterConfiguration: &kubeadmv1beta1.ClusterConfiguration{},
+					JoinConfiguration: &kubeadmv1beta1.JoinConfiguration{
+						NodeRegistration: kubeadmv1beta1.NodeRegistrationOptions{
+							KubeletExtraArgs: tc.kubeletExtraArgs,
+						},
+					},
+				},
+			}
+			machine := &clusterv1.Machine{Spec: clusterv1.MachineSpec{Version: pointer.StringPtr(tc.version)}}
+
+			k.reconcileTopLevelObjectSettings(ctx, tc.cluster, machine, config)
+			k.reconcileNodeRegistrationSettings(ctx, tc.cluster, &config.Spec.JoinConfiguration.NodeRegistration)
+
+			g.Expect(config.Spec.JoinConfiguration.NodeRegistration.KubeletExtraArgs["node-ip"]).To(Equal(tc.expectedNodeIP))
+			g.Expect(config.Spec.ClusterConfiguration.FeatureGates["IPv6DualStack"]).To(Equal(tc.expectDualStackEnabled))
+		})
+	}
+}
+
 // Allow users to skip CA Verification if they *really* want to.
 func TestKubeadmConfigReconciler_Reconcile_AlwaysCheckCAVerificationUnlessRequestedToSkip(t *testing.T) {
 	// Setup work for an initialized cluster
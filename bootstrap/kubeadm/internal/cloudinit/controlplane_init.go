@@ -37,7 +37,7 @@ const (
     content: "This placeholder file is used to create the /run/cluster-api sub directory in a way that is compatible with both Linux and Windows (mkdir -p /run/cluster-api does not work with Windows)"
 runcmd:
 {{- template "commands" .PreKubeadmCommands }}
-  - 'kubeadm init --config /run/kubeadm/kubeadm.yaml {{.KubeadmVerbosity}} && {{ .SentinelFileCommand }}'
+  - 'kubeadm init --config /run/kubeadm/kubeadm.yaml {{.KubeadmVerbosity}}{{if .KubeadmSkipPhases}} {{.KubeadmSkipPhases}}{{end}} && {{ .SentinelFileCommand }}'
 {{- template "commands" .PostKubeadmCommands }}
 {{- template "ntp" .NTP }}
 {{- template "users" .Users }}
@@ -54,6 +54,7 @@ type ControlPlaneInput struct {
 
 	ClusterConfiguration string
 	InitConfiguration    string
+	KubeadmSkipPhases    string
 }
 
 // NewInitControlPlane returns the user data string to be used on a controlplane instance.
@@ -47,6 +47,13 @@ type InitConfiguration struct {
 	// fails you may set the desired value here.
 	// +optional
 	LocalAPIEndpoint APIEndpoint `json:"localAPIEndpoint,omitempty"`
+
+	// SkipPhases is a list of phases to skip during command execution.
+	// The list of phases can be obtained with the "kubeadm init --help" command.
+	// NOTE: This field is not part of the kubeadm v1beta1 configuration format; it is backported from
+	// kubeadm v1beta3 and passed to kubeadm using the --skip-phases flag instead.
+	// +optional
+	SkipPhases []string `json:"skipPhases,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
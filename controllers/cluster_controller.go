@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -32,6 +33,7 @@ import (
 	"k8s.io/client-go/tools/record"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/controllers/external"
+	"sigs.k8s.io/cluster-api/controllers/remote"
 	expv1 "sigs.k8s.io/cluster-api/exp/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/feature"
 	"sigs.k8s.io/cluster-api/util"
@@ -52,6 +54,12 @@ const (
 	// deleteRequeueAfter is how long to wait before checking again to see if the cluster still has children during
 	// deletion.
 	deleteRequeueAfter = 5 * time.Second
+
+	// deleteBlockedRequeueAfter is how long to wait before running the pre-delete checks again when the deletion is blocked.
+	deleteBlockedRequeueAfter = 30 * time.Second
+
+	// clusterControllerName defines the controller used when creating clients.
+	clusterControllerName = "cluster-controller"
 )
 
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;patch
@@ -66,9 +74,10 @@ type ClusterReconciler struct {
 	Client           client.Client
 	WatchFilterValue string
 
-	restConfig      *rest.Config
-	recorder        record.EventRecorder
-	externalTracker external.ObjectTracker
+	restConfig         *rest.Config
+	recorder           record.EventRecorder
+	externalTracker    external.ObjectTracker
+	remoteClientGetter remote.ClusterClientGetter
 }
 
 func (r *ClusterReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, options controller.Options) error {
@@ -86,8 +95,11 @@ func (r *ClusterReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manag
 		return errors.Wrap(err, "failed setting up with a controller manager")
 	}
 
-	r.recorder = mgr.GetEventRecorderFor("cluster-controller")
+	r.recorder = mgr.GetEventRecorderFor(clusterControllerName)
 	r.restConfig = mgr.GetConfig()
+	if r.remoteClientGetter == nil {
+		r.remoteClientGetter = remote.NewClusterClient
+	}
 	r.externalTracker = external.ObjectTracker{
 		Controller: controller,
 	}
@@ -110,18 +122,26 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_
 		return ctrl.Result{}, err
 	}
 
-	// Return early if the object or Cluster is paused.
-	if annotations.IsPaused(cluster, cluster) {
-		log.Info("Reconciliation is paused for this object")
-		return ctrl.Result{}, nil
-	}
-
 	// Initialize the patch helper.
 	patchHelper, err := patch.NewHelper(cluster, r.Client)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	// Return early if the object or Cluster is paused, reporting why in the Paused condition.
+	if annotations.IsPaused(cluster, cluster) {
+		reasons := annotations.PauseReasons(cluster)
+		log.Info("Reconciliation is paused for this object", "reasons", reasons)
+		conditions.Set(cluster, &clusterv1.Condition{
+			Type:    clusterv1.PausedCondition,
+			Status:  corev1.ConditionTrue,
+			Reason:  clusterv1.ClusterPausedReason,
+			Message: fmt.Sprintf("Cluster is paused: %s", strings.Join(reasons, "; ")),
+		})
+		return ctrl.Result{}, patchHelper.Patch(ctx, cluster, patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{clusterv1.PausedCondition}})
+	}
+	conditions.Delete(cluster, clusterv1.PausedCondition)
+
 	defer func() {
 		// Always reconcile the Status.Phase field.
 		r.reconcilePhase(ctx, cluster)
@@ -169,6 +189,8 @@ func patchCluster(ctx context.Context, patchHelper *patch.Helper, cluster *clust
 			clusterv1.ReadyCondition,
 			clusterv1.ControlPlaneReadyCondition,
 			clusterv1.InfrastructureReadyCondition,
+			clusterv1.DeletingCondition,
+			clusterv1.PausedCondition,
 		}},
 	)
 	return patchHelper.Patch(ctx, cluster, options...)
@@ -203,6 +225,20 @@ func (r *ClusterReconciler) reconcile(ctx context.Context, cluster *clusterv1.Cl
 func (r *ClusterReconciler) reconcileDelete(ctx context.Context, cluster *clusterv1.Cluster) (reconcile.Result, error) {
 	log := ctrl.LoggerFrom(ctx)
 
+	// Run the pre-delete checks until the deletion of the Cluster's descendants starts.
+	if !conditions.IsTrue(cluster, clusterv1.DeletingCondition) {
+		message, err := r.runPreDeleteChecks(ctx, cluster)
+		if err != nil {
+			conditions.MarkFalse(cluster, clusterv1.DeletingCondition, clusterv1.DeletionBlockedReason, clusterv1.ConditionSeverityWarning, err.Error())
+			return reconcile.Result{}, err
+		}
+		if message != "" {
+			log.Info("Cluster deletion is blocked", "reason", message)
+			conditions.MarkFalse(cluster, clusterv1.DeletingCondition, clusterv1.DeletionBlockedReason, clusterv1.ConditionSeverityWarning, "%s", message)
+			return reconcile.Result{RequeueAfter: deleteBlockedRequeueAfter}, nil
+		}
+	}
+
 	descendants, err := r.listDescendants(ctx, cluster)
 	if err != nil {
 		log.Error(err, "Failed to list descendants")
@@ -215,6 +251,16 @@ func (r *ClusterReconciler) reconcileDelete(ctx context.Context, cluster *cluste
 		return reconcile.Result{}, err
 	}
 
+	// When deleting the workers first, control plane machines are deleted only after all the worker descendants are gone.
+	deletingReason := clusterv1.DeletingMachinesReason
+	if getClusterDeletionOrder(cluster) == clusterv1.WorkersFirstClusterDeletionOrder {
+		deletingReason = clusterv1.DeletingControlPlaneReason
+		if descendants.workersLength() > 0 {
+			deletingReason = clusterv1.DeletingWorkersReason
+			children = filterOutControlPlaneMachines(children)
+		}
+	}
+
 	if len(children) > 0 {
 		log.Info("Cluster still has children - deleting them first", "count", len(children))
 
@@ -240,9 +286,10 @@ func (r *ClusterReconciler) reconcileDelete(ctx context.Context, cluster *cluste
 		}
 	}
 
-	if descendantCount := descendants.length(); descendantCount > 0 {
+	if descendantCount := descendants.length(); descendantCount > 0 || deletingReason == clusterv1.DeletingWorkersReason {
 		indirect := descendantCount - len(children)
 		log.Info("Cluster still has descendants - need to requeue", "descendants", descendants.descendantNames(), "indirect descendants count", indirect)
+		markDeletingPhase(cluster, deletingReason, "Waiting for descendants to be deleted (%s)", descendants.descendantNames())
 		// Requeue so we can check the next time to see if there are still any descendants left.
 		return ctrl.Result{RequeueAfter: deleteRequeueAfter}, nil
 	}
@@ -264,6 +311,8 @@ func (r *ClusterReconciler) reconcileDelete(ctx context.Context, cluster *cluste
 				conditions.WithFallbackValue(false, clusterv1.DeletingReason, clusterv1.ConditionSeverityInfo, ""),
 			)
 
+			markDeletingPhase(cluster, clusterv1.DeletingControlPlaneReason, "Waiting for %s %s to be deleted", obj.GetKind(), obj.GetName())
+
 			// Issue a deletion request for the control plane object.
 			// Once it's been deleted, the cluster will get processed again.
 			if err := r.Client.Delete(ctx, obj); err != nil {
@@ -295,6 +344,8 @@ func (r *ClusterReconciler) reconcileDelete(ctx context.Context, cluster *cluste
 				conditions.WithFallbackValue(false, clusterv1.DeletingReason, clusterv1.ConditionSeverityInfo, ""),
 			)
 
+			markDeletingPhase(cluster, clusterv1.DeletingInfrastructureReason, "Waiting for %s %s to be deleted", obj.GetKind(), obj.GetName())
+
 			// Issue a deletion request for the infrastructure object.
 			// Once it's been deleted, the cluster will get processed again.
 			if err := r.Client.Delete(ctx, obj); err != nil {
@@ -313,6 +364,90 @@ func (r *ClusterReconciler) reconcileDelete(ctx context.Context, cluster *cluste
 	return ctrl.Result{}, nil
 }
 
+// runPreDeleteChecks checks whether the deletion of the Cluster is allowed, returning a message
+// documenting why the deletion is blocked, if any.
+func (r *ClusterReconciler) runPreDeleteChecks(ctx context.Context, cluster *clusterv1.Cluster) (string, error) {
+	if _, ok := cluster.Annotations[clusterv1.PreventDeleteAnnotation]; ok {
+		return fmt.Sprintf("Cluster has the %q annotation", clusterv1.PreventDeleteAnnotation), nil
+	}
+
+	if cluster.Spec.DeletionPolicy == nil {
+		return "", nil
+	}
+
+	for _, check := range cluster.Spec.DeletionPolicy.PreDeleteChecks {
+		switch check {
+		case clusterv1.RetainedPersistentVolumesPreDeleteCheck:
+			message, err := r.checkRetainedPersistentVolumes(ctx, cluster)
+			if err != nil || message != "" {
+				return message, err
+			}
+		default:
+			return "", errors.Errorf("unknown pre-delete check %q", check)
+		}
+	}
+	return "", nil
+}
+
+// checkRetainedPersistentVolumes checks that no PersistentVolumes with the Retain reclaim policy exist in the workload cluster.
+func (r *ClusterReconciler) checkRetainedPersistentVolumes(ctx context.Context, cluster *clusterv1.Cluster) (string, error) {
+	// If the control plane has never been initialized there is no workload cluster to check.
+	if !cluster.Status.ControlPlaneInitialized {
+		return "", nil
+	}
+
+	remoteClient, err := r.remoteClientGetter(ctx, clusterControllerName, r.Client, util.ObjectKey(cluster))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create client for the workload cluster %s/%s", cluster.Namespace, cluster.Name)
+	}
+
+	pvs := &corev1.PersistentVolumeList{}
+	if err := remoteClient.List(ctx, pvs); err != nil {
+		return "", errors.Wrapf(err, "failed to list PersistentVolumes in the workload cluster %s/%s", cluster.Namespace, cluster.Name)
+	}
+
+	retained := []string{}
+	for _, pv := range pvs.Items {
+		if pv.Spec.PersistentVolumeReclaimPolicy == corev1.PersistentVolumeReclaimRetain {
+			retained = append(retained, pv.Name)
+		}
+	}
+	if len(retained) > 0 {
+		return fmt.Sprintf("PersistentVolumes with the Retain reclaim policy exist in the workload cluster: %s", strings.Join(retained, ",")), nil
+	}
+	return "", nil
+}
+
+// getClusterDeletionOrder returns the order in which the Cluster's machines are deleted.
+func getClusterDeletionOrder(cluster *clusterv1.Cluster) clusterv1.ClusterDeletionOrder {
+	if cluster.Spec.DeletionPolicy == nil || cluster.Spec.DeletionPolicy.Order == "" {
+		return clusterv1.ParallelClusterDeletionOrder
+	}
+	return cluster.Spec.DeletionPolicy.Order
+}
+
+// markDeletingPhase sets the Deleting condition to True, documenting the current deletion phase.
+func markDeletingPhase(cluster *clusterv1.Cluster, reason, messageFormat string, messageArgs ...interface{}) {
+	conditions.Set(cluster, &clusterv1.Condition{
+		Type:    clusterv1.DeletingCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  reason,
+		Message: fmt.Sprintf(messageFormat, messageArgs...),
+	})
+}
+
+// filterOutControlPlaneMachines returns the given objects without the control plane machines.
+func filterOutControlPlaneMachines(objs []client.Object) []client.Object {
+	var filtered []client.Object
+	for _, obj := range objs {
+		if machine, ok := obj.(*clusterv1.Machine); ok && util.IsControlPlaneMachine(machine) {
+			continue
+		}
+		filtered = append(filtered, obj)
+	}
+	return filtered
+}
+
 type clusterDescendants struct {
 	machineDeployments   clusterv1.MachineDeploymentList
 	machineSets          clusterv1.MachineSetList
@@ -329,6 +464,14 @@ func (c *clusterDescendants) length() int {
 		len(c.workerMachines.Items)
 }
 
+// workersLength returns the number of worker descendants, i.e. all the descendants except the control plane machines.
+func (c *clusterDescendants) workersLength() int {
+	return len(c.machineDeployments.Items) +
+		len(c.machineSets.Items) +
+		len(c.machinePools.Items) +
+		len(c.workerMachines.Items)
+}
+
 func (c *clusterDescendants) descendantNames() string {
 	descendants := make([]string, 0)
 	controlPlaneMachineNames := make([]string, len(c.controlPlaneMachines.Items))
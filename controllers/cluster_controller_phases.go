@@ -235,15 +235,13 @@ func (r *ClusterReconciler) reconcileControlPlane(ctx context.Context, cluster *
 		conditions.WithFallbackValue(ready, clusterv1.WaitingForControlPlaneFallbackReason, clusterv1.ConditionSeverityInfo, ""),
 	)
 
-	// Update cluster.Status.ControlPlaneInitialized if it hasn't already been set
-	// Determine if the control plane provider is initialized.
-	if !cluster.Status.ControlPlaneInitialized {
-		initialized, err := external.IsInitialized(controlPlaneConfig)
-		if err != nil {
-			return ctrl.Result{}, err
-		}
-		cluster.Status.ControlPlaneInitialized = initialized
+	// Update cluster.Status.ControlPlaneInitialized from the control plane provider, which might reset it, e.g. when
+	// restoring the control plane from an etcd snapshot.
+	initialized, err := external.IsInitialized(controlPlaneConfig)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
+	cluster.Status.ControlPlaneInitialized = initialized
 
 	return ctrl.Result{}, nil
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
@@ -24,11 +25,13 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -644,3 +647,182 @@ func TestReconcileControlPlaneInitializedControlPlaneRef(t *testing.T) {
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(c.Status.ControlPlaneInitialized).To(BeFalse())
 }
+
+func TestClusterReconcilerPaused(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cluster",
+			Namespace: "test",
+			Annotations: map[string]string{
+				clusterv1.PauseReasonAnnotationPrefix + "/etcd-restore": "restoring from snapshot",
+			},
+		},
+		Spec: clusterv1.ClusterSpec{Paused: true},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(cluster).Build()
+	r := &ClusterReconciler{Client: c}
+	request := ctrl.Request{NamespacedName: util.ObjectKey(cluster)}
+
+	_, err := r.Reconcile(ctx, request)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	paused := &clusterv1.Cluster{}
+	g.Expect(c.Get(ctx, util.ObjectKey(cluster), paused)).To(Succeed())
+	g.Expect(paused.Finalizers).To(BeEmpty())
+	g.Expect(conditions.IsTrue(paused, clusterv1.PausedCondition)).To(BeTrue())
+	g.Expect(conditions.GetMessage(paused, clusterv1.PausedCondition)).To(Equal("Cluster is paused: spec.paused; etcd-restore: restoring from snapshot"))
+
+	// Resuming the Cluster removes the condition.
+	paused.Spec.Paused = false
+	paused.Annotations = nil
+	g.Expect(c.Update(ctx, paused)).To(Succeed())
+
+	_, err = r.Reconcile(ctx, request)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	resumed := &clusterv1.Cluster{}
+	g.Expect(c.Get(ctx, util.ObjectKey(cluster), resumed)).To(Succeed())
+	g.Expect(resumed.Finalizers).To(ContainElement(clusterv1.ClusterFinalizer))
+	g.Expect(conditions.Has(resumed, clusterv1.PausedCondition)).To(BeFalse())
+}
+
+func TestClusterReconcilerReconcileDelete(t *testing.T) {
+	newCluster := func() *clusterv1.Cluster {
+		return &clusterv1.Cluster{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: clusterv1.GroupVersion.String(),
+				Kind:       "Cluster",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "test-cluster",
+				Namespace:  "test",
+				Finalizers: []string{clusterv1.ClusterFinalizer},
+			},
+		}
+	}
+	newMachine := func(cluster *clusterv1.Cluster, name string, controlPlane bool) *clusterv1.Machine {
+		m := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: cluster.Namespace,
+				Labels:    map[string]string{clusterv1.ClusterLabelName: cluster.Name},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: clusterv1.GroupVersion.String(),
+					Kind:       "Cluster",
+					Name:       cluster.Name,
+				}},
+			},
+		}
+		if controlPlane {
+			m.Labels[clusterv1.MachineControlPlaneLabelName] = ""
+		}
+		return m
+	}
+
+	t.Run("blocks the deletion when the cluster has the prevent-delete annotation", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cluster := newCluster()
+		cluster.Annotations = map[string]string{clusterv1.PreventDeleteAnnotation: ""}
+		worker := newMachine(cluster, "worker", false)
+
+		c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(cluster, worker).Build()
+		r := &ClusterReconciler{Client: c}
+
+		res, err := r.reconcileDelete(ctx, cluster)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(res.RequeueAfter).To(Equal(deleteBlockedRequeueAfter))
+		g.Expect(conditions.IsFalse(cluster, clusterv1.DeletingCondition)).To(BeTrue())
+		g.Expect(conditions.GetReason(cluster, clusterv1.DeletingCondition)).To(Equal(clusterv1.DeletionBlockedReason))
+		g.Expect(c.Get(ctx, util.ObjectKey(worker), &clusterv1.Machine{})).To(Succeed())
+	})
+
+	t.Run("blocks the deletion while retained PersistentVolumes exist in the workload cluster", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cluster := newCluster()
+		cluster.Status.ControlPlaneInitialized = true
+		cluster.Spec.DeletionPolicy = &clusterv1.ClusterDeletionPolicy{
+			PreDeleteChecks: []clusterv1.ClusterPreDeleteCheck{clusterv1.RetainedPersistentVolumesPreDeleteCheck},
+		}
+		worker := newMachine(cluster, "worker", false)
+		retainedPV := &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-retain"},
+			Spec:       corev1.PersistentVolumeSpec{PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain},
+		}
+		deletedPV := &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-delete"},
+			Spec:       corev1.PersistentVolumeSpec{PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(cluster, worker).Build()
+		remoteClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(retainedPV, deletedPV).Build()
+		r := &ClusterReconciler{
+			Client: c,
+			remoteClientGetter: func(ctx context.Context, sourceName string, c client.Client, cluster client.ObjectKey) (client.Client, error) {
+				return remoteClient, nil
+			},
+		}
+
+		res, err := r.reconcileDelete(ctx, cluster)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(res.RequeueAfter).To(Equal(deleteBlockedRequeueAfter))
+		g.Expect(conditions.GetReason(cluster, clusterv1.DeletingCondition)).To(Equal(clusterv1.DeletionBlockedReason))
+		g.Expect(conditions.GetMessage(cluster, clusterv1.DeletingCondition)).To(ContainSubstring("pv-retain"))
+		g.Expect(conditions.GetMessage(cluster, clusterv1.DeletingCondition)).ToNot(ContainSubstring("pv-delete"))
+		g.Expect(c.Get(ctx, util.ObjectKey(worker), &clusterv1.Machine{})).To(Succeed())
+
+		// Once the retained PersistentVolume is gone, the deletion proceeds.
+		g.Expect(remoteClient.Delete(ctx, retainedPV)).To(Succeed())
+		_, err = r.reconcileDelete(ctx, cluster)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(conditions.IsTrue(cluster, clusterv1.DeletingCondition)).To(BeTrue())
+		g.Expect(apierrors.IsNotFound(c.Get(ctx, util.ObjectKey(worker), &clusterv1.Machine{}))).To(BeTrue())
+	})
+
+	t.Run("deletes the workers before the control plane", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cluster := newCluster()
+		cluster.Spec.DeletionPolicy = &clusterv1.ClusterDeletionPolicy{Order: clusterv1.WorkersFirstClusterDeletionOrder}
+		worker := newMachine(cluster, "worker", false)
+		controlPlane := newMachine(cluster, "control-plane", true)
+
+		c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(cluster, worker, controlPlane).Build()
+		r := &ClusterReconciler{Client: c}
+
+		_, err := r.reconcileDelete(ctx, cluster)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(conditions.GetReason(cluster, clusterv1.DeletingCondition)).To(Equal(clusterv1.DeletingWorkersReason))
+		g.Expect(apierrors.IsNotFound(c.Get(ctx, util.ObjectKey(worker), &clusterv1.Machine{}))).To(BeTrue())
+		g.Expect(c.Get(ctx, util.ObjectKey(controlPlane), &clusterv1.Machine{})).To(Succeed())
+
+		_, err = r.reconcileDelete(ctx, cluster)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(conditions.GetReason(cluster, clusterv1.DeletingCondition)).To(Equal(clusterv1.DeletingControlPlaneReason))
+		g.Expect(apierrors.IsNotFound(c.Get(ctx, util.ObjectKey(controlPlane), &clusterv1.Machine{}))).To(BeTrue())
+
+		_, err = r.reconcileDelete(ctx, cluster)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(cluster.Finalizers).To(BeEmpty())
+	})
+
+	t.Run("deletes workers and control plane machines in parallel by default", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cluster := newCluster()
+		worker := newMachine(cluster, "worker", false)
+		controlPlane := newMachine(cluster, "control-plane", true)
+
+		c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(cluster, worker, controlPlane).Build()
+		r := &ClusterReconciler{Client: c}
+
+		_, err := r.reconcileDelete(ctx, cluster)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(apierrors.IsNotFound(c.Get(ctx, util.ObjectKey(worker), &clusterv1.Machine{}))).To(BeTrue())
+		g.Expect(apierrors.IsNotFound(c.Get(ctx, util.ObjectKey(controlPlane), &clusterv1.Machine{}))).To(BeTrue())
+	})
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apiserver/pkg/storage/names"
@@ -227,3 +228,27 @@ func IsInitialized(obj *unstructured.Unstructured) (bool, error) {
 	}
 	return initialized && found, nil
 }
+
+// CapacityFrom returns the Status.Capacity field from an external object, if defined; this is the amount of resources
+// of the machines created from an infrastructure machine template, e.g. cpu, memory and nvidia.com/gpu.
+func CapacityFrom(obj *unstructured.Unstructured) (corev1.ResourceList, error) {
+	capacity, found, err := unstructured.NestedStringMap(obj.Object, "status", "capacity")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to determine capacity on %v %q",
+			obj.GroupVersionKind(), obj.GetName())
+	}
+	if !found {
+		return nil, nil
+	}
+
+	resources := corev1.ResourceList{}
+	for name, value := range capacity {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse capacity %q on %v %q",
+				name, obj.GroupVersionKind(), obj.GetName())
+		}
+		resources[corev1.ResourceName(name)] = quantity
+	}
+	return resources, nil
+}
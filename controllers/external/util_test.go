@@ -321,3 +321,32 @@ func TestCloneTemplateMissingSpecTemplate(t *testing.T) {
 	})
 	g.Expect(err).To(HaveOccurred())
 }
+
+func TestCapacityFrom(t *testing.T) {
+	g := NewWithT(t)
+
+	template := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       "AquaTemplate",
+			"apiVersion": "aqua.io/v1",
+			"metadata": map[string]interface{}{
+				"name": "aquaTemplate",
+			},
+		},
+	}
+
+	capacity, err := CapacityFrom(template)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(capacity).To(BeNil())
+
+	g.Expect(unstructured.SetNestedStringMap(template.Object, map[string]string{"cpu": "4", "memory": "16Gi"}, "status", "capacity")).To(Succeed())
+	capacity, err = CapacityFrom(template)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(capacity).To(HaveLen(2))
+	g.Expect(capacity.Cpu().String()).To(Equal("4"))
+	g.Expect(capacity.Memory().String()).To(Equal("16Gi"))
+
+	g.Expect(unstructured.SetNestedStringMap(template.Object, map[string]string{"cpu": "four"}, "status", "capacity")).To(Succeed())
+	_, err = CapacityFrom(template)
+	g.Expect(err).To(HaveOccurred())
+}
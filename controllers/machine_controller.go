@@ -211,6 +211,9 @@ func (r *MachineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_
 }
 
 func patchMachine(ctx context.Context, patchHelper *patch.Helper, machine *clusterv1.Machine, options ...patch.Option) error {
+	// Summarize the conditions in the readiness gates, so they are considered when computing the readyCondition.
+	setReadinessGatesCondition(machine)
+
 	// Always update the readyCondition by summarizing the state of other conditions.
 	// A step counter is added to represent progress during the provisioning process (instead we are hiding it
 	// after provisioning - e.g. when a MHC condition exists - or during the deletion process).
@@ -220,6 +223,8 @@ func patchMachine(ctx context.Context, patchHelper *patch.Helper, machine *clust
 			clusterv1.InfrastructureReadyCondition,
 			// Boostrap comes after, but it is relevant only during initial machine provisioning.
 			clusterv1.BootstrapReadyCondition,
+			// Readiness gates are set by external controllers once the machine is provisioned.
+			clusterv1.ReadinessGatesReadyCondition,
 			// MHC reported condition should take precedence over the remediation progress
 			clusterv1.MachineHealthCheckSuccededCondition,
 			clusterv1.MachineOwnerRemediatedCondition,
@@ -239,7 +244,11 @@ func patchMachine(ctx context.Context, patchHelper *patch.Helper, machine *clust
 			clusterv1.ReadyCondition,
 			clusterv1.BootstrapReadyCondition,
 			clusterv1.InfrastructureReadyCondition,
+			clusterv1.ReadinessGatesReadyCondition,
 			clusterv1.DrainingSucceededCondition,
+			clusterv1.VolumeDetachSucceededCondition,
+			clusterv1.PreDrainDeleteHookSucceededCondition,
+			clusterv1.PreTerminateDeleteHookSucceededCondition,
 			clusterv1.MachineHealthCheckSuccededCondition,
 			clusterv1.MachineOwnerRemediatedCondition,
 		}},
@@ -333,7 +342,7 @@ func (r *MachineReconciler) reconcileDelete(ctx context.Context, cluster *cluste
 				return ctrl.Result{}, errors.Wrap(err, "failed to patch Machine")
 			}
 
-			if result, err := r.drainNode(ctx, cluster, m.Status.NodeRef.Name); !result.IsZero() || err != nil {
+			if result, err := r.drainNode(ctx, cluster, m); !result.IsZero() || err != nil {
 				if err != nil {
 					conditions.MarkFalse(m, clusterv1.DrainingSucceededCondition, clusterv1.DrainingFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
 					r.recorder.Eventf(m, corev1.EventTypeWarning, "FailedDrainNode", "error draining Machine's node %q: %v", m.Status.NodeRef.Name, err)
@@ -346,6 +355,41 @@ func (r *MachineReconciler) reconcileDelete(ctx context.Context, cluster *cluste
 		}
 	}
 
+	// Wait for the volumes attached to the node to be detached before deleting the infrastructure,
+	// otherwise the underlying cloud volumes could be left attached to a deleted instance.
+	// This applies only to drained nodes, given that the volumes used by pods still running on the node,
+	// e.g. on the last control plane node or when the Cluster is being deleted, are never detached.
+	if m.Status.NodeRef != nil && conditions.IsTrue(m, clusterv1.DrainingSucceededCondition) {
+		if r.isNodeVolumeDetachingAllowed(m) {
+			// The VolumeDetachSucceededCondition never exists before we wait for volume detachment for the first time,
+			// so its transition time can be used to record the first time we wait for volume detachment.
+			// This `if` condition prevents the transition time to be changed more than once.
+			if conditions.Get(m, clusterv1.VolumeDetachSucceededCondition) == nil {
+				conditions.MarkFalse(m, clusterv1.VolumeDetachSucceededCondition, clusterv1.WaitingForVolumeDetachReason, clusterv1.ConditionSeverityInfo, "Waiting for node volumes to be detached")
+			}
+
+			// NOTE: errors getting the node, e.g. because the workload cluster is not reachable, are retried
+			// until the NodeVolumeDetachTimeout expires.
+			attached, err := r.nodeAttachedVolumes(ctx, cluster, m.Status.NodeRef.Name)
+			if err != nil {
+				r.recorder.Eventf(m, corev1.EventTypeWarning, "FailedWaitForVolumeDetach", "error waiting for the volumes of Machine's node %q to be detached: %v", m.Status.NodeRef.Name, err)
+				return ctrl.Result{}, err
+			}
+			if attached > 0 {
+				log.Info("Waiting for node volumes to be detached", "node", m.Status.NodeRef.Name, "volumes", attached)
+				conditions.MarkFalse(m, clusterv1.VolumeDetachSucceededCondition, clusterv1.WaitingForVolumeDetachReason, clusterv1.ConditionSeverityInfo, "Waiting for %d node volumes to be detached", attached)
+				return ctrl.Result{RequeueAfter: 20 * time.Second}, nil
+			}
+			conditions.MarkTrue(m, clusterv1.VolumeDetachSucceededCondition)
+		} else if r.nodeVolumeDetachTimeoutExceeded(m) &&
+			!conditions.IsTrue(m, clusterv1.VolumeDetachSucceededCondition) &&
+			conditions.GetReason(m, clusterv1.VolumeDetachSucceededCondition) != clusterv1.VolumeDetachTimedOutReason {
+			log.Info("Timed out waiting for node volumes to be detached, moving on", "node", m.Status.NodeRef.Name)
+			conditions.MarkFalse(m, clusterv1.VolumeDetachSucceededCondition, clusterv1.VolumeDetachTimedOutReason, clusterv1.ConditionSeverityWarning, "Timed out waiting for node volumes to be detached")
+			r.recorder.Eventf(m, corev1.EventTypeWarning, "VolumeDetachTimedOut", "timed out waiting for the volumes of Machine's node %q to be detached", m.Status.NodeRef.Name)
+		}
+	}
+
 	// pre-term.delete lifecycle hook
 	// Return early without error, will requeue if/when the hook owner removes the annotation.
 	if annotations.HasWithPrefix(clusterv1.PreTerminateDeleteHookAnnotationPrefix, m.ObjectMeta.Annotations) {
@@ -428,6 +472,40 @@ func (r *MachineReconciler) nodeDrainTimeoutExceeded(machine *clusterv1.Machine)
 	return diff.Seconds() >= machine.Spec.NodeDrainTimeout.Seconds()
 }
 
+func (r *MachineReconciler) isNodeVolumeDetachingAllowed(m *clusterv1.Machine) bool {
+	if _, exists := m.ObjectMeta.Annotations[clusterv1.ExcludeWaitForNodeVolumeDetachAnnotation]; exists {
+		return false
+	}
+
+	if r.nodeVolumeDetachTimeoutExceeded(m) {
+		return false
+	}
+
+	return true
+}
+
+func (r *MachineReconciler) nodeVolumeDetachTimeoutExceeded(machine *clusterv1.Machine) bool {
+	// if the NodeVolumeDetachTimeout is not set by user, the default timeout applies
+	timeout := clusterv1.DefaultNodeVolumeDetachTimeout
+	if machine.Spec.NodeVolumeDetachTimeout != nil {
+		timeout = machine.Spec.NodeVolumeDetachTimeout.Duration
+	}
+	// if the NodeVolumeDetachTimeout is set to 0 by user
+	if timeout <= 0 {
+		return false
+	}
+
+	// if the volume detach succeeded condition does not exist
+	if conditions.Get(machine, clusterv1.VolumeDetachSucceededCondition) == nil {
+		return false
+	}
+
+	now := time.Now()
+	firstTimeDetach := conditions.GetLastTransitionTime(machine, clusterv1.VolumeDetachSucceededCondition)
+	diff := now.Sub(firstTimeDetach.Time)
+	return diff >= timeout
+}
+
 // isDeleteNodeAllowed returns nil only if the Machine's NodeRef is not nil
 // and if the Machine is not the last control plane node in the cluster.
 func (r *MachineReconciler) isDeleteNodeAllowed(ctx context.Context, cluster *clusterv1.Cluster, machine *clusterv1.Machine) error {
@@ -491,32 +569,11 @@ func (r *MachineReconciler) isDeleteNodeAllowed(ctx context.Context, cluster *cl
 	}
 }
 
-func (r *MachineReconciler) drainNode(ctx context.Context, cluster *clusterv1.Cluster, nodeName string) (ctrl.Result, error) {
+func (r *MachineReconciler) drainNode(ctx context.Context, cluster *clusterv1.Cluster, m *clusterv1.Machine) (ctrl.Result, error) {
+	nodeName := m.Status.NodeRef.Name
 	log := ctrl.LoggerFrom(ctx, "cluster", cluster.Name, "node", nodeName)
 
-	restConfig, err := remote.RESTConfig(ctx, MachineControllerName, r.Client, util.ObjectKey(cluster))
-	if err != nil {
-		log.Error(err, "Error creating a remote client while deleting Machine, won't retry")
-		return ctrl.Result{}, nil
-	}
-	kubeClient, err := kubernetes.NewForConfig(restConfig)
-	if err != nil {
-		log.Error(err, "Error creating a remote client while deleting Machine, won't retry")
-		return ctrl.Result{}, nil
-	}
-
-	node, err := kubeClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
-	if err != nil {
-		if apierrors.IsNotFound(err) {
-			// If an admin deletes the node directly, we'll end up here.
-			log.Error(err, "Could not find node from noderef, it may have already been deleted")
-			return ctrl.Result{}, nil
-		}
-		return ctrl.Result{}, errors.Errorf("unable to get node %q: %v", nodeName, err)
-	}
-
 	drainer := &kubedrain.Helper{
-		Client:              kubeClient,
 		Force:               true,
 		IgnoreAllDaemonSets: true,
 		DeleteLocalData:     true,
@@ -537,6 +594,35 @@ func (r *MachineReconciler) drainNode(ctx context.Context, cluster *clusterv1.Cl
 		DryRun: false,
 	}
 
+	if err := applyNodeDrainOptions(drainer, m.Spec.NodeDrainOptions); err != nil {
+		// The options are validated by the webhook, this is reported as a drain failure so the node is never
+		// considered drained.
+		return ctrl.Result{}, errors.Wrap(err, "invalid node drain options")
+	}
+
+	restConfig, err := remote.RESTConfig(ctx, MachineControllerName, r.Client, util.ObjectKey(cluster))
+	if err != nil {
+		log.Error(err, "Error creating a remote client while deleting Machine, won't retry")
+		return ctrl.Result{}, nil
+	}
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		log.Error(err, "Error creating a remote client while deleting Machine, won't retry")
+		return ctrl.Result{}, nil
+	}
+
+	drainer.Client = kubeClient
+
+	node, err := kubeClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// If an admin deletes the node directly, we'll end up here.
+			log.Error(err, "Could not find node from noderef, it may have already been deleted")
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Errorf("unable to get node %q: %v", nodeName, err)
+	}
+
 	if noderefutil.IsNodeUnreachable(node) {
 		// When the node is unreachable and some pods are not evicted for as long as this timeout, we ignore them.
 		drainer.SkipWaitForDeleteTimeoutSeconds = 60 * 5 // 5 minutes
@@ -551,13 +637,46 @@ func (r *MachineReconciler) drainNode(ctx context.Context, cluster *clusterv1.Cl
 	if err := kubedrain.RunNodeDrain(ctx, drainer, node.Name); err != nil {
 		// Machine will be re-reconciled after a drain failure.
 		log.Error(err, "Drain failed, retry in 20s")
+		conditions.MarkFalse(m, clusterv1.DrainingSucceededCondition, clusterv1.DrainingReason, clusterv1.ConditionSeverityInfo,
+			"Draining the node before deletion, %s", drainBlockingPodsMessage(ctx, drainer, node.Name))
 		return ctrl.Result{RequeueAfter: 20 * time.Second}, nil
 	}
 
+	if m.Spec.NodeDrainOptions != nil && m.Spec.NodeDrainOptions.DaemonSetPods == clusterv1.EvictDaemonSetPodsPolicy {
+		if err := evictDaemonSetPods(ctx, drainer, node.Name); err != nil {
+			log.Error(err, "Evicting DaemonSet pods failed, retry in 20s")
+			conditions.MarkFalse(m, clusterv1.DrainingSucceededCondition, clusterv1.DrainingReason, clusterv1.ConditionSeverityInfo,
+				"Draining the node before deletion, evicting DaemonSet pods: %v", err)
+			return ctrl.Result{RequeueAfter: 20 * time.Second}, nil
+		}
+	}
+
 	log.Info("Drain successful")
 	return ctrl.Result{}, nil
 }
 
+// nodeAttachedVolumes returns the number of volumes still attached to the node.
+func (r *MachineReconciler) nodeAttachedVolumes(ctx context.Context, cluster *clusterv1.Cluster, nodeName string) (int, error) {
+	log := ctrl.LoggerFrom(ctx, "cluster", cluster.Name, "node", nodeName)
+
+	remoteClient, err := r.Tracker.GetClient(ctx, util.ObjectKey(cluster))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create a remote client for cluster")
+	}
+
+	node := &corev1.Node{}
+	if err := remoteClient.Get(ctx, client.ObjectKey{Name: nodeName}, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			// If an admin deletes the node directly, we'll end up here.
+			log.Error(err, "Could not find node from noderef, it may have already been deleted")
+			return 0, nil
+		}
+		return 0, errors.Wrapf(err, "failed to get node %q", nodeName)
+	}
+
+	return len(node.Status.VolumesAttached), nil
+}
+
 func (r *MachineReconciler) deleteNode(ctx context.Context, cluster *clusterv1.Cluster, name string) error {
 	log := ctrl.LoggerFrom(ctx, "cluster", cluster.Name)
 
@@ -0,0 +1,161 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	kubedrain "sigs.k8s.io/cluster-api/third_party/kubernetes-drain"
+)
+
+const (
+	// maxDrainBlockingPodsInMessage is the maximum number of pods listed in the DrainingSucceeded condition message.
+	maxDrainBlockingPodsInMessage = 5
+)
+
+// applyNodeDrainOptions configures the drainer according to the Machine's NodeDrainOptions.
+func applyNodeDrainOptions(drainer *kubedrain.Helper, options *clusterv1.NodeDrainOptions) error {
+	if options == nil {
+		return nil
+	}
+
+	if options.GracePeriodSeconds != nil {
+		drainer.GracePeriodSeconds = int(*options.GracePeriodSeconds)
+	}
+
+	// The drainer fails on pods using emptyDir volumes if DeleteLocalData is false, so those pods are skipped
+	// by an additional filter instead, leaving them running on the node.
+	if options.DeleteEmptyDirData != nil && !*options.DeleteEmptyDirData {
+		drainer.AdditionalFilters = append(drainer.AdditionalFilters, func(pod corev1.Pod) kubedrain.PodDeleteStatus {
+			if usesEmptyDir(pod) && pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+				return kubedrain.MakePodDeleteStatusSkip()
+			}
+			return kubedrain.MakePodDeleteStatusOkay()
+		})
+	}
+
+	if options.SkipPodSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(options.SkipPodSelector)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse skipPodSelector")
+		}
+		drainer.AdditionalFilters = append(drainer.AdditionalFilters, func(pod corev1.Pod) kubedrain.PodDeleteStatus {
+			if selector.Matches(labels.Set(pod.Labels)) {
+				return kubedrain.MakePodDeleteStatusSkip()
+			}
+			return kubedrain.MakePodDeleteStatusOkay()
+		})
+	}
+
+	if len(options.SkipNamespaces) > 0 {
+		namespaces := sets.NewString(options.SkipNamespaces...)
+		drainer.AdditionalFilters = append(drainer.AdditionalFilters, func(pod corev1.Pod) kubedrain.PodDeleteStatus {
+			if namespaces.Has(pod.Namespace) {
+				return kubedrain.MakePodDeleteStatusSkip()
+			}
+			return kubedrain.MakePodDeleteStatusOkay()
+		})
+	}
+
+	return nil
+}
+
+// usesEmptyDir returns true if the pod uses at least an emptyDir volume.
+func usesEmptyDir(pod corev1.Pod) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// drainBlockingPodsMessage returns a human readable message listing the pods still blocking the drain of the node.
+func drainBlockingPodsMessage(ctx context.Context, drainer *kubedrain.Helper, nodeName string) string {
+	list, errs := drainer.GetPodsForDeletion(ctx, nodeName)
+	if list == nil {
+		return fmt.Sprintf("failed to list the pods blocking the drain: %v", kerrors.NewAggregate(errs))
+	}
+	if len(errs) > 0 {
+		return fmt.Sprintf("some pods can not be evicted: %v", kerrors.NewAggregate(errs))
+	}
+
+	pods := list.Pods()
+	if len(pods) == 0 {
+		return "waiting for the evicted pods to terminate"
+	}
+
+	names := make([]string, 0, maxDrainBlockingPodsInMessage)
+	for i := range pods {
+		if i == maxDrainBlockingPodsInMessage {
+			break
+		}
+		names = append(names, fmt.Sprintf("%s/%s", pods[i].Namespace, pods[i].Name))
+	}
+	message := fmt.Sprintf("waiting for %d pods to be evicted: %s", len(pods), strings.Join(names, ", "))
+	if len(pods) > maxDrainBlockingPodsInMessage {
+		message += fmt.Sprintf(" and %d more", len(pods)-maxDrainBlockingPodsInMessage)
+	}
+	return message
+}
+
+// evictDaemonSetPods evicts the running DaemonSet-managed pods from the node, honoring the drainer's additional filters.
+func evictDaemonSetPods(ctx context.Context, drainer *kubedrain.Helper, nodeName string) error {
+	podList, err := drainer.Client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{"spec.nodeName": nodeName}).String(),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list pods on node %q", nodeName)
+	}
+
+	pods := []corev1.Pod{}
+	for _, pod := range podList.Items {
+		controllerRef := metav1.GetControllerOf(&pod)
+		if controllerRef == nil || controllerRef.Kind != "DaemonSet" {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if !shouldEvictPod(drainer, pod) {
+			continue
+		}
+		pods = append(pods, pod)
+	}
+
+	return drainer.DeleteOrEvictPods(ctx, pods)
+}
+
+// shouldEvictPod returns true if none of the drainer's additional filters excludes the pod.
+func shouldEvictPod(drainer *kubedrain.Helper, pod corev1.Pod) bool {
+	for _, filter := range drainer.AdditionalFilters {
+		if !filter(pod).Delete {
+			return false
+		}
+	}
+	return true
+}
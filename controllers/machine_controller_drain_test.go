@@ -0,0 +1,240 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"io/ioutil"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	kubedrain "sigs.k8s.io/cluster-api/third_party/kubernetes-drain"
+)
+
+func newDrainTestPod(namespace, name string, labels map[string]string, controllerKind string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    labels,
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "test-node",
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+		},
+	}
+	if controllerKind != "" {
+		pod.OwnerReferences = []metav1.OwnerReference{
+			{Kind: controllerKind, Name: "owner", Controller: pointer.BoolPtr(true)},
+		}
+	}
+	return pod
+}
+
+func newTestDrainer(objs ...*corev1.Pod) *kubedrain.Helper {
+	clientset := fake.NewSimpleClientset()
+	for _, o := range objs {
+		_ = clientset.Tracker().Add(o)
+	}
+	return &kubedrain.Helper{
+		Client:              clientset,
+		Force:               true,
+		IgnoreAllDaemonSets: true,
+		DeleteLocalData:     true,
+		GracePeriodSeconds:  -1,
+		Out:                 ioutil.Discard,
+		ErrOut:              ioutil.Discard,
+	}
+}
+
+func TestApplyNodeDrainOptions(t *testing.T) {
+	pods := []*corev1.Pod{
+		newDrainTestPod("default", "app", map[string]string{"app": "web"}, "ReplicaSet"),
+		newDrainTestPod("default", "storage", map[string]string{"app": "storage"}, "ReplicaSet"),
+		newDrainTestPod("kube-system", "coredns", map[string]string{"app": "coredns"}, "ReplicaSet"),
+	}
+
+	tests := []struct {
+		name         string
+		options      *clusterv1.NodeDrainOptions
+		expectErr    bool
+		expectedPods []string
+	}{
+		{
+			name:         "no options evicts all the pods",
+			options:      nil,
+			expectedPods: []string{"app", "storage", "coredns"},
+		},
+		{
+			name: "pods matching the skip selector are not evicted",
+			options: &clusterv1.NodeDrainOptions{
+				SkipPodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "storage"}},
+			},
+			expectedPods: []string{"app", "coredns"},
+		},
+		{
+			name: "pods in skipped namespaces are not evicted",
+			options: &clusterv1.NodeDrainOptions{
+				SkipNamespaces: []string{"kube-system"},
+			},
+			expectedPods: []string{"app", "storage"},
+		},
+		{
+			name: "invalid skip selector returns an error",
+			options: &clusterv1.NodeDrainOptions{
+				SkipPodSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Unknown"}},
+				},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			drainer := newTestDrainer(pods...)
+			err := applyNodeDrainOptions(drainer, tt.options)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			list, errs := drainer.GetPodsForDeletion(ctx, "test-node")
+			g.Expect(errs).To(BeEmpty())
+			names := []string{}
+			for _, pod := range list.Pods() {
+				names = append(names, pod.Name)
+			}
+			g.Expect(names).To(ConsistOf(tt.expectedPods))
+		})
+	}
+}
+
+func TestApplyNodeDrainOptionsOverrides(t *testing.T) {
+	g := NewWithT(t)
+
+	drainer := newTestDrainer()
+	g.Expect(applyNodeDrainOptions(drainer, &clusterv1.NodeDrainOptions{
+		GracePeriodSeconds: pointer.Int32Ptr(30),
+		DeleteEmptyDirData: pointer.BoolPtr(false),
+	})).To(Succeed())
+	g.Expect(drainer.GracePeriodSeconds).To(Equal(30))
+	g.Expect(drainer.DeleteLocalData).To(BeTrue())
+}
+
+func TestApplyNodeDrainOptionsEmptyDir(t *testing.T) {
+	withEmptyDir := func(pod *corev1.Pod, phase corev1.PodPhase) *corev1.Pod {
+		pod.Spec.Volumes = []corev1.Volume{{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+		pod.Status.Phase = phase
+		return pod
+	}
+	pods := []*corev1.Pod{
+		newDrainTestPod("default", "app", nil, "ReplicaSet"),
+		withEmptyDir(newDrainTestPod("default", "cache", nil, "ReplicaSet"), corev1.PodRunning),
+		withEmptyDir(newDrainTestPod("default", "job", nil, "Job"), corev1.PodSucceeded),
+	}
+
+	tests := []struct {
+		name         string
+		options      *clusterv1.NodeDrainOptions
+		expectedPods []string
+	}{
+		{
+			name:         "pods using emptyDir volumes are evicted by default",
+			options:      &clusterv1.NodeDrainOptions{},
+			expectedPods: []string{"app", "cache", "job"},
+		},
+		{
+			name:         "pods using emptyDir volumes are evicted if deleteEmptyDirData is true",
+			options:      &clusterv1.NodeDrainOptions{DeleteEmptyDirData: pointer.BoolPtr(true)},
+			expectedPods: []string{"app", "cache", "job"},
+		},
+		{
+			name:         "running pods using emptyDir volumes are not evicted if deleteEmptyDirData is false",
+			options:      &clusterv1.NodeDrainOptions{DeleteEmptyDirData: pointer.BoolPtr(false)},
+			expectedPods: []string{"app", "job"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			drainer := newTestDrainer(pods...)
+			g.Expect(applyNodeDrainOptions(drainer, tt.options)).To(Succeed())
+
+			list, errs := drainer.GetPodsForDeletion(ctx, "test-node")
+			g.Expect(errs).To(BeEmpty())
+			names := []string{}
+			for _, pod := range list.Pods() {
+				names = append(names, pod.Name)
+			}
+			g.Expect(names).To(ConsistOf(tt.expectedPods))
+		})
+	}
+}
+
+func TestDrainBlockingPodsMessage(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(drainBlockingPodsMessage(ctx, newTestDrainer(), "test-node")).To(Equal("waiting for the evicted pods to terminate"))
+
+	pods := []*corev1.Pod{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		pods = append(pods, newDrainTestPod("default", name, nil, "ReplicaSet"))
+	}
+	message := drainBlockingPodsMessage(ctx, newTestDrainer(pods...), "test-node")
+	g.Expect(message).To(HavePrefix("waiting for 7 pods to be evicted: "))
+	g.Expect(message).To(HaveSuffix(" and 2 more"))
+
+	drainer := newTestDrainer()
+	drainer.PodSelector = "app in (web"
+	g.Expect(drainBlockingPodsMessage(ctx, drainer, "test-node")).To(HavePrefix("failed to list the pods blocking the drain: "))
+}
+
+func TestEvictDaemonSetPods(t *testing.T) {
+	g := NewWithT(t)
+
+	drainer := newTestDrainer(
+		newDrainTestPod("default", "app", nil, "ReplicaSet"),
+		newDrainTestPod("default", "log-collector", nil, "DaemonSet"),
+		newDrainTestPod("kube-system", "kube-proxy", nil, "DaemonSet"),
+	)
+	g.Expect(applyNodeDrainOptions(drainer, &clusterv1.NodeDrainOptions{
+		SkipNamespaces: []string{"kube-system"},
+	})).To(Succeed())
+
+	g.Expect(evictDaemonSetPods(ctx, drainer, "test-node")).To(Succeed())
+
+	podList, err := drainer.Client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	names := []string{}
+	for _, pod := range podList.Items {
+		names = append(names, pod.Name)
+	}
+	g.Expect(names).To(ConsistOf("app", "kube-proxy"))
+}
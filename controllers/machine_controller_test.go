@@ -27,6 +27,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/controllers/external"
@@ -1142,6 +1143,111 @@ func TestRemoveMachineFinalizerAfterDeleteReconcile(t *testing.T) {
 	g.Expect(actual.ObjectMeta.Finalizers).To(BeEmpty())
 }
 
+func TestMachineDeletionLifecycleHooks(t *testing.T) {
+	dt := metav1.Now()
+
+	testCluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-cluster"},
+	}
+
+	controlPlaneMachine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cp1",
+			Namespace: "default",
+			Labels: map[string]string{
+				clusterv1.ClusterLabelName:             "test-cluster",
+				clusterv1.MachineControlPlaneLabelName: "",
+			},
+		},
+		Spec: clusterv1.MachineSpec{
+			ClusterName: "test-cluster",
+			Bootstrap:   clusterv1.Bootstrap{DataSecretName: pointer.StringPtr("data")},
+		},
+		Status: clusterv1.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "cp1-node"},
+		},
+	}
+
+	newMachine := func(nodeRef *corev1.ObjectReference, annotations map[string]string) *clusterv1.Machine {
+		return &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "delete123",
+				Namespace: "default",
+				Labels: map[string]string{
+					clusterv1.ClusterLabelName: "test-cluster",
+				},
+				Annotations:       annotations,
+				Finalizers:        []string{clusterv1.MachineFinalizer},
+				DeletionTimestamp: &dt,
+			},
+			Spec: clusterv1.MachineSpec{
+				ClusterName: "test-cluster",
+				InfrastructureRef: corev1.ObjectReference{
+					APIVersion: "infrastructure.cluster.x-k8s.io/v1alpha4",
+					Kind:       "InfrastructureMachine",
+					Name:       "infra-config1",
+				},
+				Bootstrap: clusterv1.Bootstrap{DataSecretName: pointer.StringPtr("data")},
+			},
+			Status: clusterv1.MachineStatus{
+				NodeRef: nodeRef,
+			},
+		}
+	}
+
+	testCases := []struct {
+		name                string
+		machine             *clusterv1.Machine
+		expectedCondition   clusterv1.ConditionType
+		expectFinalizerGone bool
+	}{
+		{
+			name: "pre-drain hook blocks draining",
+			machine: newMachine(&corev1.ObjectReference{Name: "test-node"}, map[string]string{
+				clusterv1.PreDrainDeleteHookAnnotationPrefix + "/migrate-storage": "storage-controller",
+			}),
+			expectedCondition: clusterv1.PreDrainDeleteHookSucceededCondition,
+		},
+		{
+			name: "pre-terminate hook blocks infrastructure deletion",
+			machine: newMachine(nil, map[string]string{
+				clusterv1.PreTerminateDeleteHookAnnotationPrefix + "/evacuate": "etcd-operator",
+			}),
+			expectedCondition: clusterv1.PreTerminateDeleteHookSucceededCondition,
+		},
+		{
+			name:                "no hooks, deletion proceeds",
+			machine:             newMachine(nil, nil),
+			expectFinalizerGone: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			key := client.ObjectKey{Namespace: tc.machine.Namespace, Name: tc.machine.Name}
+			mr := &MachineReconciler{
+				Client: helpers.NewFakeClientWithScheme(scheme.Scheme, testCluster, controlPlaneMachine, tc.machine),
+			}
+			_, err := mr.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			actual := &clusterv1.Machine{}
+			g.Expect(mr.Client.Get(ctx, key, actual)).To(Succeed())
+			if tc.expectFinalizerGone {
+				g.Expect(actual.ObjectMeta.Finalizers).To(BeEmpty())
+				g.Expect(conditions.IsTrue(actual, clusterv1.PreDrainDeleteHookSucceededCondition)).To(BeFalse())
+				g.Expect(conditions.IsTrue(actual, clusterv1.PreTerminateDeleteHookSucceededCondition)).To(BeTrue())
+				return
+			}
+			g.Expect(actual.ObjectMeta.Finalizers).To(ContainElement(clusterv1.MachineFinalizer))
+			g.Expect(conditions.IsFalse(actual, tc.expectedCondition)).To(BeTrue())
+			g.Expect(conditions.GetReason(actual, tc.expectedCondition)).To(Equal(clusterv1.WaitingExternalHookReason))
+		})
+	}
+}
+
 func Test_clusterToActiveMachines(t *testing.T) {
 	testCluster2Machines := &clusterv1.Cluster{
 		TypeMeta:   metav1.TypeMeta{Kind: "Cluster", APIVersion: clusterv1.GroupVersion.String()},
@@ -1349,6 +1455,284 @@ func TestIsNodeDrainedAllowed(t *testing.T) {
 	}
 }
 
+func TestIsNodeVolumeDetachingAllowed(t *testing.T) {
+	newMachine := func(annotations map[string]string, timeout *metav1.Duration, waitingSince *time.Time) *clusterv1.Machine {
+		m := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test-machine",
+				Namespace:   "default",
+				Finalizers:  []string{clusterv1.MachineFinalizer},
+				Annotations: annotations,
+			},
+			Spec: clusterv1.MachineSpec{
+				ClusterName:             "test-cluster",
+				InfrastructureRef:       corev1.ObjectReference{},
+				Bootstrap:               clusterv1.Bootstrap{DataSecretName: pointer.StringPtr("data")},
+				NodeVolumeDetachTimeout: timeout,
+			},
+		}
+		if waitingSince != nil {
+			m.Status.Conditions = clusterv1.Conditions{
+				{
+					Type:               clusterv1.VolumeDetachSucceededCondition,
+					Status:             corev1.ConditionFalse,
+					LastTransitionTime: metav1.Time{Time: waitingSince.UTC()},
+				},
+			}
+		}
+		return m
+	}
+
+	longAgo := time.Now().Add(-(time.Second * 70))
+	recently := time.Now().Add(-(time.Second * 10))
+	pastDefaultTimeout := time.Now().Add(-(clusterv1.DefaultNodeVolumeDetachTimeout + time.Second))
+
+	tests := []struct {
+		name     string
+		machine  *clusterv1.Machine
+		expected bool
+	}{
+		{
+			name:     "Exclude wait for node volume detach annotation exists",
+			machine:  newMachine(map[string]string{clusterv1.ExcludeWaitForNodeVolumeDetachAnnotation: "existed!!"}, nil, nil),
+			expected: false,
+		},
+		{
+			name:     "Volume detach timeout is over",
+			machine:  newMachine(nil, &metav1.Duration{Duration: time.Second * 60}, &longAgo),
+			expected: false,
+		},
+		{
+			name:     "Volume detach timeout is not yet over",
+			machine:  newMachine(nil, &metav1.Duration{Duration: time.Second * 60}, &recently),
+			expected: true,
+		},
+		{
+			name:     "NodeVolumeDetachTimeout is not set and the default timeout is not yet over",
+			machine:  newMachine(nil, nil, &longAgo),
+			expected: true,
+		},
+		{
+			name:     "NodeVolumeDetachTimeout is not set and the default timeout is over",
+			machine:  newMachine(nil, nil, &pastDefaultTimeout),
+			expected: false,
+		},
+		{
+			name:     "NodeVolumeDetachTimeout option is set to 0",
+			machine:  newMachine(nil, &metav1.Duration{}, &pastDefaultTimeout),
+			expected: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			r := &MachineReconciler{}
+			g.Expect(r.isNodeVolumeDetachingAllowed(tt.machine)).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestReconcileDeleteNodeVolumeDetach(t *testing.T) {
+	testCluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-cluster"},
+	}
+
+	controlPlaneMachine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cp1",
+			Namespace: "default",
+			Labels: map[string]string{
+				clusterv1.ClusterLabelName:             "test-cluster",
+				clusterv1.MachineControlPlaneLabelName: "",
+			},
+		},
+		Spec: clusterv1.MachineSpec{
+			ClusterName: "test-cluster",
+			Bootstrap:   clusterv1.Bootstrap{DataSecretName: pointer.StringPtr("data")},
+		},
+		Status: clusterv1.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "cp1-node"},
+		},
+	}
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-node"},
+		Status: corev1.NodeStatus{
+			VolumesAttached: []corev1.AttachedVolume{{Name: "kubernetes.io/csi/volume-1", DevicePath: "/dev/sdb"}},
+		},
+	}
+
+	newMachine := func(drained bool, waitingSince *time.Time) *clusterv1.Machine {
+		dt := metav1.Now()
+		m := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "delete123",
+				Namespace: "default",
+				Labels: map[string]string{
+					clusterv1.ClusterLabelName: "test-cluster",
+				},
+				Annotations:       map[string]string{clusterv1.ExcludeNodeDrainingAnnotation: ""},
+				Finalizers:        []string{clusterv1.MachineFinalizer},
+				DeletionTimestamp: &dt,
+			},
+			Spec: clusterv1.MachineSpec{
+				ClusterName: "test-cluster",
+				InfrastructureRef: corev1.ObjectReference{
+					APIVersion: "infrastructure.cluster.x-k8s.io/v1alpha4",
+					Kind:       "InfrastructureMachine",
+					Name:       "infra-config1",
+				},
+				Bootstrap: clusterv1.Bootstrap{DataSecretName: pointer.StringPtr("data")},
+			},
+			Status: clusterv1.MachineStatus{
+				NodeRef: &corev1.ObjectReference{Name: "test-node"},
+			},
+		}
+		if drained {
+			conditions.MarkTrue(m, clusterv1.DrainingSucceededCondition)
+		}
+		if waitingSince != nil {
+			m.Status.Conditions = append(m.Status.Conditions, clusterv1.Condition{
+				Type:               clusterv1.VolumeDetachSucceededCondition,
+				Status:             corev1.ConditionFalse,
+				Reason:             clusterv1.WaitingForVolumeDetachReason,
+				LastTransitionTime: metav1.Time{Time: waitingSince.UTC()},
+			})
+		}
+		return m
+	}
+
+	recently := time.Now().Add(-(time.Second * 10))
+	pastDefaultTimeout := time.Now().Add(-(clusterv1.DefaultNodeVolumeDetachTimeout + time.Second))
+
+	tests := []struct {
+		name                string
+		machine             *clusterv1.Machine
+		expectRequeue       bool
+		expectReason        string
+		expectFinalizerGone bool
+	}{
+		{
+			name:          "waits for the volumes of a drained node to be detached",
+			machine:       newMachine(true, &recently),
+			expectRequeue: true,
+			expectReason:  clusterv1.WaitingForVolumeDetachReason,
+		},
+		{
+			name:                "stops waiting once the default timeout is over",
+			machine:             newMachine(true, &pastDefaultTimeout),
+			expectReason:        clusterv1.VolumeDetachTimedOutReason,
+			expectFinalizerGone: true,
+		},
+		{
+			name:                "does not wait for the volumes of a node that is not drained",
+			machine:             newMachine(false, nil),
+			expectFinalizerGone: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := helpers.NewFakeClientWithScheme(scheme.Scheme, testCluster, controlPlaneMachine, tt.machine, node.DeepCopy())
+			recorder := record.NewFakeRecorder(32)
+			r := &MachineReconciler{
+				Client:   c,
+				Tracker:  remote.NewTestClusterCacheTracker(log.NullLogger{}, c, scheme.Scheme, client.ObjectKey{Name: testCluster.Name, Namespace: testCluster.Namespace}),
+				recorder: recorder,
+			}
+
+			res, err := r.reconcileDelete(ctx, testCluster, tt.machine)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(res.RequeueAfter > 0).To(Equal(tt.expectRequeue))
+			if tt.expectReason == "" {
+				g.Expect(conditions.Get(tt.machine, clusterv1.VolumeDetachSucceededCondition)).To(BeNil())
+			} else {
+				g.Expect(conditions.IsFalse(tt.machine, clusterv1.VolumeDetachSucceededCondition)).To(BeTrue())
+				g.Expect(conditions.GetReason(tt.machine, clusterv1.VolumeDetachSucceededCondition)).To(Equal(tt.expectReason))
+			}
+			if tt.expectReason == clusterv1.VolumeDetachTimedOutReason {
+				g.Expect(recorder.Events).To(Receive(ContainSubstring("VolumeDetachTimedOut")))
+			}
+			if tt.expectFinalizerGone {
+				g.Expect(tt.machine.Finalizers).To(BeEmpty())
+			} else {
+				g.Expect(tt.machine.Finalizers).To(ContainElement(clusterv1.MachineFinalizer))
+			}
+		})
+	}
+}
+
+func TestReconcileDeleteInvalidNodeDrainOptions(t *testing.T) {
+	g := NewWithT(t)
+
+	testCluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-cluster"},
+	}
+	controlPlaneMachine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cp1",
+			Namespace: "default",
+			Labels: map[string]string{
+				clusterv1.ClusterLabelName:             "test-cluster",
+				clusterv1.MachineControlPlaneLabelName: "",
+			},
+		},
+		Spec: clusterv1.MachineSpec{
+			ClusterName: "test-cluster",
+			Bootstrap:   clusterv1.Bootstrap{DataSecretName: pointer.StringPtr("data")},
+		},
+		Status: clusterv1.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "cp1-node"},
+		},
+	}
+	dt := metav1.Now()
+	m := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "delete123",
+			Namespace: "default",
+			Labels: map[string]string{
+				clusterv1.ClusterLabelName: "test-cluster",
+			},
+			Finalizers:        []string{clusterv1.MachineFinalizer},
+			DeletionTimestamp: &dt,
+		},
+		Spec: clusterv1.MachineSpec{
+			ClusterName: "test-cluster",
+			InfrastructureRef: corev1.ObjectReference{
+				APIVersion: "infrastructure.cluster.x-k8s.io/v1alpha4",
+				Kind:       "InfrastructureMachine",
+				Name:       "infra-config1",
+			},
+			Bootstrap: clusterv1.Bootstrap{DataSecretName: pointer.StringPtr("data")},
+			NodeDrainOptions: &clusterv1.NodeDrainOptions{
+				SkipPodSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Invalid"}},
+				},
+			},
+		},
+		Status: clusterv1.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "test-node"},
+		},
+	}
+
+	c := helpers.NewFakeClientWithScheme(scheme.Scheme, testCluster, controlPlaneMachine, m)
+	recorder := record.NewFakeRecorder(32)
+	r := &MachineReconciler{
+		Client:   c,
+		recorder: recorder,
+	}
+
+	// The node is never reported as drained, so its infrastructure is not deleted.
+	_, err := r.reconcileDelete(ctx, testCluster, m)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(conditions.IsFalse(m, clusterv1.DrainingSucceededCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(m, clusterv1.DrainingSucceededCondition)).To(Equal(clusterv1.DrainingFailedReason))
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("FailedDrainNode")))
+	g.Expect(m.Finalizers).To(ContainElement(clusterv1.MachineFinalizer))
+}
+
 func TestIsDeleteNodeAllowed(t *testing.T) {
 	deletionts := metav1.Now()
 
@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+// readinessGatesNotReady returns the condition types in the Machine's readiness gates that are missing or not True.
+func readinessGatesNotReady(machine *clusterv1.Machine) []string {
+	var notReady []string
+	for _, gate := range machine.Spec.ReadinessGates {
+		if !conditions.IsTrue(machine, gate.ConditionType) {
+			notReady = append(notReady, string(gate.ConditionType))
+		}
+	}
+	return notReady
+}
+
+// readinessGatesAvailable returns true if all the conditions in the Machine's readiness gates
+// have been True for at least minReadySeconds.
+func readinessGatesAvailable(machine *clusterv1.Machine, minReadySeconds int32, now metav1.Time) bool {
+	minReadySecondsDuration := time.Duration(minReadySeconds) * time.Second
+	for _, gate := range machine.Spec.ReadinessGates {
+		if !conditions.IsTrue(machine, gate.ConditionType) {
+			return false
+		}
+		if minReadySeconds == 0 {
+			continue
+		}
+		lastTransitionTime := conditions.GetLastTransitionTime(machine, gate.ConditionType)
+		if lastTransitionTime == nil || lastTransitionTime.IsZero() || !lastTransitionTime.Add(minReadySecondsDuration).Before(now.Time) {
+			return false
+		}
+	}
+	return true
+}
+
+// setReadinessGatesCondition sets the ReadinessGatesReady condition summarizing the conditions in the Machine's
+// readiness gates; the condition is removed if the Machine does not define readiness gates.
+func setReadinessGatesCondition(machine *clusterv1.Machine) {
+	if len(machine.Spec.ReadinessGates) == 0 {
+		conditions.Delete(machine, clusterv1.ReadinessGatesReadyCondition)
+		return
+	}
+
+	if notReady := readinessGatesNotReady(machine); len(notReady) > 0 {
+		conditions.MarkFalse(machine, clusterv1.ReadinessGatesReadyCondition, clusterv1.ReadinessGatesNotReadyReason, clusterv1.ConditionSeverityInfo,
+			"Waiting for readiness gates %s", strings.Join(notReady, ", "))
+		return
+	}
+	conditions.MarkTrue(machine, clusterv1.ReadinessGatesReadyCondition)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+func TestReadinessGates(t *testing.T) {
+	now := metav1.Now()
+	gates := []clusterv1.MachineReadinessGate{{ConditionType: "CNIReady"}, {ConditionType: "StorageAttached"}}
+	trueSince := func(t clusterv1.ConditionType, ago time.Duration) clusterv1.Condition {
+		return clusterv1.Condition{Type: t, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(now.Add(-ago))}
+	}
+
+	testCases := []struct {
+		name              string
+		gates             []clusterv1.MachineReadinessGate
+		conditions        clusterv1.Conditions
+		minReadySeconds   int32
+		expectedNotReady  []string
+		expectedAvailable bool
+		expectedCondition *corev1.ConditionStatus
+	}{
+		{
+			name:              "without readiness gates",
+			expectedAvailable: true,
+		},
+		{
+			name:              "with missing conditions",
+			gates:             gates,
+			conditions:        clusterv1.Conditions{trueSince("CNIReady", time.Minute)},
+			expectedNotReady:  []string{"StorageAttached"},
+			expectedAvailable: false,
+			expectedCondition: statusPtr(corev1.ConditionFalse),
+		},
+		{
+			name:  "with conditions not True",
+			gates: gates,
+			conditions: clusterv1.Conditions{
+				trueSince("CNIReady", time.Minute),
+				{Type: "StorageAttached", Status: corev1.ConditionFalse},
+			},
+			expectedNotReady:  []string{"StorageAttached"},
+			expectedAvailable: false,
+			expectedCondition: statusPtr(corev1.ConditionFalse),
+		},
+		{
+			name:              "with all conditions True",
+			gates:             gates,
+			conditions:        clusterv1.Conditions{trueSince("CNIReady", time.Minute), trueSince("StorageAttached", time.Minute)},
+			expectedAvailable: true,
+			expectedCondition: statusPtr(corev1.ConditionTrue),
+		},
+		{
+			name:              "with all conditions True for less than minReadySeconds",
+			gates:             gates,
+			conditions:        clusterv1.Conditions{trueSince("CNIReady", time.Minute), trueSince("StorageAttached", 10*time.Second)},
+			minReadySeconds:   30,
+			expectedAvailable: false,
+			expectedCondition: statusPtr(corev1.ConditionTrue),
+		},
+		{
+			name:              "with all conditions True for more than minReadySeconds",
+			gates:             gates,
+			conditions:        clusterv1.Conditions{trueSince("CNIReady", time.Minute), trueSince("StorageAttached", time.Minute)},
+			minReadySeconds:   30,
+			expectedAvailable: true,
+			expectedCondition: statusPtr(corev1.ConditionTrue),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			machine := &clusterv1.Machine{
+				Spec:   clusterv1.MachineSpec{ReadinessGates: tc.gates},
+				Status: clusterv1.MachineStatus{Conditions: tc.conditions},
+			}
+
+			g.Expect(readinessGatesNotReady(machine)).To(Equal(tc.expectedNotReady))
+			g.Expect(readinessGatesAvailable(machine, tc.minReadySeconds, now)).To(Equal(tc.expectedAvailable))
+
+			setReadinessGatesCondition(machine)
+			if tc.expectedCondition == nil {
+				g.Expect(conditions.Has(machine, clusterv1.ReadinessGatesReadyCondition)).To(BeFalse())
+			} else {
+				g.Expect(conditions.Get(machine, clusterv1.ReadinessGatesReadyCondition).Status).To(Equal(*tc.expectedCondition))
+			}
+		})
+	}
+}
+
+func statusPtr(status corev1.ConditionStatus) *corev1.ConditionStatus {
+	return &status
+}
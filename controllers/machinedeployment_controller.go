@@ -19,6 +19,8 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -29,8 +31,11 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/controllers/mdutil"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/maintenance"
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/cluster-api/util/predicates"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -186,22 +191,80 @@ func (r *MachineDeploymentReconciler) reconcile(ctx context.Context, cluster *cl
 		}
 	}
 
+	// Surface the capacity of the machines, used by autoscalers to scale from zero.
+	capacity, err := getMachineCapacity(ctx, r.Client, d.Namespace, &d.Spec.Template, d.Spec.Capacity)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	d.Status.Capacity = capacity
+
 	msList, err := r.getMachineSetsForDeployment(ctx, d)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	// Surface rollouts or scale downs stalled by machines protected from deletion.
+	setMachinesDeletableCondition(d, msList)
+
 	if d.Spec.Paused {
+		// Progress is not estimated while the MachineDeployment is paused.
+		d.Status.LastProgressTime = nil
 		return ctrl.Result{}, r.sync(ctx, d, msList)
 	}
 
+	// Defer rollouts outside of the Cluster's maintenance windows, only scaling is performed meanwhile.
+	if oldMSs, _ := mdutil.FindOldMachineSets(d, msList); len(oldMSs) > 0 {
+		now := time.Now()
+		allowed, next, err := maintenance.DisruptionAllowed(cluster, now)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !allowed {
+			log.Info("Rollout deferred until the next maintenance window opens", "next", next)
+			maintenance.MarkDeferred(d, "Rollout", next)
+			d.Status.LastProgressTime = nil
+			return ctrl.Result{RequeueAfter: maintenance.RequeueAfter(next, now)}, r.sync(ctx, d, msList)
+		}
+	}
+	maintenance.MarkAllowed(d, cluster)
+
 	if d.Spec.Strategy.Type == clusterv1.RollingUpdateMachineDeploymentStrategyType {
-		return ctrl.Result{}, r.rolloutRolling(ctx, d, msList)
+		prev := d.DeepCopy()
+		if err := r.rolloutRolling(ctx, d, msList); err != nil {
+			return ctrl.Result{}, err
+		}
+		return r.reconcileProgress(ctx, prev, d, msList)
 	}
 
 	return ctrl.Result{}, errors.Errorf("unexpected deployment strategy type: %s", d.Spec.Strategy.Type)
 }
 
+// setMachinesDeletableCondition sets the MachinesDeletable condition on the MachineDeployment according to the condition
+// of the same type on its MachineSets, documenting a rollout or a scale down stalled by machines protected from deletion.
+func setMachinesDeletableCondition(d *clusterv1.MachineDeployment, msList []*clusterv1.MachineSet) {
+	newMS := mdutil.FindNewMachineSet(d, msList)
+
+	stalled := []string{}
+	for _, ms := range msList {
+		if !conditions.IsFalse(ms, clusterv1.MachinesDeletableCondition) {
+			continue
+		}
+		action := "Rollout"
+		if newMS != nil && newMS.Name == ms.Name {
+			action = "Scale down"
+		}
+		stalled = append(stalled, fmt.Sprintf("%s stalled, MachineSet %s cannot delete machines protected from deletion", action, ms.Name))
+	}
+
+	if len(stalled) > 0 {
+		conditions.MarkFalse(d, clusterv1.MachinesDeletableCondition, clusterv1.MachineDeletionProtectedReason, clusterv1.ConditionSeverityWarning, "%s", strings.Join(stalled, "; "))
+		return
+	}
+	if conditions.Has(d, clusterv1.MachinesDeletableCondition) {
+		conditions.MarkTrue(d, clusterv1.MachinesDeletableCondition)
+	}
+}
+
 // getMachineSetsForDeployment returns a list of MachineSets associated with a MachineDeployment.
 func (r *MachineDeploymentReconciler) getMachineSetsForDeployment(ctx context.Context, d *clusterv1.MachineDeployment) ([]*clusterv1.MachineSet, error) {
 	log := ctrl.LoggerFrom(ctx)
@@ -35,6 +35,7 @@ import (
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/controllers/external"
 	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
 )
 
 var _ reconcile.Reconciler = &MachineDeploymentReconciler{}
@@ -723,3 +724,93 @@ func TestGetMachineSetsForDeployment(t *testing.T) {
 		})
 	}
 }
+
+func TestSetMachinesDeletableCondition(t *testing.T) {
+	template := func(version string) clusterv1.MachineTemplateSpec {
+		return clusterv1.MachineTemplateSpec{
+			ObjectMeta: clusterv1.ObjectMeta{Labels: map[string]string{"version": version}},
+		}
+	}
+	newMachineSetWithCondition := func(name, version string, condition *clusterv1.Condition) *clusterv1.MachineSet {
+		ms := &clusterv1.MachineSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       clusterv1.MachineSetSpec{Template: template(version)},
+		}
+		if condition != nil {
+			conditions.Set(ms, condition)
+		}
+		return ms
+	}
+	protected := conditions.FalseCondition(clusterv1.MachinesDeletableCondition, clusterv1.MachineDeletionProtectedReason, clusterv1.ConditionSeverityWarning, "")
+	deletable := conditions.TrueCondition(clusterv1.MachinesDeletableCondition)
+
+	testCases := []struct {
+		name            string
+		existing        *clusterv1.Condition
+		msList          []*clusterv1.MachineSet
+		expectCondition bool
+		expectStatus    corev1.ConditionStatus
+		expectMessage   string
+	}{
+		{
+			name: "no condition when machines are deletable",
+			msList: []*clusterv1.MachineSet{
+				newMachineSetWithCondition("ms-old", "old", nil),
+				newMachineSetWithCondition("ms-new", "new", nil),
+			},
+			expectCondition: false,
+		},
+		{
+			name: "rollout stalled by an old MachineSet",
+			msList: []*clusterv1.MachineSet{
+				newMachineSetWithCondition("ms-old", "old", protected),
+				newMachineSetWithCondition("ms-new", "new", nil),
+			},
+			expectCondition: true,
+			expectStatus:    corev1.ConditionFalse,
+			expectMessage:   "Rollout stalled, MachineSet ms-old cannot delete machines protected from deletion",
+		},
+		{
+			name: "scale down stalled by the new MachineSet",
+			msList: []*clusterv1.MachineSet{
+				newMachineSetWithCondition("ms-new", "new", protected),
+			},
+			expectCondition: true,
+			expectStatus:    corev1.ConditionFalse,
+			expectMessage:   "Scale down stalled, MachineSet ms-new cannot delete machines protected from deletion",
+		},
+		{
+			name:     "condition set to true when machines become deletable",
+			existing: protected,
+			msList: []*clusterv1.MachineSet{
+				newMachineSetWithCondition("ms-new", "new", deletable),
+			},
+			expectCondition: true,
+			expectStatus:    corev1.ConditionTrue,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			d := &clusterv1.MachineDeployment{
+				Spec: clusterv1.MachineDeploymentSpec{Template: template("new")},
+			}
+			if tc.existing != nil {
+				conditions.Set(d, tc.existing)
+			}
+
+			setMachinesDeletableCondition(d, tc.msList)
+
+			if !tc.expectCondition {
+				g.Expect(conditions.Has(d, clusterv1.MachinesDeletableCondition)).To(BeFalse())
+				return
+			}
+			c := conditions.Get(d, clusterv1.MachinesDeletableCondition)
+			g.Expect(c).ToNot(BeNil())
+			g.Expect(c.Status).To(Equal(tc.expectStatus))
+			g.Expect(c.Message).To(Equal(tc.expectMessage))
+		})
+	}
+}
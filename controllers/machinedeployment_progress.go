@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/controllers/mdutil"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// reconcileProgress estimates the progress of the rollout of a MachineDeployment against spec.progressDeadlineSeconds,
+// setting the Progressing condition accordingly; when the deadline is exceeded and spec.autoRollback is set, the machine
+// template is rolled back to the one of the previous MachineSet revision.
+// prev is the MachineDeployment as it was before reconciling the rollout, msList its MachineSets.
+func (r *MachineDeploymentReconciler) reconcileProgress(ctx context.Context, prev, d *clusterv1.MachineDeployment, msList []*clusterv1.MachineSet) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+	now := time.Now()
+
+	if mdutil.DeploymentComplete(d, &d.Status) {
+		d.Status.LastProgressTime = nil
+		conditions.MarkTrue(d, clusterv1.MachineDeploymentProgressingCondition)
+		return ctrl.Result{}, nil
+	}
+
+	rolledBack := conditions.GetReason(d, clusterv1.MachineDeploymentProgressingCondition) == clusterv1.RolledBackReason
+	if d.Status.LastProgressTime == nil || prev.Status.ObservedGeneration != d.Generation || mdutil.DeploymentProgressing(prev, &d.Status) {
+		lastProgressTime := metav1.NewTime(now)
+		d.Status.LastProgressTime = &lastProgressTime
+		// NOTE: keep surfacing the rollback until the rolled back template is fully rolled out.
+		if !rolledBack {
+			conditions.MarkTrue(d, clusterv1.MachineDeploymentProgressingCondition)
+		}
+	}
+
+	if d.Spec.ProgressDeadlineSeconds == nil {
+		return ctrl.Result{}, nil
+	}
+	progressDeadline := time.Duration(*d.Spec.ProgressDeadlineSeconds) * time.Second
+	if deadline := d.Status.LastProgressTime.Add(progressDeadline); now.Before(deadline) {
+		return ctrl.Result{RequeueAfter: deadline.Sub(now)}, nil
+	}
+
+	// The deadline has already been reported, wait for the rollout to make progress or for the user to intervene.
+	if conditions.GetReason(d, clusterv1.MachineDeploymentProgressingCondition) == clusterv1.ProgressDeadlineExceededReason {
+		return ctrl.Result{}, nil
+	}
+
+	newMS := mdutil.FindNewMachineSet(d, msList)
+	newMSName := ""
+	if newMS != nil {
+		newMSName = newMS.Name
+	}
+	log.Info("Rollout did not make progress within the progress deadline", "machineset", newMSName, "progressDeadlineSeconds", *d.Spec.ProgressDeadlineSeconds)
+	r.recorder.Eventf(d, corev1.EventTypeWarning, clusterv1.ProgressDeadlineExceededReason, "Rollout of MachineSet %q did not make progress within %d seconds", newMSName, *d.Spec.ProgressDeadlineSeconds)
+
+	// Automatic rollbacks are performed once: if the rollout of the previous revision does not make progress either,
+	// the user has to intervene.
+	if !d.Spec.AutoRollback || rolledBack || newMS == nil {
+		conditions.MarkFalse(d, clusterv1.MachineDeploymentProgressingCondition, clusterv1.ProgressDeadlineExceededReason, clusterv1.ConditionSeverityWarning,
+			"Rollout of MachineSet %s did not make progress within %d seconds", newMSName, *d.Spec.ProgressDeadlineSeconds)
+		return ctrl.Result{}, nil
+	}
+
+	_, oldMSs := mdutil.FindOldMachineSets(d, msList)
+	target, targetRevision := findRollbackMachineSet(ctx, oldMSs)
+	if target == nil {
+		conditions.MarkFalse(d, clusterv1.MachineDeploymentProgressingCondition, clusterv1.ProgressDeadlineExceededReason, clusterv1.ConditionSeverityWarning,
+			"Rollout of MachineSet %s did not make progress within %d seconds, no previous revision to roll back to", newMSName, *d.Spec.ProgressDeadlineSeconds)
+		return ctrl.Result{}, nil
+	}
+
+	// Roll back the machine template; the next reconcile adopts the MachineSet of the previous revision as the new one.
+	revision := strconv.FormatInt(targetRevision, 10)
+	template := target.Spec.Template.DeepCopy()
+	delete(template.Labels, mdutil.DefaultMachineDeploymentUniqueLabelKey)
+	d.Spec.Template = *template
+
+	lastProgressTime := metav1.NewTime(now)
+	d.Status.LastProgressTime = &lastProgressTime
+	conditions.MarkFalse(d, clusterv1.MachineDeploymentProgressingCondition, clusterv1.RolledBackReason, clusterv1.ConditionSeverityWarning,
+		"Rollout of MachineSet %s did not make progress within %d seconds, rolled back to revision %s", newMSName, *d.Spec.ProgressDeadlineSeconds, revision)
+
+	log.Info("Rolled back the machine template to the previous revision", "machineset", target.Name, "revision", revision)
+	r.recorder.Eventf(d, corev1.EventTypeNormal, clusterv1.RolledBackReason, "Rolled back to revision %s of MachineSet %q", revision, target.Name)
+	return ctrl.Result{}, nil
+}
+
+// findRollbackMachineSet returns the old MachineSet with the highest revision, i.e. the previous revision
+// of the MachineDeployment, and its revision; it returns nil if there are no old MachineSets.
+func findRollbackMachineSet(ctx context.Context, oldMSs []*clusterv1.MachineSet) (*clusterv1.MachineSet, int64) {
+	log := ctrl.LoggerFrom(ctx)
+
+	var target *clusterv1.MachineSet
+	maxRevision := int64(-1)
+	for _, ms := range oldMSs {
+		if !ms.DeletionTimestamp.IsZero() {
+			continue
+		}
+		revision, err := mdutil.Revision(ms)
+		if err != nil {
+			log.V(4).Info("Skipping MachineSet with an invalid revision", "machineset", ms.Name, "revision", ms.Annotations[clusterv1.RevisionAnnotation])
+			continue
+		}
+		if revision > maxRevision {
+			target, maxRevision = ms, revision
+		}
+	}
+	return target, maxRevision
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/controllers/mdutil"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+func TestReconcileProgress(t *testing.T) {
+	machineSet := func(name, revision, version string) *clusterv1.MachineSet {
+		return &clusterv1.MachineSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				UID:         types.UID(name),
+				Annotations: map[string]string{clusterv1.RevisionAnnotation: revision},
+			},
+			Spec: clusterv1.MachineSetSpec{
+				Replicas: pointer.Int32Ptr(3),
+				Template: clusterv1.MachineTemplateSpec{
+					ObjectMeta: clusterv1.ObjectMeta{
+						Labels: map[string]string{"foo": "bar", mdutil.DefaultMachineDeploymentUniqueLabelKey: name},
+					},
+					Spec: clusterv1.MachineSpec{Version: pointer.StringPtr(version)},
+				},
+			},
+		}
+	}
+	msList := func() []*clusterv1.MachineSet {
+		return []*clusterv1.MachineSet{
+			machineSet("ms-1", "1", "v1.19.1"),
+			machineSet("ms-2", "2", "v1.20.1"),
+			machineSet("ms-3", "3", "v1.21.1"),
+		}
+	}
+	deployment := func(version string, autoRollback bool, lastProgress time.Duration, status clusterv1.MachineDeploymentStatus) *clusterv1.MachineDeployment {
+		d := &clusterv1.MachineDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "md", Generation: 1},
+			Spec: clusterv1.MachineDeploymentSpec{
+				Replicas:                pointer.Int32Ptr(3),
+				ProgressDeadlineSeconds: pointer.Int32Ptr(600),
+				AutoRollback:            autoRollback,
+				Template: clusterv1.MachineTemplateSpec{
+					ObjectMeta: clusterv1.ObjectMeta{Labels: map[string]string{"foo": "bar"}},
+					Spec:       clusterv1.MachineSpec{Version: pointer.StringPtr(version)},
+				},
+			},
+			Status: status,
+		}
+		d.Status.ObservedGeneration = 1
+		if lastProgress != 0 {
+			lastProgressTime := metav1.NewTime(time.Now().Add(-lastProgress))
+			d.Status.LastProgressTime = &lastProgressTime
+		}
+		return d
+	}
+	rollingOut := clusterv1.MachineDeploymentStatus{Replicas: 4, UpdatedReplicas: 1, ReadyReplicas: 3, AvailableReplicas: 3}
+
+	t.Run("sets Progressing to true when the rollout is complete", func(t *testing.T) {
+		g := NewWithT(t)
+		r := &MachineDeploymentReconciler{recorder: record.NewFakeRecorder(32)}
+
+		d := deployment("v1.21.1", false, 20*time.Minute, clusterv1.MachineDeploymentStatus{Replicas: 3, UpdatedReplicas: 3, ReadyReplicas: 3, AvailableReplicas: 3})
+		result, err := r.reconcileProgress(ctx, d.DeepCopy(), d, msList())
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(BeZero())
+		g.Expect(d.Status.LastProgressTime).To(BeNil())
+		g.Expect(conditions.IsTrue(d, clusterv1.MachineDeploymentProgressingCondition)).To(BeTrue())
+	})
+
+	t.Run("requeues until the progress deadline when the rollout makes progress", func(t *testing.T) {
+		g := NewWithT(t)
+		r := &MachineDeploymentReconciler{recorder: record.NewFakeRecorder(32)}
+
+		d := deployment("v1.21.1", false, 20*time.Minute, rollingOut)
+		prev := d.DeepCopy()
+		d.Status.UpdatedReplicas = 2
+		result, err := r.reconcileProgress(ctx, prev, d, msList())
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(BeNumerically("~", 10*time.Minute, time.Second))
+		g.Expect(d.Status.LastProgressTime.Time).To(BeTemporally("~", time.Now(), time.Second))
+		g.Expect(conditions.IsTrue(d, clusterv1.MachineDeploymentProgressingCondition)).To(BeTrue())
+	})
+
+	t.Run("reports the progress deadline exceeded without rolling back", func(t *testing.T) {
+		g := NewWithT(t)
+		r := &MachineDeploymentReconciler{recorder: record.NewFakeRecorder(32)}
+
+		d := deployment("v1.21.1", false, 20*time.Minute, rollingOut)
+		result, err := r.reconcileProgress(ctx, d.DeepCopy(), d, msList())
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(BeZero())
+		g.Expect(d.Spec.Template.Spec.Version).To(Equal(pointer.StringPtr("v1.21.1")))
+		g.Expect(conditions.IsFalse(d, clusterv1.MachineDeploymentProgressingCondition)).To(BeTrue())
+		g.Expect(conditions.GetReason(d, clusterv1.MachineDeploymentProgressingCondition)).To(Equal(clusterv1.ProgressDeadlineExceededReason))
+	})
+
+	t.Run("rolls back to the previous revision when the progress deadline is exceeded", func(t *testing.T) {
+		g := NewWithT(t)
+		r := &MachineDeploymentReconciler{recorder: record.NewFakeRecorder(32)}
+
+		d := deployment("v1.21.1", true, 20*time.Minute, rollingOut)
+		_, err := r.reconcileProgress(ctx, d.DeepCopy(), d, msList())
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(d.Spec.Template.Spec.Version).To(Equal(pointer.StringPtr("v1.20.1")))
+		g.Expect(d.Spec.Template.Labels).ToNot(HaveKey(mdutil.DefaultMachineDeploymentUniqueLabelKey))
+		g.Expect(d.Status.LastProgressTime.Time).To(BeTemporally("~", time.Now(), time.Second))
+		g.Expect(conditions.GetReason(d, clusterv1.MachineDeploymentProgressingCondition)).To(Equal(clusterv1.RolledBackReason))
+	})
+
+	t.Run("does not roll back twice", func(t *testing.T) {
+		g := NewWithT(t)
+		r := &MachineDeploymentReconciler{recorder: record.NewFakeRecorder(32)}
+
+		// The rollout of the previous revision, now the latest one, does not make progress either.
+		list := msList()
+		list[1].Annotations[clusterv1.RevisionAnnotation] = "4"
+		d := deployment("v1.20.1", true, 20*time.Minute, rollingOut)
+		conditions.MarkFalse(d, clusterv1.MachineDeploymentProgressingCondition, clusterv1.RolledBackReason, clusterv1.ConditionSeverityWarning, "")
+		_, err := r.reconcileProgress(ctx, d.DeepCopy(), d, list)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(d.Spec.Template.Spec.Version).To(Equal(pointer.StringPtr("v1.20.1")))
+		g.Expect(conditions.GetReason(d, clusterv1.MachineDeploymentProgressingCondition)).To(Equal(clusterv1.ProgressDeadlineExceededReason))
+	})
+
+	t.Run("does not estimate progress without a progress deadline", func(t *testing.T) {
+		g := NewWithT(t)
+		r := &MachineDeploymentReconciler{recorder: record.NewFakeRecorder(32)}
+
+		d := deployment("v1.21.1", true, 20*time.Minute, rollingOut)
+		d.Spec.ProgressDeadlineSeconds = nil
+		result, err := r.reconcileProgress(ctx, d.DeepCopy(), d, msList())
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(BeZero())
+		g.Expect(d.Spec.Template.Spec.Version).To(Equal(pointer.StringPtr("v1.21.1")))
+		g.Expect(conditions.IsFalse(d, clusterv1.MachineDeploymentProgressingCondition)).To(BeFalse())
+	})
+}
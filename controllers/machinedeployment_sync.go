@@ -380,6 +380,10 @@ func calculateStatus(allMSs []*clusterv1.MachineSet, newMS *clusterv1.MachineSet
 		ReadyReplicas:       mdutil.GetReadyReplicaCountForMachineSets(allMSs),
 		AvailableReplicas:   availableReplicas,
 		UnavailableReplicas: unavailableReplicas,
+		// Conditions, capacity and progress are set by the reconcile loop, preserve them.
+		Conditions:       deployment.Status.Conditions,
+		Capacity:         deployment.Status.Capacity,
+		LastProgressTime: deployment.Status.LastProgressTime,
 	}
 
 	if *deployment.Spec.Replicas == status.ReadyReplicas {
@@ -21,6 +21,7 @@ import (
 
 	. "github.com/onsi/gomega"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
@@ -209,6 +210,52 @@ func TestMachineDeploymentSyncStatus(t *testing.T) {
 				Phase:               "Failed",
 			},
 		},
+		"conditions are preserved": {
+			machineSets: []*clusterv1.MachineSet{{
+				Spec: clusterv1.MachineSetSpec{
+					Replicas: pointer.Int32Ptr(1),
+				},
+				Status: clusterv1.MachineSetStatus{
+					AvailableReplicas: 1,
+					ReadyReplicas:     1,
+					Replicas:          1,
+				},
+			}},
+			newMachineSet: &clusterv1.MachineSet{
+				Spec: clusterv1.MachineSetSpec{
+					Replicas: pointer.Int32Ptr(1),
+				},
+				Status: clusterv1.MachineSetStatus{
+					AvailableReplicas: 1,
+					ReadyReplicas:     1,
+					Replicas:          1,
+				},
+			},
+			deployment: &clusterv1.MachineDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Generation: 2,
+				},
+				Spec: clusterv1.MachineDeploymentSpec{
+					Replicas: pointer.Int32Ptr(1),
+				},
+				Status: clusterv1.MachineDeploymentStatus{
+					Conditions: clusterv1.Conditions{
+						{Type: clusterv1.DisruptionAllowedCondition, Status: corev1.ConditionTrue},
+					},
+				},
+			},
+			expectedStatus: clusterv1.MachineDeploymentStatus{
+				ObservedGeneration: 2,
+				Replicas:           1,
+				UpdatedReplicas:    1,
+				ReadyReplicas:      1,
+				AvailableReplicas:  1,
+				Phase:              "Running",
+				Conditions: clusterv1.Conditions{
+					{Type: clusterv1.DisruptionAllowedCondition, Status: corev1.ConditionTrue},
+				},
+			},
+		},
 	}
 
 	for name, test := range tests {
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -40,6 +41,7 @@ import (
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/maintenance"
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/cluster-api/util/predicates"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -71,6 +73,11 @@ type MachineHealthCheckReconciler struct {
 
 	controller controller.Controller
 	recorder   record.EventRecorder
+
+	// machineProbeTimes stores the last time each target has been probed, by MachineHealthCheck key;
+	// values are maps from target machine keys to probe times, replaced on every reconcile to only
+	// include the current targets.
+	machineProbeTimes sync.Map
 }
 
 func (r *MachineHealthCheckReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, options controller.Options) error {
@@ -111,6 +118,7 @@ func (r *MachineHealthCheckReconciler) Reconcile(ctx context.Context, req ctrl.R
 		if apierrors.IsNotFound(err) {
 			// Object not found, return.  Created objects are automatically garbage collected.
 			// For additional cleanup logic use finalizers.
+			r.machineProbeTimes.Delete(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 
@@ -208,16 +216,26 @@ func (r *MachineHealthCheckReconciler) reconcile(ctx context.Context, logger log
 	// do sort to avoid keep changing m.Status as the returned machines are not in order
 	sort.Strings(m.Status.Targets)
 
+	// execute the machine probe against all targets
+	if err := r.probeTargets(ctx, logger, cluster, m, targets); err != nil {
+		logger.Error(err, "Failed to probe targets")
+		return ctrl.Result{}, err
+	}
+
 	// health check all targets and reconcile mhc status
 	healthy, unhealthy, nextCheckTimes := r.healthCheckTargets(targets, logger, m.Spec.NodeStartupTimeout.Duration)
 	m.Status.CurrentHealthy = int32(len(healthy))
 
-	// check MHC current health against MaxUnhealthy
+	// drop the remediations that are no longer relevant for rate limiting from the history
+	pruneRemediationHistory(m, time.Now())
+
+	// check MHC current health against MaxUnhealthy or UnhealthyRange
 	if !isAllowedRemediation(m) {
 		logger.V(3).Info(
 			"Short-circuiting remediation",
 			"total target", totalTargets,
 			"max unhealthy", m.Spec.MaxUnhealthy,
+			"unhealthy range", m.Spec.UnhealthyRange,
 			"unhealthy targets", len(unhealthy),
 		)
 		message := fmt.Sprintf("Remediation is not allowed, the number of not started or unhealthy machines exceeds maxUnhealthy (total: %v, unhealthy: %v, maxUnhealthy: %v)",
@@ -225,8 +243,15 @@ func (r *MachineHealthCheckReconciler) reconcile(ctx context.Context, logger log
 			len(unhealthy),
 			m.Spec.MaxUnhealthy,
 		)
+		if m.Spec.UnhealthyRange != nil {
+			message = fmt.Sprintf("Remediation is not allowed, the number of not started or unhealthy machines does not fall within the range (total: %v, unhealthy: %v, unhealthyRange: %v)",
+				totalTargets,
+				len(unhealthy),
+				*m.Spec.UnhealthyRange,
+			)
+		}
 
-		// Remediation not allowed, the number of not started or unhealthy machines exceeds maxUnhealthy
+		// Remediation not allowed, the number of not started or unhealthy machines exceeds maxUnhealthy or does not fall within unhealthyRange
 		m.Status.RemediationsAllowed = 0
 		conditions.Set(m, &clusterv1.Condition{
 			Type:     clusterv1.RemediationAllowedCondition,
@@ -259,6 +284,7 @@ func (r *MachineHealthCheckReconciler) reconcile(ctx context.Context, logger log
 		"Remediations are allowed",
 		"total target", totalTargets,
 		"max unhealthy", m.Spec.MaxUnhealthy,
+		"unhealthy range", m.Spec.UnhealthyRange,
 		"unhealthy targets", len(unhealthy),
 	)
 
@@ -271,6 +297,29 @@ func (r *MachineHealthCheckReconciler) reconcile(ctx context.Context, logger log
 	m.Status.RemediationsAllowed = int32(maxUnhealthy - unhealthyMachineCount(m))
 	conditions.MarkTrue(m, clusterv1.RemediationAllowedCondition)
 
+	// Remediation is a disruptive action, so it is deferred outside of the Cluster's maintenance windows.
+	now := time.Now()
+	disruptionAllowed, nextWindow, err := maintenance.DisruptionAllowed(cluster, now)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if len(unhealthy) > 0 && !disruptionAllowed {
+		logger.Info("Remediation deferred until the next maintenance window opens", "unhealthy targets", len(unhealthy), "next", nextWindow)
+		maintenance.MarkDeferred(m, "Remediation", nextWindow)
+
+		errList := r.PatchHealthyTargets(ctx, logger, healthy, cluster, m)
+		for _, t := range unhealthy {
+			if err := t.patchHelper.Patch(ctx, t.Machine); err != nil {
+				errList = append(errList, errors.Wrapf(err, "failed to patch unhealthy machine status for machine: %s/%s", t.Machine.Namespace, t.Machine.Name))
+			}
+		}
+		if len(errList) > 0 {
+			return ctrl.Result{}, kerrors.NewAggregate(errList)
+		}
+		return ctrl.Result{RequeueAfter: minDuration(append(nextCheckTimes, maintenance.RequeueAfter(nextWindow, now)))}, nil
+	}
+	maintenance.MarkAllowed(m, cluster)
+
 	errList := r.PatchUnhealthyTargets(ctx, logger, unhealthy, cluster, m)
 	errList = append(errList, r.PatchHealthyTargets(ctx, logger, healthy, cluster, m)...)
 
@@ -280,6 +329,33 @@ func (r *MachineHealthCheckReconciler) reconcile(ctx context.Context, logger log
 		return reconcile.Result{}, kerrors.NewAggregate(errList)
 	}
 
+	// report the progress of the Cluster's remediation escalation chain, and ensure a requeue happens
+	// when the remediation attempts in progress time out.
+	m.Status.MachineRemediations = machineRemediations(targets)
+	if steps := remediationSteps(cluster, m); len(steps) > 0 {
+		now := time.Now()
+		for _, t := range unhealthy {
+			if delay := remediationAttemptRequeueAfter(t.Machine, steps, now); delay > 0 {
+				nextCheckTimes = append(nextCheckTimes, delay)
+			}
+		}
+	}
+
+	// if remediations have been deferred by the rate limit, ensure a requeue happens when they are allowed.
+	if conditions.GetReason(m, clusterv1.RemediationAllowedCondition) == clusterv1.RemediationRateLimitedReason {
+		now := time.Now()
+		for _, t := range unhealthy {
+			if delay, _ := remediationDelay(m, t.Machine, conditions.GetReason(t.Machine, clusterv1.MachineHealthCheckSuccededCondition), now); delay > 0 {
+				nextCheckTimes = append(nextCheckTimes, delay)
+			}
+		}
+	}
+
+	// if a machine probe is defined, ensure a requeue happens to execute it again.
+	if m.Spec.MachineProbe != nil {
+		nextCheckTimes = append(nextCheckTimes, machineProbePeriod(m.Spec.MachineProbe))
+	}
+
 	if minNextCheck := minDuration(nextCheckTimes); minNextCheck > 0 {
 		logger.V(3).Info("Some targets might go unhealthy. Ensuring a requeue happens", "requeueIn", minNextCheck.Truncate(time.Second).String())
 		return ctrl.Result{RequeueAfter: minNextCheck}, nil
@@ -311,6 +387,11 @@ func (r *MachineHealthCheckReconciler) PatchHealthyTargets(ctx context.Context,
 					logger.Error(err, "failed to delete %v %q for Machine %q", obj.GroupVersionKind(), obj.GetName(), t.Machine.Name)
 				}
 			}
+		} else if steps := remediationSteps(cluster, m); len(steps) > 0 {
+			if err := r.resetRemediation(ctx, logger, t, steps); err != nil {
+				errList = append(errList, err)
+				continue
+			}
 		}
 
 		if err := t.patchHelper.Patch(ctx, t.Machine); err != nil {
@@ -338,54 +419,31 @@ func (r *MachineHealthCheckReconciler) PatchUnhealthyTargets(ctx context.Context
 					return errList
 				}
 
-				cloneOwnerRef := &metav1.OwnerReference{
-					APIVersion: clusterv1.GroupVersion.String(),
-					Kind:       "Machine",
-					Name:       t.Machine.Name,
-					UID:        t.Machine.UID,
-				}
-
-				from, err := external.Get(ctx, r.Client, m.Spec.RemediationTemplate, t.Machine.Namespace)
-				if err != nil {
-					conditions.MarkFalse(m, clusterv1.ExternalRemediationTemplateAvailable, clusterv1.ExternalRemediationTemplateNotFound, clusterv1.ConditionSeverityError, err.Error())
-					errList = append(errList, errors.Wrapf(err, "error retrieving remediation template %v %q for machine %q in namespace %q within cluster %q", m.Spec.RemediationTemplate.GroupVersionKind(), m.Spec.RemediationTemplate.Name, t.Machine.Name, t.Machine.Namespace, m.Spec.ClusterName))
-					return errList
+				if r.isRemediationRateLimited(logger, t, condition.Reason, m) {
+					if err := t.patchHelper.Patch(ctx, t.Machine); err != nil {
+						errList = append(errList, errors.Wrapf(err, "failed to patch unhealthy machine status for machine: %s/%s", t.Machine.Namespace, t.Machine.Name))
+					}
+					continue
 				}
 
-				generateTemplateInput := &external.GenerateTemplateInput{
-					Template:    from,
-					TemplateRef: m.Spec.RemediationTemplate,
-					Namespace:   t.Machine.Namespace,
-					ClusterName: t.Machine.ClusterName,
-					OwnerRef:    cloneOwnerRef,
-				}
-				to, err := external.GenerateTemplate(generateTemplateInput)
-				if err != nil {
-					errList = append(errList, errors.Wrapf(err, "failed to create template for remediation request %v %q for machine %q in namespace %q within cluster %q", m.Spec.RemediationTemplate.GroupVersionKind(), m.Spec.RemediationTemplate.Name, t.Machine.Name, t.Machine.Namespace, m.Spec.ClusterName))
+				if err := r.createExternalRemediationRequest(ctx, logger, t, m, m.Spec.RemediationTemplate, condition); err != nil {
+					errList = append(errList, err)
 					return errList
 				}
-
-				// Set the Remediation Request to match the Machine name, the name is used to
-				// guarantee uniqueness between runs. A Machine should only ever have a single
-				// remediation object of a specific GVK created.
-				//
-				// NOTE: This doesn't guarantee uniqueness across different MHC objects watching
-				// the same Machine, users are in charge of setting health checks and remediation properly.
-				to.SetName(t.Machine.Name)
-
-				logger.Info("Target has failed health check, creating an external remediation request", "remediation request name", to.GetName(), "target", t.string(), "reason", condition.Reason, "message", condition.Message)
-				// Create the external clone.
-				if err := r.Client.Create(ctx, to); err != nil {
-					conditions.MarkFalse(m, clusterv1.ExternalRemediationRequestAvailable, clusterv1.ExternalRemediationRequestCreationFailed, clusterv1.ConditionSeverityError, err.Error())
-					errList = append(errList, errors.Wrapf(err, "error creating remediation request for machine %q in namespace %q within cluster %q", t.Machine.Name, t.Machine.Namespace, t.Machine.ClusterName))
-					return errList
+				recordRemediation(m, t.Machine, condition.Reason, time.Now())
+			} else if steps := remediationSteps(cluster, m); len(steps) > 0 {
+				if err := r.escalateRemediation(ctx, logger, t, m, steps, condition); err != nil {
+					errList = append(errList, err)
 				}
 			} else {
 				logger.Info("Target has failed health check, marking for remediation", "target", t.string(), "reason", condition.Reason, "message", condition.Message)
 				// NOTE: MHC is responsible for creating MachineOwnerRemediatedCondition if missing or to trigger another remediation if the previous one is completed;
 				// instead, if a remediation is in already progress, the remediation owner is responsible for completing the process and MHC should not overwrite the condition.
 				if !conditions.Has(t.Machine, clusterv1.MachineOwnerRemediatedCondition) || conditions.IsTrue(t.Machine, clusterv1.MachineOwnerRemediatedCondition) {
-					conditions.MarkFalse(t.Machine, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "")
+					if !r.isRemediationRateLimited(logger, t, condition.Reason, m) {
+						conditions.MarkFalse(t.Machine, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "")
+						recordRemediation(m, t.Machine, condition.Reason, time.Now())
+					}
 				}
 			}
 		}
@@ -405,6 +463,29 @@ func (r *MachineHealthCheckReconciler) PatchUnhealthyTargets(ctx context.Context
 	return errList
 }
 
+// isRemediationRateLimited returns true if the remediation of the target must be deferred according to the
+// MachineHealthCheck's remediation rate limit; in this case the target's health check condition is patched
+// and the MachineHealthCheck's RemediationAllowed condition is updated accordingly.
+func (r *MachineHealthCheckReconciler) isRemediationRateLimited(logger logr.Logger, t healthCheckTarget, reason string, m *clusterv1.MachineHealthCheck) bool {
+	delay, message := remediationDelay(m, t.Machine, reason, time.Now())
+	if delay <= 0 {
+		return false
+	}
+
+	logger.Info("Target has failed health check, but remediation is rate limited", "target", t.string(), "reason", reason, "retryAfter", delay.Truncate(time.Second).String())
+	conditions.MarkFalse(m, clusterv1.RemediationAllowedCondition, clusterv1.RemediationRateLimitedReason, clusterv1.ConditionSeverityWarning, message)
+	r.recorder.Eventf(
+		m,
+		corev1.EventTypeWarning,
+		EventRemediationRestricted,
+		"Remediation of machine %v deferred by %s: %s",
+		t.string(),
+		delay.Truncate(time.Second).String(),
+		message,
+	)
+	return true
+}
+
 // clusterToMachineHealthCheck maps events from Cluster objects to
 // MachineHealthCheck objects that belong to the Cluster
 func (r *MachineHealthCheckReconciler) clusterToMachineHealthCheck(o client.Object) []reconcile.Request {
@@ -517,9 +598,21 @@ func (r *MachineHealthCheckReconciler) watchClusterNodes(ctx context.Context, cl
 	return nil
 }
 
-// isAllowedRemediation checks the value of the MaxUnhealthy field to determine
+// isAllowedRemediation checks the value of the UnhealthyRange or MaxUnhealthy field to determine
 // whether remediation should be allowed or not
 func isAllowedRemediation(mhc *clusterv1.MachineHealthCheck) bool {
+	// UnhealthyRange takes precedence over MaxUnhealthy
+	if mhc.Spec.UnhealthyRange != nil {
+		min, max, err := clusterv1.ParseUnhealthyRange(*mhc.Spec.UnhealthyRange)
+		if err != nil {
+			return false
+		}
+
+		// Remediation is not allowed if unhealthy is outside of the range
+		unhealthy := unhealthyMachineCount(mhc)
+		return unhealthy >= min && unhealthy <= max
+	}
+
 	// TODO(JoelSpeed): return an error from isAllowedRemediation when maxUnhealthy
 	// is nil, we expect it to be defaulted always.
 	if mhc.Spec.MaxUnhealthy == nil {
@@ -536,6 +629,13 @@ func isAllowedRemediation(mhc *clusterv1.MachineHealthCheck) bool {
 }
 
 func getMaxUnhealthy(mhc *clusterv1.MachineHealthCheck) (int, error) {
+	if mhc.Spec.UnhealthyRange != nil {
+		_, max, err := clusterv1.ParseUnhealthyRange(*mhc.Spec.UnhealthyRange)
+		if err != nil {
+			return 0, err
+		}
+		return max, nil
+	}
 	if mhc.Spec.MaxUnhealthy == nil {
 		return 0, errors.New("spec.maxUnhealthy must be set")
 	}
@@ -562,18 +662,67 @@ func machineNames(machines []*clusterv1.Machine) []string {
 
 // getExternalRemediationRequest gets reference to External Remediation Request, unstructured object.
 func (r *MachineHealthCheckReconciler) getExternalRemediationRequest(ctx context.Context, m *clusterv1.MachineHealthCheck, machineName string) (*unstructured.Unstructured, error) {
+	return r.getRemediationRequest(ctx, m.Spec.RemediationTemplate, m.Namespace, machineName)
+}
+
+// getRemediationRequest gets the External Remediation Request created from the given template for a machine.
+func (r *MachineHealthCheckReconciler) getRemediationRequest(ctx context.Context, templateRef *corev1.ObjectReference, namespace, machineName string) (*unstructured.Unstructured, error) {
 	remediationRef := &corev1.ObjectReference{
-		APIVersion: m.Spec.RemediationTemplate.APIVersion,
-		Kind:       strings.TrimSuffix(m.Spec.RemediationTemplate.Kind, external.TemplateSuffix),
+		APIVersion: templateRef.APIVersion,
+		Kind:       strings.TrimSuffix(templateRef.Kind, external.TemplateSuffix),
 		Name:       machineName,
 	}
-	remediationReq, err := external.Get(ctx, r.Client, remediationRef, m.Namespace)
+	remediationReq, err := external.Get(ctx, r.Client, remediationRef, namespace)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to retrieve external remediation request object")
 	}
 	return remediationReq, nil
 }
 
+// createExternalRemediationRequest creates an External Remediation Request for the target from the given template.
+func (r *MachineHealthCheckReconciler) createExternalRemediationRequest(ctx context.Context, logger logr.Logger, t healthCheckTarget, m *clusterv1.MachineHealthCheck, templateRef *corev1.ObjectReference, condition *clusterv1.Condition) error {
+	cloneOwnerRef := &metav1.OwnerReference{
+		APIVersion: clusterv1.GroupVersion.String(),
+		Kind:       "Machine",
+		Name:       t.Machine.Name,
+		UID:        t.Machine.UID,
+	}
+
+	from, err := external.Get(ctx, r.Client, templateRef, t.Machine.Namespace)
+	if err != nil {
+		conditions.MarkFalse(m, clusterv1.ExternalRemediationTemplateAvailable, clusterv1.ExternalRemediationTemplateNotFound, clusterv1.ConditionSeverityError, err.Error())
+		return errors.Wrapf(err, "error retrieving remediation template %v %q for machine %q in namespace %q within cluster %q", templateRef.GroupVersionKind(), templateRef.Name, t.Machine.Name, t.Machine.Namespace, m.Spec.ClusterName)
+	}
+
+	generateTemplateInput := &external.GenerateTemplateInput{
+		Template:    from,
+		TemplateRef: templateRef,
+		Namespace:   t.Machine.Namespace,
+		ClusterName: t.Machine.ClusterName,
+		OwnerRef:    cloneOwnerRef,
+	}
+	to, err := external.GenerateTemplate(generateTemplateInput)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create template for remediation request %v %q for machine %q in namespace %q within cluster %q", templateRef.GroupVersionKind(), templateRef.Name, t.Machine.Name, t.Machine.Namespace, m.Spec.ClusterName)
+	}
+
+	// Set the Remediation Request to match the Machine name, the name is used to
+	// guarantee uniqueness between runs. A Machine should only ever have a single
+	// remediation object of a specific GVK created.
+	//
+	// NOTE: This doesn't guarantee uniqueness across different MHC objects watching
+	// the same Machine, users are in charge of setting health checks and remediation properly.
+	to.SetName(t.Machine.Name)
+
+	logger.Info("Target has failed health check, creating an external remediation request", "remediation request name", to.GetName(), "target", t.string(), "reason", condition.Reason, "message", condition.Message)
+	// Create the external clone.
+	if err := r.Client.Create(ctx, to); err != nil {
+		conditions.MarkFalse(m, clusterv1.ExternalRemediationRequestAvailable, clusterv1.ExternalRemediationRequestCreationFailed, clusterv1.ConditionSeverityError, err.Error())
+		return errors.Wrapf(err, "error creating remediation request for machine %q in namespace %q within cluster %q", t.Machine.Name, t.Machine.Namespace, t.Machine.ClusterName)
+	}
+	return nil
+}
+
 // externalRemediationRequestExists checks if the External Remediation Request is created
 // for the machine.
 func (r *MachineHealthCheckReconciler) externalRemediationRequestExists(ctx context.Context, m *clusterv1.MachineHealthCheck, machineName string) bool {
@@ -1667,6 +1667,7 @@ func TestIsAllowedRemediation(t *testing.T) {
 	testCases := []struct {
 		name               string
 		maxUnhealthy       *intstr.IntOrString
+		unhealthyRange     *string
 		expectedMachines   int32
 		currentHealthy     int32
 		allowed            bool
@@ -1728,6 +1729,38 @@ func TestIsAllowedRemediation(t *testing.T) {
 			currentHealthy:   int32(2),
 			allowed:          true,
 		},
+		{
+			name:             "when unhealthyRange is not a valid range",
+			maxUnhealthy:     &intstr.IntOrString{Type: intstr.String, StrVal: "100%"},
+			unhealthyRange:   pointer.StringPtr("[3-1]"),
+			expectedMachines: int32(5),
+			currentHealthy:   int32(3),
+			allowed:          false,
+		},
+		{
+			name:             "when current unhealthy is within unhealthyRange",
+			maxUnhealthy:     &intstr.IntOrString{Type: intstr.Int, IntVal: int32(0)},
+			unhealthyRange:   pointer.StringPtr("[1-3]"),
+			expectedMachines: int32(5),
+			currentHealthy:   int32(3),
+			allowed:          true,
+		},
+		{
+			name:             "when current unhealthy is below unhealthyRange",
+			maxUnhealthy:     &intstr.IntOrString{Type: intstr.String, StrVal: "100%"},
+			unhealthyRange:   pointer.StringPtr("[3-5]"),
+			expectedMachines: int32(5),
+			currentHealthy:   int32(3),
+			allowed:          false,
+		},
+		{
+			name:             "when current unhealthy is above unhealthyRange",
+			maxUnhealthy:     &intstr.IntOrString{Type: intstr.String, StrVal: "100%"},
+			unhealthyRange:   pointer.StringPtr("[0-1]"),
+			expectedMachines: int32(5),
+			currentHealthy:   int32(3),
+			allowed:          false,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -1737,6 +1770,7 @@ func TestIsAllowedRemediation(t *testing.T) {
 			mhc := &clusterv1.MachineHealthCheck{
 				Spec: clusterv1.MachineHealthCheckSpec{
 					MaxUnhealthy:       tc.maxUnhealthy,
+					UnhealthyRange:     tc.unhealthyRange,
 					NodeStartupTimeout: &metav1.Duration{Duration: 1 * time.Millisecond},
 				},
 				Status: clusterv1.MachineHealthCheckStatus{
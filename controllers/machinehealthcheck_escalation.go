@@ -0,0 +1,284 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+const (
+	// EventRemediationEscalated is emitted when the remediation of a machine moves
+	// to the next step of the Cluster's remediation escalation chain.
+	EventRemediationEscalated string = "RemediationEscalated"
+
+	// remediationEscalationRequeueAfter is how long to wait before checking again a machine whose
+	// remediation attempt timed out, e.g. while its external remediation request is being deleted.
+	remediationEscalationRequeueAfter = 10 * time.Second
+)
+
+// remediationState is the progress of the Cluster's remediation escalation chain for a machine,
+// stored in the machine's annotations.
+type remediationState struct {
+	step        int
+	attempts    int32
+	lastAttempt *metav1.Time
+}
+
+// hasRemediationState returns true if the machine is being remediated by the Cluster's remediation escalation chain.
+func hasRemediationState(machine *clusterv1.Machine) bool {
+	_, ok := machine.GetAnnotations()[clusterv1.RemediationStepAnnotation]
+	return ok
+}
+
+// getRemediationState reads the progress of the Cluster's remediation escalation chain from the machine's annotations;
+// invalid values are ignored.
+func getRemediationState(machine *clusterv1.Machine) remediationState {
+	state := remediationState{}
+	annotations := machine.GetAnnotations()
+	if step, err := strconv.Atoi(annotations[clusterv1.RemediationStepAnnotation]); err == nil && step >= 0 {
+		state.step = step
+	}
+	if attempts, err := strconv.ParseInt(annotations[clusterv1.RemediationAttemptsAnnotation], 10, 32); err == nil && attempts >= 0 {
+		state.attempts = int32(attempts)
+	}
+	if lastAttempt, err := time.Parse(time.RFC3339, annotations[clusterv1.RemediationLastAttemptAnnotation]); err == nil {
+		t := metav1.NewTime(lastAttempt)
+		state.lastAttempt = &t
+	}
+	return state
+}
+
+// setRemediationState stores the progress of the Cluster's remediation escalation chain in the machine's annotations.
+func setRemediationState(machine *clusterv1.Machine, state remediationState) {
+	annotations := machine.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[clusterv1.RemediationStepAnnotation] = strconv.Itoa(state.step)
+	annotations[clusterv1.RemediationAttemptsAnnotation] = strconv.Itoa(int(state.attempts))
+	if state.lastAttempt != nil {
+		annotations[clusterv1.RemediationLastAttemptAnnotation] = state.lastAttempt.UTC().Format(time.RFC3339)
+	} else {
+		delete(annotations, clusterv1.RemediationLastAttemptAnnotation)
+	}
+	machine.SetAnnotations(annotations)
+}
+
+// clearRemediationState removes the progress of the Cluster's remediation escalation chain from the machine's annotations.
+func clearRemediationState(machine *clusterv1.Machine) {
+	annotations := machine.GetAnnotations()
+	delete(annotations, clusterv1.RemediationStepAnnotation)
+	delete(annotations, clusterv1.RemediationAttemptsAnnotation)
+	delete(annotations, clusterv1.RemediationLastAttemptAnnotation)
+	machine.SetAnnotations(annotations)
+}
+
+// remediationSteps returns the Cluster's remediation escalation chain to be applied by the MachineHealthCheck;
+// it returns nil if the MachineHealthCheck defines its own remediation template.
+func remediationSteps(cluster *clusterv1.Cluster, m *clusterv1.MachineHealthCheck) []clusterv1.RemediationStep {
+	if m.Spec.RemediationTemplate != nil || cluster.Spec.Remediation == nil {
+		return nil
+	}
+	return cluster.Spec.Remediation.Steps
+}
+
+// currentRemediationStep returns the index of the current step of the escalation chain, capped to the last step
+// in case the chain has been shortened since the remediation started.
+func currentRemediationStep(state remediationState, steps []clusterv1.RemediationStep) int {
+	if state.step >= len(steps) {
+		return len(steps) - 1
+	}
+	return state.step
+}
+
+func remediationStepMaxAttempts(step clusterv1.RemediationStep) int32 {
+	if step.MaxAttempts != nil {
+		return *step.MaxAttempts
+	}
+	return 1
+}
+
+func remediationStepTimeout(step clusterv1.RemediationStep) time.Duration {
+	if step.Timeout != nil {
+		return step.Timeout.Duration
+	}
+	return clusterv1.DefaultRemediationStepTimeout
+}
+
+// escalateRemediation remediates an unhealthy target according to the Cluster's remediation escalation chain:
+// every step is attempted up to its MaxAttempts, each attempt being given the step's Timeout to make the machine
+// healthy, before moving to the next step.
+func (r *MachineHealthCheckReconciler) escalateRemediation(ctx context.Context, logger logr.Logger, t healthCheckTarget, m *clusterv1.MachineHealthCheck, steps []clusterv1.RemediationStep, condition *clusterv1.Condition) error {
+	now := time.Now()
+	state := getRemediationState(t.Machine)
+	state.step = currentRemediationStep(state, steps)
+	step := steps[state.step]
+
+	// If an external remediation attempt is in progress, wait for it to time out; the attempt is given the step's
+	// Timeout, measured from when it started, to make the machine healthy, even if the external remediation request
+	// is gone before, e.g. because the remediation completed.
+	if step.TemplateRef != nil {
+		timedOut := state.lastAttempt == nil || !now.Before(state.lastAttempt.Add(remediationStepTimeout(step)))
+		req, err := r.getRemediationRequest(ctx, step.TemplateRef, t.Machine.Namespace, t.Machine.Name)
+		if err != nil && !apierrors.IsNotFound(errors.Cause(err)) {
+			return err
+		}
+		if !timedOut {
+			return nil
+		}
+		if err == nil {
+			if req.GetDeletionTimestamp() == nil {
+				logger.Info("Remediation attempt timed out, deleting the external remediation request", "target", t.string(), "step", state.step, "attempts", state.attempts)
+				if err := r.Client.Delete(ctx, req); err != nil && !apierrors.IsNotFound(err) {
+					return errors.Wrapf(err, "failed to delete %v %q for machine %q", req.GroupVersionKind(), req.GetName(), t.Machine.Name)
+				}
+			}
+			// The next attempt is triggered once the external remediation request is gone.
+			return nil
+		}
+	}
+
+	// Move to the next step once all the attempts of the current one did not make the machine healthy.
+	if state.attempts >= remediationStepMaxAttempts(step) {
+		if state.step == len(steps)-1 {
+			logger.V(3).Info("Remediation escalation chain exhausted, the machine requires manual intervention", "target", t.string())
+			return nil
+		}
+		state.step++
+		state.attempts = 0
+		state.lastAttempt = nil
+		step = steps[state.step]
+		setRemediationState(t.Machine, state)
+
+		logger.Info("Escalating remediation to the next step", "target", t.string(), "step", state.step)
+		r.recorder.Eventf(
+			t.Machine,
+			corev1.EventTypeWarning,
+			EventRemediationEscalated,
+			"Remediation of machine %v escalated to step %d",
+			t.string(),
+			state.step,
+		)
+	}
+
+	if step.TemplateRef == nil {
+		// NOTE: if a remediation is already in progress, the remediation owner is responsible for completing the process.
+		if conditions.Has(t.Machine, clusterv1.MachineOwnerRemediatedCondition) && !conditions.IsTrue(t.Machine, clusterv1.MachineOwnerRemediatedCondition) {
+			return nil
+		}
+		if r.isRemediationRateLimited(logger, t, condition.Reason, m) {
+			return nil
+		}
+		logger.Info("Target has failed health check, marking for remediation", "target", t.string(), "reason", condition.Reason, "message", condition.Message)
+		conditions.MarkFalse(t.Machine, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "")
+	} else {
+		if r.isRemediationRateLimited(logger, t, condition.Reason, m) {
+			return nil
+		}
+		if err := r.createExternalRemediationRequest(ctx, logger, t, m, step.TemplateRef, condition); err != nil {
+			return err
+		}
+	}
+
+	recordRemediation(m, t.Machine, condition.Reason, now)
+	lastAttempt := metav1.NewTime(now)
+	state.attempts++
+	state.lastAttempt = &lastAttempt
+	setRemediationState(t.Machine, state)
+	return nil
+}
+
+// resetRemediation resets the Cluster's remediation escalation chain for a target that is healthy again,
+// deleting the external remediation request of the current step, if any.
+func (r *MachineHealthCheckReconciler) resetRemediation(ctx context.Context, logger logr.Logger, t healthCheckTarget, steps []clusterv1.RemediationStep) error {
+	if !hasRemediationState(t.Machine) {
+		return nil
+	}
+
+	state := getRemediationState(t.Machine)
+	if step := steps[currentRemediationStep(state, steps)]; step.TemplateRef != nil {
+		req, err := r.getRemediationRequest(ctx, step.TemplateRef, t.Machine.Namespace, t.Machine.Name)
+		if err != nil && !apierrors.IsNotFound(errors.Cause(err)) {
+			return err
+		}
+		if err == nil && req.GetDeletionTimestamp() == nil {
+			if err := r.Client.Delete(ctx, req); err != nil && !apierrors.IsNotFound(err) {
+				return errors.Wrapf(err, "failed to delete %v %q for machine %q", req.GroupVersionKind(), req.GetName(), t.Machine.Name)
+			}
+		}
+	}
+
+	logger.Info("Target is healthy again, resetting its remediation", "target", t.string())
+	clearRemediationState(t.Machine)
+	return nil
+}
+
+// remediationAttemptRequeueAfter returns when the machine must be checked again because its remediation attempt
+// times out; it returns zero if no attempt is in progress or the escalation chain is exhausted.
+func remediationAttemptRequeueAfter(machine *clusterv1.Machine, steps []clusterv1.RemediationStep, now time.Time) time.Duration {
+	if !hasRemediationState(machine) {
+		return 0
+	}
+
+	state := getRemediationState(machine)
+	if state.lastAttempt == nil {
+		return 0
+	}
+	state.step = currentRemediationStep(state, steps)
+	step := steps[state.step]
+
+	if deadline := state.lastAttempt.Add(remediationStepTimeout(step)); now.Before(deadline) {
+		return deadline.Sub(now)
+	}
+	if state.step == len(steps)-1 && state.attempts >= remediationStepMaxAttempts(step) {
+		return 0
+	}
+	return remediationEscalationRequeueAfter
+}
+
+// machineRemediations returns the progress of the Cluster's remediation escalation chain for the targets
+// being remediated, sorted by machine name.
+func machineRemediations(targets []healthCheckTarget) []clusterv1.MachineRemediationStatus {
+	var result []clusterv1.MachineRemediationStatus
+	for _, t := range targets {
+		if !hasRemediationState(t.Machine) {
+			continue
+		}
+		state := getRemediationState(t.Machine)
+		result = append(result, clusterv1.MachineRemediationStatus{
+			MachineName:     t.Machine.Name,
+			Step:            int32(state.step),
+			Attempts:        state.attempts,
+			LastAttemptTime: state.lastAttempt,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].MachineName < result[j].MachineName
+	})
+	return result
+}
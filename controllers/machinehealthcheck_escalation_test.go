@@ -0,0 +1,263 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func newRemediationTemplate(namespace string) (*unstructured.Unstructured, *corev1.ObjectReference) {
+	tmpl := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"strategy": "reboot",
+					},
+				},
+			},
+		},
+	}
+	tmpl.SetKind("InfrastructureRemediationTemplate")
+	tmpl.SetAPIVersion("infrastructure.cluster.x-k8s.io/v1alpha4")
+	tmpl.SetName("reboot")
+	tmpl.SetNamespace(namespace)
+
+	ref := &corev1.ObjectReference{
+		APIVersion: "infrastructure.cluster.x-k8s.io/v1alpha4",
+		Kind:       "InfrastructureRemediationTemplate",
+		Name:       "reboot",
+		Namespace:  namespace,
+	}
+	return tmpl, ref
+}
+
+func TestPatchUnhealthyTargetsEscalation(t *testing.T) {
+	_ = clusterv1.AddToScheme(scheme.Scheme)
+	g := NewWithT(t)
+
+	namespace := defaultNamespaceName
+	clusterName := "test-cluster"
+	tmpl, ref := newRemediationTemplate(namespace)
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterName,
+			Namespace: namespace,
+		},
+		Spec: clusterv1.ClusterSpec{
+			Remediation: &clusterv1.ClusterRemediation{
+				Steps: []clusterv1.RemediationStep{
+					{TemplateRef: ref, MaxAttempts: pointer.Int32Ptr(2), Timeout: &metav1.Duration{Duration: 5 * time.Minute}},
+					{},
+				},
+			},
+		},
+	}
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+	machine := newTestMachine("machine1", namespace, clusterName, "node1", labels)
+	conditions.MarkFalse(machine, clusterv1.MachineHealthCheckSuccededCondition, clusterv1.NodeNotFoundReason, clusterv1.ConditionSeverityWarning, "")
+
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(machine, mhc, tmpl).Build()
+	r := &MachineHealthCheckReconciler{
+		Client:   cl,
+		recorder: record.NewFakeRecorder(32),
+	}
+
+	patchHelper, err := patch.NewHelper(machine, cl)
+	g.Expect(err).ToNot(HaveOccurred())
+	targets := []healthCheckTarget{{MHC: mhc, Machine: machine, patchHelper: patchHelper, nodeMissing: true}}
+
+	requestExists := func() bool {
+		_, err := r.getRemediationRequest(ctx, ref, namespace, machine.Name)
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		g.Expect(err).ToNot(HaveOccurred())
+		return true
+	}
+	expireLastAttempt := func() {
+		machine.Annotations[clusterv1.RemediationLastAttemptAnnotation] = time.Now().Add(-10 * time.Minute).UTC().Format(time.RFC3339)
+	}
+
+	// The first attempt of the first step creates an external remediation request.
+	g.Expect(r.PatchUnhealthyTargets(context.TODO(), log.NullLogger{}, targets, cluster, mhc)).To(BeEmpty())
+	g.Expect(requestExists()).To(BeTrue())
+	g.Expect(getRemediationState(machine).step).To(Equal(0))
+	g.Expect(getRemediationState(machine).attempts).To(Equal(int32(1)))
+
+	// The attempt is in progress until it times out.
+	g.Expect(r.PatchUnhealthyTargets(context.TODO(), log.NullLogger{}, targets, cluster, mhc)).To(BeEmpty())
+	g.Expect(requestExists()).To(BeTrue())
+	g.Expect(getRemediationState(machine).attempts).To(Equal(int32(1)))
+
+	// The attempt is given the step's timeout even if its request is gone before, e.g. because the remediation completed.
+	req, err := r.getRemediationRequest(ctx, ref, namespace, machine.Name)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cl.Delete(ctx, req)).To(Succeed())
+	g.Expect(r.PatchUnhealthyTargets(context.TODO(), log.NullLogger{}, targets, cluster, mhc)).To(BeEmpty())
+	g.Expect(requestExists()).To(BeFalse())
+	g.Expect(getRemediationState(machine).attempts).To(Equal(int32(1)))
+
+	// Once the attempt timed out, a new attempt is triggered.
+	expireLastAttempt()
+	g.Expect(r.PatchUnhealthyTargets(context.TODO(), log.NullLogger{}, targets, cluster, mhc)).To(BeEmpty())
+	g.Expect(requestExists()).To(BeTrue())
+	g.Expect(getRemediationState(machine).attempts).To(Equal(int32(2)))
+
+	// Once all the attempts of the first step failed, the request of the timed out attempt is deleted,
+	// then the remediation escalates to the machine owner.
+	expireLastAttempt()
+	g.Expect(r.PatchUnhealthyTargets(context.TODO(), log.NullLogger{}, targets, cluster, mhc)).To(BeEmpty())
+	g.Expect(r.PatchUnhealthyTargets(context.TODO(), log.NullLogger{}, targets, cluster, mhc)).To(BeEmpty())
+	g.Expect(requestExists()).To(BeFalse())
+	g.Expect(getRemediationState(machine).step).To(Equal(1))
+	g.Expect(getRemediationState(machine).attempts).To(Equal(int32(1)))
+	g.Expect(conditions.IsFalse(machine, clusterv1.MachineOwnerRemediatedCondition)).To(BeTrue())
+
+	g.Expect(mhc.Status.RemediationHistory).To(HaveLen(3))
+	g.Expect(machineRemediations(targets)).To(HaveLen(1))
+
+	// The remediation state is persisted in the machine's annotations.
+	persisted := &clusterv1.Machine{}
+	g.Expect(cl.Get(ctx, client.ObjectKey{Name: machine.Name, Namespace: namespace}, persisted)).To(Succeed())
+	g.Expect(persisted.Annotations).To(HaveKeyWithValue(clusterv1.RemediationStepAnnotation, "1"))
+}
+
+func TestPatchHealthyTargetsResetsEscalation(t *testing.T) {
+	_ = clusterv1.AddToScheme(scheme.Scheme)
+	g := NewWithT(t)
+
+	namespace := defaultNamespaceName
+	clusterName := "test-cluster"
+	tmpl, ref := newRemediationTemplate(namespace)
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterName,
+			Namespace: namespace,
+		},
+		Spec: clusterv1.ClusterSpec{
+			Remediation: &clusterv1.ClusterRemediation{
+				Steps: []clusterv1.RemediationStep{{TemplateRef: ref}},
+			},
+		},
+	}
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+	machine := newTestMachine("machine1", namespace, clusterName, "node1", labels)
+	conditions.MarkFalse(machine, clusterv1.MachineHealthCheckSuccededCondition, clusterv1.NodeNotFoundReason, clusterv1.ConditionSeverityWarning, "")
+
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(machine, mhc, tmpl).Build()
+	r := &MachineHealthCheckReconciler{
+		Client:   cl,
+		recorder: record.NewFakeRecorder(32),
+	}
+
+	patchHelper, err := patch.NewHelper(machine, cl)
+	g.Expect(err).ToNot(HaveOccurred())
+	targets := []healthCheckTarget{{MHC: mhc, Machine: machine, patchHelper: patchHelper, nodeMissing: true}}
+
+	g.Expect(r.PatchUnhealthyTargets(context.TODO(), log.NullLogger{}, targets, cluster, mhc)).To(BeEmpty())
+	_, err = r.getRemediationRequest(ctx, ref, namespace, machine.Name)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Once healthy again, the remediation request is deleted and the remediation state is reset.
+	g.Expect(cl.Get(ctx, client.ObjectKey{Name: machine.Name, Namespace: namespace}, machine)).To(Succeed())
+	patchHelper, err = patch.NewHelper(machine, cl)
+	g.Expect(err).ToNot(HaveOccurred())
+	targets = []healthCheckTarget{{MHC: mhc, Machine: machine, patchHelper: patchHelper}}
+	conditions.MarkTrue(machine, clusterv1.MachineHealthCheckSuccededCondition)
+	g.Expect(r.PatchHealthyTargets(context.TODO(), log.NullLogger{}, targets, cluster, mhc)).To(BeEmpty())
+	_, err = r.getRemediationRequest(ctx, ref, namespace, machine.Name)
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	g.Expect(cl.Get(ctx, client.ObjectKey{Name: machine.Name, Namespace: namespace}, machine)).To(Succeed())
+	g.Expect(hasRemediationState(machine)).To(BeFalse())
+}
+
+func TestRemediationAttemptRequeueAfter(t *testing.T) {
+	now := time.Now()
+	steps := []clusterv1.RemediationStep{
+		{TemplateRef: &corev1.ObjectReference{}, MaxAttempts: pointer.Int32Ptr(1), Timeout: &metav1.Duration{Duration: 5 * time.Minute}},
+		{Timeout: &metav1.Duration{Duration: 5 * time.Minute}},
+	}
+
+	machineWithState := func(state *remediationState) *clusterv1.Machine {
+		m := &clusterv1.Machine{}
+		if state != nil {
+			setRemediationState(m, *state)
+		}
+		return m
+	}
+	lastAttempt := func(ago time.Duration) *metav1.Time {
+		t := metav1.NewTime(now.Add(-ago))
+		return &t
+	}
+
+	testCases := []struct {
+		name     string
+		state    *remediationState
+		expected time.Duration
+	}{
+		{
+			name:     "when the machine is not being remediated",
+			expected: 0,
+		},
+		{
+			name:     "when an attempt is in progress",
+			state:    &remediationState{step: 0, attempts: 1, lastAttempt: lastAttempt(2 * time.Minute)},
+			expected: 3 * time.Minute,
+		},
+		{
+			name:     "when an attempt timed out",
+			state:    &remediationState{step: 0, attempts: 1, lastAttempt: lastAttempt(10 * time.Minute)},
+			expected: remediationEscalationRequeueAfter,
+		},
+		{
+			name:     "when the escalation chain is exhausted",
+			state:    &remediationState{step: 1, attempts: 1, lastAttempt: lastAttempt(10 * time.Minute)},
+			expected: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(remediationAttemptRequeueAfter(machineWithState(tc.state), steps, now)).To(BeNumerically("~", tc.expected, time.Second))
+		})
+	}
+}
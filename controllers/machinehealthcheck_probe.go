@@ -0,0 +1,258 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// defaultMachineProbePeriod and defaultMachineProbeTimeout are used when the MachineHealthCheck
+	// has not been defaulted by the webhook.
+	defaultMachineProbePeriod  = 30 * time.Second
+	defaultMachineProbeTimeout = 5 * time.Second
+)
+
+// machineProbePeriod returns how often the MachineHealthCheck's machine probe is executed.
+func machineProbePeriod(probe *clusterv1.MachineProbe) time.Duration {
+	if probe.Period != nil {
+		return probe.Period.Duration
+	}
+	return defaultMachineProbePeriod
+}
+
+// probeTargets executes the MachineHealthCheck's machine probe against the targets, reporting the result
+// in the MachineProbeSucceeded condition of each target machine.
+// Targets are probed concurrently, all within the probe timeout, and targets already probed within the probe
+// period are skipped; the probe times of machines which are no longer targets are forgotten. Targets whose condition changes are patched immediately, so the time the probe started
+// failing is preserved even if the target is not patched later in the reconcile; a failure to patch a target
+// is logged and does not prevent the other targets from being patched.
+func (r *MachineHealthCheckReconciler) probeTargets(ctx context.Context, logger logr.Logger, cluster *clusterv1.Cluster, m *clusterv1.MachineHealthCheck, targets []healthCheckTarget) error {
+	probe := m.Spec.MachineProbe
+	if probe == nil {
+		r.machineProbeTimes.Delete(util.ObjectKey(m))
+		return nil
+	}
+
+	timeout := defaultMachineProbeTimeout
+	if probe.ProbeTimeout != nil {
+		timeout = probe.ProbeTimeout.Duration
+	}
+
+	var kubeClient kubernetes.Interface
+	if probe.KubeletHealthz != nil {
+		var err error
+		kubeClient, err = r.Tracker.GetClientset(ctx, util.ObjectKey(cluster))
+		if err != nil {
+			return errors.Wrap(err, "failed to get client for the machine probe")
+		}
+	}
+
+	// Select the targets due for a probe; the probe times are carried over only for the current targets,
+	// so that the machines deleted or no longer matching the MachineHealthCheck are forgotten.
+	lastProbeTimes := map[client.ObjectKey]time.Time{}
+	if v, ok := r.machineProbeTimes.Load(util.ObjectKey(m)); ok {
+		lastProbeTimes = v.(map[client.ObjectKey]time.Time)
+	}
+	probeTimes := map[client.ObjectKey]time.Time{}
+	defer r.machineProbeTimes.Store(util.ObjectKey(m), probeTimes)
+
+	now := time.Now()
+	period := machineProbePeriod(probe)
+	due := []*healthCheckTarget{}
+	for i := range targets {
+		t := &targets[i]
+		if !t.Machine.DeletionTimestamp.IsZero() {
+			continue
+		}
+		key := util.ObjectKey(t.Machine)
+		if last, ok := lastProbeTimes[key]; ok {
+			probeTimes[key] = last
+			if now.Sub(last) < period {
+				continue
+			}
+		}
+		due = append(due, t)
+	}
+
+	// Probe all the targets concurrently, within a single deadline.
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	results := make([]error, len(due))
+	wg := sync.WaitGroup{}
+	for i := range due {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = executeMachineProbe(probeCtx, probe, due[i].Machine, kubeClient, timeout)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, t := range due {
+		err := results[i]
+		if errors.Cause(err) == errMachineProbeSkipped {
+			continue
+		}
+		probeTimes[util.ObjectKey(t.Machine)] = now
+
+		before := conditions.Get(t.Machine, clusterv1.MachineProbeSucceededCondition)
+		if err != nil {
+			logger.V(3).Info("Machine probe failed", "target", t.string(), "error", err.Error())
+			conditions.MarkFalse(t.Machine, clusterv1.MachineProbeSucceededCondition, clusterv1.MachineProbeFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		} else {
+			conditions.MarkTrue(t.Machine, clusterv1.MachineProbeSucceededCondition)
+		}
+
+		after := conditions.Get(t.Machine, clusterv1.MachineProbeSucceededCondition)
+		if before != nil && before.Status == after.Status && before.Message == after.Message {
+			continue
+		}
+
+		if err := t.patchHelper.Patch(ctx, t.Machine); err != nil {
+			logger.Error(err, "Failed to patch machine probe status", "target", t.string())
+			continue
+		}
+		patchHelper, err := patch.NewHelper(t.Machine, r.Client)
+		if err != nil {
+			logger.Error(err, "Failed to create patch helper", "target", t.string())
+			continue
+		}
+		t.patchHelper = patchHelper
+	}
+	return nil
+}
+
+// errMachineProbeSkipped is returned when a machine cannot be probed yet, e.g. because it has no address.
+var errMachineProbeSkipped = errors.New("machine probe skipped")
+
+// executeMachineProbe executes the probe against the machine, returning an error if the probe fails.
+func executeMachineProbe(ctx context.Context, probe *clusterv1.MachineProbe, machine *clusterv1.Machine, kubeClient kubernetes.Interface, timeout time.Duration) error {
+	if probe.KubeletHealthz != nil {
+		if machine.Status.NodeRef == nil || kubeClient == nil {
+			return errMachineProbeSkipped
+		}
+		return probeKubeletHealthz(ctx, kubeClient, machine.Status.NodeRef.Name)
+	}
+
+	address := machineProbeAddress(machine, probe.AddressType)
+	if address == "" {
+		return errMachineProbeSkipped
+	}
+
+	switch {
+	case probe.HTTPGet != nil:
+		return probeHTTPGet(ctx, probe.HTTPGet, address, timeout)
+	case probe.TCPSocket != nil:
+		return probeTCPSocket(ctx, probe.TCPSocket, address, timeout)
+	}
+	return errMachineProbeSkipped
+}
+
+// machineProbeAddress returns the first machine address of the given type, defaulting to InternalIP.
+func machineProbeAddress(machine *clusterv1.Machine, addressType clusterv1.MachineAddressType) string {
+	if addressType == "" {
+		addressType = clusterv1.MachineInternalIP
+	}
+	for _, address := range machine.Status.Addresses {
+		if address.Type == addressType {
+			return address.Address
+		}
+	}
+	return ""
+}
+
+func probeHTTPGet(ctx context.Context, probe *clusterv1.HTTPGetMachineProbe, address string, timeout time.Duration) error {
+	scheme := probe.Scheme
+	if scheme == "" {
+		scheme = corev1.URISchemeHTTP
+	}
+	u := &url.URL{
+		Scheme: string(scheme),
+		Host:   net.JoinHostPort(address, strconv.Itoa(int(probe.Port))),
+		Path:   probe.Path,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create request for %s", u.String())
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// Certificates presented by machines are not verified, consistently with Kubernetes HTTPS probes.
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
+			DisableKeepAlives: true,
+		},
+		// Do not follow redirects, a redirect is considered a success.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "HTTP probe against %s failed", u.String())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("HTTP probe against %s failed with status code %d", u.String(), resp.StatusCode)
+	}
+	return nil
+}
+
+func probeTCPSocket(ctx context.Context, probe *clusterv1.TCPSocketMachineProbe, address string, timeout time.Duration) error {
+	hostPort := net.JoinHostPort(address, strconv.Itoa(int(probe.Port)))
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", hostPort)
+	if err != nil {
+		return errors.Wrapf(err, "TCP probe against %s failed", hostPort)
+	}
+	return conn.Close()
+}
+
+func probeKubeletHealthz(ctx context.Context, kubeClient kubernetes.Interface, nodeName string) error {
+	result := kubeClient.CoreV1().RESTClient().Get().
+		Resource("nodes").
+		Name(nodeName).
+		SubResource("proxy").
+		Suffix("healthz").
+		Do(ctx)
+	if err := result.Error(); err != nil {
+		return errors.Wrapf(err, "kubelet healthz probe against node %s failed", nodeName)
+	}
+	return nil
+}
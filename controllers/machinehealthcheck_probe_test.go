@@ -0,0 +1,323 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// newProbeTestServer starts an HTTP server on the loopback interface, returning its address and port.
+func newProbeTestServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, string, int32) {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, host, int32(p)
+}
+
+func newProbeTestMachine(address string) *clusterv1.Machine {
+	machine := newTestMachine("machine1", defaultNamespaceName, "test-cluster", "node1", nil)
+	machine.Status.Addresses = clusterv1.MachineAddresses{
+		{Type: clusterv1.MachineExternalIP, Address: "192.0.2.1"},
+		{Type: clusterv1.MachineInternalIP, Address: address},
+	}
+	return machine
+}
+
+func TestExecuteMachineProbe(t *testing.T) {
+	_, address, port := newProbeTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	testCases := []struct {
+		name       string
+		probe      *clusterv1.MachineProbe
+		machine    *clusterv1.Machine
+		expectErr  bool
+		expectSkip bool
+	}{
+		{
+			name: "HTTP probe succeeds",
+			probe: &clusterv1.MachineProbe{
+				HTTPGet: &clusterv1.HTTPGetMachineProbe{Path: "/healthz", Port: port},
+			},
+			machine: newProbeTestMachine(address),
+		},
+		{
+			name: "HTTP probe fails with an error status code",
+			probe: &clusterv1.MachineProbe{
+				HTTPGet: &clusterv1.HTTPGetMachineProbe{Path: "/broken", Port: port},
+			},
+			machine:   newProbeTestMachine(address),
+			expectErr: true,
+		},
+		{
+			name: "TCP probe succeeds",
+			probe: &clusterv1.MachineProbe{
+				TCPSocket: &clusterv1.TCPSocketMachineProbe{Port: port},
+			},
+			machine: newProbeTestMachine(address),
+		},
+		{
+			name: "TCP probe fails when the address is unreachable",
+			probe: &clusterv1.MachineProbe{
+				TCPSocket:   &clusterv1.TCPSocketMachineProbe{Port: port},
+				AddressType: clusterv1.MachineExternalIP,
+			},
+			machine:   newProbeTestMachine(address),
+			expectErr: true,
+		},
+		{
+			name: "probe is skipped when the machine has no address of the given type",
+			probe: &clusterv1.MachineProbe{
+				TCPSocket:   &clusterv1.TCPSocketMachineProbe{Port: port},
+				AddressType: clusterv1.MachineHostName,
+			},
+			machine:    newProbeTestMachine(address),
+			expectSkip: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := executeMachineProbe(ctx, tc.probe, tc.machine, nil, 100*time.Millisecond)
+			switch {
+			case tc.expectSkip:
+				g.Expect(err).To(Equal(errMachineProbeSkipped))
+			case tc.expectErr:
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err).ToNot(Equal(errMachineProbeSkipped))
+			default:
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestProbeKubeletHealthz(t *testing.T) {
+	g := NewWithT(t)
+
+	srv, _, _ := newProbeTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/nodes/healthy-node/proxy/healthz":
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("ok"))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	kubeClient, err := kubernetes.NewForConfig(&rest.Config{Host: srv.URL})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(probeKubeletHealthz(ctx, kubeClient, "healthy-node")).To(Succeed())
+	g.Expect(probeKubeletHealthz(ctx, kubeClient, "unhealthy-node")).ToNot(Succeed())
+
+	machine := newProbeTestMachine("")
+	machine.Status.NodeRef = nil
+	probe := &clusterv1.MachineProbe{KubeletHealthz: &clusterv1.KubeletHealthzMachineProbe{}}
+	g.Expect(executeMachineProbe(ctx, probe, machine, kubeClient, time.Second)).To(Equal(errMachineProbeSkipped))
+}
+
+func TestProbeTargets(t *testing.T) {
+	_ = clusterv1.AddToScheme(scheme.Scheme)
+	g := NewWithT(t)
+
+	healthy := true
+	_, address, port := newProbeTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if healthy {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	mhc := newMachineHealthCheckWithLabels("mhc", defaultNamespaceName, "test-cluster", nil)
+	mhc.Spec.MachineProbe = &clusterv1.MachineProbe{
+		HTTPGet: &clusterv1.HTTPGetMachineProbe{Port: port},
+		Timeout: metav1.Duration{Duration: 5 * time.Minute},
+	}
+	machine := newProbeTestMachine(address)
+
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(machine).Build()
+	r := &MachineHealthCheckReconciler{Client: cl}
+
+	newTargets := func() []healthCheckTarget {
+		m := &clusterv1.Machine{}
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), m)).To(Succeed())
+		patchHelper, err := patch.NewHelper(m, cl)
+		g.Expect(err).ToNot(HaveOccurred())
+		return []healthCheckTarget{{MHC: mhc, Machine: m, patchHelper: patchHelper}}
+	}
+
+	// The probe succeeds, the condition is set to true and persisted.
+	g.Expect(r.probeTargets(ctx, log.NullLogger{}, &clusterv1.Cluster{}, mhc, newTargets())).To(Succeed())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+	g.Expect(conditions.IsTrue(machine, clusterv1.MachineProbeSucceededCondition)).To(BeTrue())
+
+	// The probe would fail, but the target has been probed within the period, so it is not probed again.
+	healthy = false
+	mhc.Spec.MachineProbe.Period = &metav1.Duration{Duration: time.Hour}
+	g.Expect(r.probeTargets(ctx, log.NullLogger{}, &clusterv1.Cluster{}, mhc, newTargets())).To(Succeed())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+	g.Expect(conditions.IsTrue(machine, clusterv1.MachineProbeSucceededCondition)).To(BeTrue())
+
+	// The probe fails once the period has elapsed, the condition is set to false and persisted.
+	mhc.Spec.MachineProbe.Period = &metav1.Duration{Duration: time.Nanosecond}
+	targets := newTargets()
+	g.Expect(r.probeTargets(ctx, log.NullLogger{}, &clusterv1.Cluster{}, mhc, targets)).To(Succeed())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+	g.Expect(conditions.IsFalse(machine, clusterv1.MachineProbeSucceededCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(machine, clusterv1.MachineProbeSucceededCondition)).To(Equal(clusterv1.MachineProbeFailedReason))
+
+	// The target can still be patched after the probe.
+	conditions.MarkTrue(targets[0].Machine, clusterv1.MachineHealthCheckSuccededCondition)
+	g.Expect(targets[0].patchHelper.Patch(ctx, targets[0].Machine)).To(Succeed())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+	g.Expect(conditions.IsTrue(machine, clusterv1.MachineHealthCheckSuccededCondition)).To(BeTrue())
+	g.Expect(conditions.Get(machine, clusterv1.MachineProbeSucceededCondition).Status).To(Equal(corev1.ConditionFalse))
+}
+
+func TestProbeTargetsConcurrently(t *testing.T) {
+	_ = clusterv1.AddToScheme(scheme.Scheme)
+	g := NewWithT(t)
+
+	_, address, port := newProbeTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mhc := newMachineHealthCheckWithLabels("mhc", defaultNamespaceName, "test-cluster", nil)
+	mhc.Spec.MachineProbe = &clusterv1.MachineProbe{
+		HTTPGet:      &clusterv1.HTTPGetMachineProbe{Port: port},
+		ProbeTimeout: &metav1.Duration{Duration: 2 * time.Second},
+		Timeout:      metav1.Duration{Duration: 5 * time.Minute},
+	}
+
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	r := &MachineHealthCheckReconciler{Client: cl}
+
+	targets := []healthCheckTarget{}
+	for i := 0; i < 5; i++ {
+		machine := newProbeTestMachine(address)
+		machine.Name = fmt.Sprintf("machine%d", i)
+		// The first machine does not exist, so patching it fails.
+		if i > 0 {
+			g.Expect(cl.Create(ctx, machine)).To(Succeed())
+		}
+		patchHelper, err := patch.NewHelper(machine, cl)
+		g.Expect(err).ToNot(HaveOccurred())
+		targets = append(targets, healthCheckTarget{MHC: mhc, Machine: machine, patchHelper: patchHelper})
+	}
+
+	// All the targets are probed within the probe timeout, and failing to patch a target does not prevent
+	// the other targets from being patched.
+	start := time.Now()
+	g.Expect(r.probeTargets(ctx, log.NullLogger{}, &clusterv1.Cluster{}, mhc, targets)).To(Succeed())
+	g.Expect(time.Since(start)).To(BeNumerically("<", 2*time.Second))
+	for _, target := range targets[1:] {
+		machine := &clusterv1.Machine{}
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(target.Machine), machine)).To(Succeed())
+		g.Expect(conditions.IsTrue(machine, clusterv1.MachineProbeSucceededCondition)).To(BeTrue())
+	}
+}
+
+func TestProbeTargetsForgetsMachinesNoLongerTargeted(t *testing.T) {
+	_ = clusterv1.AddToScheme(scheme.Scheme)
+	g := NewWithT(t)
+
+	_, address, port := newProbeTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mhc := newMachineHealthCheckWithLabels("mhc", defaultNamespaceName, "test-cluster", nil)
+	mhc.Spec.MachineProbe = &clusterv1.MachineProbe{
+		HTTPGet: &clusterv1.HTTPGetMachineProbe{Port: port},
+		Period:  &metav1.Duration{Duration: time.Hour},
+		Timeout: metav1.Duration{Duration: 5 * time.Minute},
+	}
+
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	r := &MachineHealthCheckReconciler{Client: cl}
+
+	targets := []healthCheckTarget{}
+	for i := 0; i < 3; i++ {
+		machine := newProbeTestMachine(address)
+		machine.Name = fmt.Sprintf("machine%d", i)
+		g.Expect(cl.Create(ctx, machine)).To(Succeed())
+		patchHelper, err := patch.NewHelper(machine, cl)
+		g.Expect(err).ToNot(HaveOccurred())
+		targets = append(targets, healthCheckTarget{MHC: mhc, Machine: machine, patchHelper: patchHelper})
+	}
+	probeTimes := func() map[client.ObjectKey]time.Time {
+		v, ok := r.machineProbeTimes.Load(client.ObjectKeyFromObject(mhc))
+		if !ok {
+			return nil
+		}
+		return v.(map[client.ObjectKey]time.Time)
+	}
+
+	g.Expect(r.probeTargets(ctx, log.NullLogger{}, &clusterv1.Cluster{}, mhc, targets)).To(Succeed())
+	g.Expect(probeTimes()).To(HaveLen(3))
+	probedAt := probeTimes()[client.ObjectKeyFromObject(targets[0].Machine)]
+
+	// The probe time of the machine being deleted and of the machine no longer targeted are forgotten,
+	// while the probe time of the other machine is kept, given that it is not probed again within the period.
+	targets[1].Machine.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	g.Expect(r.probeTargets(ctx, log.NullLogger{}, &clusterv1.Cluster{}, mhc, targets[:2])).To(Succeed())
+	g.Expect(probeTimes()).To(Equal(map[client.ObjectKey]time.Time{
+		client.ObjectKeyFromObject(targets[0].Machine): probedAt,
+	}))
+
+	// All the probe times are forgotten once the MachineHealthCheck has no machine probe.
+	mhc.Spec.MachineProbe = nil
+	g.Expect(r.probeTargets(ctx, log.NullLogger{}, &clusterv1.Cluster{}, mhc, targets[:1])).To(Succeed())
+	g.Expect(probeTimes()).To(BeNil())
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+// remediationWindow returns the time window used to count remediations and to retain the remediation history.
+func remediationWindow(mhc *clusterv1.MachineHealthCheck) time.Duration {
+	if mhc.Spec.RemediationRateLimit != nil && mhc.Spec.RemediationRateLimit.Window != nil {
+		return mhc.Spec.RemediationRateLimit.Window.Duration
+	}
+	return clusterv1.DefaultRemediationWindow
+}
+
+// pruneRemediationHistory removes the remediations older than the remediation window from the MachineHealthCheck's history.
+func pruneRemediationHistory(mhc *clusterv1.MachineHealthCheck, now time.Time) {
+	window := remediationWindow(mhc)
+	history := []clusterv1.RemediationRecord{}
+	for _, r := range mhc.Status.RemediationHistory {
+		if r.Timestamp.Add(window).After(now) {
+			history = append(history, r)
+		}
+	}
+	if len(history) == 0 {
+		history = nil
+	}
+	mhc.Status.RemediationHistory = history
+}
+
+// remediationOwner returns the owner the remediations of a machine are backed off by, so that the machines
+// re-created by the same MachineDeployment, or by the same controller, share the backoff.
+func remediationOwner(machine *clusterv1.Machine) string {
+	if name, ok := machine.Labels[clusterv1.MachineDeploymentLabelName]; ok && name != "" {
+		return fmt.Sprintf("MachineDeployment/%s", name)
+	}
+	if ref := metav1.GetControllerOf(machine); ref != nil {
+		return fmt.Sprintf("%s/%s", ref.Kind, ref.Name)
+	}
+	return fmt.Sprintf("Machine/%s", machine.Name)
+}
+
+// recordRemediation appends a remediation to the MachineHealthCheck's history.
+func recordRemediation(mhc *clusterv1.MachineHealthCheck, machine *clusterv1.Machine, reason string, now time.Time) {
+	mhc.Status.RemediationHistory = append(mhc.Status.RemediationHistory, clusterv1.RemediationRecord{
+		MachineName: machine.Name,
+		Owner:       remediationOwner(machine),
+		Reason:      reason,
+		Timestamp:   metav1.NewTime(now),
+	})
+}
+
+// remediationDelay returns how long a remediation of the given machine, unhealthy for the given reason, must be deferred
+// according to the MachineHealthCheck's remediation rate limit, together with a message explaining why.
+// A zero duration means the remediation can be triggered now.
+func remediationDelay(mhc *clusterv1.MachineHealthCheck, machine *clusterv1.Machine, reason string, now time.Time) (time.Duration, string) {
+	rateLimit := mhc.Spec.RemediationRateLimit
+	if rateLimit == nil {
+		return 0, ""
+	}
+	window := remediationWindow(mhc)
+
+	// Check the remediation budget; history is ordered oldest first, so the oldest remediation
+	// within the window is the first one to expire.
+	var inWindow []clusterv1.RemediationRecord
+	for _, r := range mhc.Status.RemediationHistory {
+		if r.Timestamp.Add(window).After(now) {
+			inWindow = append(inWindow, r)
+		}
+	}
+	if rateLimit.MaxRemediations != nil && int32(len(inWindow)) >= *rateLimit.MaxRemediations {
+		delay := inWindow[len(inWindow)-int(*rateLimit.MaxRemediations)].Timestamp.Add(window).Sub(now)
+		return delay, fmt.Sprintf("Remediation is rate limited, %d remediations have been triggered within %s", len(inWindow), window)
+	}
+
+	// Check the backoff for remediations triggered repeatedly for the machines of the same owner and for the same reason,
+	// e.g. when every machine re-created by a MachineDeployment fails because of a bad image.
+	if rateLimit.BackoffDelay == nil {
+		return 0, ""
+	}
+	owner := remediationOwner(machine)
+	var count int
+	var last time.Time
+	for _, r := range inWindow {
+		if r.Owner == owner && r.Reason == reason {
+			count++
+			last = r.Timestamp.Time
+		}
+	}
+	if count == 0 {
+		return 0, ""
+	}
+	backoff := remediationBackoff(rateLimit, window, count)
+	if delay := last.Add(backoff).Sub(now); delay > 0 {
+		return delay, fmt.Sprintf("Remediation is backing off, %d remediations have been triggered for the machines of %s with reason %s within %s", count, owner, reason, window)
+	}
+	return 0, ""
+}
+
+// remediationBackoff returns the backoff applied after the given number of remediations with the same owner and reason.
+func remediationBackoff(rateLimit *clusterv1.RemediationRateLimit, window time.Duration, count int) time.Duration {
+	maxBackoff := window
+	if rateLimit.MaxBackoffDelay != nil {
+		maxBackoff = rateLimit.MaxBackoffDelay.Duration
+	}
+
+	backoff := rateLimit.BackoffDelay.Duration
+	for i := 1; i < count; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
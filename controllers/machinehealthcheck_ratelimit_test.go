@@ -0,0 +1,274 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestPruneRemediationHistory(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Now()
+	mhc := &clusterv1.MachineHealthCheck{
+		Spec: clusterv1.MachineHealthCheckSpec{
+			RemediationRateLimit: &clusterv1.RemediationRateLimit{
+				Window: &metav1.Duration{Duration: 10 * time.Minute},
+			},
+		},
+		Status: clusterv1.MachineHealthCheckStatus{
+			RemediationHistory: []clusterv1.RemediationRecord{
+				{MachineName: "m1", Reason: "a", Timestamp: metav1.NewTime(now.Add(-20 * time.Minute))},
+				{MachineName: "m2", Reason: "a", Timestamp: metav1.NewTime(now.Add(-5 * time.Minute))},
+			},
+		},
+	}
+
+	pruneRemediationHistory(mhc, now)
+	g.Expect(mhc.Status.RemediationHistory).To(HaveLen(1))
+	g.Expect(mhc.Status.RemediationHistory[0].MachineName).To(Equal("m2"))
+
+	pruneRemediationHistory(mhc, now.Add(10*time.Minute))
+	g.Expect(mhc.Status.RemediationHistory).To(BeNil())
+}
+
+func TestRemediationDelay(t *testing.T) {
+	now := time.Now()
+	machine := func(name, machineDeployment string) *clusterv1.Machine {
+		m := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if machineDeployment != "" {
+			m.Labels = map[string]string{clusterv1.MachineDeploymentLabelName: machineDeployment}
+		}
+		return m
+	}
+	record := func(machineName string, age time.Duration) clusterv1.RemediationRecord {
+		return clusterv1.RemediationRecord{MachineName: machineName, Owner: "Machine/" + machineName, Reason: clusterv1.NodeNotFoundReason, Timestamp: metav1.NewTime(now.Add(-age))}
+	}
+	mdRecord := func(machineName, reason string, age time.Duration) clusterv1.RemediationRecord {
+		return clusterv1.RemediationRecord{MachineName: machineName, Owner: "MachineDeployment/md-1", Reason: reason, Timestamp: metav1.NewTime(now.Add(-age))}
+	}
+
+	testCases := []struct {
+		name          string
+		rateLimit     *clusterv1.RemediationRateLimit
+		history       []clusterv1.RemediationRecord
+		machine       *clusterv1.Machine
+		expectedDelay time.Duration
+	}{
+		{
+			name:          "when the rate limit is not set",
+			rateLimit:     nil,
+			history:       []clusterv1.RemediationRecord{record("machine-a", time.Minute)},
+			machine:       machine("machine-a", ""),
+			expectedDelay: 0,
+		},
+		{
+			name: "when the remediation budget is not exhausted",
+			rateLimit: &clusterv1.RemediationRateLimit{
+				MaxRemediations: pointer.Int32Ptr(2),
+				Window:          &metav1.Duration{Duration: time.Hour},
+			},
+			history:       []clusterv1.RemediationRecord{record("machine-a", time.Minute)},
+			machine:       machine("machine-b", ""),
+			expectedDelay: 0,
+		},
+		{
+			name: "when the remediation budget is exhausted",
+			rateLimit: &clusterv1.RemediationRateLimit{
+				MaxRemediations: pointer.Int32Ptr(2),
+				Window:          &metav1.Duration{Duration: time.Hour},
+			},
+			history:       []clusterv1.RemediationRecord{record("machine-a", 40*time.Minute), record("machine-b", 10*time.Minute)},
+			machine:       machine("machine-c", ""),
+			expectedDelay: 20 * time.Minute,
+		},
+		{
+			name: "when the remediations are outside of the window",
+			rateLimit: &clusterv1.RemediationRateLimit{
+				MaxRemediations: pointer.Int32Ptr(1),
+				Window:          &metav1.Duration{Duration: 10 * time.Minute},
+			},
+			history:       []clusterv1.RemediationRecord{record("machine-a", 20*time.Minute)},
+			machine:       machine("machine-a", ""),
+			expectedDelay: 0,
+		},
+		{
+			name: "when the backoff applies to the first repeated remediation of a machine",
+			rateLimit: &clusterv1.RemediationRateLimit{
+				Window:       &metav1.Duration{Duration: time.Hour},
+				BackoffDelay: &metav1.Duration{Duration: 5 * time.Minute},
+			},
+			history:       []clusterv1.RemediationRecord{record("machine-a", time.Minute)},
+			machine:       machine("machine-a", ""),
+			expectedDelay: 4 * time.Minute,
+		},
+		{
+			name: "when the backoff doubles for every repeated remediation of a machine",
+			rateLimit: &clusterv1.RemediationRateLimit{
+				Window:       &metav1.Duration{Duration: time.Hour},
+				BackoffDelay: &metav1.Duration{Duration: 5 * time.Minute},
+			},
+			history:       []clusterv1.RemediationRecord{record("machine-a", 30*time.Minute), record("machine-a", 20*time.Minute), record("machine-a", time.Minute)},
+			machine:       machine("machine-a", ""),
+			expectedDelay: 19 * time.Minute,
+		},
+		{
+			name: "when the backoff is capped by maxBackoffDelay",
+			rateLimit: &clusterv1.RemediationRateLimit{
+				Window:          &metav1.Duration{Duration: time.Hour},
+				BackoffDelay:    &metav1.Duration{Duration: 5 * time.Minute},
+				MaxBackoffDelay: &metav1.Duration{Duration: 8 * time.Minute},
+			},
+			history:       []clusterv1.RemediationRecord{record("machine-a", 30*time.Minute), record("machine-a", 20*time.Minute), record("machine-a", time.Minute)},
+			machine:       machine("machine-a", ""),
+			expectedDelay: 7 * time.Minute,
+		},
+		{
+			name: "when the backoff does not apply to a different machine",
+			rateLimit: &clusterv1.RemediationRateLimit{
+				Window:       &metav1.Duration{Duration: time.Hour},
+				BackoffDelay: &metav1.Duration{Duration: 5 * time.Minute},
+			},
+			history:       []clusterv1.RemediationRecord{record("machine-a", time.Minute)},
+			machine:       machine("machine-b", ""),
+			expectedDelay: 0,
+		},
+		{
+			name: "when the backoff applies to the replacement machines of the same owner failing for the same reason",
+			rateLimit: &clusterv1.RemediationRateLimit{
+				Window:       &metav1.Duration{Duration: time.Hour},
+				BackoffDelay: &metav1.Duration{Duration: 5 * time.Minute},
+			},
+			history:       []clusterv1.RemediationRecord{mdRecord("md-1-a", clusterv1.NodeNotFoundReason, 20*time.Minute), mdRecord("md-1-b", clusterv1.NodeNotFoundReason, time.Minute)},
+			machine:       machine("md-1-c", "md-1"),
+			expectedDelay: 9 * time.Minute,
+		},
+		{
+			name: "when the backoff does not apply to the machines of the same owner failing for a different reason",
+			rateLimit: &clusterv1.RemediationRateLimit{
+				Window:       &metav1.Duration{Duration: time.Hour},
+				BackoffDelay: &metav1.Duration{Duration: 5 * time.Minute},
+			},
+			history:       []clusterv1.RemediationRecord{mdRecord("md-1-a", clusterv1.UnhealthyNodeConditionReason, time.Minute)},
+			machine:       machine("md-1-b", "md-1"),
+			expectedDelay: 0,
+		},
+		{
+			name: "when the backoff has expired",
+			rateLimit: &clusterv1.RemediationRateLimit{
+				Window:       &metav1.Duration{Duration: time.Hour},
+				BackoffDelay: &metav1.Duration{Duration: 5 * time.Minute},
+			},
+			history:       []clusterv1.RemediationRecord{record("machine-a", 10*time.Minute)},
+			machine:       machine("machine-a", ""),
+			expectedDelay: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mhc := &clusterv1.MachineHealthCheck{
+				Spec: clusterv1.MachineHealthCheckSpec{
+					RemediationRateLimit: tc.rateLimit,
+				},
+				Status: clusterv1.MachineHealthCheckStatus{
+					RemediationHistory: tc.history,
+				},
+			}
+
+			delay, message := remediationDelay(mhc, tc.machine, clusterv1.NodeNotFoundReason, now)
+			g.Expect(delay).To(Equal(tc.expectedDelay))
+			if tc.expectedDelay > 0 {
+				g.Expect(message).ToNot(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestPatchUnhealthyTargetsRateLimited(t *testing.T) {
+	_ = clusterv1.AddToScheme(scheme.Scheme)
+	g := NewWithT(t)
+
+	namespace := defaultNamespaceName
+	clusterName := "test-cluster"
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterName,
+			Namespace: namespace,
+		},
+	}
+	labels := map[string]string{"cluster": "foo", "nodepool": "bar"}
+
+	mhc := newMachineHealthCheckWithLabels("mhc", namespace, clusterName, labels)
+	mhc.Spec.RemediationRateLimit = &clusterv1.RemediationRateLimit{
+		MaxRemediations: pointer.Int32Ptr(1),
+		Window:          &metav1.Duration{Duration: time.Hour},
+	}
+	conditions.MarkTrue(mhc, clusterv1.RemediationAllowedCondition)
+
+	machine1 := newTestMachine("machine1", namespace, clusterName, "node1", labels)
+	conditions.MarkFalse(machine1, clusterv1.MachineHealthCheckSuccededCondition, clusterv1.NodeNotFoundReason, clusterv1.ConditionSeverityWarning, "")
+	machine2 := newTestMachine("machine2", namespace, clusterName, "node2", labels)
+	conditions.MarkFalse(machine2, clusterv1.MachineHealthCheckSuccededCondition, clusterv1.NodeNotFoundReason, clusterv1.ConditionSeverityWarning, "")
+
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(machine1, machine2, mhc).Build()
+	r := &MachineHealthCheckReconciler{
+		Client:   cl,
+		recorder: record.NewFakeRecorder(32),
+	}
+
+	targets := []healthCheckTarget{}
+	for _, m := range []*clusterv1.Machine{machine1, machine2} {
+		patchHelper, err := patch.NewHelper(m, cl)
+		g.Expect(err).ToNot(HaveOccurred())
+		targets = append(targets, healthCheckTarget{MHC: mhc, Machine: m, patchHelper: patchHelper, nodeMissing: true})
+	}
+
+	g.Expect(r.PatchUnhealthyTargets(context.TODO(), log.NullLogger{}, targets, cluster, mhc)).To(BeEmpty())
+
+	// Only the first machine is remediated, the second one is deferred by the rate limit.
+	g.Expect(cl.Get(ctx, client.ObjectKey{Name: machine1.Name, Namespace: namespace}, machine1)).To(Succeed())
+	g.Expect(conditions.IsFalse(machine1, clusterv1.MachineOwnerRemediatedCondition)).To(BeTrue())
+	g.Expect(cl.Get(ctx, client.ObjectKey{Name: machine2.Name, Namespace: namespace}, machine2)).To(Succeed())
+	g.Expect(conditions.Has(machine2, clusterv1.MachineOwnerRemediatedCondition)).To(BeFalse())
+
+	g.Expect(mhc.Status.RemediationHistory).To(HaveLen(1))
+	g.Expect(mhc.Status.RemediationHistory[0].MachineName).To(Equal(machine1.Name))
+	g.Expect(mhc.Status.RemediationHistory[0].Owner).To(Equal("Machine/" + machine1.Name))
+	g.Expect(mhc.Status.RemediationHistory[0].Reason).To(Equal(clusterv1.NodeNotFoundReason))
+	g.Expect(conditions.IsFalse(mhc, clusterv1.RemediationAllowedCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(mhc, clusterv1.RemediationAllowedCondition)).To(Equal(clusterv1.RemediationRateLimitedReason))
+	g.Expect(conditions.Get(machine2, clusterv1.MachineHealthCheckSuccededCondition).Status).To(Equal(corev1.ConditionFalse))
+}
@@ -88,6 +88,8 @@ func (t *healthCheckTarget) nodeName() string {
 // - The Machine has failed for some reason
 // - The Machine did not get a node before `timeoutForMachineToHaveNode` elapses
 // - The Node has gone away
+// - Any condition on the machine is matched for the given timeout
+// - The machine probe is failing for the given timeout
 // - Any condition on the node is matched for the given timeout
 // If the target doesn't currently need rememdiation, provide a duration after
 // which the target should next be checked.
@@ -115,11 +117,55 @@ func (t *healthCheckTarget) needsRemediation(logger logr.Logger, timeoutForMachi
 		return true, time.Duration(0)
 	}
 
+	// check machine conditions
+	for _, c := range t.MHC.Spec.UnhealthyMachineConditions {
+		machineCondition := conditions.Get(t.Machine, c.Type)
+
+		// Skip when current machine condition is different from the one reported
+		// in the MachineHealthCheck.
+		if machineCondition == nil || machineCondition.Status != c.Status {
+			continue
+		}
+
+		// If the condition has been in the unhealthy state for longer than the
+		// timeout, return true with no requeue time.
+		if machineCondition.LastTransitionTime.Add(c.Timeout.Duration).Before(now) {
+			conditions.MarkFalse(t.Machine, clusterv1.MachineHealthCheckSuccededCondition, clusterv1.UnhealthyMachineConditionReason, clusterv1.ConditionSeverityWarning, "Condition %s on machine is reporting status %s for more than %s", c.Type, c.Status, c.Timeout.Duration.String())
+			logger.V(3).Info("Target is unhealthy: machine condition is in state longer than allowed timeout", "condition", c.Type, "state", c.Status, "timeout", c.Timeout.Duration.String())
+			return true, time.Duration(0)
+		}
+
+		durationUnhealthy := now.Sub(machineCondition.LastTransitionTime.Time)
+		nextCheck := c.Timeout.Duration - durationUnhealthy + time.Second
+		if nextCheck > 0 {
+			nextCheckTimes = append(nextCheckTimes, nextCheck)
+		}
+	}
+
+	// check the machine probe
+	if probe := t.MHC.Spec.MachineProbe; probe != nil {
+		probeCondition := conditions.Get(t.Machine, clusterv1.MachineProbeSucceededCondition)
+		if probeCondition != nil && probeCondition.Status == corev1.ConditionFalse {
+			// If the probe has been failing for longer than the timeout, return true with no requeue time.
+			if probeCondition.LastTransitionTime.Add(probe.Timeout.Duration).Before(now) {
+				conditions.MarkFalse(t.Machine, clusterv1.MachineHealthCheckSuccededCondition, clusterv1.MachineProbeFailedReason, clusterv1.ConditionSeverityWarning, "Machine probe is failing for more than %s: %s", probe.Timeout.Duration.String(), probeCondition.Message)
+				logger.V(3).Info("Target is unhealthy: machine probe is failing longer than allowed timeout", "timeout", probe.Timeout.Duration.String())
+				return true, time.Duration(0)
+			}
+
+			durationUnhealthy := now.Sub(probeCondition.LastTransitionTime.Time)
+			nextCheck := probe.Timeout.Duration - durationUnhealthy + time.Second
+			if nextCheck > 0 {
+				nextCheckTimes = append(nextCheckTimes, nextCheck)
+			}
+		}
+	}
+
 	// the node has not been set yet
 	if t.Node == nil {
 		// status not updated yet
 		if t.Machine.Status.LastUpdated == nil {
-			return false, timeoutForMachineToHaveNode
+			return false, minDuration(append(nextCheckTimes, timeoutForMachineToHaveNode))
 		}
 		if t.Machine.Status.LastUpdated.Add(timeoutForMachineToHaveNode).Before(now) {
 			conditions.MarkFalse(t.Machine, clusterv1.MachineHealthCheckSuccededCondition, clusterv1.NodeStartupTimeoutReason, clusterv1.ConditionSeverityWarning, "Node failed to report startup in %s", timeoutForMachineToHaveNode.String())
@@ -128,7 +174,7 @@ func (t *healthCheckTarget) needsRemediation(logger logr.Logger, timeoutForMachi
 		}
 		durationUnhealthy := now.Sub(t.Machine.Status.LastUpdated.Time)
 		nextCheck := timeoutForMachineToHaveNode - durationUnhealthy + time.Second
-		return false, nextCheck
+		return false, minDuration(append(nextCheckTimes, nextCheck))
 	}
 
 	// check conditions
@@ -317,5 +363,9 @@ func shouldSkipRemediation(m *clusterv1.Machine) (bool, string) {
 		return true, fmt.Sprintf("machine has %q annotation", clusterv1.MachineSkipRemediationAnnotation)
 	}
 
+	if annotations.HasDeletionProtectionAnnotation(m) {
+		return true, fmt.Sprintf("machine has %q annotation", clusterv1.MachineDeletionProtectionAnnotation)
+	}
+
 	return false, ""
 }
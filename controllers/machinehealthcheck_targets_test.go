@@ -82,6 +82,9 @@ func TestGetTargetsFromMHC(t *testing.T) {
 	testNode6 := newTestNode("node6")
 	testMachine6 := newTestMachine("machine6", namespace, clusterName, testNode6.Name, mhcSelector)
 	testMachine6.Annotations = map[string]string{"cluster.x-k8s.io/paused": ""}
+	testNode7 := newTestNode("node7")
+	testMachine7 := newTestMachine("machine7", namespace, clusterName, testNode7.Name, mhcSelector)
+	testMachine7.Annotations = map[string]string{"cluster.x-k8s.io/deletion-protection": ""}
 
 	testCases := []struct {
 		desc            string
@@ -133,8 +136,8 @@ func TestGetTargetsFromMHC(t *testing.T) {
 			},
 		},
 		{
-			desc:     "with machines having skip-remediation or paused annotation",
-			toCreate: append(baseObjects, testNode1, testMachine1, testMachine5, testMachine6),
+			desc:     "with machines having skip-remediation, paused or deletion-protection annotation",
+			toCreate: append(baseObjects, testNode1, testMachine1, testMachine5, testMachine6, testMachine7),
 			expectedTargets: []healthCheckTarget{
 				{
 					Machine: testMachine1,
@@ -204,6 +207,17 @@ func TestHealthCheckTargets(t *testing.T) {
 					Timeout: metav1.Duration{Duration: 5 * time.Minute},
 				},
 			},
+			UnhealthyMachineConditions: []clusterv1.UnhealthyMachineCondition{
+				{
+					Type:    clusterv1.InfrastructureReadyCondition,
+					Status:  corev1.ConditionFalse,
+					Timeout: metav1.Duration{Duration: 5 * time.Minute},
+				},
+			},
+			MachineProbe: &clusterv1.MachineProbe{
+				KubeletHealthz: &clusterv1.KubeletHealthzMachineProbe{},
+				Timeout:        metav1.Duration{Duration: 5 * time.Minute},
+			},
 		},
 	}
 
@@ -265,6 +279,55 @@ func TestHealthCheckTargets(t *testing.T) {
 		nodeMissing: false,
 	}
 
+	// Target for when a machine condition has been in the unhealthy state for shorter than the timeout
+	testMachineInfraNotReady200 := testMachine.DeepCopy()
+	testMachineInfraNotReady200.Status.Conditions = clusterv1.Conditions{
+		{
+			Type:               clusterv1.InfrastructureReadyCondition,
+			Status:             corev1.ConditionFalse,
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-200 * time.Second)),
+		},
+	}
+	machineInfraNotReady200 := healthCheckTarget{
+		MHC:         testMHC,
+		Machine:     testMachineInfraNotReady200,
+		Node:        testNodeHealthy,
+		nodeMissing: false,
+	}
+
+	// Target for when a machine condition has been in the unhealthy state for longer than the timeout
+	testMachineInfraNotReady400 := testMachine.DeepCopy()
+	testMachineInfraNotReady400.Status.Conditions = clusterv1.Conditions{
+		{
+			Type:               clusterv1.InfrastructureReadyCondition,
+			Status:             corev1.ConditionFalse,
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-400 * time.Second)),
+		},
+	}
+	machineInfraNotReady400 := healthCheckTarget{
+		MHC:         testMHC,
+		Machine:     testMachineInfraNotReady400,
+		Node:        testNodeHealthy,
+		nodeMissing: false,
+	}
+
+	// Target for when the machine probe has been failing for longer than the timeout
+	testMachineProbeFailing400 := testMachine.DeepCopy()
+	testMachineProbeFailing400.Status.Conditions = clusterv1.Conditions{
+		{
+			Type:               clusterv1.MachineProbeSucceededCondition,
+			Status:             corev1.ConditionFalse,
+			Reason:             clusterv1.MachineProbeFailedReason,
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-400 * time.Second)),
+		},
+	}
+	machineProbeFailing400 := healthCheckTarget{
+		MHC:         testMHC,
+		Machine:     testMachineProbeFailing400,
+		Node:        testNodeHealthy,
+		nodeMissing: false,
+	}
+
 	testCases := []struct {
 		desc                     string
 		targets                  []healthCheckTarget
@@ -314,6 +377,27 @@ func TestHealthCheckTargets(t *testing.T) {
 			expectedNeedsRemediation: []healthCheckTarget{nodeUnknown400},
 			expectedNextCheckTimes:   []time.Duration{200 * time.Second, 100 * time.Second},
 		},
+		{
+			desc:                     "when a machine condition has been unhealthy for shorter than the timeout",
+			targets:                  []healthCheckTarget{machineInfraNotReady200},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{},
+			expectedNextCheckTimes:   []time.Duration{100 * time.Second},
+		},
+		{
+			desc:                     "when a machine condition has been unhealthy for longer than the timeout",
+			targets:                  []healthCheckTarget{machineInfraNotReady400},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{machineInfraNotReady400},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
+		{
+			desc:                     "when the machine probe has been failing for longer than the timeout",
+			targets:                  []healthCheckTarget{machineProbeFailing400},
+			expectedHealthy:          []healthCheckTarget{},
+			expectedNeedsRemediation: []healthCheckTarget{machineProbeFailing400},
+			expectedNextCheckTimes:   []time.Duration{},
+		},
 	}
 
 	for _, tc := range testCases {
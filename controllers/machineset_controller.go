@@ -38,6 +38,7 @@ import (
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	utilconversion "sigs.k8s.io/cluster-api/util/conversion"
+	"sigs.k8s.io/cluster-api/util/maintenance"
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/cluster-api/util/predicates"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -192,6 +193,13 @@ func (r *MachineSetReconciler) reconcile(ctx context.Context, cluster *clusterv1
 		}
 	}
 
+	// Surface the capacity of the machines, used by autoscalers to scale from zero.
+	capacity, err := getMachineCapacity(ctx, r.Client, machineSet.Namespace, &machineSet.Spec.Template, machineSet.Spec.Capacity)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	machineSet.Status.Capacity = capacity
+
 	// Make sure selector and template to be in the same cluster.
 	machineSet.Spec.Selector.MatchLabels[clusterv1.ClusterLabelName] = machineSet.Spec.ClusterName
 	machineSet.Spec.Template.Labels[clusterv1.ClusterLabelName] = machineSet.Spec.ClusterName
@@ -242,6 +250,10 @@ func (r *MachineSetReconciler) reconcile(ctx context.Context, cluster *clusterv1
 			continue
 		}
 		if conditions.IsFalse(machine, clusterv1.MachineOwnerRemediatedCondition) {
+			if isDeletionProtected(machine) {
+				log.Info("Unhealthy machine is protected from deletion, skipping remediation", "machine", machine.GetName())
+				continue
+			}
 			log.Info("Deleting unhealthy machine", "machine", machine.GetName())
 			patch := client.MergeFrom(machine.DeepCopy())
 			if err := r.Client.Delete(ctx, machine); err != nil {
@@ -261,7 +273,25 @@ func (r *MachineSetReconciler) reconcile(ctx context.Context, cluster *clusterv1
 		return ctrl.Result{}, errors.Wrap(err, "failed to remediate machines")
 	}
 
-	syncErr := r.syncReplicas(ctx, machineSet, filteredMachines)
+	// Defer scaling down outside of the Cluster's maintenance windows.
+	var syncErr error
+	var deferredRequeueAfter time.Duration
+	if machineSet.Spec.Replicas != nil && len(filteredMachines) > int(*machineSet.Spec.Replicas) {
+		now := time.Now()
+		allowed, next, err := maintenance.DisruptionAllowed(cluster, now)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !allowed {
+			log.Info("Scale down deferred until the next maintenance window opens", "next", next)
+			maintenance.MarkDeferred(machineSet, "Scale down", next)
+			deferredRequeueAfter = maintenance.RequeueAfter(next, now)
+		}
+	}
+	if deferredRequeueAfter == 0 {
+		maintenance.MarkAllowed(machineSet, cluster)
+		syncErr = r.syncReplicas(ctx, machineSet, filteredMachines)
+	}
 
 	// Always updates status as machines come up or die.
 	if err := r.updateStatus(ctx, cluster, machineSet, filteredMachines); err != nil {
@@ -272,6 +302,10 @@ func (r *MachineSetReconciler) reconcile(ctx context.Context, cluster *clusterv1
 		return ctrl.Result{}, errors.Wrapf(syncErr, "failed to sync MachineSet replicas")
 	}
 
+	if deferredRequeueAfter > 0 {
+		return ctrl.Result{RequeueAfter: deferredRequeueAfter}, nil
+	}
+
 	var replicas int32
 	if machineSet.Spec.Replicas != nil {
 		replicas = *machineSet.Spec.Replicas
@@ -308,6 +342,10 @@ func (r *MachineSetReconciler) syncReplicas(ctx context.Context, ms *clusterv1.M
 	}
 
 	diff := len(machines) - int(*(ms.Spec.Replicas))
+	if diff <= 0 && conditions.Has(ms, clusterv1.MachinesDeletableCondition) {
+		conditions.MarkTrue(ms, clusterv1.MachinesDeletableCondition)
+	}
+
 	switch {
 	case diff < 0:
 		diff *= -1
@@ -393,6 +431,17 @@ func (r *MachineSetReconciler) syncReplicas(ctx context.Context, ms *clusterv1.M
 
 		var errs []error
 		machinesToDelete := getMachinesToDeletePrioritized(machines, diff, deletePriorityFunc)
+
+		// Machines protected from deletion are never deleted, so scaling down might be stalled.
+		if len(machinesToDelete) < diff {
+			protected := getDeletionProtectedMachineNames(machines)
+			log.Info("Scale down stalled by machines protected from deletion", "protected", protected)
+			conditions.MarkFalse(ms, clusterv1.MachinesDeletableCondition, clusterv1.MachineDeletionProtectedReason, clusterv1.ConditionSeverityWarning,
+				"Scale down stalled, %d replicas to be deleted are protected from deletion (protected machines: %s)", diff-len(machinesToDelete), strings.Join(protected, ", "))
+		} else if conditions.Has(ms, clusterv1.MachinesDeletableCondition) {
+			conditions.MarkTrue(ms, clusterv1.MachinesDeletableCondition)
+		}
+
 		for _, machine := range machinesToDelete {
 			if err := r.Client.Delete(ctx, machine); err != nil {
 				log.Error(err, "Unable to delete Machine", "machine", machine.Name)
@@ -628,9 +677,11 @@ func (r *MachineSetReconciler) updateStatus(ctx context.Context, cluster *cluste
 			continue
 		}
 
-		if noderefutil.IsNodeReady(node) {
+		// A machine is ready and available only when all the conditions in its readiness gates are True as well.
+		if noderefutil.IsNodeReady(node) && len(readinessGatesNotReady(machine)) == 0 {
 			readyReplicasCount++
-			if noderefutil.IsNodeAvailable(node, ms.Spec.MinReadySeconds, metav1.Now()) {
+			now := metav1.Now()
+			if noderefutil.IsNodeAvailable(node, ms.Spec.MinReadySeconds, now) && readinessGatesAvailable(machine, ms.Spec.MinReadySeconds, now) {
 				availableReplicasCount++
 			}
 		}
@@ -706,3 +757,37 @@ func reconcileExternalTemplateReference(ctx context.Context, c client.Client, re
 	}
 	return nil
 }
+
+// getMachineCapacity returns the capacity of the machines created from the given template, computed from the capacity
+// defined by users and from the status.capacity field of the infrastructure template; resources defined by users take precedence.
+func getMachineCapacity(ctx context.Context, c client.Client, namespace string, template *clusterv1.MachineTemplateSpec, capacity *clusterv1.MachineCapacity) (*clusterv1.MachineCapacity, error) {
+	var resources corev1.ResourceList
+	if strings.HasSuffix(template.Spec.InfrastructureRef.Kind, external.TemplateSuffix) {
+		infraTemplate, err := external.Get(ctx, c, &template.Spec.InfrastructureRef, namespace)
+		if err != nil {
+			return nil, err
+		}
+		resources, err = external.CapacityFrom(infraTemplate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if capacity == nil && len(resources) == 0 {
+		return nil, nil
+	}
+
+	result := &clusterv1.MachineCapacity{}
+	if capacity != nil {
+		result = capacity.DeepCopy()
+	}
+	for name, quantity := range resources {
+		if result.Resources == nil {
+			result.Resources = corev1.ResourceList{}
+		}
+		if _, ok := result.Resources[name]; !ok {
+			result.Resources[name] = quantity
+		}
+	}
+	return result, nil
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package controllers
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -25,6 +26,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -32,6 +34,7 @@ import (
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/controllers/external"
 	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -398,6 +401,89 @@ func TestMachineSetReconcile(t *testing.T) {
 		_, _ = msr.Reconcile(ctx, request)
 		g.Eventually(rec.Events).Should(Receive())
 	})
+
+	t.Run("defers scale down outside of the cluster's maintenance windows", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cluster := testCluster.DeepCopy()
+		cluster.Spec.MaintenanceWindows = []clusterv1.MaintenanceWindow{
+			// A window opening in 30 minutes, so it is closed now.
+			{Schedule: fmt.Sprintf("%d * * * *", (time.Now().UTC().Minute()+30)%60), Duration: metav1.Duration{Duration: time.Minute}},
+		}
+		ms := newMachineSet("machineset1", "test-cluster")
+		machine := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "machine1",
+				Namespace:       "default",
+				Labels:          ms.Spec.Selector.MatchLabels,
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(ms, machineSetKind)},
+			},
+			Spec: clusterv1.MachineSpec{ClusterName: "test-cluster"},
+		}
+		request := reconcile.Request{
+			NamespacedName: util.ObjectKey(ms),
+		}
+
+		g.Expect(clusterv1.AddToScheme(scheme.Scheme)).To(Succeed())
+
+		c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(cluster, ms, machine).Build()
+		msr := &MachineSetReconciler{
+			Client:   c,
+			recorder: record.NewFakeRecorder(32),
+		}
+		result, err := msr.Reconcile(ctx, request)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		g.Expect(result.RequeueAfter).To(BeNumerically("<=", 31*time.Minute))
+
+		// The machine exceeding the desired replicas is not deleted.
+		g.Expect(c.Get(ctx, util.ObjectKey(machine), &clusterv1.Machine{})).To(Succeed())
+
+		updatedMS := &clusterv1.MachineSet{}
+		g.Expect(c.Get(ctx, util.ObjectKey(ms), updatedMS)).To(Succeed())
+		g.Expect(conditions.IsFalse(updatedMS, clusterv1.DisruptionAllowedCondition)).To(BeTrue())
+		g.Expect(conditions.GetReason(updatedMS, clusterv1.DisruptionAllowedCondition)).To(Equal(clusterv1.OutsideMaintenanceWindowReason))
+	})
+
+	t.Run("does not delete machines protected from deletion when scaling down", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ms := newMachineSet("machineset1", "test-cluster")
+		newMachine := func(name string, annotations map[string]string) *clusterv1.Machine {
+			return &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					Namespace:       "default",
+					Labels:          ms.Spec.Selector.MatchLabels,
+					Annotations:     annotations,
+					OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(ms, machineSetKind)},
+				},
+				Spec: clusterv1.MachineSpec{ClusterName: "test-cluster"},
+			}
+		}
+		protectedMachine := newMachine("machine1", map[string]string{clusterv1.MachineDeletionProtectionAnnotation: ""})
+		machine := newMachine("machine2", nil)
+		request := reconcile.Request{
+			NamespacedName: util.ObjectKey(ms),
+		}
+
+		g.Expect(clusterv1.AddToScheme(scheme.Scheme)).To(Succeed())
+
+		c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(testCluster, ms, protectedMachine, machine).Build()
+		msr := &MachineSetReconciler{
+			Client:   c,
+			recorder: record.NewFakeRecorder(32),
+		}
+		_, _ = msr.Reconcile(ctx, request)
+
+		g.Expect(c.Get(ctx, util.ObjectKey(protectedMachine), &clusterv1.Machine{})).To(Succeed())
+		g.Expect(apierrors.IsNotFound(c.Get(ctx, util.ObjectKey(machine), &clusterv1.Machine{}))).To(BeTrue())
+
+		updatedMS := &clusterv1.MachineSet{}
+		g.Expect(c.Get(ctx, util.ObjectKey(ms), updatedMS)).To(Succeed())
+		g.Expect(conditions.IsFalse(updatedMS, clusterv1.MachinesDeletableCondition)).To(BeTrue())
+		g.Expect(conditions.GetMessage(updatedMS, clusterv1.MachinesDeletableCondition)).To(ContainSubstring("machine1"))
+	})
 }
 
 func TestMachineSetToMachines(t *testing.T) {
@@ -770,3 +856,90 @@ func newMachineSet(name, cluster string) *clusterv1.MachineSet {
 		},
 	}
 }
+
+func TestGetMachineCapacity(t *testing.T) {
+	infraTmpl := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"status": map[string]interface{}{
+				"capacity": map[string]interface{}{
+					"cpu":    "4",
+					"memory": "16Gi",
+				},
+			},
+		},
+	}
+	infraTmpl.SetKind("InfrastructureMachineTemplate")
+	infraTmpl.SetAPIVersion("infrastructure.cluster.x-k8s.io/v1alpha4")
+	infraTmpl.SetName("infra-template")
+	infraTmpl.SetNamespace("default")
+
+	emptyInfraTmpl := infraTmpl.DeepCopy()
+	emptyInfraTmpl.SetName("empty-infra-template")
+	unstructured.RemoveNestedField(emptyInfraTmpl.Object, "status")
+
+	template := func(name string) *clusterv1.MachineTemplateSpec {
+		return &clusterv1.MachineTemplateSpec{
+			Spec: clusterv1.MachineSpec{
+				InfrastructureRef: corev1.ObjectReference{
+					Kind:       "InfrastructureMachineTemplate",
+					APIVersion: "infrastructure.cluster.x-k8s.io/v1alpha4",
+					Name:       name,
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		template *clusterv1.MachineTemplateSpec
+		capacity *clusterv1.MachineCapacity
+		expected *clusterv1.MachineCapacity
+	}{
+		{
+			name:     "should return nil without capacity",
+			template: template("empty-infra-template"),
+		},
+		{
+			name:     "should return the capacity defined by users",
+			template: template("empty-infra-template"),
+			capacity: &clusterv1.MachineCapacity{Labels: map[string]string{"foo": "bar"}},
+			expected: &clusterv1.MachineCapacity{Labels: map[string]string{"foo": "bar"}},
+		},
+		{
+			name:     "should return the capacity of the infrastructure template",
+			template: template("infra-template"),
+			expected: &clusterv1.MachineCapacity{
+				Resources: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("16Gi"),
+				},
+			},
+		},
+		{
+			name:     "should give precedence to the capacity defined by users",
+			template: template("infra-template"),
+			capacity: &clusterv1.MachineCapacity{
+				Resources: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+				Labels:    map[string]string{"foo": "bar"},
+			},
+			expected: &clusterv1.MachineCapacity{
+				Resources: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("2"),
+					corev1.ResourceMemory: resource.MustParse("16Gi"),
+				},
+				Labels: map[string]string{"foo": "bar"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := fake.NewClientBuilder().WithObjects(infraTmpl.DeepCopy(), emptyInfraTmpl.DeepCopy()).Build()
+			capacity, err := getMachineCapacity(ctx, c, "default", tt.template, tt.capacity)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(capacity).To(Equal(tt.expected))
+		})
+	}
+}
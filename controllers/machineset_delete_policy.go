@@ -23,6 +23,7 @@ import (
 	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/annotations"
 )
 
 type (
@@ -106,7 +107,34 @@ func (m sortableMachines) Less(i, j int) bool {
 	return m.priority(m.machines[j]) < m.priority(m.machines[i]) // high to low
 }
 
+// isDeletionProtected returns true if the machine is protected from deletion and it is not already being deleted.
+func isDeletionProtected(machine *clusterv1.Machine) bool {
+	return machine.DeletionTimestamp.IsZero() && annotations.HasDeletionProtectionAnnotation(machine)
+}
+
+// getDeletionProtectedMachineNames returns the sorted names of the machines protected from deletion.
+func getDeletionProtectedMachineNames(machines []*clusterv1.Machine) []string {
+	names := []string{}
+	for _, m := range machines {
+		if isDeletionProtected(m) {
+			names = append(names, m.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getMachinesToDeletePrioritized returns up to diff machines to be deleted, sorted by the given priority.
+// Machines protected from deletion are never returned, so less than diff machines might be returned.
 func getMachinesToDeletePrioritized(filteredMachines []*clusterv1.Machine, diff int, fun deletePriorityFunc) []*clusterv1.Machine {
+	deletableMachines := make([]*clusterv1.Machine, 0, len(filteredMachines))
+	for _, m := range filteredMachines {
+		if !isDeletionProtected(m) {
+			deletableMachines = append(deletableMachines, m)
+		}
+	}
+	filteredMachines = deletableMachines
+
 	if diff >= len(filteredMachines) {
 		return filteredMachines
 	} else if diff <= 0 {
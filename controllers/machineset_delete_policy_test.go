@@ -44,6 +44,19 @@ func TestMachineToDelete(t *testing.T) {
 		Status:     clusterv1.MachineStatus{NodeRef: nodeRef},
 	}
 	deleteMachineWithoutNodeRef := &clusterv1.Machine{}
+	protectedMachine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			clusterv1.MachineDeletionProtectionAnnotation: "",
+			clusterv1.DeleteMachineAnnotation:             "",
+		}},
+	}
+	deletingProtectedMachine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			DeletionTimestamp: &now,
+			Annotations:       map[string]string{clusterv1.MachineDeletionProtectionAnnotation: ""},
+		},
+		Status: clusterv1.MachineStatus{NodeRef: nodeRef},
+	}
 
 	tests := []struct {
 		desc     string
@@ -174,6 +187,29 @@ func TestMachineToDelete(t *testing.T) {
 				deleteMachineWithoutNodeRef,
 			},
 		},
+		{
+			desc: "func=randomDeletePolicy, MachineDeletionProtectionAnnotation, diff=2",
+			diff: 2,
+			machines: []*clusterv1.Machine{
+				protectedMachine,
+				healthyMachine,
+				protectedMachine,
+			},
+			expect: []*clusterv1.Machine{
+				healthyMachine,
+			},
+		},
+		{
+			desc: "func=randomDeletePolicy, MachineDeletionProtectionAnnotation on a deleting machine, diff=1",
+			diff: 1,
+			machines: []*clusterv1.Machine{
+				healthyMachine,
+				deletingProtectedMachine,
+			},
+			expect: []*clusterv1.Machine{
+				deletingProtectedMachine,
+			},
+		},
 	}
 
 	for _, test := range tests {
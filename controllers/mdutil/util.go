@@ -524,6 +524,22 @@ func DeploymentComplete(deployment *clusterv1.MachineDeployment, newStatus *clus
 		newStatus.ObservedGeneration >= deployment.Generation
 }
 
+// DeploymentProgressing reads the status of the deployment and the new status computed by the controller
+// and returns true if the rollout made progress: more machines were updated, became ready or available,
+// or old machines were scaled down.
+func DeploymentProgressing(deployment *clusterv1.MachineDeployment, newStatus *clusterv1.MachineDeploymentStatus) bool {
+	oldStatus := deployment.Status
+
+	// Old replicas that need to be scaled down
+	oldStatusOldReplicas := oldStatus.Replicas - oldStatus.UpdatedReplicas
+	newStatusOldReplicas := newStatus.Replicas - newStatus.UpdatedReplicas
+
+	return (newStatus.UpdatedReplicas > oldStatus.UpdatedReplicas) ||
+		(newStatusOldReplicas < oldStatusOldReplicas) ||
+		(newStatus.ReadyReplicas > oldStatus.ReadyReplicas) ||
+		(newStatus.AvailableReplicas > oldStatus.AvailableReplicas)
+}
+
 // NewMSNewReplicas calculates the number of replicas a deployment's new MS should have.
 // When one of the following is true, we're rolling out the deployment; otherwise, we're scaling it.
 // 1) The new MS is saturated: newMS's replicas == deployment's replicas
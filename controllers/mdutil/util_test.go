@@ -635,6 +635,94 @@ func TestDeploymentComplete(t *testing.T) {
 	}
 }
 
+func TestDeploymentProgressing(t *testing.T) {
+	deployment := func(current, updated, ready, available int32) *clusterv1.MachineDeployment {
+		return &clusterv1.MachineDeployment{
+			Status: clusterv1.MachineDeploymentStatus{
+				Replicas:          current,
+				UpdatedReplicas:   updated,
+				ReadyReplicas:     ready,
+				AvailableReplicas: available,
+			},
+		}
+	}
+	newStatus := func(current, updated, ready, available int32) clusterv1.MachineDeploymentStatus {
+		return clusterv1.MachineDeploymentStatus{
+			Replicas:          current,
+			UpdatedReplicas:   updated,
+			ReadyReplicas:     ready,
+			AvailableReplicas: available,
+		}
+	}
+
+	tests := []struct {
+		name string
+
+		d         *clusterv1.MachineDeployment
+		newStatus clusterv1.MachineDeploymentStatus
+
+		expected bool
+	}{
+		{
+			name: "progressing: updated machines",
+
+			d:         deployment(10, 4, 4, 4),
+			newStatus: newStatus(10, 6, 4, 4),
+			expected:  true,
+		},
+		{
+			name: "not progressing: no change",
+
+			d:         deployment(10, 4, 4, 4),
+			newStatus: newStatus(10, 4, 4, 4),
+			expected:  false,
+		},
+		{
+			name: "progressing: old machines removed",
+
+			d:         deployment(10, 4, 6, 6),
+			newStatus: newStatus(8, 4, 6, 6),
+			expected:  true,
+		},
+		{
+			name: "not progressing: less new machines",
+
+			d:         deployment(10, 7, 3, 3),
+			newStatus: newStatus(10, 6, 3, 3),
+			expected:  false,
+		},
+		{
+			name: "progressing: less overall but more new machines",
+
+			d:         deployment(10, 4, 7, 7),
+			newStatus: newStatus(8, 8, 7, 7),
+			expected:  true,
+		},
+		{
+			name: "progressing: more ready machines",
+
+			d:         deployment(10, 10, 3, 3),
+			newStatus: newStatus(10, 10, 4, 3),
+			expected:  true,
+		},
+		{
+			name: "progressing: more available machines",
+
+			d:         deployment(10, 10, 4, 3),
+			newStatus: newStatus(10, 10, 4, 4),
+			expected:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(DeploymentProgressing(test.d, &test.newStatus)).To(Equal(test.expected))
+		})
+	}
+}
+
 func TestMaxUnavailable(t *testing.T) {
 	deployment := func(replicas int32, maxUnavailable intstr.IntOrString) clusterv1.MachineDeployment {
 		return clusterv1.MachineDeployment{
@@ -29,6 +29,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
@@ -81,11 +82,26 @@ func (t *ClusterCacheTracker) GetClient(ctx context.Context, cluster client.Obje
 	return accessor.client, nil
 }
 
+// GetClientset returns a clientset for the given cluster, for the requests which cannot be sent with the
+// client returned by GetClient, e.g. requests to the proxy subresource of nodes.
+func (t *ClusterCacheTracker) GetClientset(ctx context.Context, cluster client.ObjectKey) (kubernetes.Interface, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	accessor, err := t.getClusterAccessorLH(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return accessor.clientset, nil
+}
+
 // clusterAccessor represents the combination of a delegating client, cache, and watches for a remote cluster.
 type clusterAccessor struct {
-	cache   *stoppableCache
-	client  client.Client
-	watches sets.String
+	cache     *stoppableCache
+	client    client.Client
+	clientset kubernetes.Interface
+	watches   sets.String
 }
 
 // clusterAccessorExists returns true if a clusterAccessor exists for cluster.
@@ -135,6 +151,12 @@ func (t *ClusterCacheTracker) newClusterAccessor(ctx context.Context, cluster cl
 		return nil, errors.Wrapf(err, "error creating client for remote cluster %q", cluster.String())
 	}
 
+	// Create the clientset for the remote cluster
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating clientset for remote cluster %q", cluster.String())
+	}
+
 	// Create the cache for the remote cluster
 	cacheOptions := cache.Options{
 		Scheme: t.scheme,
@@ -175,9 +197,10 @@ func (t *ClusterCacheTracker) newClusterAccessor(ctx context.Context, cluster cl
 	}
 
 	return &clusterAccessor{
-		cache:   cache,
-		client:  delegatingClient,
-		watches: sets.NewString(),
+		cache:     cache,
+		client:    delegatingClient,
+		clientset: clientset,
+		watches:   sets.NewString(),
 	}, nil
 }
 
@@ -19,9 +19,12 @@ package v1alpha3
 import (
 	"testing"
 
+	fuzz "github.com/google/gofuzz"
 	. "github.com/onsi/gomega"
+	runtimeserializer "k8s.io/apimachinery/pkg/runtime/serializer"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/types/v1beta1"
 	"sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
 	utilconversion "sigs.k8s.io/cluster-api/util/conversion"
 )
@@ -32,5 +35,27 @@ func TestFuzzyConversion(t *testing.T) {
 	g.Expect(AddToScheme(scheme)).To(Succeed())
 	g.Expect(v1alpha4.AddToScheme(scheme)).To(Succeed())
 
-	t.Run("for KubeadmControlPLane", utilconversion.FuzzTestFunc(scheme, &v1alpha4.KubeadmControlPlane{}, &KubeadmControlPlane{}))
+	t.Run("for KubeadmControlPLane", utilconversion.FuzzTestFunc(scheme, &v1alpha4.KubeadmControlPlane{}, &KubeadmControlPlane{}, KubeadmControlPlaneFuzzFuncs))
+}
+
+func KubeadmControlPlaneFuzzFuncs(_ runtimeserializer.CodecFactory) []interface{} {
+	return []interface{}{
+		BootstrapTokenStringFuzzer,
+	}
+}
+
+func BootstrapTokenStringFuzzer(obj *kubeadmv1beta1.BootstrapTokenString, c fuzz.Continue) {
+	// Hub data is preserved in an annotation as JSON on down-conversion, so the bootstrap tokens
+	// must be valid in order to survive the round trip.
+	obj.ID = randomTokenString(c, 6)
+	obj.Secret = randomTokenString(c, 16)
+}
+
+func randomTokenString(c fuzz.Continue, n int) string {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = chars[c.Intn(len(chars))]
+	}
+	return string(b)
 }
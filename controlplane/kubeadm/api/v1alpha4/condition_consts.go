@@ -119,9 +119,14 @@ const (
 	// EtcdClusterUnknownReason reports an etcd cluster in unknown status.
 	EtcdClusterUnknownReason = "EtcdClusterUnknown"
 
-	// EtcdClusterUnhealthyReason (Severity=Error) is set when the etcd cluster is unhealthy.
+	// EtcdClusterUnhealthyReason (Severity=Error) is set when the etcd cluster is unhealthy; it is
+	// set with Severity=Warning when the database of some etcd members is close to the quota.
 	EtcdClusterUnhealthyReason = "EtcdClusterUnhealthy"
 
+	// EtcdDefragmentationFailedReason (Severity=Warning) documents a KubeadmControlPlane failing to defragment
+	// an etcd member.
+	EtcdDefragmentationFailedReason = "EtcdDefragmentationFailed"
+
 	// MachineEtcdMemberHealthyCondition report the machine's etcd member's health status.
 	// NOTE: This conditions exists only if a stacked etcd cluster is used.
 	MachineEtcdMemberHealthyCondition clusterv1.ConditionType = "EtcdMemberHealthy"
@@ -149,3 +154,52 @@ const (
 	// generate a machine object
 	MachineGenerationFailedReason = "MachineGenerationFailed"
 )
+
+const (
+	// EtcdBackupSucceededCondition documents that the last scheduled snapshot of the etcd cluster has been
+	// taken and stored successfully; it is set only when an etcd backup policy is defined.
+	EtcdBackupSucceededCondition clusterv1.ConditionType = "EtcdBackupSucceeded"
+
+	// EtcdBackupFailedReason (Severity=Warning) documents a KubeadmControlPlane failing to take or to store
+	// a snapshot of the etcd cluster.
+	EtcdBackupFailedReason = "EtcdBackupFailed"
+)
+
+const (
+	// EtcdRestoredCondition documents the restore of the etcd cluster from the snapshot requested with
+	// the RestoreEtcdSnapshotAnnotation; it is set to true once the restore is complete.
+	EtcdRestoredCondition clusterv1.ConditionType = "EtcdRestored"
+
+	// DeletingMachinesForEtcdRestoreReason (Severity=Info) documents a KubeadmControlPlane deleting its machines
+	// before restoring etcd from a snapshot.
+	DeletingMachinesForEtcdRestoreReason = "DeletingMachinesForEtcdRestore"
+
+	// RestoringEtcdReason (Severity=Info) documents a KubeadmControlPlane waiting for the first control plane machine
+	// to be bootstrapped with the etcd data restored from a snapshot.
+	RestoringEtcdReason = "RestoringEtcd"
+
+	// EtcdRestoreFailedReason (Severity=Error) documents a KubeadmControlPlane failing to restore etcd from a snapshot.
+	EtcdRestoreFailedReason = "EtcdRestoreFailed"
+)
+
+const (
+	// CertificateAuthoritiesRotatedCondition documents the rotation of the certificate authorities requested with
+	// the RotateCertificateAuthoritiesAnnotation; it is set to true once the rotation is complete.
+	CertificateAuthoritiesRotatedCondition clusterv1.ConditionType = "CertificateAuthoritiesRotated"
+
+	// TrustingNewCertificateAuthoritiesReason (Severity=Info) documents a KubeadmControlPlane rolling out machines
+	// to trust new certificate authorities.
+	TrustingNewCertificateAuthoritiesReason = "TrustingNewCertificateAuthorities"
+
+	// SigningWithNewCertificateAuthoritiesReason (Severity=Info) documents a KubeadmControlPlane rolling out machines
+	// to get certificates signed by new certificate authorities.
+	SigningWithNewCertificateAuthoritiesReason = "SigningWithNewCertificateAuthorities"
+
+	// RemovingOldCertificateAuthoritiesReason (Severity=Info) documents a KubeadmControlPlane rolling out machines
+	// to stop trusting old certificate authorities.
+	RemovingOldCertificateAuthoritiesReason = "RemovingOldCertificateAuthorities"
+
+	// CertificateAuthoritiesRotationFailedReason (Severity=Error) documents a KubeadmControlPlane failing to rotate
+	// the certificate authorities.
+	CertificateAuthoritiesRotationFailedReason = "CertificateAuthoritiesRotationFailed"
+)
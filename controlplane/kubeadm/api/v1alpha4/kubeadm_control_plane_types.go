@@ -19,6 +19,7 @@ package v1alpha4
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 
 	cabpkv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1alpha4"
@@ -37,6 +38,40 @@ const (
 	// KubeadmClusterConfigurationAnnotation is a machine annotation that stores the json-marshalled string of KCP ClusterConfiguration.
 	// This annotation is used to detect any changes in ClusterConfiguration and trigger machine rollout in KCP.
 	KubeadmClusterConfigurationAnnotation = "controlplane.cluster.x-k8s.io/kubeadm-cluster-configuration"
+
+	// RestoreEtcdSnapshotAnnotation requests the KubeadmControlPlane to restore etcd from the snapshot with the given name,
+	// replacing all its machines; the annotation is removed once the restore is complete.
+	RestoreEtcdSnapshotAnnotation = "controlplane.cluster.x-k8s.io/restore-etcd-snapshot"
+
+	// RestoredEtcdSnapshotAnnotation is a machine annotation that stores the name of the etcd snapshot the machine
+	// has been bootstrapped from.
+	RestoredEtcdSnapshotAnnotation = "controlplane.cluster.x-k8s.io/restored-etcd-snapshot"
+
+	// CertificatesExpiryAnnotation is a machine annotation that stores the earliest expiry date, in RFC3339 format,
+	// of the serving certificates of the control plane components running on the machine.
+	CertificatesExpiryAnnotation = "controlplane.cluster.x-k8s.io/certificates-expiry"
+
+	// RotateCertificateAuthoritiesAnnotation requests the KubeadmControlPlane to rotate the certificate authorities
+	// and the service account key of the cluster; a new rotation is started each time the value of the annotation changes.
+	RotateCertificateAuthoritiesAnnotation = "controlplane.cluster.x-k8s.io/rotate-certificate-authorities"
+
+	// CertificateAuthoritiesRotationAnnotation is set by the KubeadmControlPlane on the machine template of the
+	// MachineDeployments of the cluster to roll out their machines during a rotation of the certificate authorities.
+	CertificateAuthoritiesRotationAnnotation = "controlplane.cluster.x-k8s.io/certificate-authorities-rotation"
+
+	// EtcdDefragmentedAnnotation is a machine annotation that stores the time, in RFC3339 format,
+	// the etcd member running on the machine has been last defragmented.
+	EtcdDefragmentedAnnotation = "controlplane.cluster.x-k8s.io/etcd-defragmented"
+
+	// RemediationInProgressAnnotation is set on the KubeadmControlPlane while a remediation is in progress, i.e. after
+	// an unhealthy machine has been deleted and until its replacement is created, or the unhealthy machine is gone
+	// and no replacement is needed; it stores the json-marshalled details of the remediation (the remediated machine,
+	// when the remediation happened and the retry count).
+	RemediationInProgressAnnotation = "controlplane.cluster.x-k8s.io/remediation-in-progress"
+
+	// RemediationForAnnotation is a machine annotation that stores the json-marshalled details of the remediation
+	// the machine has been created for, so retries can be tracked if the machine becomes unhealthy in turn.
+	RemediationForAnnotation = "controlplane.cluster.x-k8s.io/remediation-for"
 )
 
 // KubeadmControlPlaneSpec defines the desired state of KubeadmControlPlane.
@@ -64,11 +99,251 @@ type KubeadmControlPlaneSpec struct {
 	// +optional
 	UpgradeAfter *metav1.Time `json:"upgradeAfter,omitempty"`
 
+	// RolloutBefore is a field to indicate a rollout should be performed
+	// if the specified criteria is met.
+	// +optional
+	RolloutBefore *RolloutBefore `json:"rolloutBefore,omitempty"`
+
 	// NodeDrainTimeout is the total amount of time that the controller will spend on draining a controlplane node
 	// The default value is 0, meaning that the node can be drained without any time limitations.
 	// NOTE: NodeDrainTimeout is different from `kubectl drain --timeout`
 	// +optional
 	NodeDrainTimeout *metav1.Duration `json:"nodeDrainTimeout,omitempty"`
+
+	// The RolloutStrategy to use to replace control plane machines with
+	// new ones.
+	// +optional
+	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy,omitempty"`
+
+	// EtcdBackup defines a policy for taking periodic snapshots of the etcd cluster
+	// managed by the control plane; it can't be used with an external etcd cluster.
+	// +optional
+	EtcdBackup *EtcdBackup `json:"etcdBackup,omitempty"`
+
+	// EtcdMaintenance defines the maintenance operations run on the members of the etcd cluster
+	// managed by the control plane; it can't be used with an external etcd cluster.
+	// +optional
+	EtcdMaintenance *EtcdMaintenance `json:"etcdMaintenance,omitempty"`
+
+	// RemediationStrategy controls how unhealthy control plane machines are remediated.
+	// +optional
+	RemediationStrategy *RemediationStrategy `json:"remediationStrategy,omitempty"`
+
+	// Addons controls which of the addons installed by kubeadm are managed by the control plane.
+	// +optional
+	Addons *Addons `json:"addons,omitempty"`
+}
+
+// RolloutBefore describes when a rollout should be performed on the KCP machines.
+type RolloutBefore struct {
+	// CertificatesExpiryDays indicates a rollout needs to be performed if the
+	// certificates of the control plane will expire within the specified days.
+	// +kubebuilder:validation:Minimum=7
+	// +optional
+	CertificatesExpiryDays *int32 `json:"certificatesExpiryDays,omitempty"`
+}
+
+// RolloutStrategyType defines the rollout strategies for a KubeadmControlPlane.
+type RolloutStrategyType string
+
+const (
+	// RollingUpdateStrategyType replaces the old control planes by new one using rolling update
+	// i.e. gradually scale up or down the old control planes and scale up or down the new one.
+	RollingUpdateStrategyType RolloutStrategyType = "RollingUpdate"
+)
+
+// RolloutStrategy describes how to replace existing machines
+// with new ones.
+type RolloutStrategy struct {
+	// Type of rollout. Currently the only supported strategy is
+	// "RollingUpdate".
+	// Default is RollingUpdate.
+	// +kubebuilder:validation:Enum=RollingUpdate
+	// +optional
+	Type RolloutStrategyType `json:"type,omitempty"`
+
+	// Rolling update config params. Present only if
+	// RolloutStrategyType = RollingUpdate.
+	// +optional
+	RollingUpdate *RollingUpdate `json:"rollingUpdate,omitempty"`
+}
+
+// RollingUpdate is used to control the desired behavior of rolling update.
+type RollingUpdate struct {
+	// The maximum number of control plane machines that can be scheduled above the
+	// desired number of control plane machines.
+	// Value can be an absolute number 1 or 0.
+	// Defaults to 1.
+	// Example: when this is set to 1, a new control plane machine is created
+	// before an outdated one is removed; when this is set to 0, an outdated
+	// control plane machine is removed before its replacement is created,
+	// which requires at least 3 replicas.
+	// +optional
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+}
+
+// RemediationStrategy allows to define how control plane machine remediation happens.
+type RemediationStrategy struct {
+	// MaxRetry is the maximum number of retries while attempting to remediate an unhealthy machine.
+	// A retry happens when a machine that was created as a replacement for an unhealthy machine also fails
+	// within MinHealthyPeriod. If not set, remediation is retried indefinitely.
+	// Example: when this is set to 3, an unhealthy machine is remediated, then its replacement is remediated
+	// up to three times if each replacement becomes unhealthy within MinHealthyPeriod; remediation then stops.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxRetry *int32 `json:"maxRetry,omitempty"`
+
+	// RetryPeriod is the minimum amount of time between two consecutive remediations of the replacements of
+	// the same unhealthy machine, e.g. 10m. If not set, a retry happens as soon as the replacement is unhealthy.
+	// +optional
+	RetryPeriod metav1.Duration `json:"retryPeriod,omitempty"`
+
+	// MinHealthyPeriod defines how long a machine created as a replacement for an unhealthy machine must stay
+	// healthy before a new failure is not considered a retry anymore, resetting the retry count.
+	// Defaults to 1h.
+	// +optional
+	MinHealthyPeriod *metav1.Duration `json:"minHealthyPeriod,omitempty"`
+}
+
+// Addons defines which of the addons installed by kubeadm are managed by the control plane.
+type Addons struct {
+	// SkipCoreDNS skips the installation of CoreDNS when initializing the control plane and
+	// stops reconciling it, e.g. during upgrades; an existing CoreDNS deployment is left untouched.
+	// +optional
+	SkipCoreDNS bool `json:"skipCoreDNS,omitempty"`
+
+	// SkipKubeProxy skips the installation of kube-proxy when initializing the control plane and
+	// stops reconciling it, e.g. during upgrades; an existing kube-proxy daemonset is left untouched.
+	// +optional
+	SkipKubeProxy bool `json:"skipKubeProxy,omitempty"`
+}
+
+// EtcdBackup defines a policy for taking periodic snapshots of the etcd cluster.
+type EtcdBackup struct {
+	// Schedule is a cron expression in the standard five fields format (minute, hour, day of month,
+	// month, day of week) defining when snapshots are taken, e.g. "0 */6 * * *" for every 6 hours.
+	// Schedules are evaluated in UTC.
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Retention is the number of snapshots to keep; when a new snapshot is taken
+	// the oldest snapshots exceeding the retention are deleted.
+	// Defaults to 3.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Retention *int32 `json:"retention,omitempty"`
+
+	// Storage defines where snapshots are stored.
+	Storage EtcdBackupStorage `json:"storage"`
+}
+
+// EtcdMaintenance defines the maintenance operations run on the members of the etcd cluster.
+type EtcdMaintenance struct {
+	// DefragmentationInterval is the minimum amount of time between two defragmentations
+	// of the same etcd member, e.g. 168h. Members are defragmented one at a time, the leader last;
+	// members raising a NOSPACE alarm are defragmented regardless of the interval, and the alarm
+	// is disarmed once the defragmentation succeeds.
+	DefragmentationInterval metav1.Duration `json:"defragmentationInterval"`
+}
+
+// EtcdBackupStorageType defines the storage backends for etcd snapshots.
+type EtcdBackupStorageType string
+
+const (
+	// SecretEtcdBackupStorageType stores snapshots in chunks of Secrets
+	// in the namespace of the KubeadmControlPlane.
+	SecretEtcdBackupStorageType EtcdBackupStorageType = "Secret"
+
+	// VolumeEtcdBackupStorageType stores snapshots as files in the directory
+	// the KubeadmControlPlane controller has been configured with using the
+	// --etcd-backup-dir flag, usually a mounted PersistentVolumeClaim.
+	VolumeEtcdBackupStorageType EtcdBackupStorageType = "Volume"
+
+	// S3EtcdBackupStorageType stores snapshots in a bucket of an S3-compatible object storage.
+	S3EtcdBackupStorageType EtcdBackupStorageType = "S3"
+)
+
+// EtcdBackupStorage defines where etcd snapshots are stored.
+type EtcdBackupStorage struct {
+	// Type of storage, one of Secret, Volume or S3.
+	// +kubebuilder:validation:Enum=Secret;Volume;S3
+	Type EtcdBackupStorageType `json:"type"`
+
+	// S3 configures the S3-compatible object storage.
+	// Present only if Type = S3.
+	// +optional
+	S3 *S3EtcdBackupStorage `json:"s3,omitempty"`
+}
+
+// S3EtcdBackupStorage defines a bucket of an S3-compatible object storage.
+type S3EtcdBackupStorage struct {
+	// Endpoint is the URL of the object storage, e.g. https://s3.eu-west-1.amazonaws.com.
+	// Buckets are addressed using path-style requests.
+	Endpoint string `json:"endpoint"`
+
+	// Region of the bucket.
+	// Defaults to us-east-1.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// Bucket is the name of the bucket.
+	Bucket string `json:"bucket"`
+
+	// Prefix is prepended to the keys of the snapshots.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// CredentialsSecret is a reference to a Secret in the namespace of the KubeadmControlPlane
+	// holding the accessKeyID and secretAccessKey to use.
+	CredentialsSecret corev1.LocalObjectReference `json:"credentialsSecret"`
+}
+
+// EtcdSnapshot is a snapshot of the etcd cluster taken by the KubeadmControlPlane controller.
+type EtcdSnapshot struct {
+	// Name of the snapshot.
+	Name string `json:"name"`
+
+	// Timestamp is the time the snapshot was taken.
+	Timestamp metav1.Time `json:"timestamp"`
+
+	// SizeBytes is the size of the snapshot in bytes.
+	SizeBytes int64 `json:"sizeBytes"`
+
+	// StorageType is the type of storage the snapshot has been stored in.
+	StorageType EtcdBackupStorageType `json:"storageType"`
+}
+
+// CertificateAuthoritiesRotationPhase is a phase of the rotation of the certificate authorities.
+type CertificateAuthoritiesRotationPhase string
+
+const (
+	// TrustingNewCertificateAuthoritiesPhase adds new certificate authorities next to the current ones,
+	// and rolls out all the machines so they trust both.
+	TrustingNewCertificateAuthoritiesPhase = CertificateAuthoritiesRotationPhase("TrustingNewCertificateAuthorities")
+
+	// SigningWithNewCertificateAuthoritiesPhase makes the new certificate authorities sign the certificates,
+	// and rolls out all the machines so they get certificates signed by them.
+	SigningWithNewCertificateAuthoritiesPhase = CertificateAuthoritiesRotationPhase("SigningWithNewCertificateAuthorities")
+
+	// RemovingOldCertificateAuthoritiesPhase removes the old certificate authorities,
+	// and rolls out all the machines so they stop trusting them.
+	RemovingOldCertificateAuthoritiesPhase = CertificateAuthoritiesRotationPhase("RemovingOldCertificateAuthorities")
+
+	// CertificateAuthoritiesRotationCompletedPhase documents a completed rotation.
+	CertificateAuthoritiesRotationCompletedPhase = CertificateAuthoritiesRotationPhase("Completed")
+)
+
+// CertificateAuthoritiesRotation reports the progress of a rotation of the certificate authorities.
+type CertificateAuthoritiesRotation struct {
+	// Request is the value of the RotateCertificateAuthoritiesAnnotation which requested the rotation.
+	Request string `json:"request"`
+
+	// Phase is the current phase of the rotation.
+	Phase CertificateAuthoritiesRotationPhase `json:"phase"`
+
+	// PhaseStartTime is the time the current phase started;
+	// machines created before it are rolled out.
+	PhaseStartTime metav1.Time `json:"phaseStartTime"`
 }
 
 // KubeadmControlPlaneStatus defines the observed state of KubeadmControlPlane.
@@ -128,6 +403,19 @@ type KubeadmControlPlaneStatus struct {
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 
+	// EtcdSnapshots lists the etcd snapshots available, oldest first.
+	// +optional
+	EtcdSnapshots []EtcdSnapshot `json:"etcdSnapshots,omitempty"`
+
+	// CertificatesExpiryDate is the earliest expiry date of the serving certificates
+	// of the control plane components across all the control plane machines.
+	// +optional
+	CertificatesExpiryDate *metav1.Time `json:"certificatesExpiryDate,omitempty"`
+
+	// CertificateAuthoritiesRotation reports the progress of the last rotation of the certificate authorities.
+	// +optional
+	CertificateAuthoritiesRotation *CertificateAuthoritiesRotation `json:"certificateAuthoritiesRotation,omitempty"`
+
 	// Conditions defines current service state of the KubeadmControlPlane.
 	// +optional
 	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
@@ -19,17 +19,22 @@ package v1alpha4
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/blang/semver"
 	"github.com/coredns/corefile-migration/migration"
 	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/pkg/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	kubeadmv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/types/v1beta1"
 	"sigs.k8s.io/cluster-api/util/container"
+	"sigs.k8s.io/cluster-api/util/cron"
 	"sigs.k8s.io/cluster-api/util/version"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -61,6 +66,36 @@ func (in *KubeadmControlPlane) Default() {
 	if !strings.HasPrefix(in.Spec.Version, "v") {
 		in.Spec.Version = "v" + in.Spec.Version
 	}
+
+	if in.Spec.RolloutStrategy == nil {
+		in.Spec.RolloutStrategy = &RolloutStrategy{}
+	}
+	if in.Spec.RolloutStrategy.Type == "" {
+		in.Spec.RolloutStrategy.Type = RollingUpdateStrategyType
+	}
+	if in.Spec.RolloutStrategy.Type == RollingUpdateStrategyType {
+		if in.Spec.RolloutStrategy.RollingUpdate == nil {
+			in.Spec.RolloutStrategy.RollingUpdate = &RollingUpdate{}
+		}
+		if in.Spec.RolloutStrategy.RollingUpdate.MaxSurge == nil {
+			ios1 := intstr.FromInt(1)
+			in.Spec.RolloutStrategy.RollingUpdate.MaxSurge = &ios1
+		}
+	}
+
+	if in.Spec.RemediationStrategy != nil && in.Spec.RemediationStrategy.MinHealthyPeriod == nil {
+		in.Spec.RemediationStrategy.MinHealthyPeriod = &metav1.Duration{Duration: time.Hour}
+	}
+
+	if in.Spec.EtcdBackup != nil {
+		if in.Spec.EtcdBackup.Retention == nil {
+			retention := int32(3)
+			in.Spec.EtcdBackup.Retention = &retention
+		}
+		if in.Spec.EtcdBackup.Storage.S3 != nil && in.Spec.EtcdBackup.Storage.S3.Region == "" {
+			in.Spec.EtcdBackup.Storage.S3.Region = "us-east-1"
+		}
+	}
 }
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
@@ -115,7 +150,13 @@ func (in *KubeadmControlPlane) ValidateUpdate(old runtime.Object) error {
 		{spec, "replicas"},
 		{spec, "version"},
 		{spec, "upgradeAfter"},
+		{spec, "rolloutBefore", "*"},
 		{spec, "nodeDrainTimeout"},
+		{spec, "rolloutStrategy", "*"},
+		{spec, "etcdBackup", "*"},
+		{spec, "etcdMaintenance", "*"},
+		{spec, "remediationStrategy", "*"},
+		{spec, "addons", "*"},
 	}
 
 	allErrs := in.validateCommon()
@@ -270,6 +311,258 @@ func (in *KubeadmControlPlane) validateCommon() (allErrs field.ErrorList) {
 	}
 
 	allErrs = append(allErrs, in.validateCoreDNSImage()...)
+	allErrs = append(allErrs, in.validateRolloutBefore()...)
+	allErrs = append(allErrs, in.validateRolloutStrategy()...)
+	allErrs = append(allErrs, in.validateEtcdBackup(externalEtcd)...)
+	allErrs = append(allErrs, in.validateEtcdMaintenance(externalEtcd)...)
+	allErrs = append(allErrs, in.validateRemediationStrategy()...)
+
+	return allErrs
+}
+
+func (in *KubeadmControlPlane) validateRolloutBefore() (allErrs field.ErrorList) {
+	if in.Spec.RolloutBefore == nil || in.Spec.RolloutBefore.CertificatesExpiryDays == nil {
+		return allErrs
+	}
+
+	if *in.Spec.RolloutBefore.CertificatesExpiryDays < 7 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(
+				field.NewPath("spec", "rolloutBefore", "certificatesExpiryDays"),
+				*in.Spec.RolloutBefore.CertificatesExpiryDays,
+				"must be at least 7",
+			),
+		)
+	}
+
+	return allErrs
+}
+
+func (in *KubeadmControlPlane) validateRolloutStrategy() (allErrs field.ErrorList) {
+	if in.Spec.RolloutStrategy == nil {
+		return allErrs
+	}
+
+	if in.Spec.RolloutStrategy.Type != "" && in.Spec.RolloutStrategy.Type != RollingUpdateStrategyType {
+		allErrs = append(
+			allErrs,
+			field.NotSupported(
+				field.NewPath("spec", "rolloutStrategy", "type"),
+				in.Spec.RolloutStrategy.Type,
+				[]string{string(RollingUpdateStrategyType)},
+			),
+		)
+	}
+
+	if in.Spec.RolloutStrategy.RollingUpdate == nil || in.Spec.RolloutStrategy.RollingUpdate.MaxSurge == nil {
+		return allErrs
+	}
+
+	maxSurge := in.Spec.RolloutStrategy.RollingUpdate.MaxSurge
+	if maxSurge.Type != intstr.Int || (maxSurge.IntValue() != 0 && maxSurge.IntValue() != 1) {
+		allErrs = append(
+			allErrs,
+			field.Invalid(
+				field.NewPath("spec", "rolloutStrategy", "rollingUpdate", "maxSurge"),
+				maxSurge.String(),
+				"must be either 0 or 1",
+			),
+		)
+		return allErrs
+	}
+
+	// Removing a control plane machine before creating its replacement requires enough replicas to tolerate the loss of one of them.
+	if maxSurge.IntValue() == 0 && in.Spec.Replicas != nil && *in.Spec.Replicas < 3 {
+		allErrs = append(
+			allErrs,
+			field.Forbidden(
+				field.NewPath("spec", "rolloutStrategy", "rollingUpdate", "maxSurge"),
+				"can be 0 only when replicas is at least 3",
+			),
+		)
+	}
+
+	return allErrs
+}
+
+func (in *KubeadmControlPlane) validateRemediationStrategy() (allErrs field.ErrorList) {
+	if in.Spec.RemediationStrategy == nil {
+		return allErrs
+	}
+
+	if in.Spec.RemediationStrategy.MaxRetry != nil && *in.Spec.RemediationStrategy.MaxRetry < 0 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(
+				field.NewPath("spec", "remediationStrategy", "maxRetry"),
+				*in.Spec.RemediationStrategy.MaxRetry,
+				"must be greater than or equal to 0",
+			),
+		)
+	}
+
+	if in.Spec.RemediationStrategy.RetryPeriod.Duration < 0 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(
+				field.NewPath("spec", "remediationStrategy", "retryPeriod"),
+				in.Spec.RemediationStrategy.RetryPeriod.Duration.String(),
+				"must be greater than or equal to 0",
+			),
+		)
+	}
+
+	if in.Spec.RemediationStrategy.MinHealthyPeriod != nil && in.Spec.RemediationStrategy.MinHealthyPeriod.Duration <= 0 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(
+				field.NewPath("spec", "remediationStrategy", "minHealthyPeriod"),
+				in.Spec.RemediationStrategy.MinHealthyPeriod.Duration.String(),
+				"must be greater than 0",
+			),
+		)
+	}
+
+	return allErrs
+}
+
+func (in *KubeadmControlPlane) validateEtcdBackup(externalEtcd bool) (allErrs field.ErrorList) {
+	if in.Spec.EtcdBackup == nil {
+		return allErrs
+	}
+
+	if externalEtcd {
+		allErrs = append(
+			allErrs,
+			field.Forbidden(
+				field.NewPath("spec", "etcdBackup"),
+				"cannot be used with an external etcd cluster",
+			),
+		)
+	}
+
+	if schedule, err := cron.Parse(in.Spec.EtcdBackup.Schedule); err != nil {
+		allErrs = append(
+			allErrs,
+			field.Invalid(
+				field.NewPath("spec", "etcdBackup", "schedule"),
+				in.Spec.EtcdBackup.Schedule,
+				err.Error(),
+			),
+		)
+	} else if schedule.Next(time.Now()).IsZero() {
+		allErrs = append(
+			allErrs,
+			field.Invalid(
+				field.NewPath("spec", "etcdBackup", "schedule"),
+				in.Spec.EtcdBackup.Schedule,
+				"never activates",
+			),
+		)
+	}
+
+	if in.Spec.EtcdBackup.Retention != nil && *in.Spec.EtcdBackup.Retention < 1 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(
+				field.NewPath("spec", "etcdBackup", "retention"),
+				*in.Spec.EtcdBackup.Retention,
+				"must be at least 1",
+			),
+		)
+	}
+
+	storage := in.Spec.EtcdBackup.Storage
+	switch storage.Type {
+	case SecretEtcdBackupStorageType, VolumeEtcdBackupStorageType:
+		if storage.S3 != nil {
+			allErrs = append(
+				allErrs,
+				field.Forbidden(
+					field.NewPath("spec", "etcdBackup", "storage", "s3"),
+					"can be set only when type is S3",
+				),
+			)
+		}
+	case S3EtcdBackupStorageType:
+		if storage.S3 == nil {
+			allErrs = append(
+				allErrs,
+				field.Required(
+					field.NewPath("spec", "etcdBackup", "storage", "s3"),
+					"is required when type is S3",
+				),
+			)
+			break
+		}
+		if _, err := url.ParseRequestURI(storage.S3.Endpoint); err != nil {
+			allErrs = append(
+				allErrs,
+				field.Invalid(
+					field.NewPath("spec", "etcdBackup", "storage", "s3", "endpoint"),
+					storage.S3.Endpoint,
+					"must be a valid URL",
+				),
+			)
+		}
+		if storage.S3.Bucket == "" {
+			allErrs = append(
+				allErrs,
+				field.Required(
+					field.NewPath("spec", "etcdBackup", "storage", "s3", "bucket"),
+					"is required",
+				),
+			)
+		}
+		if storage.S3.CredentialsSecret.Name == "" {
+			allErrs = append(
+				allErrs,
+				field.Required(
+					field.NewPath("spec", "etcdBackup", "storage", "s3", "credentialsSecret", "name"),
+					"is required",
+				),
+			)
+		}
+	default:
+		allErrs = append(
+			allErrs,
+			field.NotSupported(
+				field.NewPath("spec", "etcdBackup", "storage", "type"),
+				storage.Type,
+				[]string{string(SecretEtcdBackupStorageType), string(VolumeEtcdBackupStorageType), string(S3EtcdBackupStorageType)},
+			),
+		)
+	}
+
+	return allErrs
+}
+
+func (in *KubeadmControlPlane) validateEtcdMaintenance(externalEtcd bool) (allErrs field.ErrorList) {
+	if in.Spec.EtcdMaintenance == nil {
+		return allErrs
+	}
+
+	if externalEtcd {
+		allErrs = append(
+			allErrs,
+			field.Forbidden(
+				field.NewPath("spec", "etcdMaintenance"),
+				"cannot be used with an external etcd cluster",
+			),
+		)
+	}
+
+	if in.Spec.EtcdMaintenance.DefragmentationInterval.Duration < time.Hour {
+		allErrs = append(
+			allErrs,
+			field.Invalid(
+				field.NewPath("spec", "etcdMaintenance", "defragmentationInterval"),
+				in.Spec.EtcdMaintenance.DefragmentationInterval.Duration.String(),
+				"must be at least 1h",
+			),
+		)
+	}
 
 	return allErrs
 }
@@ -24,6 +24,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/pointer"
 	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1alpha4"
 	kubeadmv1beta1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/types/v1beta1"
@@ -45,6 +46,19 @@ func TestKubeadmControlPlaneDefault(t *testing.T) {
 
 	g.Expect(kcp.Spec.InfrastructureTemplate.Namespace).To(Equal(kcp.Namespace))
 	g.Expect(kcp.Spec.Version).To(Equal("v1.18.3"))
+	g.Expect(kcp.Spec.RolloutStrategy.Type).To(Equal(RollingUpdateStrategyType))
+	g.Expect(kcp.Spec.RolloutStrategy.RollingUpdate.MaxSurge.IntValue()).To(Equal(1))
+	g.Expect(kcp.Spec.EtcdBackup).To(BeNil())
+
+	kcp.Spec.EtcdBackup = &EtcdBackup{Storage: EtcdBackupStorage{Type: S3EtcdBackupStorageType, S3: &S3EtcdBackupStorage{}}}
+	kcp.Default()
+	g.Expect(*kcp.Spec.EtcdBackup.Retention).To(Equal(int32(3)))
+	g.Expect(kcp.Spec.EtcdBackup.Storage.S3.Region).To(Equal("us-east-1"))
+
+	g.Expect(kcp.Spec.RemediationStrategy).To(BeNil())
+	kcp.Spec.RemediationStrategy = &RemediationStrategy{}
+	kcp.Default()
+	g.Expect(kcp.Spec.RemediationStrategy.MinHealthyPeriod.Duration).To(Equal(time.Hour))
 }
 
 func TestKubeadmControlPlaneValidateCreate(t *testing.T) {
@@ -92,6 +106,87 @@ func TestKubeadmControlPlaneValidateCreate(t *testing.T) {
 	invalidVersion2 := valid.DeepCopy()
 	invalidVersion2.Spec.Version = "1.16.6"
 
+	maxSurge0 := intstr.FromInt(0)
+	scaleInRolloutStrategy := valid.DeepCopy()
+	scaleInRolloutStrategy.Spec.Replicas = pointer.Int32Ptr(3)
+	scaleInRolloutStrategy.Spec.RolloutStrategy = &RolloutStrategy{
+		Type:          RollingUpdateStrategyType,
+		RollingUpdate: &RollingUpdate{MaxSurge: &maxSurge0},
+	}
+
+	scaleInRolloutStrategyOneReplica := scaleInRolloutStrategy.DeepCopy()
+	scaleInRolloutStrategyOneReplica.Spec.Replicas = pointer.Int32Ptr(1)
+
+	maxSurge2 := intstr.FromInt(2)
+	invalidMaxSurge := scaleInRolloutStrategy.DeepCopy()
+	invalidMaxSurge.Spec.RolloutStrategy.RollingUpdate.MaxSurge = &maxSurge2
+
+	maxSurgePercent := intstr.FromString("50%")
+	percentMaxSurge := scaleInRolloutStrategy.DeepCopy()
+	percentMaxSurge.Spec.RolloutStrategy.RollingUpdate.MaxSurge = &maxSurgePercent
+
+	rolloutBefore := valid.DeepCopy()
+	rolloutBefore.Spec.RolloutBefore = &RolloutBefore{CertificatesExpiryDays: pointer.Int32Ptr(21)}
+
+	invalidRolloutBefore := valid.DeepCopy()
+	invalidRolloutBefore.Spec.RolloutBefore = &RolloutBefore{CertificatesExpiryDays: pointer.Int32Ptr(5)}
+
+	etcdBackup := valid.DeepCopy()
+	etcdBackup.Spec.EtcdBackup = &EtcdBackup{
+		Schedule: "0 */6 * * *",
+		Storage: EtcdBackupStorage{
+			Type: S3EtcdBackupStorageType,
+			S3: &S3EtcdBackupStorage{
+				Endpoint:          "https://s3.eu-west-1.amazonaws.com",
+				Bucket:            "backups",
+				CredentialsSecret: corev1.LocalObjectReference{Name: "credentials"},
+			},
+		},
+	}
+
+	etcdBackupExternalEtcd := etcdBackup.DeepCopy()
+	etcdBackupExternalEtcd.Spec.KubeadmConfigSpec = evenReplicasExternalEtcd.Spec.KubeadmConfigSpec
+
+	etcdBackupInvalidSchedule := etcdBackup.DeepCopy()
+	etcdBackupInvalidSchedule.Spec.EtcdBackup.Schedule = "every 6 hours"
+
+	etcdBackupNeverActivatingSchedule := etcdBackup.DeepCopy()
+	etcdBackupNeverActivatingSchedule.Spec.EtcdBackup.Schedule = "0 0 30 2 *"
+
+	etcdBackupMissingS3 := etcdBackup.DeepCopy()
+	etcdBackupMissingS3.Spec.EtcdBackup.Storage.S3 = nil
+
+	etcdBackupUnexpectedS3 := etcdBackup.DeepCopy()
+	etcdBackupUnexpectedS3.Spec.EtcdBackup.Storage.Type = SecretEtcdBackupStorageType
+
+	etcdBackupInvalidEndpoint := etcdBackup.DeepCopy()
+	etcdBackupInvalidEndpoint.Spec.EtcdBackup.Storage.S3.Endpoint = "s3"
+
+	etcdMaintenance := valid.DeepCopy()
+	etcdMaintenance.Spec.EtcdMaintenance = &EtcdMaintenance{DefragmentationInterval: metav1.Duration{Duration: 24 * time.Hour}}
+
+	etcdMaintenanceExternalEtcd := etcdMaintenance.DeepCopy()
+	etcdMaintenanceExternalEtcd.Spec.KubeadmConfigSpec = evenReplicasExternalEtcd.Spec.KubeadmConfigSpec
+
+	etcdMaintenanceShortInterval := etcdMaintenance.DeepCopy()
+	etcdMaintenanceShortInterval.Spec.EtcdMaintenance.DefragmentationInterval.Duration = time.Minute
+
+	remediationStrategy := valid.DeepCopy()
+	remediationStrategy.Spec.RemediationStrategy = &RemediationStrategy{
+		MaxRetry:         pointer.Int32Ptr(3),
+		RetryPeriod:      metav1.Duration{Duration: 10 * time.Minute},
+		MinHealthyPeriod: &metav1.Duration{Duration: 2 * time.Hour},
+	}
+
+	remediationStrategyNegativeMaxRetry := remediationStrategy.DeepCopy()
+	remediationStrategyNegativeMaxRetry.Spec.RemediationStrategy.MaxRetry = pointer.Int32Ptr(-1)
+
+	remediationStrategyNegativeRetryPeriod := remediationStrategy.DeepCopy()
+	remediationStrategyNegativeRetryPeriod.Spec.RemediationStrategy.RetryPeriod.Duration = -time.Minute
+
+	remediationStrategyZeroMinHealthyPeriod := remediationStrategy.DeepCopy()
+	remediationStrategyZeroMinHealthyPeriod.Spec.RemediationStrategy.MinHealthyPeriod.Duration = 0
+
 	tests := []struct {
 		name      string
 		expectErr bool
@@ -102,6 +197,76 @@ func TestKubeadmControlPlaneValidateCreate(t *testing.T) {
 			expectErr: false,
 			kcp:       valid,
 		},
+		{
+			name:      "should succeed when given a valid remediation strategy",
+			expectErr: false,
+			kcp:       remediationStrategy,
+		},
+		{
+			name:      "should return error when the remediation max retry is negative",
+			expectErr: true,
+			kcp:       remediationStrategyNegativeMaxRetry,
+		},
+		{
+			name:      "should return error when the remediation retry period is negative",
+			expectErr: true,
+			kcp:       remediationStrategyNegativeRetryPeriod,
+		},
+		{
+			name:      "should return error when the remediation min healthy period is not positive",
+			expectErr: true,
+			kcp:       remediationStrategyZeroMinHealthyPeriod,
+		},
+		{
+			name:      "should succeed when given a valid etcd maintenance policy",
+			expectErr: false,
+			kcp:       etcdMaintenance,
+		},
+		{
+			name:      "should return error when maintaining an external etcd cluster",
+			expectErr: true,
+			kcp:       etcdMaintenanceExternalEtcd,
+		},
+		{
+			name:      "should return error when the etcd defragmentation interval is less than an hour",
+			expectErr: true,
+			kcp:       etcdMaintenanceShortInterval,
+		},
+		{
+			name:      "should succeed when given a valid etcd backup policy",
+			expectErr: false,
+			kcp:       etcdBackup,
+		},
+		{
+			name:      "should return error when backing up an external etcd cluster",
+			expectErr: true,
+			kcp:       etcdBackupExternalEtcd,
+		},
+		{
+			name:      "should return error when the etcd backup schedule is invalid",
+			expectErr: true,
+			kcp:       etcdBackupInvalidSchedule,
+		},
+		{
+			name:      "should return error when the etcd backup schedule never activates",
+			expectErr: true,
+			kcp:       etcdBackupNeverActivatingSchedule,
+		},
+		{
+			name:      "should return error when the S3 etcd backup storage is not configured",
+			expectErr: true,
+			kcp:       etcdBackupMissingS3,
+		},
+		{
+			name:      "should return error when the S3 etcd backup storage is configured for another storage type",
+			expectErr: true,
+			kcp:       etcdBackupUnexpectedS3,
+		},
+		{
+			name:      "should return error when the S3 endpoint is not a URL",
+			expectErr: true,
+			kcp:       etcdBackupInvalidEndpoint,
+		},
 		{
 			name:      "should return error when kubeadmControlPlane namespace and infrastructureTemplate  namespace mismatch",
 			expectErr: true,
@@ -142,6 +307,36 @@ func TestKubeadmControlPlaneValidateCreate(t *testing.T) {
 			expectErr: true,
 			kcp:       invalidVersion1,
 		},
+		{
+			name:      "should succeed when rolling out before certificates expire",
+			expectErr: false,
+			kcp:       rolloutBefore,
+		},
+		{
+			name:      "should return error when rolling out less than 7 days before certificates expire",
+			expectErr: true,
+			kcp:       invalidRolloutBefore,
+		},
+		{
+			name:      "should succeed when scaling in first with 3 replicas",
+			expectErr: false,
+			kcp:       scaleInRolloutStrategy,
+		},
+		{
+			name:      "should return error when scaling in first with less than 3 replicas",
+			expectErr: true,
+			kcp:       scaleInRolloutStrategyOneReplica,
+		},
+		{
+			name:      "should return error when maxSurge is greater than 1",
+			expectErr: true,
+			kcp:       invalidMaxSurge,
+		},
+		{
+			name:      "should return error when maxSurge is a percentage",
+			expectErr: true,
+			kcp:       percentMaxSurge,
+		},
 	}
 
 	for _, tt := range tests {
@@ -263,6 +458,15 @@ func TestKubeadmControlPlaneValidateUpdate(t *testing.T) {
 	validUpdate.Spec.Replicas = pointer.Int32Ptr(5)
 	now := metav1.NewTime(time.Now())
 	validUpdate.Spec.UpgradeAfter = &now
+	validUpdate.Spec.RolloutBefore = &RolloutBefore{CertificatesExpiryDays: pointer.Int32Ptr(14)}
+	maxSurge0 := intstr.FromInt(0)
+	validUpdate.Spec.RolloutStrategy = &RolloutStrategy{
+		Type:          RollingUpdateStrategyType,
+		RollingUpdate: &RollingUpdate{MaxSurge: &maxSurge0},
+	}
+	validUpdate.Spec.EtcdMaintenance = &EtcdMaintenance{DefragmentationInterval: metav1.Duration{Duration: 24 * time.Hour}}
+	validUpdate.Spec.RemediationStrategy = &RemediationStrategy{MaxRetry: pointer.Int32Ptr(3)}
+	validUpdate.Spec.Addons = &Addons{SkipCoreDNS: true, SkipKubeProxy: true}
 
 	scaleToZero := before.DeepCopy()
 	scaleToZero.Spec.Replicas = pointer.Int32Ptr(0)
@@ -0,0 +1,291 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/kubeconfig"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/cluster-api/util/secret"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// rotatedCertificatePurposes are the certificate authorities and keys replaced by a rotation of the certificate authorities.
+var rotatedCertificatePurposes = []secret.Purpose{secret.ClusterCA, secret.EtcdCA, secret.FrontProxyCA, secret.ServiceAccount}
+
+// certificateAuthoritiesRotationPhases are the phases of a rotation of the certificate authorities, in order,
+// with the reason documenting each of them in the CertificateAuthoritiesRotatedCondition.
+var certificateAuthoritiesRotationPhases = []struct {
+	phase  controlplanev1.CertificateAuthoritiesRotationPhase
+	reason string
+}{
+	{controlplanev1.TrustingNewCertificateAuthoritiesPhase, controlplanev1.TrustingNewCertificateAuthoritiesReason},
+	{controlplanev1.SigningWithNewCertificateAuthoritiesPhase, controlplanev1.SigningWithNewCertificateAuthoritiesReason},
+	{controlplanev1.RemovingOldCertificateAuthoritiesPhase, controlplanev1.RemovingOldCertificateAuthoritiesReason},
+	{controlplanev1.CertificateAuthoritiesRotationCompletedPhase, ""},
+}
+
+// reconcileCertificateAuthoritiesRotation rotates the certificate authorities and the service account key of the cluster
+// when requested with the RotateCertificateAuthoritiesAnnotation. Each phase of the rotation updates the certificate
+// authorities secrets, then waits for all the control plane machines, and after them the machines of all the
+// MachineDeployments of the cluster, to be rolled out.
+//
+// The state of the secrets for the current phase is ensured at every reconciliation, so a rotation is safely
+// resumed after a restart of the controller.
+func (r *KubeadmControlPlaneReconciler) reconcileCertificateAuthoritiesRotation(ctx context.Context, controlPlane *internal.ControlPlane) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx, "cluster", controlPlane.Cluster.Name)
+	kcp := controlPlane.KCP
+
+	rotation := kcp.Status.CertificateAuthoritiesRotation
+	if rotation == nil || rotation.Phase == controlplanev1.CertificateAuthoritiesRotationCompletedPhase {
+		request := kcp.Annotations[controlplanev1.RotateCertificateAuthoritiesAnnotation]
+		if request == "" || (rotation != nil && rotation.Request == request) {
+			return ctrl.Result{}, nil
+		}
+		// The certificate authorities can be rotated only after kubeadm init completed.
+		if !kcp.Status.Initialized {
+			log.Info("Waiting for the control plane to be initialized before rotating the certificate authorities")
+			return ctrl.Result{}, nil
+		}
+
+		log.Info("Starting the rotation of the certificate authorities", "request", request)
+		rotation = &controlplanev1.CertificateAuthoritiesRotation{
+			Request:        request,
+			Phase:          controlplanev1.TrustingNewCertificateAuthoritiesPhase,
+			PhaseStartTime: metav1.Now(),
+		}
+		kcp.Status.CertificateAuthoritiesRotation = rotation
+	}
+
+	if err := r.applyCertificateAuthoritiesRotationPhase(ctx, controlPlane, rotation.Phase); err != nil {
+		conditions.MarkFalse(kcp, controlplanev1.CertificateAuthoritiesRotatedCondition, controlplanev1.CertificateAuthoritiesRotationFailedReason, clusterv1.ConditionSeverityError, err.Error())
+		return ctrl.Result{}, err
+	}
+
+	message, err := r.certificateAuthoritiesRotationPhaseProgress(ctx, controlPlane)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if message != "" {
+		conditions.MarkFalse(kcp, controlplanev1.CertificateAuthoritiesRotatedCondition, certificateAuthoritiesRotationReason(rotation.Phase), clusterv1.ConditionSeverityInfo, message)
+		// MachineDeployments are not watched, so their rollout is checked periodically.
+		return ctrl.Result{RequeueAfter: certificateAuthoritiesRotationRequeueAfter}, nil
+	}
+
+	// The current phase is complete, move to the next one.
+	rotation.Phase = nextCertificateAuthoritiesRotationPhase(rotation.Phase)
+	rotation.PhaseStartTime = metav1.Now()
+	if rotation.Phase == controlplanev1.CertificateAuthoritiesRotationCompletedPhase {
+		log.Info("Completed the rotation of the certificate authorities", "request", rotation.Request)
+		conditions.MarkTrue(kcp, controlplanev1.CertificateAuthoritiesRotatedCondition)
+		r.recorder.Eventf(kcp, corev1.EventTypeNormal, "CertificateAuthoritiesRotated", "Rotated the certificate authorities of the cluster")
+		return ctrl.Result{}, nil
+	}
+
+	log.Info("Moving to the next phase of the rotation of the certificate authorities", "phase", rotation.Phase)
+	// The secrets are updated before any machine is rolled out for the new phase.
+	if err := r.applyCertificateAuthoritiesRotationPhase(ctx, controlPlane, rotation.Phase); err != nil {
+		conditions.MarkFalse(kcp, controlplanev1.CertificateAuthoritiesRotatedCondition, controlplanev1.CertificateAuthoritiesRotationFailedReason, clusterv1.ConditionSeverityError, err.Error())
+		return ctrl.Result{}, err
+	}
+	conditions.MarkFalse(kcp, controlplanev1.CertificateAuthoritiesRotatedCondition, certificateAuthoritiesRotationReason(rotation.Phase), clusterv1.ConditionSeverityInfo, "Rolling out control plane machines")
+	return ctrl.Result{RequeueAfter: certificateAuthoritiesRotationRequeueAfter}, nil
+}
+
+// applyCertificateAuthoritiesRotationPhase updates the certificate authorities secrets for the given phase, then
+// publishes the trusted cluster certificate authorities in the kubeconfig secret and in the workload cluster.
+func (r *KubeadmControlPlaneReconciler) applyCertificateAuthoritiesRotationPhase(ctx context.Context, controlPlane *internal.ControlPlane, phase controlplanev1.CertificateAuthoritiesRotationPhase) error {
+	clusterKey := util.ObjectKey(controlPlane.Cluster)
+
+	for _, purpose := range rotatedCertificatePurposes {
+		s, err := secret.GetFromNamespacedName(ctx, r.Client, clusterKey, purpose)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return errors.Wrapf(err, "failed to get the %s certificate authority", purpose)
+		}
+		// Certificate authorities without a key are provided by the user, e.g. for an external etcd.
+		if len(s.Data[secret.TLSKeyDataName]) == 0 {
+			continue
+		}
+
+		original := s.DeepCopy()
+		switch phase {
+		case controlplanev1.TrustingNewCertificateAuthoritiesPhase:
+			err = secret.AddNextKeyPair(s, purpose)
+		case controlplanev1.SigningWithNewCertificateAuthoritiesPhase:
+			err = secret.PromoteNextKeyPair(s)
+		case controlplanev1.RemovingOldCertificateAuthoritiesPhase:
+			err = secret.RemovePreviousKeyPairs(s)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to rotate the %s certificate authority", purpose)
+		}
+		if reflect.DeepEqual(original.Data, s.Data) {
+			continue
+		}
+		if err := r.Client.Update(ctx, s); err != nil {
+			return errors.Wrapf(err, "failed to update the %s certificate authority", purpose)
+		}
+	}
+
+	clusterCA, err := secret.GetFromNamespacedName(ctx, r.Client, clusterKey, secret.ClusterCA)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the cluster certificate authority")
+	}
+	caData := clusterCA.Data[secret.TLSCrtDataName]
+
+	// Nodes joining the cluster discover the certificate authorities they trust from the cluster-info ConfigMap.
+	workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, clusterKey)
+	if err != nil {
+		return errors.Wrap(err, "cannot get remote client to workload cluster")
+	}
+	if err := workloadCluster.UpdateClusterInfoCertificateAuthorities(ctx, caData); err != nil {
+		return errors.Wrap(err, "failed to update the certificate authorities in the cluster-info ConfigMap")
+	}
+
+	configSecret, err := secret.GetFromNamespacedName(ctx, r.Client, clusterKey, secret.Kubeconfig)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrap(err, "failed to get the kubeconfig secret")
+	}
+	needsUpdate, err := kubeconfig.NeedsCertificateAuthorityUpdate(configSecret, caData)
+	if err != nil {
+		return err
+	}
+	if needsUpdate {
+		if err := kubeconfig.RegenerateSecret(ctx, r.Client, configSecret); err != nil {
+			return errors.Wrap(err, "failed to regenerate the kubeconfig secret")
+		}
+	}
+	return nil
+}
+
+// certificateAuthoritiesRotationPhaseProgress rolls out the machines for the current phase of the rotation of
+// the certificate authorities, and returns a message describing the pending rollouts; the message is empty
+// once the phase is complete.
+func (r *KubeadmControlPlaneReconciler) certificateAuthoritiesRotationPhaseProgress(ctx context.Context, controlPlane *internal.ControlPlane) (string, error) {
+	rotation := controlPlane.KCP.Status.CertificateAuthoritiesRotation
+
+	// Control plane machines created before the phase started are rolled out by the KCP rollout.
+	pending := 0
+	for _, machine := range controlPlane.Machines {
+		if machine.CreationTimestamp.Before(&rotation.PhaseStartTime) || machine.Status.NodeRef == nil || !machine.DeletionTimestamp.IsZero() {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Sprintf("Rolling out %d control plane machines", pending), nil
+	}
+
+	// Worker machines are rolled out once the control plane is, so e.g. their kubelet certificates are signed
+	// by the new certificate authorities.
+	machineDeployments := &clusterv1.MachineDeploymentList{}
+	if err := r.Client.List(ctx, machineDeployments, client.InNamespace(controlPlane.Cluster.Namespace), client.MatchingLabels{clusterv1.ClusterLabelName: controlPlane.Cluster.Name}); err != nil {
+		return "", errors.Wrap(err, "failed to list MachineDeployments")
+	}
+	rolloutValue := fmt.Sprintf("%s-%s", rotation.Request, rotation.Phase)
+	var pendingNames []string
+	for i := range machineDeployments.Items {
+		md := &machineDeployments.Items[i]
+		if md.Spec.Template.Annotations[controlplanev1.CertificateAuthoritiesRotationAnnotation] != rolloutValue {
+			patchHelper, err := patch.NewHelper(md, r.Client)
+			if err != nil {
+				return "", err
+			}
+			if md.Spec.Template.Annotations == nil {
+				md.Spec.Template.Annotations = map[string]string{}
+			}
+			md.Spec.Template.Annotations[controlplanev1.CertificateAuthoritiesRotationAnnotation] = rolloutValue
+			if err := patchHelper.Patch(ctx, md); err != nil {
+				return "", errors.Wrapf(err, "failed to patch MachineDeployment %s", md.Name)
+			}
+			pendingNames = append(pendingNames, md.Name)
+			continue
+		}
+		if !isMachineDeploymentRolledOut(md) {
+			pendingNames = append(pendingNames, md.Name)
+		}
+	}
+	if len(pendingNames) > 0 {
+		return fmt.Sprintf("Rolling out MachineDeployments %s", strings.Join(pendingNames, ", ")), nil
+	}
+
+	// Nodes of the other workers, e.g. MachinePools, standalone MachineSets or Machines, are not rolled out
+	// automatically; the phase waits for them to be replaced, so no node relies on certificate authorities which
+	// are not trusted or not signing anymore.
+	workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, util.ObjectKey(controlPlane.Cluster))
+	if err != nil {
+		return "", errors.Wrap(err, "cannot get remote client to workload cluster")
+	}
+	oldNodes, err := workloadCluster.NodesCreatedBefore(ctx, rotation.PhaseStartTime.Time)
+	if err != nil {
+		return "", err
+	}
+	if len(oldNodes) > 0 {
+		return fmt.Sprintf("Waiting for nodes %s to be replaced", strings.Join(oldNodes, ", ")), nil
+	}
+	return "", nil
+}
+
+// isMachineDeploymentRolledOut returns true if all the machines of a MachineDeployment match its current
+// machine template and are available.
+func isMachineDeploymentRolledOut(md *clusterv1.MachineDeployment) bool {
+	replicas := int32(1)
+	if md.Spec.Replicas != nil {
+		replicas = *md.Spec.Replicas
+	}
+	return md.Status.ObservedGeneration >= md.Generation &&
+		md.Status.Replicas == replicas &&
+		md.Status.UpdatedReplicas == replicas &&
+		md.Status.AvailableReplicas == replicas
+}
+
+func nextCertificateAuthoritiesRotationPhase(phase controlplanev1.CertificateAuthoritiesRotationPhase) controlplanev1.CertificateAuthoritiesRotationPhase {
+	for i, p := range certificateAuthoritiesRotationPhases[:len(certificateAuthoritiesRotationPhases)-1] {
+		if p.phase == phase {
+			return certificateAuthoritiesRotationPhases[i+1].phase
+		}
+	}
+	return controlplanev1.CertificateAuthoritiesRotationCompletedPhase
+}
+
+func certificateAuthoritiesRotationReason(phase controlplanev1.CertificateAuthoritiesRotationPhase) string {
+	for _, p := range certificateAuthoritiesRotationPhases {
+		if p.phase == phase {
+			return p.reason
+		}
+	}
+	return ""
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/cert"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/bootstrap/kubeadm/types/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/secret"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileCertificateAuthoritiesRotation(t *testing.T) {
+	phaseStartTime := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+
+	controlPlaneMachine := func(name string, creationTimestamp time.Time) *clusterv1.Machine {
+		return &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(creationTimestamp)},
+			Status:     clusterv1.MachineStatus{NodeRef: &corev1.ObjectReference{Kind: "Node", Name: name}},
+		}
+	}
+	machineDeployment := func(name, rotationValue string, rolledOut bool) *clusterv1.MachineDeployment {
+		md := &clusterv1.MachineDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       clusterv1.MachineDeploymentSpec{Replicas: pointer.Int32Ptr(2)},
+		}
+		if rotationValue != "" {
+			md.Spec.Template.Annotations = map[string]string{controlplanev1.CertificateAuthoritiesRotationAnnotation: rotationValue}
+		}
+		if rolledOut {
+			md.Status = clusterv1.MachineDeploymentStatus{Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}
+		}
+		return md
+	}
+
+	setup := func(g *WithT, rotation *controlplanev1.CertificateAuthoritiesRotation, machines []*clusterv1.Machine, objs ...client.Object) (*KubeadmControlPlaneReconciler, *internal.ControlPlane, client.Client) {
+		cluster, kcp, _ := createClusterWithControlPlane()
+		kcp.Annotations = map[string]string{controlplanev1.RotateCertificateAuthoritiesAnnotation: "1"}
+		kcp.Status.Initialized = true
+		kcp.Status.CertificateAuthoritiesRotation = rotation
+
+		certificates := secret.NewCertificatesForInitialControlPlane(&v1beta1.ClusterConfiguration{})
+		g.Expect(certificates.Generate()).To(Succeed())
+		objs = append(objs, cluster.DeepCopy(), kcp.DeepCopy())
+		for _, c := range certificates {
+			objs = append(objs, c.AsSecret(util.ObjectKey(cluster), metav1.OwnerReference{}))
+		}
+		for _, o := range objs {
+			if md, ok := o.(*clusterv1.MachineDeployment); ok {
+				md.Namespace = cluster.Namespace
+				md.Labels = map[string]string{clusterv1.ClusterLabelName: cluster.Name}
+			}
+		}
+		for _, m := range machines {
+			m.Namespace = cluster.Namespace
+		}
+		fakeClient := newFakeClient(g, objs...)
+
+		r := &KubeadmControlPlaneReconciler{
+			Client:            fakeClient,
+			recorder:          record.NewFakeRecorder(32),
+			managementCluster: &fakeManagementCluster{Workload: fakeWorkloadCluster{}},
+		}
+		controlPlane := &internal.ControlPlane{Cluster: cluster, KCP: kcp, Machines: collections.FromMachines(machines...)}
+		return r, controlPlane, fakeClient
+	}
+	getSecret := func(g *WithT, c client.Client, controlPlane *internal.ControlPlane, purpose secret.Purpose) *corev1.Secret {
+		s, err := secret.GetFromNamespacedName(ctx, c, util.ObjectKey(controlPlane.Cluster), purpose)
+		g.Expect(err).ToNot(HaveOccurred())
+		return s
+	}
+	trustedCertificates := func(g *WithT, c client.Client, controlPlane *internal.ControlPlane) int {
+		certificates, err := cert.ParseCertsPEM(getSecret(g, c, controlPlane, secret.ClusterCA).Data[secret.TLSCrtDataName])
+		g.Expect(err).ToNot(HaveOccurred())
+		return len(certificates)
+	}
+
+	t.Run("does nothing if no rotation is requested", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, fakeClient := setup(g, nil, nil)
+		delete(controlPlane.KCP.Annotations, controlplanev1.RotateCertificateAuthoritiesAnnotation)
+
+		result, err := r.reconcileCertificateAuthoritiesRotation(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.IsZero()).To(BeTrue())
+		g.Expect(controlPlane.KCP.Status.CertificateAuthoritiesRotation).To(BeNil())
+		g.Expect(trustedCertificates(g, fakeClient, controlPlane)).To(Equal(1))
+	})
+
+	t.Run("does nothing if the rotation requested has been completed", func(t *testing.T) {
+		g := NewWithT(t)
+		rotation := &controlplanev1.CertificateAuthoritiesRotation{Request: "1", Phase: controlplanev1.CertificateAuthoritiesRotationCompletedPhase, PhaseStartTime: phaseStartTime}
+		r, controlPlane, fakeClient := setup(g, rotation, nil)
+
+		result, err := r.reconcileCertificateAuthoritiesRotation(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.IsZero()).To(BeTrue())
+		g.Expect(controlPlane.KCP.Status.CertificateAuthoritiesRotation.Phase).To(Equal(controlplanev1.CertificateAuthoritiesRotationCompletedPhase))
+		g.Expect(trustedCertificates(g, fakeClient, controlPlane)).To(Equal(1))
+	})
+
+	t.Run("waits for the control plane to be initialized", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, _ := setup(g, nil, nil)
+		controlPlane.KCP.Status.Initialized = false
+
+		result, err := r.reconcileCertificateAuthoritiesRotation(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.IsZero()).To(BeTrue())
+		g.Expect(controlPlane.KCP.Status.CertificateAuthoritiesRotation).To(BeNil())
+	})
+
+	t.Run("starts a rotation by trusting new certificate authorities", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, fakeClient := setup(g, nil, []*clusterv1.Machine{controlPlaneMachine("old", phaseStartTime.Add(-time.Minute))})
+
+		result, err := r.reconcileCertificateAuthoritiesRotation(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(certificateAuthoritiesRotationRequeueAfter))
+
+		rotation := controlPlane.KCP.Status.CertificateAuthoritiesRotation
+		g.Expect(rotation).ToNot(BeNil())
+		g.Expect(rotation.Request).To(Equal("1"))
+		g.Expect(rotation.Phase).To(Equal(controlplanev1.TrustingNewCertificateAuthoritiesPhase))
+		g.Expect(conditions.GetReason(controlPlane.KCP, controlplanev1.CertificateAuthoritiesRotatedCondition)).To(Equal(controlplanev1.TrustingNewCertificateAuthoritiesReason))
+		g.Expect(conditions.GetMessage(controlPlane.KCP, controlplanev1.CertificateAuthoritiesRotatedCondition)).To(Equal("Rolling out 1 control plane machines"))
+
+		g.Expect(trustedCertificates(g, fakeClient, controlPlane)).To(Equal(2))
+		for _, purpose := range rotatedCertificatePurposes {
+			g.Expect(getSecret(g, fakeClient, controlPlane, purpose).Data).To(HaveKey(secret.TLSNextKeyDataName))
+		}
+	})
+
+	t.Run("rolls out the MachineDeployments once the control plane is rolled out", func(t *testing.T) {
+		g := NewWithT(t)
+		rotation := &controlplanev1.CertificateAuthoritiesRotation{Request: "1", Phase: controlplanev1.TrustingNewCertificateAuthoritiesPhase, PhaseStartTime: phaseStartTime}
+		r, controlPlane, fakeClient := setup(g, rotation,
+			[]*clusterv1.Machine{controlPlaneMachine("new", phaseStartTime.Add(time.Minute))},
+			machineDeployment("md-1", "", false),
+			machineDeployment("md-2", "1-TrustingNewCertificateAuthorities", true),
+		)
+
+		result, err := r.reconcileCertificateAuthoritiesRotation(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(certificateAuthoritiesRotationRequeueAfter))
+		g.Expect(rotation.Phase).To(Equal(controlplanev1.TrustingNewCertificateAuthoritiesPhase))
+		g.Expect(conditions.GetMessage(controlPlane.KCP, controlplanev1.CertificateAuthoritiesRotatedCondition)).To(Equal("Rolling out MachineDeployments md-1"))
+
+		md := &clusterv1.MachineDeployment{}
+		g.Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: controlPlane.Cluster.Namespace, Name: "md-1"}, md)).To(Succeed())
+		g.Expect(md.Spec.Template.Annotations).To(HaveKeyWithValue(controlplanev1.CertificateAuthoritiesRotationAnnotation, "1-TrustingNewCertificateAuthorities"))
+	})
+
+	t.Run("waits for the nodes of the other workers to be replaced once the MachineDeployments are rolled out", func(t *testing.T) {
+		g := NewWithT(t)
+		rotation := &controlplanev1.CertificateAuthoritiesRotation{Request: "1", Phase: controlplanev1.SigningWithNewCertificateAuthoritiesPhase, PhaseStartTime: phaseStartTime}
+		r, controlPlane, fakeClient := setup(g, rotation,
+			[]*clusterv1.Machine{controlPlaneMachine("new", phaseStartTime.Add(time.Minute))},
+			machineDeployment("md-1", "1-SigningWithNewCertificateAuthorities", true),
+		)
+		r.managementCluster = &fakeManagementCluster{Workload: fakeWorkloadCluster{NodesCreatedBeforeResult: []string{"machinepool-node-1", "machinepool-node-2"}}}
+
+		result, err := r.reconcileCertificateAuthoritiesRotation(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(certificateAuthoritiesRotationRequeueAfter))
+		g.Expect(rotation.Phase).To(Equal(controlplanev1.SigningWithNewCertificateAuthoritiesPhase))
+		g.Expect(conditions.GetMessage(controlPlane.KCP, controlplanev1.CertificateAuthoritiesRotatedCondition)).To(Equal("Waiting for nodes machinepool-node-1, machinepool-node-2 to be replaced"))
+		g.Expect(trustedCertificates(g, fakeClient, controlPlane)).To(Equal(1))
+	})
+
+	t.Run("moves to signing with the new certificate authorities once all the machines are rolled out", func(t *testing.T) {
+		g := NewWithT(t)
+		rotation := &controlplanev1.CertificateAuthoritiesRotation{Request: "1", Phase: controlplanev1.TrustingNewCertificateAuthoritiesPhase, PhaseStartTime: phaseStartTime}
+		r, controlPlane, fakeClient := setup(g, rotation,
+			[]*clusterv1.Machine{controlPlaneMachine("new", phaseStartTime.Add(time.Minute))},
+			machineDeployment("md-1", "1-TrustingNewCertificateAuthorities", true),
+		)
+
+		result, err := r.reconcileCertificateAuthoritiesRotation(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(certificateAuthoritiesRotationRequeueAfter))
+		g.Expect(rotation.Phase).To(Equal(controlplanev1.SigningWithNewCertificateAuthoritiesPhase))
+		g.Expect(rotation.PhaseStartTime.After(phaseStartTime.Time)).To(BeTrue())
+		g.Expect(conditions.GetReason(controlPlane.KCP, controlplanev1.CertificateAuthoritiesRotatedCondition)).To(Equal(controlplanev1.SigningWithNewCertificateAuthoritiesReason))
+
+		for _, purpose := range rotatedCertificatePurposes {
+			g.Expect(getSecret(g, fakeClient, controlPlane, purpose).Data).ToNot(HaveKey(secret.TLSNextKeyDataName))
+		}
+		g.Expect(trustedCertificates(g, fakeClient, controlPlane)).To(Equal(2))
+	})
+
+	t.Run("resumes a phase whose secrets have not been updated yet", func(t *testing.T) {
+		g := NewWithT(t)
+		// The rotation moved to the last phase, but the secrets were not updated before the controller restarted.
+		rotation := &controlplanev1.CertificateAuthoritiesRotation{Request: "1", Phase: controlplanev1.RemovingOldCertificateAuthoritiesPhase, PhaseStartTime: metav1.Now()}
+		r, controlPlane, fakeClient := setup(g, rotation, []*clusterv1.Machine{controlPlaneMachine("old", phaseStartTime.Time)})
+		s := getSecret(g, fakeClient, controlPlane, secret.ClusterCA)
+		g.Expect(secret.AddNextKeyPair(s, secret.ClusterCA)).To(Succeed())
+		g.Expect(secret.PromoteNextKeyPair(s)).To(Succeed())
+		g.Expect(fakeClient.Update(ctx, s)).To(Succeed())
+
+		_, err := r.reconcileCertificateAuthoritiesRotation(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(rotation.Phase).To(Equal(controlplanev1.RemovingOldCertificateAuthoritiesPhase))
+		g.Expect(trustedCertificates(g, fakeClient, controlPlane)).To(Equal(1))
+	})
+
+	t.Run("completes the rotation", func(t *testing.T) {
+		g := NewWithT(t)
+		rotation := &controlplanev1.CertificateAuthoritiesRotation{Request: "1", Phase: controlplanev1.RemovingOldCertificateAuthoritiesPhase, PhaseStartTime: phaseStartTime}
+		r, controlPlane, _ := setup(g, rotation, []*clusterv1.Machine{controlPlaneMachine("new", phaseStartTime.Add(time.Minute))})
+
+		result, err := r.reconcileCertificateAuthoritiesRotation(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.IsZero()).To(BeTrue())
+		g.Expect(rotation.Phase).To(Equal(controlplanev1.CertificateAuthoritiesRotationCompletedPhase))
+		g.Expect(conditions.IsTrue(controlPlane.KCP, controlplanev1.CertificateAuthoritiesRotatedCondition)).To(BeTrue())
+	})
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// reconcileCertificatesExpiry reads the expiry date of the certificates of the control plane machines which
+// do not have it yet, or have it in an invalid format, and records it in the CertificatesExpiryAnnotation of the machines.
+// The certificates of the machines to be rolled out because of their expiry date are read again before the rollout,
+// so certificates renewed in place, e.g. with kubeadm certs renew, are taken into account.
+//
+// NOTE: failing to read the certificates of a machine does not block the other KCP operations; the check is retried
+// at the next reconciliation.
+func (r *KubeadmControlPlaneReconciler) reconcileCertificatesExpiry(ctx context.Context, controlPlane *internal.ControlPlane) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx, "cluster", controlPlane.Cluster.Name)
+
+	// Certificates are issued when the node joins the cluster, so only provisioned machines are checked.
+	now := metav1.Now()
+	machines := controlPlane.Machines.Filter(
+		collections.Not(collections.HasDeletionTimestamp),
+		func(machine *clusterv1.Machine) bool { return machine.Status.NodeRef != nil },
+	).AnyFilter(
+		collections.Not(internal.HasCertificatesExpiry),
+		internal.ShouldRolloutBefore(&now, controlPlane.KCP.Spec.RolloutBefore),
+	)
+	if machines.Len() == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, util.ObjectKey(controlPlane.Cluster))
+	if err != nil {
+		log.V(2).Info("cannot get remote client to workload cluster, skipping certificates expiry check", "cause", err)
+		return ctrl.Result{}, nil
+	}
+
+	for _, machine := range machines {
+		expiry, err := workloadCluster.CertificatesExpiry(ctx, machine.Status.NodeRef.Name, controlPlane.KCP)
+		if err != nil {
+			log.Error(err, "failed to read the certificates expiry date", "machine", machine.Name)
+			continue
+		}
+		if err := r.setCertificatesExpiry(ctx, machine, expiry); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// setCertificatesExpiry records the expiry date of the certificates of a machine in its CertificatesExpiryAnnotation.
+func (r *KubeadmControlPlaneReconciler) setCertificatesExpiry(ctx context.Context, machine *clusterv1.Machine, expiry time.Time) error {
+	patchHelper, err := patch.NewHelper(machine, r.Client)
+	if err != nil {
+		return err
+	}
+	annotations := machine.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[controlplanev1.CertificatesExpiryAnnotation] = expiry.UTC().Format(time.RFC3339)
+	machine.SetAnnotations(annotations)
+	if err := patchHelper.Patch(ctx, machine); err != nil {
+		return errors.Wrapf(err, "failed to patch machine %s", machine.Name)
+	}
+	return nil
+}
+
+// earliestCertificatesExpiry returns the earliest certificates expiry date recorded on the given machines,
+// or nil if none is recorded.
+func earliestCertificatesExpiry(machines collections.Machines) *metav1.Time {
+	var earliest *metav1.Time
+	for _, machine := range machines {
+		value, ok := machine.Annotations[controlplanev1.CertificatesExpiryAnnotation]
+		if !ok {
+			continue
+		}
+		expiry, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			continue
+		}
+		if earliest == nil || expiry.Before(earliest.Time) {
+			t := metav1.NewTime(expiry)
+			earliest = &t
+		}
+	}
+	return earliest
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileCertificatesExpiry(t *testing.T) {
+	expiry := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	machine := func(name, nodeName string, annotations map[string]string) *clusterv1.Machine {
+		m := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "test",
+				Annotations: annotations,
+			},
+		}
+		if nodeName != "" {
+			m.Status.NodeRef = &corev1.ObjectReference{Kind: "Node", Name: nodeName}
+		}
+		return m
+	}
+	getAnnotations := func(g *WithT, c client.Client, name string) map[string]string {
+		m := &clusterv1.Machine{}
+		g.Expect(c.Get(ctx, client.ObjectKey{Namespace: "test", Name: name}, m)).To(Succeed())
+		return m.Annotations
+	}
+
+	g := NewWithT(t)
+
+	cluster, kcp, _ := createClusterWithControlPlane()
+	kcp.Spec.RolloutBefore = &controlplanev1.RolloutBefore{CertificatesExpiryDays: pointer.Int32Ptr(21)}
+	machines := []*clusterv1.Machine{
+		// The certificates of this machine are read and recorded.
+		machine("new", "node-new", nil),
+		// The certificates of this machine were already read.
+		machine("checked", "node-checked", map[string]string{controlplanev1.CertificatesExpiryAnnotation: "2029-01-01T00:00:00Z"}),
+		// The expiry date of this machine cannot be parsed, so its certificates are read again.
+		machine("invalid", "node-invalid", map[string]string{controlplanev1.CertificatesExpiryAnnotation: "tomorrow"}),
+		// The certificates of this machine are about to expire, so they are read again in case they were renewed in place.
+		machine("expiring", "node-expiring", map[string]string{controlplanev1.CertificatesExpiryAnnotation: time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)}),
+		// This machine does not have a node yet.
+		machine("provisioning", "", nil),
+		// The certificates of this machine cannot be read.
+		machine("unreachable", "node-unreachable", nil),
+	}
+	objs := []client.Object{cluster.DeepCopy(), kcp.DeepCopy()}
+	for _, m := range machines {
+		objs = append(objs, m)
+	}
+	fakeClient := newFakeClient(g, objs...)
+
+	r := &KubeadmControlPlaneReconciler{
+		Client: fakeClient,
+		managementCluster: &fakeManagementCluster{
+			Workload: fakeWorkloadCluster{
+				CertificatesExpiryResult: map[string]time.Time{
+					"node-new":      expiry,
+					"node-checked":  expiry,
+					"node-invalid":  expiry,
+					"node-expiring": expiry,
+				},
+			},
+		},
+	}
+	controlPlane := &internal.ControlPlane{Cluster: cluster, KCP: kcp, Machines: collections.FromMachines(machines...)}
+
+	result, err := r.reconcileCertificatesExpiry(ctx, controlPlane)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.IsZero()).To(BeTrue())
+
+	g.Expect(getAnnotations(g, fakeClient, "new")).To(HaveKeyWithValue(controlplanev1.CertificatesExpiryAnnotation, "2030-01-01T00:00:00Z"))
+	g.Expect(getAnnotations(g, fakeClient, "checked")).To(HaveKeyWithValue(controlplanev1.CertificatesExpiryAnnotation, "2029-01-01T00:00:00Z"))
+	g.Expect(getAnnotations(g, fakeClient, "invalid")).To(HaveKeyWithValue(controlplanev1.CertificatesExpiryAnnotation, "2030-01-01T00:00:00Z"))
+	g.Expect(getAnnotations(g, fakeClient, "expiring")).To(HaveKeyWithValue(controlplanev1.CertificatesExpiryAnnotation, "2030-01-01T00:00:00Z"))
+	g.Expect(getAnnotations(g, fakeClient, "provisioning")).ToNot(HaveKey(controlplanev1.CertificatesExpiryAnnotation))
+	g.Expect(getAnnotations(g, fakeClient, "unreachable")).ToNot(HaveKey(controlplanev1.CertificatesExpiryAnnotation))
+}
+
+func TestEarliestCertificatesExpiry(t *testing.T) {
+	g := NewWithT(t)
+
+	machineExpiringAt := func(name, value string) *clusterv1.Machine {
+		return &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Annotations: map[string]string{controlplanev1.CertificatesExpiryAnnotation: value},
+			},
+		}
+	}
+
+	g.Expect(earliestCertificatesExpiry(collections.FromMachines(&clusterv1.Machine{}))).To(BeNil())
+
+	machines := collections.FromMachines(
+		machineExpiringAt("m1", "2030-06-01T00:00:00Z"),
+		machineExpiringAt("m2", "2030-01-01T00:00:00Z"),
+		machineExpiringAt("m3", "invalid"),
+		&clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "m4"}},
+	)
+	g.Expect(earliestCertificatesExpiry(machines).Time).To(Equal(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
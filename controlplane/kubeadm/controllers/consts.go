@@ -30,4 +30,24 @@ const (
 	// dependentCertRequeueAfter is how long to wait before checking again to see if
 	// dependent certificates have been created.
 	dependentCertRequeueAfter = 30 * time.Second
+
+	// etcdBackupUnhealthyRequeueAfter is how long to wait before checking again
+	// if the etcd cluster is healthy when a snapshot is due.
+	etcdBackupUnhealthyRequeueAfter = 1 * time.Minute
+
+	// etcdRestoreRequeueAfter is how long to wait before checking again
+	// if etcd has been restored on the first control plane machine.
+	etcdRestoreRequeueAfter = 20 * time.Second
+
+	// certificateAuthoritiesRotationRequeueAfter is how long to wait before checking again
+	// if the machines have been rolled out during a rotation of the certificate authorities.
+	certificateAuthoritiesRotationRequeueAfter = 30 * time.Second
+
+	// etcdMaintenanceRequeueAfter is how long to wait before checking again if an etcd member
+	// has to be defragmented after a member has been defragmented, or if the members are unavailable.
+	etcdMaintenanceRequeueAfter = 1 * time.Minute
+
+	// defaultRemediationMinHealthyPeriod is how long a machine created by a remediation must stay healthy
+	// for a new failure not to be considered a retry, when not set in the remediation strategy.
+	defaultRemediationMinHealthyPeriod = 1 * time.Hour
 )
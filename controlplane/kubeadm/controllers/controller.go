@@ -40,6 +40,7 @@ import (
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/maintenance"
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/cluster-api/util/predicates"
 	"sigs.k8s.io/cluster-api/util/secret"
@@ -52,10 +53,11 @@ import (
 )
 
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;patch
-// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete;deletecollection
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io;bootstrap.cluster.x-k8s.io;controlplane.cluster.x-k8s.io,resources=*,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters;clusters/status,verbs=get;list;watch
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines;machines/status,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machinedeployments,verbs=get;list;watch;patch
 
 // KubeadmControlPlaneReconciler reconciles a KubeadmControlPlane object
 type KubeadmControlPlaneReconciler struct {
@@ -64,6 +66,9 @@ type KubeadmControlPlaneReconciler struct {
 	recorder   record.EventRecorder
 	Tracker    *remote.ClusterCacheTracker
 
+	// EtcdBackupDir is the directory where etcd snapshots are stored when using the Volume etcd backup storage.
+	EtcdBackupDir string
+
 	managementCluster         internal.ManagementCluster
 	managementClusterUncached internal.ManagementCluster
 }
@@ -227,6 +232,11 @@ func patchKubeadmControlPlane(ctx context.Context, patchHelper *patch.Helper, kc
 			controlplanev1.MachinesReadyCondition,
 			controlplanev1.AvailableCondition,
 			controlplanev1.CertificatesAvailableCondition,
+			controlplanev1.EtcdBackupSucceededCondition,
+			controlplanev1.EtcdRestoredCondition,
+			controlplanev1.CertificateAuthoritiesRotatedCondition,
+			clusterv1.DisruptionAllowedCondition,
+			clusterv1.MachinesDeletableCondition,
 		}},
 	)
 }
@@ -295,6 +305,12 @@ func (r *KubeadmControlPlaneReconciler) reconcile(ctx context.Context, cluster *
 		return ctrl.Result{}, err
 	}
 
+	// Restore etcd from a snapshot if requested; this replaces all the machines, so it is performed before anything
+	// else, including checks requiring a reachable workload cluster.
+	if result, err := r.reconcileEtcdRestore(ctx, controlPlane); err != nil || !result.IsZero() {
+		return result, err
+	}
+
 	// Aggregate the operational state of all the machines; while aggregating we are adding the
 	// source ref (reason@machine/name) so the problem can be easily tracked down to its source machine.
 	conditions.SetAggregate(controlPlane.KCP, controlplanev1.MachinesReadyCondition, ownedMachines.ConditionGetters(), conditions.AddSourceRef(), conditions.WithStepCounterIf(false))
@@ -317,9 +333,44 @@ func (r *KubeadmControlPlaneReconciler) reconcile(ctx context.Context, cluster *
 		return result, err
 	}
 
+	// Record the certificates expiry date of the machines, so machines with expiring certificates are rolled out
+	// if KCP.Spec.RolloutBefore is set.
+	if result, err := r.reconcileCertificatesExpiry(ctx, controlPlane); err != nil || !result.IsZero() {
+		return result, err
+	}
+
+	// Rotate the certificate authorities if requested; the rotation marks the machines to be rolled out
+	// by the rollout below.
+	rotationResult, err := r.reconcileCertificateAuthoritiesRotation(ctx, controlPlane)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Defragment an etcd member if one is due, or if one ran out of space; this is done before rollouts and scaling
+	// given that they are blocked by the etcd members being unhealthy, e.g. because of a NOSPACE alarm.
+	maintenanceResult, err := r.reconcileEtcdMaintenance(ctx, controlPlane)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Disruptive operations, i.e. rollouts and scaling down, are deferred outside of the Cluster's maintenance windows.
+	now := time.Now()
+	disruptionAllowed, nextWindow, err := maintenance.DisruptionAllowed(cluster, now)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if disruptionAllowed {
+		maintenance.MarkAllowed(controlPlane.KCP, cluster)
+	}
+
 	// Control plane machines rollout due to configuration changes (e.g. upgrades) takes precedence over other operations.
 	needRollout := controlPlane.MachinesNeedingRollout()
+	rolloutDeferred := false
 	switch {
+	case len(needRollout) > 0 && !disruptionAllowed:
+		log.Info("Rollout deferred until the next maintenance window opens", "needRollout", needRollout.Names(), "next", nextWindow)
+		maintenance.MarkDeferred(controlPlane.KCP, "Rollout", nextWindow)
+		rolloutDeferred = true
 	case len(needRollout) > 0:
 		log.Info("Rolling out Control Plane machines", "needRollout", needRollout.Names())
 		conditions.MarkFalse(controlPlane.KCP, controlplanev1.MachinesSpecUpToDateCondition, controlplanev1.RollingUpdateInProgressReason, clusterv1.ConditionSeverityWarning, "Rolling %d replicas with outdated spec (%d replicas up to date)", len(needRollout), len(controlPlane.Machines)-len(needRollout))
@@ -349,6 +400,11 @@ func (r *KubeadmControlPlaneReconciler) reconcile(ctx context.Context, cluster *
 		// Create a new Machine w/ join
 		log.Info("Scaling up control plane", "Desired", desiredReplicas, "Existing", numMachines)
 		return r.scaleUpControlPlane(ctx, cluster, kcp, controlPlane)
+	// We are scaling down, but we are outside of the Cluster's maintenance windows
+	case numMachines > desiredReplicas && !disruptionAllowed:
+		log.Info("Scale down deferred until the next maintenance window opens", "Desired", desiredReplicas, "Existing", numMachines, "next", nextWindow)
+		maintenance.MarkDeferred(controlPlane.KCP, "Scale down", nextWindow)
+		return ctrl.Result{RequeueAfter: maintenance.RequeueAfter(nextWindow, now)}, nil
 	// We are scaling down
 	case numMachines > desiredReplicas:
 		log.Info("Scaling down control plane", "Desired", desiredReplicas, "Existing", numMachines)
@@ -356,6 +412,11 @@ func (r *KubeadmControlPlaneReconciler) reconcile(ctx context.Context, cluster *
 		return r.scaleDownControlPlane(ctx, cluster, kcp, controlPlane, collections.Machines{})
 	}
 
+	// Make sure a scale down previously stalled by machines protected from deletion is marked as completed.
+	if conditions.Has(controlPlane.KCP, clusterv1.MachinesDeletableCondition) {
+		conditions.MarkTrue(controlPlane.KCP, clusterv1.MachinesDeletableCondition)
+	}
+
 	// Get the workload cluster client.
 	workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, util.ObjectKey(cluster))
 	if err != nil {
@@ -379,7 +440,18 @@ func (r *KubeadmControlPlaneReconciler) reconcile(ctx context.Context, cluster *
 		return ctrl.Result{}, errors.Wrap(err, "failed to update CoreDNS deployment")
 	}
 
-	return ctrl.Result{}, nil
+	// Take a snapshot of the etcd cluster if one is due.
+	result, err := r.reconcileEtcdBackup(ctx, controlPlane)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	result = util.LowestNonZeroResult(result, rotationResult)
+	result = util.LowestNonZeroResult(result, maintenanceResult)
+
+	if rolloutDeferred {
+		return util.LowestNonZeroResult(result, ctrl.Result{RequeueAfter: maintenance.RequeueAfter(nextWindow, now)}), nil
+	}
+	return result, nil
 }
 
 // reconcileDelete handles KubeadmControlPlane deletion.
@@ -0,0 +1,224 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd/backup"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/cron"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// etcdSnapshotNamePrefix is the prefix of the names of etcd snapshots, followed by the time the snapshot was taken.
+	etcdSnapshotNamePrefix = "etcd-snapshot-"
+
+	// defaultEtcdBackupRetention is the number of snapshots kept when the retention is not set.
+	defaultEtcdBackupRetention = 3
+
+	// etcdSnapshotTimeout is the maximum amount of time taking and storing a snapshot can take; snapshots are taken
+	// within the reconcile of the KubeadmControlPlane, so this bounds the time a reconcile worker is busy, while
+	// snapshots failing to complete in time are retried with the next reconcile.
+	etcdSnapshotTimeout = 2 * time.Minute
+)
+
+// reconcileEtcdBackup takes a snapshot of the etcd cluster when one is due according to the etcd backup schedule,
+// and deletes the oldest snapshots exceeding the retention.
+//
+// NOTE: this func uses KCP conditions, it is required to call reconcileControlPlaneConditions before this.
+func (r *KubeadmControlPlaneReconciler) reconcileEtcdBackup(ctx context.Context, controlPlane *internal.ControlPlane) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx, "cluster", controlPlane.Cluster.Name)
+	kcp := controlPlane.KCP
+
+	// If there is no backup policy or etcd is not managed by KCP this is a no-op.
+	if kcp.Spec.EtcdBackup == nil || !controlPlane.IsEtcdManaged() {
+		conditions.Delete(kcp, controlplanev1.EtcdBackupSucceededCondition)
+		return ctrl.Result{}, nil
+	}
+
+	schedule, err := cron.Parse(kcp.Spec.EtcdBackup.Schedule)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "invalid etcd backup schedule")
+	}
+
+	storageType := kcp.Spec.EtcdBackup.Storage.Type
+	store, err := r.etcdBackupStore(ctx, controlPlane, storageType)
+	if err != nil {
+		conditions.MarkFalse(kcp, controlplanev1.EtcdBackupSucceededCondition, controlplanev1.EtcdBackupFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		return ctrl.Result{}, err
+	}
+
+	// The status does not record any snapshot when none has been taken yet, but also when it has been lost,
+	// e.g. when the KubeadmControlPlane has been moved to another management cluster; hence the snapshots are
+	// looked up in the storage, to schedule the next snapshot according to the last one.
+	if len(kcp.Status.EtcdSnapshots) == 0 {
+		snapshots, err := store.List(ctx)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		kcp.Status.EtcdSnapshots = etcdSnapshotsFromStore(snapshots, storageType)
+	}
+
+	now := time.Now()
+	last := kcp.CreationTimestamp.Time
+	if n := len(kcp.Status.EtcdSnapshots); n > 0 {
+		last = kcp.Status.EtcdSnapshots[n-1].Timestamp.Time
+	}
+	next := schedule.Next(last)
+	if next.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	if now.Before(next) {
+		return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+	}
+
+	// Snapshots are taken only from a healthy etcd cluster; warnings, e.g. about the size of the database
+	// of the members, do not prevent taking snapshots.
+	healthy := conditions.IsTrue(kcp, controlplanev1.EtcdClusterHealthyCondition)
+	if severity := conditions.GetSeverity(kcp, controlplanev1.EtcdClusterHealthyCondition); severity != nil && *severity == clusterv1.ConditionSeverityWarning {
+		healthy = true
+	}
+	if !healthy {
+		log.Info("Waiting for the etcd cluster to be healthy before taking a snapshot")
+		return ctrl.Result{RequeueAfter: etcdBackupUnhealthyRequeueAfter}, nil
+	}
+
+	snapshot := controlplanev1.EtcdSnapshot{
+		Name:        etcdSnapshotNamePrefix + now.UTC().Format("20060102150405"),
+		Timestamp:   metav1.NewTime(now),
+		StorageType: storageType,
+	}
+	snapshotCtx, cancel := context.WithTimeout(ctx, etcdSnapshotTimeout)
+	size, err := r.saveEtcdSnapshot(snapshotCtx, controlPlane, store, snapshot.Name)
+	cancel()
+	if err != nil {
+		conditions.MarkFalse(kcp, controlplanev1.EtcdBackupSucceededCondition, controlplanev1.EtcdBackupFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		r.recorder.Eventf(kcp, corev1.EventTypeWarning, controlplanev1.EtcdBackupFailedReason, "Failed to take etcd snapshot %s: %v", snapshot.Name, err)
+		return ctrl.Result{}, err
+	}
+	snapshot.SizeBytes = size
+	conditions.MarkTrue(kcp, controlplanev1.EtcdBackupSucceededCondition)
+	log.Info("Took etcd snapshot", "snapshot", snapshot.Name, "sizeBytes", size)
+	r.recorder.Eventf(kcp, corev1.EventTypeNormal, "EtcdSnapshotTaken", "Took etcd snapshot %s", snapshot.Name)
+
+	result := ctrl.Result{RequeueAfter: schedule.Next(now).Sub(now)}
+
+	// Delete the oldest snapshots exceeding the retention. Snapshots are listed from the storage, so snapshots
+	// missing from the status, e.g. because patching the status failed, are deleted as well; snapshots failing
+	// to be deleted are kept, so deleting them is retried with the next snapshot.
+	snapshots, err := store.List(ctx)
+	if err != nil {
+		log.Error(err, "Failed to list etcd snapshots")
+		kcp.Status.EtcdSnapshots = append(kcp.Status.EtcdSnapshots, snapshot)
+		return result, nil
+	}
+	taken := etcdSnapshotsFromStore(snapshots, storageType)
+	retention := defaultEtcdBackupRetention
+	if kcp.Spec.EtcdBackup.Retention != nil {
+		retention = int(*kcp.Spec.EtcdBackup.Retention)
+	}
+	for len(taken) > retention {
+		oldest := taken[0]
+		if err := store.Delete(ctx, oldest.Name); err != nil {
+			log.Error(err, "Failed to delete etcd snapshot exceeding the retention", "snapshot", oldest.Name)
+			break
+		}
+		taken = taken[1:]
+	}
+	kcp.Status.EtcdSnapshots = taken
+
+	return result, nil
+}
+
+// etcdSnapshotsFromStore returns the snapshots taken by KCP among the ones in a storage, oldest first;
+// other snapshots, e.g. uploaded to be restored, are ignored.
+func etcdSnapshotsFromStore(snapshots []backup.SnapshotInfo, storageType controlplanev1.EtcdBackupStorageType) []controlplanev1.EtcdSnapshot {
+	taken := []controlplanev1.EtcdSnapshot{}
+	for _, s := range snapshots {
+		if !strings.HasPrefix(s.Name, etcdSnapshotNamePrefix) {
+			continue
+		}
+		taken = append(taken, controlplanev1.EtcdSnapshot{
+			Name:        s.Name,
+			Timestamp:   metav1.NewTime(s.Timestamp),
+			SizeBytes:   s.Size,
+			StorageType: storageType,
+		})
+	}
+	return taken
+}
+
+// saveEtcdSnapshot streams a snapshot of the etcd cluster to the given store, and returns its size in bytes.
+func (r *KubeadmControlPlaneReconciler) saveEtcdSnapshot(ctx context.Context, controlPlane *internal.ControlPlane, store backup.Store, name string) (int64, error) {
+	workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, util.ObjectKey(controlPlane.Cluster))
+	if err != nil {
+		return 0, errors.Wrap(err, "cannot get remote client to workload cluster")
+	}
+	snapshot, err := workloadCluster.EtcdSnapshot(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer snapshot.Close()
+
+	return store.Save(ctx, name, snapshot)
+}
+
+// etcdBackupStore returns the store for etcd snapshots of the given storage type;
+// the configuration of the storage is read from the KCP etcd backup policy.
+func (r *KubeadmControlPlaneReconciler) etcdBackupStore(ctx context.Context, controlPlane *internal.ControlPlane, storageType controlplanev1.EtcdBackupStorageType) (backup.Store, error) {
+	kcp := controlPlane.KCP
+	switch storageType {
+	case controlplanev1.SecretEtcdBackupStorageType:
+		return backup.NewSecretStore(r.Client, kcp.Namespace, controlPlane.Cluster.Name), nil
+	case controlplanev1.VolumeEtcdBackupStorageType:
+		if r.EtcdBackupDir == "" {
+			return nil, errors.New("the KubeadmControlPlane controller has not been configured with an etcd backup directory")
+		}
+		return backup.NewVolumeStore(r.EtcdBackupDir, kcp.Namespace, controlPlane.Cluster.Name), nil
+	case controlplanev1.S3EtcdBackupStorageType:
+		if kcp.Spec.EtcdBackup == nil || kcp.Spec.EtcdBackup.Storage.S3 == nil {
+			return nil, errors.New("the S3 etcd backup storage is not configured")
+		}
+		s3 := kcp.Spec.EtcdBackup.Storage.S3
+		credentials := &corev1.Secret{}
+		key := client.ObjectKey{Namespace: kcp.Namespace, Name: s3.CredentialsSecret.Name}
+		if err := r.Client.Get(ctx, key, credentials); err != nil {
+			return nil, errors.Wrapf(err, "failed to get S3 credentials Secret %s", key)
+		}
+		return backup.NewS3Store(backup.S3Options{
+			Endpoint:        s3.Endpoint,
+			Region:          s3.Region,
+			Bucket:          s3.Bucket,
+			Prefix:          s3.Prefix,
+			AccessKeyID:     string(credentials.Data["accessKeyID"]),
+			SecretAccessKey: string(credentials.Data["secretAccessKey"]),
+		}, kcp.Namespace, controlPlane.Cluster.Name)
+	}
+	return nil, errors.Errorf("unsupported etcd backup storage type %q", storageType)
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd/backup"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileEtcdBackup(t *testing.T) {
+	setup := func(g *WithT, snapshots ...controlplanev1.EtcdSnapshot) (*KubeadmControlPlaneReconciler, *internal.ControlPlane, backup.Store) {
+		cluster, kcp, _ := createClusterWithControlPlane()
+		kcp.Spec.EtcdBackup = &controlplanev1.EtcdBackup{
+			Schedule:  "0 * * * *",
+			Retention: pointer.Int32Ptr(2),
+			Storage:   controlplanev1.EtcdBackupStorage{Type: controlplanev1.SecretEtcdBackupStorageType},
+		}
+		kcp.Status.EtcdSnapshots = snapshots
+		conditions.MarkTrue(kcp, controlplanev1.EtcdClusterHealthyCondition)
+
+		fakeClient := newFakeClient(g, cluster.DeepCopy(), kcp.DeepCopy())
+		store := backup.NewSecretStore(fakeClient, kcp.Namespace, cluster.Name)
+		for _, s := range snapshots {
+			_, err := store.Save(ctx, s.Name, bytes.NewReader([]byte(s.Name)))
+			g.Expect(err).ToNot(HaveOccurred())
+		}
+
+		r := &KubeadmControlPlaneReconciler{
+			Client:   fakeClient,
+			recorder: record.NewFakeRecorder(32),
+			managementCluster: &fakeManagementCluster{
+				Workload: fakeWorkloadCluster{EtcdSnapshotData: []byte("snapshot")},
+			},
+		}
+		controlPlane := &internal.ControlPlane{Cluster: cluster, KCP: kcp}
+		return r, controlPlane, store
+	}
+	snapshotTakenAgo := func(name string, ago time.Duration) controlplanev1.EtcdSnapshot {
+		return controlplanev1.EtcdSnapshot{
+			Name:        name,
+			Timestamp:   metav1.NewTime(time.Now().Add(-ago)),
+			StorageType: controlplanev1.SecretEtcdBackupStorageType,
+		}
+	}
+
+	t.Run("takes a snapshot when none has been taken yet", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, store := setup(g)
+
+		result, err := r.reconcileEtcdBackup(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		g.Expect(result.RequeueAfter).To(BeNumerically("<=", time.Hour))
+		g.Expect(conditions.IsTrue(controlPlane.KCP, controlplanev1.EtcdBackupSucceededCondition)).To(BeTrue())
+
+		snapshots := controlPlane.KCP.Status.EtcdSnapshots
+		g.Expect(snapshots).To(HaveLen(1))
+		g.Expect(snapshots[0].SizeBytes).To(Equal(int64(len("snapshot"))))
+		r2, err := store.Load(ctx, snapshots[0].Name)
+		g.Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(r2)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(data).To(Equal([]byte("snapshot")))
+	})
+
+	t.Run("waits for the next snapshot to be due", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, _ := setup(g, snapshotTakenAgo("etcd-snapshot-1", 0))
+
+		result, err := r.reconcileEtcdBackup(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		next := time.Now().UTC().Truncate(time.Hour).Add(time.Hour)
+		g.Expect(result.RequeueAfter).To(BeNumerically("~", time.Until(next), time.Second))
+		g.Expect(controlPlane.KCP.Status.EtcdSnapshots).To(HaveLen(1))
+	})
+
+	t.Run("looks up the snapshots in the storage when the status does not record them", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, _ := setup(g)
+		r.EtcdBackupDir = t.TempDir()
+		controlPlane.KCP.Spec.EtcdBackup.Storage.Type = controlplanev1.VolumeEtcdBackupStorageType
+		store := backup.NewVolumeStore(r.EtcdBackupDir, controlPlane.KCP.Namespace, controlPlane.Cluster.Name)
+		_, err := store.Save(ctx, "etcd-snapshot-1", bytes.NewReader([]byte("etcd-snapshot-1")))
+		g.Expect(err).ToNot(HaveOccurred())
+		_, err = store.Save(ctx, "uploaded-snapshot", bytes.NewReader([]byte("uploaded-snapshot")))
+		g.Expect(err).ToNot(HaveOccurred())
+
+		result, err := r.reconcileEtcdBackup(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+		snapshots := controlPlane.KCP.Status.EtcdSnapshots
+		g.Expect(snapshots).To(HaveLen(1))
+		g.Expect(snapshots[0].Name).To(Equal("etcd-snapshot-1"))
+		g.Expect(snapshots[0].SizeBytes).To(Equal(int64(len("etcd-snapshot-1"))))
+		g.Expect(snapshots[0].StorageType).To(Equal(controlplanev1.VolumeEtcdBackupStorageType))
+	})
+
+	t.Run("waits for the etcd cluster to be healthy", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, _ := setup(g)
+		conditions.MarkFalse(controlPlane.KCP, controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, "", "")
+
+		result, err := r.reconcileEtcdBackup(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(etcdBackupUnhealthyRequeueAfter))
+		g.Expect(controlPlane.KCP.Status.EtcdSnapshots).To(BeEmpty())
+	})
+
+	t.Run("takes a snapshot when the etcd cluster reports warnings", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, _ := setup(g)
+		conditions.MarkFalse(controlPlane.KCP, controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityWarning, "Etcd member on node n1 is using 90%% of its database quota")
+
+		_, err := r.reconcileEtcdBackup(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(controlPlane.KCP.Status.EtcdSnapshots).To(HaveLen(1))
+	})
+
+	t.Run("deletes the oldest snapshots exceeding the retention", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, store := setup(g,
+			snapshotTakenAgo("etcd-snapshot-1", 3*time.Hour),
+			snapshotTakenAgo("etcd-snapshot-2", 2*time.Hour),
+		)
+
+		_, err := r.reconcileEtcdBackup(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		snapshots := controlPlane.KCP.Status.EtcdSnapshots
+		g.Expect(snapshots).To(HaveLen(2))
+		g.Expect(snapshots[0].Name).To(Equal("etcd-snapshot-2"))
+		_, err = store.Load(ctx, "etcd-snapshot-1")
+		g.Expect(err).To(HaveOccurred())
+		_, err = store.Load(ctx, "etcd-snapshot-2")
+		g.Expect(err).ToNot(HaveOccurred())
+	})
+
+	t.Run("deletes the snapshots exceeding the retention missing from the status", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, store := setup(g)
+		for _, name := range []string{"etcd-snapshot-1", "etcd-snapshot-2"} {
+			_, err := store.Save(ctx, name, bytes.NewReader([]byte(name)))
+			g.Expect(err).ToNot(HaveOccurred())
+		}
+		controlPlane.KCP.Status.EtcdSnapshots = []controlplanev1.EtcdSnapshot{snapshotTakenAgo("etcd-snapshot-2", 2*time.Hour)}
+
+		_, err := r.reconcileEtcdBackup(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		snapshots := controlPlane.KCP.Status.EtcdSnapshots
+		g.Expect(snapshots).To(HaveLen(2))
+		g.Expect(snapshots[0].Name).To(Equal("etcd-snapshot-2"))
+		_, err = store.Load(ctx, "etcd-snapshot-1")
+		g.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("reports a failure to store the snapshot", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, _ := setup(g)
+		controlPlane.KCP.Spec.EtcdBackup.Storage.Type = controlplanev1.VolumeEtcdBackupStorageType
+
+		_, err := r.reconcileEtcdBackup(ctx, controlPlane)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(conditions.GetReason(controlPlane.KCP, controlplanev1.EtcdBackupSucceededCondition)).To(Equal(controlplanev1.EtcdBackupFailedReason))
+		g.Expect(controlPlane.KCP.Status.EtcdSnapshots).To(BeEmpty())
+	})
+
+	t.Run("does nothing without an etcd backup policy", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, _ := setup(g)
+		controlPlane.KCP.Spec.EtcdBackup = nil
+
+		result, err := r.reconcileEtcdBackup(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.IsZero()).To(BeTrue())
+		g.Expect(conditions.Has(controlPlane.KCP, controlplanev1.EtcdBackupSucceededCondition)).To(BeFalse())
+
+		secrets := &corev1.SecretList{}
+		g.Expect(r.Client.List(ctx, secrets, client.InNamespace(controlPlane.KCP.Namespace))).To(Succeed())
+		g.Expect(secrets.Items).To(BeEmpty())
+	})
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// etcdDefragmentationCandidate is an etcd member due for defragmentation.
+type etcdDefragmentationCandidate struct {
+	status  internal.EtcdMemberStatus
+	machine *clusterv1.Machine
+	last    time.Time
+}
+
+// reconcileEtcdMaintenance defragments an etcd member when one is due for defragmentation according to the etcd
+// maintenance policy, or when a member raised a NOSPACE alarm, which is disarmed once the defragmentation succeeds.
+// Only one member is defragmented per reconciliation, and the leader is defragmented only when no other member is due.
+//
+// NOTE: this func uses KCP conditions, it is required to call reconcileControlPlaneConditions before this.
+func (r *KubeadmControlPlaneReconciler) reconcileEtcdMaintenance(ctx context.Context, controlPlane *internal.ControlPlane) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx, "cluster", controlPlane.Cluster.Name)
+	kcp := controlPlane.KCP
+
+	// If there is no maintenance policy or etcd is not managed by KCP this is a no-op.
+	if kcp.Spec.EtcdMaintenance == nil || !controlPlane.IsEtcdManaged() {
+		return ctrl.Result{}, nil
+	}
+
+	// Members are defragmented only when all the machines are provisioned and none is being deleted,
+	// so a defragmentation never happens while the etcd membership is changing.
+	nodeNames := make([]string, 0, len(controlPlane.Machines))
+	machinesByNode := map[string]*clusterv1.Machine{}
+	for _, machine := range controlPlane.Machines {
+		if machine.Status.NodeRef == nil || !machine.DeletionTimestamp.IsZero() {
+			return ctrl.Result{}, nil
+		}
+		nodeNames = append(nodeNames, machine.Status.NodeRef.Name)
+		machinesByNode[machine.Status.NodeRef.Name] = machine
+	}
+	if len(nodeNames) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, util.ObjectKey(controlPlane.Cluster))
+	if err != nil {
+		log.V(2).Info("cannot get remote client to workload cluster, skipping etcd maintenance", "cause", err)
+		return ctrl.Result{}, nil
+	}
+	statuses, err := workloadCluster.EtcdMemberStatuses(ctx, nodeNames)
+	if err != nil {
+		log.Info("Waiting for all the etcd members to be available before defragmenting them", "cause", err)
+		return ctrl.Result{RequeueAfter: etcdMaintenanceRequeueAfter}, nil
+	}
+
+	now := time.Now()
+	interval := kcp.Spec.EtcdMaintenance.DefragmentationInterval.Duration
+	var (
+		candidates []etcdDefragmentationCandidate
+		nextDue    time.Time
+	)
+	for _, status := range statuses {
+		// A corrupted member must be replaced, defragmenting the members won't help.
+		if hasEtcdAlarm(status, etcd.AlarmCorrupt) {
+			log.Info("Skipping etcd maintenance, an etcd member reports a CORRUPT alarm", "member", status.Name)
+			return ctrl.Result{}, nil
+		}
+		machine := machinesByNode[status.Name]
+		last := lastEtcdDefragmentation(machine)
+		if due := last.Add(interval); now.Before(due) && !hasEtcdAlarm(status, etcd.AlarmNoSpace) {
+			if nextDue.IsZero() || due.Before(nextDue) {
+				nextDue = due
+			}
+			continue
+		}
+		candidates = append(candidates, etcdDefragmentationCandidate{status: status, machine: machine, last: last})
+	}
+	if len(candidates) == 0 {
+		return ctrl.Result{RequeueAfter: nextDue.Sub(now)}, nil
+	}
+
+	// Defragment the followers before the leader, then the members which ran out of space before the others,
+	// then the members which have not been defragmented for the longest time.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.status.IsLeader != b.status.IsLeader {
+			return !a.status.IsLeader
+		}
+		if noSpaceA, noSpaceB := hasEtcdAlarm(a.status, etcd.AlarmNoSpace), hasEtcdAlarm(b.status, etcd.AlarmNoSpace); noSpaceA != noSpaceB {
+			return noSpaceA
+		}
+		return a.last.Before(b.last)
+	})
+	candidate := candidates[0]
+
+	if err := workloadCluster.DefragmentEtcdMember(ctx, candidate.status.Name); err != nil {
+		log.Error(err, "Failed to defragment etcd member", "member", candidate.status.Name)
+		r.recorder.Eventf(kcp, corev1.EventTypeWarning, controlplanev1.EtcdDefragmentationFailedReason, "Failed to defragment etcd member %s: %v", candidate.status.Name, err)
+		// Do not hide errors about the health of the etcd cluster.
+		if severity := conditions.GetSeverity(kcp, controlplanev1.EtcdClusterHealthyCondition); severity == nil || *severity != clusterv1.ConditionSeverityError {
+			conditions.MarkFalse(kcp, controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdDefragmentationFailedReason, clusterv1.ConditionSeverityWarning, "Failed to defragment etcd member %s: %v", candidate.status.Name, err)
+		}
+		return ctrl.Result{RequeueAfter: etcdMaintenanceRequeueAfter}, nil
+	}
+	log.Info("Defragmented etcd member", "member", candidate.status.Name, "dbSize", candidate.status.DBSize, "dbSizeInUse", candidate.status.DBSizeInUse)
+	r.recorder.Eventf(kcp, corev1.EventTypeNormal, "EtcdMemberDefragmented", "Defragmented etcd member %s", candidate.status.Name)
+
+	if err := r.setEtcdDefragmented(ctx, candidate.machine, now); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: etcdMaintenanceRequeueAfter}, nil
+}
+
+// hasEtcdAlarm returns true if the etcd member raised an alarm of the given type.
+func hasEtcdAlarm(status internal.EtcdMemberStatus, alarmType etcd.AlarmType) bool {
+	for _, alarm := range status.Alarms {
+		if alarm == alarmType {
+			return true
+		}
+	}
+	return false
+}
+
+// lastEtcdDefragmentation returns the time the etcd member of a machine has been last defragmented, as recorded
+// in its EtcdDefragmentedAnnotation, or the creation time of the machine if the member has never been defragmented.
+func lastEtcdDefragmentation(machine *clusterv1.Machine) time.Time {
+	if value, ok := machine.Annotations[controlplanev1.EtcdDefragmentedAnnotation]; ok {
+		if last, err := time.Parse(time.RFC3339, value); err == nil {
+			return last
+		}
+	}
+	return machine.CreationTimestamp.Time
+}
+
+// setEtcdDefragmented records the time the etcd member of a machine has been defragmented in its EtcdDefragmentedAnnotation.
+func (r *KubeadmControlPlaneReconciler) setEtcdDefragmented(ctx context.Context, machine *clusterv1.Machine, defragmented time.Time) error {
+	patchHelper, err := patch.NewHelper(machine, r.Client)
+	if err != nil {
+		return err
+	}
+	annotations := machine.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[controlplanev1.EtcdDefragmentedAnnotation] = defragmented.UTC().Format(time.RFC3339)
+	machine.SetAnnotations(annotations)
+	if err := patchHelper.Patch(ctx, machine); err != nil {
+		return errors.Wrapf(err, "failed to patch machine %s", machine.Name)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileEtcdMaintenance(t *testing.T) {
+	now := time.Now()
+	defragmentedAgo := func(ago time.Duration) string {
+		return now.Add(-ago).UTC().Format(time.RFC3339)
+	}
+	setup := func(g *WithT, lastDefragmentations map[string]string, workload fakeWorkloadCluster) (*KubeadmControlPlaneReconciler, *internal.ControlPlane) {
+		cluster, kcp, _ := createClusterWithControlPlane()
+		kcp.Spec.EtcdMaintenance = &controlplanev1.EtcdMaintenance{
+			DefragmentationInterval: metav1.Duration{Duration: 24 * time.Hour},
+		}
+		conditions.MarkTrue(kcp, controlplanev1.EtcdClusterHealthyCondition)
+
+		objs := []client.Object{cluster.DeepCopy(), kcp.DeepCopy()}
+		machines := []*clusterv1.Machine{}
+		for _, name := range []string{"m1", "m2", "m3"} {
+			m := &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Namespace:   cluster.Namespace,
+					Annotations: map[string]string{controlplanev1.EtcdDefragmentedAnnotation: lastDefragmentations[name]},
+				},
+				Status: clusterv1.MachineStatus{
+					NodeRef: &corev1.ObjectReference{Kind: "Node", Name: name},
+				},
+			}
+			machines = append(machines, m)
+			objs = append(objs, m)
+		}
+
+		r := &KubeadmControlPlaneReconciler{
+			Client:            newFakeClient(g, objs...),
+			recorder:          record.NewFakeRecorder(32),
+			managementCluster: &fakeManagementCluster{Workload: workload},
+		}
+		controlPlane := &internal.ControlPlane{Cluster: cluster, KCP: kcp, Machines: collections.FromMachines(machines...)}
+		return r, controlPlane
+	}
+	healthyMembers := func() []internal.EtcdMemberStatus {
+		return []internal.EtcdMemberStatus{
+			{Name: "m1", IsLeader: true},
+			{Name: "m2"},
+			{Name: "m3"},
+		}
+	}
+	lastDefragmentation := func(g *WithT, r *KubeadmControlPlaneReconciler, name string) string {
+		m := &clusterv1.Machine{}
+		g.Expect(r.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, m)).To(Succeed())
+		return m.Annotations[controlplanev1.EtcdDefragmentedAnnotation]
+	}
+
+	t.Run("does nothing without an etcd maintenance policy", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane := setup(g, nil, fakeWorkloadCluster{EtcdMemberStatusesResult: healthyMembers()})
+		controlPlane.KCP.Spec.EtcdMaintenance = nil
+
+		result, err := r.reconcileEtcdMaintenance(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.IsZero()).To(BeTrue())
+		g.Expect(lastDefragmentation(g, r, "m1")).To(BeEmpty())
+	})
+
+	t.Run("defragments the followers before the leader", func(t *testing.T) {
+		g := NewWithT(t)
+		last := map[string]string{"m1": defragmentedAgo(72 * time.Hour), "m2": defragmentedAgo(48 * time.Hour), "m3": defragmentedAgo(time.Hour)}
+		r, controlPlane := setup(g, last, fakeWorkloadCluster{EtcdMemberStatusesResult: healthyMembers()})
+
+		result, err := r.reconcileEtcdMaintenance(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(etcdMaintenanceRequeueAfter))
+		g.Expect(lastDefragmentation(g, r, "m1")).To(Equal(last["m1"]))
+		g.Expect(lastDefragmentation(g, r, "m2")).ToNot(Equal(last["m2"]))
+		g.Expect(lastDefragmentation(g, r, "m3")).To(Equal(last["m3"]))
+	})
+
+	t.Run("defragments the leader when no follower is due", func(t *testing.T) {
+		g := NewWithT(t)
+		last := map[string]string{"m1": defragmentedAgo(72 * time.Hour), "m2": defragmentedAgo(time.Hour), "m3": defragmentedAgo(time.Hour)}
+		r, controlPlane := setup(g, last, fakeWorkloadCluster{EtcdMemberStatusesResult: healthyMembers()})
+
+		_, err := r.reconcileEtcdMaintenance(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(lastDefragmentation(g, r, "m1")).ToNot(Equal(last["m1"]))
+	})
+
+	t.Run("defragments a member which ran out of space regardless of the interval", func(t *testing.T) {
+		g := NewWithT(t)
+		last := map[string]string{"m1": defragmentedAgo(time.Hour), "m2": defragmentedAgo(time.Hour), "m3": defragmentedAgo(time.Hour)}
+		members := healthyMembers()
+		members[2].Alarms = []etcd.AlarmType{etcd.AlarmNoSpace}
+		r, controlPlane := setup(g, last, fakeWorkloadCluster{EtcdMemberStatusesResult: members})
+
+		_, err := r.reconcileEtcdMaintenance(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(lastDefragmentation(g, r, "m2")).To(Equal(last["m2"]))
+		g.Expect(lastDefragmentation(g, r, "m3")).ToNot(Equal(last["m3"]))
+	})
+
+	t.Run("waits for the next defragmentation to be due", func(t *testing.T) {
+		g := NewWithT(t)
+		last := map[string]string{"m1": defragmentedAgo(time.Hour), "m2": defragmentedAgo(2 * time.Hour), "m3": defragmentedAgo(3 * time.Hour)}
+		r, controlPlane := setup(g, last, fakeWorkloadCluster{EtcdMemberStatusesResult: healthyMembers()})
+
+		result, err := r.reconcileEtcdMaintenance(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(BeNumerically("~", 21*time.Hour, time.Minute))
+		g.Expect(lastDefragmentation(g, r, "m3")).To(Equal(last["m3"]))
+	})
+
+	t.Run("waits for all the etcd members to be available", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane := setup(g, nil, fakeWorkloadCluster{})
+
+		result, err := r.reconcileEtcdMaintenance(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(etcdMaintenanceRequeueAfter))
+		g.Expect(lastDefragmentation(g, r, "m2")).To(BeEmpty())
+	})
+
+	t.Run("does not defragment the members if one is corrupted", func(t *testing.T) {
+		g := NewWithT(t)
+		members := healthyMembers()
+		members[1].Alarms = []etcd.AlarmType{etcd.AlarmCorrupt}
+		r, controlPlane := setup(g, nil, fakeWorkloadCluster{EtcdMemberStatusesResult: members})
+
+		result, err := r.reconcileEtcdMaintenance(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.IsZero()).To(BeTrue())
+		g.Expect(lastDefragmentation(g, r, "m2")).To(BeEmpty())
+		g.Expect(lastDefragmentation(g, r, "m3")).To(BeEmpty())
+	})
+
+	t.Run("reports a failed defragmentation", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane := setup(g, nil, fakeWorkloadCluster{
+			EtcdMemberStatusesResult: healthyMembers(),
+			DefragmentEtcdMemberErr:  errors.New("timeout"),
+		})
+
+		result, err := r.reconcileEtcdMaintenance(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(etcdMaintenanceRequeueAfter))
+		g.Expect(conditions.GetReason(controlPlane.KCP, controlplanev1.EtcdClusterHealthyCondition)).To(Equal(controlplanev1.EtcdDefragmentationFailedReason))
+		g.Expect(*conditions.GetSeverity(controlPlane.KCP, controlplanev1.EtcdClusterHealthyCondition)).To(Equal(clusterv1.ConditionSeverityWarning))
+		g.Expect(lastDefragmentation(g, r, "m2")).To(BeEmpty())
+	})
+}
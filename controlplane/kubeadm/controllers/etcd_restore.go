@@ -0,0 +1,358 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1alpha4"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd/backup"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// etcdRestoreScriptPath is the path of the script restoring etcd on the first control plane machine.
+	etcdRestoreScriptPath = "/run/kubeadm/etcd-restore.sh"
+
+	// etcdRestoreScriptKey is the key of the restore script in the etcd restore Secret.
+	etcdRestoreScriptKey = "etcd-restore.sh"
+
+	// etcdRestoreURLExpiry is how long the URL used by the restore script to download the snapshot is valid;
+	// it must cover the bootstrap of the first control plane machine, and no more, as the URL is part of its bootstrap
+	// data and gives access to the snapshot, which holds all the Secrets of the cluster.
+	etcdRestoreURLExpiry = 30 * time.Minute
+)
+
+// etcdRestoreScript stops etcd after kubeadm init, replaces its data directory with the one restored from the snapshot
+// and starts it again; then it registers the node again with the restored cluster, and finally records the name of the
+// snapshot in etcd so the controller knows the restore is complete.
+var etcdRestoreScript = template.Must(template.New("etcd-restore").Parse(`#!/bin/sh
+set -eu
+
+manifest=/etc/kubernetes/manifests/etcd.yaml
+name=$(sed -n 's/^ *- --name=//p' "${manifest}")
+peer_url=$(sed -n 's/^ *- --initial-advertise-peer-urls=//p' "${manifest}")
+data_dir=$(sed -n 's/^ *- --data-dir=//p' "${manifest}")
+image=$(sed -n 's/^ *image: *//p' "${manifest}" | head -n 1)
+work_dir="$(dirname "${data_dir}")/etcd-restore"
+
+rm -rf "${work_dir}"
+mkdir -p "${work_dir}"
+curl -fsSL --retry 5 -o "${work_dir}/snapshot.db" '{{ .URL }}'
+ctr -n k8s.io run --rm --env ETCDCTL_API=3 \
+  --mount "type=bind,src=${work_dir},dst=${work_dir},options=rbind:rw" \
+  "${image}" etcd-restore \
+  etcdctl snapshot restore "${work_dir}/snapshot.db" --data-dir "${work_dir}/data" \
+  --name "${name}" --initial-cluster "${name}=${peer_url}" --initial-advertise-peer-urls "${peer_url}"
+
+mv "${manifest}" "${work_dir}/etcd.yaml"
+while [ -n "$(crictl ps -q --name '^etcd$')" ]; do sleep 2; done
+rm -rf "${data_dir}"
+mv "${work_dir}/data" "${data_dir}"
+mv "${work_dir}/etcd.yaml" "${manifest}"
+rm -rf "${work_dir}"
+
+systemctl restart kubelet
+until kubeadm init phase mark-control-plane --config /run/kubeadm/kubeadm.yaml; do sleep 5; done
+until kubeadm init phase upload-config all --config /run/kubeadm/kubeadm.yaml; do sleep 5; done
+
+until ctr -n k8s.io run --rm --net-host --env ETCDCTL_API=3 \
+  --mount "type=bind,src=/etc/kubernetes/pki/etcd,dst=/etc/kubernetes/pki/etcd,options=rbind:ro" \
+  "${image}" etcd-restore-complete \
+  etcdctl --endpoints https://127.0.0.1:2379 --cacert /etc/kubernetes/pki/etcd/ca.crt \
+  --cert /etc/kubernetes/pki/etcd/healthcheck-client.crt --key /etc/kubernetes/pki/etcd/healthcheck-client.key \
+  put '{{ .Key }}' '{{ .Snapshot }}'; do sleep 5; done
+`))
+
+// reconcileEtcdRestore restores etcd from the snapshot requested with the RestoreEtcdSnapshotAnnotation: it deletes
+// the existing machines, bootstraps the first control plane machine with the etcd data restored from the snapshot,
+// and removes the annotation once the restore is complete, so the other machines join the restored control plane.
+// While the restore is requested, a non-zero result is returned and no other KCP operation should be performed;
+// the restore can be canceled by removing the annotation.
+func (r *KubeadmControlPlaneReconciler) reconcileEtcdRestore(ctx context.Context, controlPlane *internal.ControlPlane) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx, "cluster", controlPlane.Cluster.Name)
+	kcp := controlPlane.KCP
+
+	snapshotName, ok := kcp.Annotations[controlplanev1.RestoreEtcdSnapshotAnnotation]
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+
+	restoreMachines := controlPlane.Machines.Filter(hasRestoredEtcdSnapshot(snapshotName))
+	if restoreMachines.Len() > 0 {
+		return r.completeEtcdRestore(ctx, controlPlane, restoreMachines.Oldest(), snapshotName)
+	}
+
+	// Machines protected from deletion would block the restore after deleting the other machines, so the restore
+	// does not start until the protection is removed.
+	if protected := controlPlane.Machines.Filter(collections.IsDeletionProtected); protected.Len() > 0 {
+		names := protected.Names()
+		sort.Strings(names)
+		log.Info("Cannot restore etcd from snapshot, control plane machines are protected from deletion", "snapshot", snapshotName, "protected", names)
+		conditions.MarkFalse(kcp, controlplanev1.EtcdRestoredCondition, controlplanev1.EtcdRestoreFailedReason, clusterv1.ConditionSeverityError,
+			"Control plane machines are protected from deletion (protected machines: %s)", strings.Join(names, ", "))
+		return ctrl.Result{RequeueAfter: etcdRestoreRequeueAfter}, nil
+	}
+
+	// Make sure the snapshot exists and can be downloaded before deleting any machine.
+	snapshotURL, err := r.etcdSnapshotURL(ctx, controlPlane, snapshotName)
+	if err != nil {
+		log.Error(err, "Cannot restore etcd from snapshot", "snapshot", snapshotName)
+		conditions.MarkFalse(kcp, controlplanev1.EtcdRestoredCondition, controlplanev1.EtcdRestoreFailedReason, clusterv1.ConditionSeverityError, err.Error())
+		return ctrl.Result{RequeueAfter: etcdRestoreRequeueAfter}, nil
+	}
+
+	// Delete the existing machines; their nodes are not drained, because the whole control plane is going to be replaced.
+	if controlPlane.Machines.Len() > 0 {
+		for _, machine := range controlPlane.Machines.Filter(collections.Not(collections.HasDeletionTimestamp)) {
+			if err := r.deleteMachineForEtcdRestore(ctx, machine); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		// The restore replaces all the machines, including the replacement of a remediated machine.
+		delete(kcp.Annotations, controlplanev1.RemediationInProgressAnnotation)
+		conditions.MarkFalse(kcp, controlplanev1.EtcdRestoredCondition, controlplanev1.DeletingMachinesForEtcdRestoreReason, clusterv1.ConditionSeverityInfo,
+			"Deleting %d control plane machines", controlPlane.Machines.Len())
+		return ctrl.Result{RequeueAfter: deleteRequeueAfter}, nil
+	}
+
+	// The first machine must run kubeadm init; if the control plane has been initialized before, reset the KCP status
+	// and wait for the Cluster controller to observe the change before creating the machine, so the bootstrap provider
+	// generates the bootstrap data for kubeadm init rather than kubeadm join.
+	if kcp.Status.Initialized || controlPlane.Cluster.Status.ControlPlaneInitialized {
+		kcp.Status.Initialized = false
+		log.V(2).Info("Waiting for the Cluster to observe the control plane is not initialized", "snapshot", snapshotName)
+		return ctrl.Result{RequeueAfter: preflightFailedRequeueAfter}, nil
+	}
+
+	secretName, err := r.reconcileEtcdRestoreSecret(ctx, controlPlane, snapshotName, snapshotURL)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	bootstrapSpec := controlPlane.InitialControlPlaneConfig()
+	bootstrapSpec.Files = append(bootstrapSpec.Files, bootstrapv1.File{
+		Path:        etcdRestoreScriptPath,
+		Owner:       "root:root",
+		Permissions: "0700",
+		ContentFrom: &bootstrapv1.FileSource{
+			Secret: bootstrapv1.SecretFileSource{Name: secretName, Key: etcdRestoreScriptKey},
+		},
+	})
+	bootstrapSpec.PostKubeadmCommands = append([]string{"/bin/sh " + etcdRestoreScriptPath}, bootstrapSpec.PostKubeadmCommands...)
+	fd := controlPlane.NextFailureDomainForScaleUp()
+	annotations := map[string]string{controlplanev1.RestoredEtcdSnapshotAnnotation: snapshotName}
+	if err := r.cloneConfigsAndGenerateMachine(ctx, controlPlane.Cluster, kcp, bootstrapSpec, fd, annotations); err != nil {
+		log.Error(err, "Failed to create control plane Machine restoring etcd", "snapshot", snapshotName)
+		conditions.MarkFalse(kcp, controlplanev1.EtcdRestoredCondition, controlplanev1.EtcdRestoreFailedReason, clusterv1.ConditionSeverityError, err.Error())
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Restoring etcd from snapshot", "snapshot", snapshotName)
+	r.recorder.Eventf(kcp, corev1.EventTypeNormal, "RestoringEtcd", "Restoring etcd from snapshot %s", snapshotName)
+	conditions.MarkFalse(kcp, controlplanev1.EtcdRestoredCondition, controlplanev1.RestoringEtcdReason, clusterv1.ConditionSeverityInfo,
+		"Bootstrapping the first control plane machine from snapshot %s", snapshotName)
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// completeEtcdRestore waits for the restore script to record the snapshot in the restored etcd cluster, then cleans up
+// the control plane nodes restored from the snapshot and removes the RestoreEtcdSnapshotAnnotation.
+func (r *KubeadmControlPlaneReconciler) completeEtcdRestore(ctx context.Context, controlPlane *internal.ControlPlane, machine *clusterv1.Machine, snapshotName string) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx, "cluster", controlPlane.Cluster.Name)
+	kcp := controlPlane.KCP
+
+	conditions.MarkFalse(kcp, controlplanev1.EtcdRestoredCondition, controlplanev1.RestoringEtcdReason, clusterv1.ConditionSeverityInfo,
+		"Bootstrapping the first control plane machine from snapshot %s", snapshotName)
+	if machine.Status.NodeRef == nil {
+		return ctrl.Result{RequeueAfter: etcdRestoreRequeueAfter}, nil
+	}
+
+	workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, util.ObjectKey(controlPlane.Cluster))
+	if err != nil {
+		log.V(2).Info("Waiting for the workload cluster to be reachable", "cause", err.Error())
+		return ctrl.Result{RequeueAfter: etcdRestoreRequeueAfter}, nil
+	}
+	restored, err := workloadCluster.RestoredEtcdSnapshot(ctx)
+	if err != nil || restored != snapshotName {
+		log.V(2).Info("Waiting for etcd to be restored", "snapshot", snapshotName, "machine", machine.Name)
+		return ctrl.Result{RequeueAfter: etcdRestoreRequeueAfter}, nil
+	}
+
+	// The nodes of the machines that existed when the snapshot was taken are restored as well.
+	removed, err := workloadCluster.RemoveStaleControlPlaneNodes(ctx, []string{machine.Status.NodeRef.Name})
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to remove control plane nodes restored from the etcd snapshot")
+	}
+	if len(removed) > 0 {
+		log.Info("Removed control plane nodes restored from the etcd snapshot", "nodes", removed)
+	}
+
+	if err := r.deleteEtcdRestoreSecret(ctx, controlPlane); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Remove the annotation from the machine, so a later restore from the same snapshot does not consider it restored.
+	patchHelper, err := patch.NewHelper(machine, r.Client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	delete(machine.Annotations, controlplanev1.RestoredEtcdSnapshotAnnotation)
+	if err := patchHelper.Patch(ctx, machine); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to patch machine %s", machine.Name)
+	}
+
+	delete(kcp.Annotations, controlplanev1.RestoreEtcdSnapshotAnnotation)
+	conditions.MarkTrue(kcp, controlplanev1.EtcdRestoredCondition)
+	log.Info("Restored etcd from snapshot", "snapshot", snapshotName, "machine", machine.Name)
+	r.recorder.Eventf(kcp, corev1.EventTypeNormal, "EtcdRestored", "Restored etcd from snapshot %s", snapshotName)
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// etcdSnapshotURL checks the snapshot exists and returns a URL the first control plane machine can download it from;
+// only snapshots stored in the storage configured in the KCP etcd backup policy can be restored, and the storage must
+// support it.
+func (r *KubeadmControlPlaneReconciler) etcdSnapshotURL(ctx context.Context, controlPlane *internal.ControlPlane, snapshotName string) (string, error) {
+	kcp := controlPlane.KCP
+	if !controlPlane.IsEtcdManaged() {
+		return "", errors.New("etcd is not managed by the KubeadmControlPlane")
+	}
+	if kcp.Spec.EtcdBackup == nil {
+		return "", errors.New("an etcd backup policy is required to locate the snapshot")
+	}
+
+	storageType := kcp.Spec.EtcdBackup.Storage.Type
+	store, err := r.etcdBackupStore(ctx, controlPlane, storageType)
+	if err != nil {
+		return "", err
+	}
+	urlStore, ok := store.(backup.URLStore)
+	if !ok {
+		return "", errors.Errorf("restoring snapshots from the %s etcd backup storage is not supported", storageType)
+	}
+	if _, err := store.Stat(ctx, snapshotName); err != nil {
+		return "", err
+	}
+	return urlStore.DownloadURL(ctx, snapshotName, etcdRestoreURLExpiry)
+}
+
+// deleteMachineForEtcdRestore deletes a machine skipping the drain of its node and the wait for its volumes to be
+// detached, as the API server of the workload cluster may not be reachable.
+func (r *KubeadmControlPlaneReconciler) deleteMachineForEtcdRestore(ctx context.Context, machine *clusterv1.Machine) error {
+	patchHelper, err := patch.NewHelper(machine, r.Client)
+	if err != nil {
+		return err
+	}
+	annotations := machine.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[clusterv1.ExcludeNodeDrainingAnnotation] = ""
+	annotations[clusterv1.ExcludeWaitForNodeVolumeDetachAnnotation] = ""
+	machine.SetAnnotations(annotations)
+	if err := patchHelper.Patch(ctx, machine); err != nil {
+		return errors.Wrapf(err, "failed to patch machine %s", machine.Name)
+	}
+	if err := r.Client.Delete(ctx, machine); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete machine %s", machine.Name)
+	}
+	return nil
+}
+
+// reconcileEtcdRestoreSecret creates or updates the Secret holding the restore script, and returns its name.
+func (r *KubeadmControlPlaneReconciler) reconcileEtcdRestoreSecret(ctx context.Context, controlPlane *internal.ControlPlane, snapshotName, snapshotURL string) (string, error) {
+	var script bytes.Buffer
+	if err := etcdRestoreScript.Execute(&script, map[string]string{
+		"URL":      snapshotURL,
+		"Key":      internal.EtcdRestoredSnapshotKey,
+		"Snapshot": snapshotName,
+	}); err != nil {
+		return "", errors.Wrap(err, "failed to generate etcd restore script")
+	}
+
+	kcp := controlPlane.KCP
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      etcdRestoreSecretName(controlPlane.Cluster.Name),
+			Namespace: kcp.Namespace,
+		},
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, s, func() error {
+		s.Labels = map[string]string{clusterv1.ClusterLabelName: controlPlane.Cluster.Name}
+		s.Type = clusterv1.ClusterSecretType
+		s.Data = map[string][]byte{etcdRestoreScriptKey: script.Bytes()}
+		return controllerutil.SetControllerReference(kcp, s, r.Client.Scheme())
+	}); err != nil {
+		return "", errors.Wrap(err, "failed to reconcile etcd restore Secret")
+	}
+	return s.Name, nil
+}
+
+func (r *KubeadmControlPlaneReconciler) deleteEtcdRestoreSecret(ctx context.Context, controlPlane *internal.ControlPlane) error {
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      etcdRestoreSecretName(controlPlane.Cluster.Name),
+			Namespace: controlPlane.KCP.Namespace,
+		},
+	}
+	if err := r.Client.Delete(ctx, s); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete etcd restore Secret")
+	}
+	return nil
+}
+
+func etcdRestoreSecretName(clusterName string) string {
+	return clusterName + "-etcd-restore"
+}
+
+// hasRestoredEtcdSnapshot returns a filter to find the machines bootstrapped from the given etcd snapshot.
+func hasRestoredEtcdSnapshot(snapshotName string) collections.Func {
+	return func(machine *clusterv1.Machine) bool {
+		return machine != nil && machine.Annotations[controlplanev1.RestoredEtcdSnapshotAnnotation] == snapshotName
+	}
+}
+
+// isReinitializingForEtcdRestore returns true if an etcd restore is requested and no machine has been bootstrapped
+// from the snapshot yet, i.e. the control plane is going to be initialized again by the first machine restoring etcd.
+func isReinitializingForEtcdRestore(kcp *controlplanev1.KubeadmControlPlane, machines collections.Machines) bool {
+	snapshotName, ok := kcp.Annotations[controlplanev1.RestoreEtcdSnapshotAnnotation]
+	if !ok {
+		return false
+	}
+	restored := machines.Filter(hasRestoredEtcdSnapshot(snapshotName))
+	for _, m := range restored {
+		if m.Status.NodeRef != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1alpha4"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileEtcdRestore(t *testing.T) {
+	// The S3 server only serves the snapshot etcd-snapshot-1.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/etcd-snapshot-1.db") {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	setup := func(g *WithT, workload fakeWorkloadCluster, machines ...*clusterv1.Machine) (*KubeadmControlPlaneReconciler, *internal.ControlPlane, client.Client) {
+		cluster, kcp, genericMachineTemplate := createClusterWithControlPlane()
+		kcp.Annotations = map[string]string{controlplanev1.RestoreEtcdSnapshotAnnotation: "etcd-snapshot-1"}
+		kcp.Spec.EtcdBackup = &controlplanev1.EtcdBackup{
+			Schedule: "0 * * * *",
+			Storage: controlplanev1.EtcdBackupStorage{
+				Type: controlplanev1.S3EtcdBackupStorageType,
+				S3: &controlplanev1.S3EtcdBackupStorage{
+					Endpoint:          server.URL,
+					Region:            "us-east-1",
+					Bucket:            "bucket",
+					CredentialsSecret: corev1.LocalObjectReference{Name: "s3-credentials"},
+				},
+			},
+		}
+		credentials := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "s3-credentials", Namespace: kcp.Namespace},
+			Data:       map[string][]byte{"accessKeyID": []byte("access-key"), "secretAccessKey": []byte("secret-key")},
+		}
+
+		objs := []client.Object{cluster.DeepCopy(), kcp.DeepCopy(), genericMachineTemplate.DeepCopy(), credentials}
+		for _, m := range machines {
+			m.Namespace = kcp.Namespace
+			m.Labels = internal.ControlPlaneLabelsForCluster(cluster.Name)
+			objs = append(objs, m)
+		}
+		fakeClient := newFakeClient(g, objs...)
+
+		r := &KubeadmControlPlaneReconciler{
+			Client:            fakeClient,
+			recorder:          record.NewFakeRecorder(32),
+			managementCluster: &fakeManagementCluster{Workload: workload},
+		}
+		controlPlane := &internal.ControlPlane{Cluster: cluster, KCP: kcp, Machines: collections.FromMachines(machines...)}
+		return r, controlPlane, fakeClient
+	}
+	restoreMachine := func(nodeName string) *clusterv1.Machine {
+		m := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "restore",
+				Annotations: map[string]string{controlplanev1.RestoredEtcdSnapshotAnnotation: "etcd-snapshot-1"},
+			},
+		}
+		if nodeName != "" {
+			m.Status.NodeRef = &corev1.ObjectReference{Kind: "Node", Name: nodeName}
+		}
+		return m
+	}
+
+	t.Run("does nothing if no restore is requested", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, _ := setup(g, fakeWorkloadCluster{})
+		delete(controlPlane.KCP.Annotations, controlplanev1.RestoreEtcdSnapshotAnnotation)
+
+		result, err := r.reconcileEtcdRestore(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.IsZero()).To(BeTrue())
+		g.Expect(conditions.Has(controlPlane.KCP, controlplanev1.EtcdRestoredCondition)).To(BeFalse())
+	})
+
+	t.Run("blocks without deleting machines if the snapshot cannot be restored", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, fakeClient := setup(g, fakeWorkloadCluster{}, &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "old"}})
+		controlPlane.KCP.Spec.EtcdBackup.Storage.Type = controlplanev1.SecretEtcdBackupStorageType
+
+		result, err := r.reconcileEtcdRestore(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(etcdRestoreRequeueAfter))
+		g.Expect(conditions.GetReason(controlPlane.KCP, controlplanev1.EtcdRestoredCondition)).To(Equal(controlplanev1.EtcdRestoreFailedReason))
+
+		machines := &clusterv1.MachineList{}
+		g.Expect(fakeClient.List(ctx, machines)).To(Succeed())
+		g.Expect(machines.Items).To(HaveLen(1))
+	})
+
+	t.Run("reports a snapshot that does not exist", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, _ := setup(g, fakeWorkloadCluster{})
+		controlPlane.KCP.Annotations[controlplanev1.RestoreEtcdSnapshotAnnotation] = "etcd-snapshot-2"
+
+		result, err := r.reconcileEtcdRestore(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(etcdRestoreRequeueAfter))
+		g.Expect(conditions.GetReason(controlPlane.KCP, controlplanev1.EtcdRestoredCondition)).To(Equal(controlplanev1.EtcdRestoreFailedReason))
+	})
+
+	t.Run("deletes the existing machines", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, fakeClient := setup(g, fakeWorkloadCluster{}, &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "old"}})
+		controlPlane.KCP.Annotations[controlplanev1.RemediationInProgressAnnotation] = `{"machine":"unhealthy"}`
+
+		result, err := r.reconcileEtcdRestore(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(deleteRequeueAfter))
+		g.Expect(conditions.GetReason(controlPlane.KCP, controlplanev1.EtcdRestoredCondition)).To(Equal(controlplanev1.DeletingMachinesForEtcdRestoreReason))
+		g.Expect(controlPlane.KCP.Annotations).ToNot(HaveKey(controlplanev1.RemediationInProgressAnnotation))
+
+		machines := &clusterv1.MachineList{}
+		g.Expect(fakeClient.List(ctx, machines)).To(Succeed())
+		g.Expect(machines.Items).To(BeEmpty())
+	})
+
+	t.Run("skips the drain and the wait for volumes to be detached of the machines deleted", func(t *testing.T) {
+		g := NewWithT(t)
+		machine := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "old", Namespace: metav1.NamespaceDefault}}
+		fakeClient := newFakeClient(g, machine.DeepCopy())
+		r := &KubeadmControlPlaneReconciler{Client: fakeClient}
+
+		g.Expect(r.deleteMachineForEtcdRestore(ctx, machine)).To(Succeed())
+		g.Expect(machine.Annotations).To(HaveKey(clusterv1.ExcludeNodeDrainingAnnotation))
+		g.Expect(machine.Annotations).To(HaveKey(clusterv1.ExcludeWaitForNodeVolumeDetachAnnotation))
+	})
+
+	t.Run("marks an initialized control plane as not initialized", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, fakeClient := setup(g, fakeWorkloadCluster{})
+		controlPlane.Cluster.Status.ControlPlaneInitialized = true
+		controlPlane.KCP.Status.Initialized = true
+
+		result, err := r.reconcileEtcdRestore(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(preflightFailedRequeueAfter))
+		g.Expect(controlPlane.KCP.Status.Initialized).To(BeFalse())
+
+		// The Cluster status is left to the Cluster controller, and no machine is created until it is reset.
+		cluster := &clusterv1.Cluster{}
+		g.Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(controlPlane.Cluster), cluster)).To(Succeed())
+		g.Expect(cluster.Status.ControlPlaneInitialized).To(BeFalse())
+
+		result, err = r.reconcileEtcdRestore(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(preflightFailedRequeueAfter))
+		machines := &clusterv1.MachineList{}
+		g.Expect(fakeClient.List(ctx, machines)).To(Succeed())
+		g.Expect(machines.Items).To(BeEmpty())
+	})
+
+	t.Run("does not delete any machine if a machine is protected from deletion", func(t *testing.T) {
+		g := NewWithT(t)
+		protected := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{
+			Name:        "protected",
+			Annotations: map[string]string{clusterv1.MachineDeletionProtectionAnnotation: ""},
+		}}
+		r, controlPlane, fakeClient := setup(g, fakeWorkloadCluster{}, &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "old"}}, protected)
+
+		result, err := r.reconcileEtcdRestore(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(etcdRestoreRequeueAfter))
+		g.Expect(conditions.GetReason(controlPlane.KCP, controlplanev1.EtcdRestoredCondition)).To(Equal(controlplanev1.EtcdRestoreFailedReason))
+		g.Expect(conditions.GetMessage(controlPlane.KCP, controlplanev1.EtcdRestoredCondition)).To(ContainSubstring("protected"))
+
+		machines := &clusterv1.MachineList{}
+		g.Expect(fakeClient.List(ctx, machines)).To(Succeed())
+		g.Expect(machines.Items).To(HaveLen(2))
+	})
+
+	t.Run("does not reinitialize the control plane once a machine restored etcd", func(t *testing.T) {
+		g := NewWithT(t)
+		_, controlPlane, _ := setup(g, fakeWorkloadCluster{})
+		g.Expect(isReinitializingForEtcdRestore(controlPlane.KCP, collections.New())).To(BeTrue())
+		g.Expect(isReinitializingForEtcdRestore(controlPlane.KCP, collections.FromMachines(restoreMachine("")))).To(BeTrue())
+		g.Expect(isReinitializingForEtcdRestore(controlPlane.KCP, collections.FromMachines(restoreMachine("node-1")))).To(BeFalse())
+
+		delete(controlPlane.KCP.Annotations, controlplanev1.RestoreEtcdSnapshotAnnotation)
+		g.Expect(isReinitializingForEtcdRestore(controlPlane.KCP, collections.New())).To(BeFalse())
+	})
+
+	t.Run("creates the first machine restoring etcd from the snapshot", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, fakeClient := setup(g, fakeWorkloadCluster{})
+		controlPlane.KCP.Spec.KubeadmConfigSpec.PostKubeadmCommands = []string{"echo done"}
+
+		result, err := r.reconcileEtcdRestore(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.Requeue).To(BeTrue())
+		g.Expect(conditions.GetReason(controlPlane.KCP, controlplanev1.EtcdRestoredCondition)).To(Equal(controlplanev1.RestoringEtcdReason))
+
+		machines := &clusterv1.MachineList{}
+		g.Expect(fakeClient.List(ctx, machines)).To(Succeed())
+		g.Expect(machines.Items).To(HaveLen(1))
+		g.Expect(machines.Items[0].Annotations).To(HaveKeyWithValue(controlplanev1.RestoredEtcdSnapshotAnnotation, "etcd-snapshot-1"))
+
+		config := &bootstrapv1.KubeadmConfig{}
+		configKey := client.ObjectKey{Namespace: controlPlane.KCP.Namespace, Name: machines.Items[0].Spec.Bootstrap.ConfigRef.Name}
+		g.Expect(fakeClient.Get(ctx, configKey, config)).To(Succeed())
+		g.Expect(config.Spec.PostKubeadmCommands).To(Equal([]string{"/bin/sh " + etcdRestoreScriptPath, "echo done"}))
+		g.Expect(config.Spec.Files).To(HaveLen(1))
+		g.Expect(config.Spec.Files[0].Path).To(Equal(etcdRestoreScriptPath))
+
+		s := &corev1.Secret{}
+		secretKey := client.ObjectKey{Namespace: controlPlane.KCP.Namespace, Name: config.Spec.Files[0].ContentFrom.Secret.Name}
+		g.Expect(fakeClient.Get(ctx, secretKey, s)).To(Succeed())
+		script := string(s.Data[config.Spec.Files[0].ContentFrom.Secret.Key])
+		g.Expect(script).To(ContainSubstring(server.URL + "/bucket/default/" + controlPlane.Cluster.Name + "/etcd-snapshot-1.db?"))
+		g.Expect(script).To(ContainSubstring("X-Amz-Signature="))
+		g.Expect(script).To(ContainSubstring("put '" + internal.EtcdRestoredSnapshotKey + "' 'etcd-snapshot-1'"))
+	})
+
+	t.Run("waits for etcd to be restored", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, _ := setup(g, fakeWorkloadCluster{}, restoreMachine("node-1"))
+
+		result, err := r.reconcileEtcdRestore(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(etcdRestoreRequeueAfter))
+		g.Expect(controlPlane.KCP.Annotations).To(HaveKey(controlplanev1.RestoreEtcdSnapshotAnnotation))
+		g.Expect(conditions.GetReason(controlPlane.KCP, controlplanev1.EtcdRestoredCondition)).To(Equal(controlplanev1.RestoringEtcdReason))
+	})
+
+	t.Run("completes the restore", func(t *testing.T) {
+		g := NewWithT(t)
+		r, controlPlane, fakeClient := setup(g, fakeWorkloadCluster{RestoredSnapshot: "etcd-snapshot-1"}, restoreMachine("node-1"))
+
+		result, err := r.reconcileEtcdRestore(ctx, controlPlane)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.Requeue).To(BeTrue())
+		g.Expect(controlPlane.KCP.Annotations).ToNot(HaveKey(controlplanev1.RestoreEtcdSnapshotAnnotation))
+		g.Expect(conditions.IsTrue(controlPlane.KCP, controlplanev1.EtcdRestoredCondition)).To(BeTrue())
+
+		machine := &clusterv1.Machine{}
+		g.Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: controlPlane.KCP.Namespace, Name: "restore"}, machine)).To(Succeed())
+		g.Expect(machine.Annotations).ToNot(HaveKey(controlplanev1.RestoredEtcdSnapshotAnnotation))
+	})
+}
@@ -17,9 +17,17 @@ limitations under the License.
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"io/ioutil"
+	"time"
+
 	"github.com/blang/semver"
+	"github.com/pkg/errors"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	kubeadmv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/types/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
 	"sigs.k8s.io/cluster-api/util/collections"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -54,8 +62,15 @@ func (f *fakeManagementCluster) GetMachinesForCluster(c context.Context, n clien
 
 type fakeWorkloadCluster struct {
 	*internal.Workload
-	Status            internal.ClusterStatus
-	EtcdMembersResult []string
+	Status                   internal.ClusterStatus
+	EtcdMembersResult        []string
+	EtcdLeaderResult         string
+	EtcdSnapshotData         []byte
+	RestoredSnapshot         string
+	CertificatesExpiryResult map[string]time.Time
+	EtcdMemberStatusesResult []internal.EtcdMemberStatus
+	DefragmentEtcdMemberErr  error
+	NodesCreatedBeforeResult []string
 }
 
 func (f fakeWorkloadCluster) ForwardEtcdLeadership(_ context.Context, _ *clusterv1.Machine, _ *clusterv1.Machine) error {
@@ -86,6 +101,22 @@ func (f fakeWorkloadCluster) UpdateKubernetesVersionInKubeadmConfigMap(ctx conte
 	return nil
 }
 
+func (f fakeWorkloadCluster) UpdateImageRepositoryInKubeadmConfigMap(ctx context.Context, imageRepository string) error {
+	return nil
+}
+
+func (f fakeWorkloadCluster) UpdateAPIServerInKubeadmConfigMap(ctx context.Context, apiServer kubeadmv1.APIServer) error {
+	return nil
+}
+
+func (f fakeWorkloadCluster) UpdateControllerManagerInKubeadmConfigMap(ctx context.Context, controllerManager kubeadmv1.ControlPlaneComponent) error {
+	return nil
+}
+
+func (f fakeWorkloadCluster) UpdateSchedulerInKubeadmConfigMap(ctx context.Context, scheduler kubeadmv1.ControlPlaneComponent) error {
+	return nil
+}
+
 func (f fakeWorkloadCluster) UpdateEtcdVersionInKubeadmConfigMap(ctx context.Context, imageRepository, imageTag string) error {
 	return nil
 }
@@ -106,6 +137,52 @@ func (f fakeWorkloadCluster) EtcdMembers(_ context.Context) ([]string, error) {
 	return f.EtcdMembersResult, nil
 }
 
+func (f fakeWorkloadCluster) EtcdLeader(_ context.Context) (string, error) {
+	if f.EtcdLeaderResult == "" {
+		return "", errors.New("failed to find the etcd leader")
+	}
+	return f.EtcdLeaderResult, nil
+}
+
+func (f fakeWorkloadCluster) EtcdSnapshot(_ context.Context) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(f.EtcdSnapshotData)), nil
+}
+
+func (f fakeWorkloadCluster) RestoredEtcdSnapshot(_ context.Context) (string, error) {
+	return f.RestoredSnapshot, nil
+}
+
+func (f fakeWorkloadCluster) RemoveStaleControlPlaneNodes(_ context.Context, _ []string) ([]string, error) {
+	return nil, nil
+}
+
+func (f fakeWorkloadCluster) CertificatesExpiry(_ context.Context, nodeName string, _ *controlplanev1.KubeadmControlPlane) (time.Time, error) {
+	expiry, ok := f.CertificatesExpiryResult[nodeName]
+	if !ok {
+		return time.Time{}, errors.Errorf("node %s not found", nodeName)
+	}
+	return expiry, nil
+}
+
+func (f fakeWorkloadCluster) UpdateClusterInfoCertificateAuthorities(_ context.Context, _ []byte) error {
+	return nil
+}
+
+func (f fakeWorkloadCluster) NodesCreatedBefore(_ context.Context, _ time.Time) ([]string, error) {
+	return f.NodesCreatedBeforeResult, nil
+}
+
+func (f fakeWorkloadCluster) EtcdMemberStatuses(_ context.Context, _ []string) ([]internal.EtcdMemberStatus, error) {
+	if f.EtcdMemberStatusesResult == nil {
+		return nil, errors.New("etcd members unavailable")
+	}
+	return f.EtcdMemberStatusesResult, nil
+}
+
+func (f fakeWorkloadCluster) DefragmentEtcdMember(_ context.Context, _ string) error {
+	return f.DefragmentEtcdMemberErr
+}
+
 type fakeMigrator struct {
 	migrateCalled    bool
 	migrateErr       error
@@ -18,13 +18,20 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
 	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/collections"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -36,6 +43,11 @@ import (
 func (r *KubeadmControlPlaneReconciler) reconcileUnhealthyMachines(ctx context.Context, controlPlane *internal.ControlPlane) (ret ctrl.Result, retErr error) {
 	log := ctrl.LoggerFrom(ctx)
 
+	// Complete a previous remediation whose replacement is not going to be created.
+	if err := completeRemediationWithoutReplacement(log, controlPlane); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Gets all machines that have `MachineHealthCheckSucceeded=False` (indicating a problem was detected on the machine)
 	// and `MachineOwnerRemediated` present, indicating that this controller is responsible for performing remediation.
 	unhealthyMachines := controlPlane.UnhealthyMachines()
@@ -45,15 +57,28 @@ func (r *KubeadmControlPlaneReconciler) reconcileUnhealthyMachines(ctx context.C
 		return ctrl.Result{}, nil
 	}
 
-	// Select the machine to be remediated, which is the oldest machine marked as unhealthy.
-	//
-	// NOTE: The current solution is considered acceptable for the most frequent use case (only one unhealthy machine),
-	// however, in the future this could potentially be improved for the scenario where more than one unhealthy machine exists
-	// by considering which machine has lower impact on etcd quorum.
-	machineToBeRemediated := unhealthyMachines.Oldest()
+	// Returns if an unhealthy machine is in the process of being deleted.
+	if len(unhealthyMachines.Filter(collections.HasDeletionTimestamp)) > 0 {
+		return ctrl.Result{}, nil
+	}
 
-	// Returns if the machine is in the process of being deleted.
-	if !machineToBeRemediated.ObjectMeta.DeletionTimestamp.IsZero() {
+	// Machines protected from deletion are never remediated.
+	if protected := unhealthyMachines.Filter(collections.IsDeletionProtected); len(protected) > 0 {
+		log.Info("Unhealthy machines are protected from deletion, skipping their remediation", "protected", protected.Names())
+		unhealthyMachines = unhealthyMachines.Filter(collections.Not(collections.IsDeletionProtected))
+		if len(unhealthyMachines) == 0 {
+			return ctrl.Result{}, nil
+		}
+	}
+
+	// Select the machine to be remediated, i.e. the one whose remediation has the lower impact on etcd quorum
+	// and on the failure domains spread, and keep track of the reasons of the choice.
+	machineToBeRemediated, selectionReason := r.selectMachineForRemediation(ctx, controlPlane, unhealthyMachines)
+
+	// Returns if a previous remediation is in progress, i.e. the replacement of the remediated machine has not been
+	// created yet; this ensures KCP remediates one machine at a time.
+	if _, ok := controlPlane.KCP.Annotations[controlplanev1.RemediationInProgressAnnotation]; ok {
+		log.Info("Another remediation is already in progress. Skipping remediation", "UnhealthyMachine", machineToBeRemediated.Name)
 		return ctrl.Result{}, nil
 	}
 
@@ -77,6 +102,16 @@ func (r *KubeadmControlPlaneReconciler) reconcileUnhealthyMachines(ctx context.C
 	// Before starting remediation, run preflight checks in order to verify it is safe to remediate.
 	// If any of the following checks fails, we'll surface the reason in the MachineOwnerRemediated condition.
 
+	// Check if KCP is allowed to remediate considering the retry limits of the remediation strategy.
+	remediationInProgress, canRemediate, err := checkRetryLimits(log, machineToBeRemediated, controlPlane, time.Now())
+	if err != nil {
+		conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.RemediationFailedReason, clusterv1.ConditionSeverityError, err.Error())
+		return ctrl.Result{}, err
+	}
+	if !canRemediate {
+		return ctrl.Result{}, nil
+	}
+
 	desiredReplicas := int(*controlPlane.KCP.Spec.Replicas)
 
 	// The cluster MUST have spec.replicas >= 3, because this is the smallest cluster size that allows any etcd failure tolerance.
@@ -145,11 +180,225 @@ func (r *KubeadmControlPlaneReconciler) reconcileUnhealthyMachines(ctx context.C
 		return ctrl.Result{}, errors.Wrapf(err, "failed to delete unhealthy machine %s", machineToBeRemediated.Name)
 	}
 
-	log.Info("Remediating unhealthy machine", "UnhealthyMachine", machineToBeRemediated.Name)
-	conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.RemediationInProgressReason, clusterv1.ConditionSeverityWarning, "")
+	log.Info("Remediating unhealthy machine", "UnhealthyMachine", machineToBeRemediated.Name, "RetryCount", remediationInProgress.RetryCount)
+	conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.RemediationInProgressReason, clusterv1.ConditionSeverityWarning, "%s", selectionReason)
+
+	// Track the remediation on KCP, so it can be carried over to the replacement machine created by the next scale up.
+	value, err := remediationInProgress.marshal()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if controlPlane.KCP.Annotations == nil {
+		controlPlane.KCP.Annotations = map[string]string{}
+	}
+	controlPlane.KCP.Annotations[controlplanev1.RemediationInProgressAnnotation] = value
+
 	return ctrl.Result{Requeue: true}, nil
 }
 
+// completeRemediationWithoutReplacement removes the RemediationInProgressAnnotation from KCP once the remediated machine
+// is gone and the control plane does not need a replacement for it anymore, e.g. because the replicas have been lowered;
+// otherwise the annotation is removed by the scale up creating the replacement.
+func completeRemediationWithoutReplacement(log logr.Logger, controlPlane *internal.ControlPlane) error {
+	value, ok := controlPlane.KCP.Annotations[controlplanev1.RemediationInProgressAnnotation]
+	if !ok {
+		return nil
+	}
+	remediationInProgress, err := remediationDataFromAnnotation(value)
+	if err != nil {
+		return err
+	}
+	if _, ok := controlPlane.Machines[remediationInProgress.Machine]; ok || controlPlane.Machines.Len() < int(*controlPlane.KCP.Spec.Replicas) {
+		return nil
+	}
+
+	log.Info("Remediated machine is gone and no replacement is needed, completing the remediation", "RemediatedMachine", remediationInProgress.Machine)
+	delete(controlPlane.KCP.Annotations, controlplanev1.RemediationInProgressAnnotation)
+	return nil
+}
+
+// selectMachineForRemediation selects the unhealthy machine to be remediated first, and returns it together with
+// the reasons of the choice when there is more than one unhealthy machine.
+//
+// Machines are preferred, in order, when:
+// - removing their etcd member preserves etcd quorum;
+// - their etcd member is already unhealthy, so removing it does not reduce the etcd fault tolerance;
+// - they do not host the etcd leader, so no leadership change is required;
+// - they are in the failure domain with the most control plane machines, so failure domains stay balanced;
+// - they are older.
+// The etcd related criteria are ignored if the etcd cluster can't be inspected.
+func (r *KubeadmControlPlaneReconciler) selectMachineForRemediation(ctx context.Context, controlPlane *internal.ControlPlane, unhealthyMachines collections.Machines) (*clusterv1.Machine, string) {
+	log := ctrl.LoggerFrom(ctx)
+
+	if unhealthyMachines.Len() == 1 {
+		return unhealthyMachines.Oldest(), ""
+	}
+
+	var etcdMembers []string
+	etcdLeader := ""
+	if controlPlane.IsEtcdManaged() {
+		workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, util.ObjectKey(controlPlane.Cluster))
+		if err != nil {
+			log.V(2).Info("cannot get remote client to workload cluster, ignoring etcd when selecting the machine to be remediated", "cause", err)
+		} else {
+			if etcdMembers, err = workloadCluster.EtcdMembers(ctx); err != nil {
+				log.V(2).Info("cannot get the etcd members, ignoring etcd quorum when selecting the machine to be remediated", "cause", err)
+			}
+			if etcdLeader, err = workloadCluster.EtcdLeader(ctx); err != nil {
+				log.V(2).Info("cannot get the etcd leader, ignoring etcd leadership when selecting the machine to be remediated", "cause", err)
+			}
+		}
+	}
+
+	var failureDomain *string
+	if len(controlPlane.FailureDomains().FilterControlPlane()) > 0 {
+		failureDomain = controlPlane.FailureDomainWithMostMachines(unhealthyMachines)
+	}
+
+	type candidate struct {
+		machine                 *clusterv1.Machine
+		preservesQuorum         bool
+		etcdMemberUnhealthy     bool
+		etcdLeader              bool
+		inMostUsedFailureDomain bool
+	}
+	candidates := []candidate{}
+	for _, m := range unhealthyMachines.SortedByCreationTimestamp() {
+		c := candidate{
+			machine:             m,
+			preservesQuorum:     etcdMembers == nil || canSafelyRemoveEtcdMemberFrom(log.V(4), controlPlane, etcdMembers, m),
+			etcdMemberUnhealthy: controlPlane.IsEtcdManaged() && !conditions.IsTrue(m, controlplanev1.MachineEtcdMemberHealthyCondition),
+		}
+		if etcdLeader != "" && m.Status.NodeRef != nil {
+			c.etcdLeader = m.Status.NodeRef.Name == etcdLeader
+		}
+		if failureDomain != nil && m.Spec.FailureDomain != nil {
+			c.inMostUsedFailureDomain = *m.Spec.FailureDomain == *failureDomain
+		}
+		candidates = append(candidates, c)
+	}
+
+	// Sort the candidates by preference; the sort is stable so older machines come first among equally preferred ones.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.preservesQuorum != b.preservesQuorum {
+			return a.preservesQuorum
+		}
+		if a.etcdMemberUnhealthy != b.etcdMemberUnhealthy {
+			return a.etcdMemberUnhealthy
+		}
+		if a.etcdLeader != b.etcdLeader {
+			return !a.etcdLeader
+		}
+		return a.inMostUsedFailureDomain && !b.inMostUsedFailureDomain
+	})
+
+	selected := candidates[0]
+	reasons := []string{}
+	if etcdMembers != nil && selected.preservesQuorum {
+		reasons = append(reasons, "removing its etcd member preserves etcd quorum")
+	}
+	if selected.etcdMemberUnhealthy {
+		reasons = append(reasons, "its etcd member is not healthy")
+	}
+	if etcdLeader != "" && !selected.etcdLeader {
+		reasons = append(reasons, "it does not host the etcd leader")
+	}
+	if selected.inMostUsedFailureDomain {
+		reasons = append(reasons, fmt.Sprintf("failure domain %s has the most control plane machines", *failureDomain))
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, "it is the oldest unhealthy machine")
+	}
+	reason := fmt.Sprintf("Selected for remediation among %d unhealthy machines because %s", len(candidates), strings.Join(reasons, ", "))
+
+	log.Info("Selected the control plane machine to be remediated", "UnhealthyMachine", selected.machine.Name, "Reason", reason)
+	return selected.machine, reason
+}
+
+// remediationData stores the details of a remediation in the RemediationInProgressAnnotation of KCP and in the
+// RemediationForAnnotation of the machine created as a replacement of the remediated one.
+type remediationData struct {
+	// Machine is the name of the remediated machine.
+	Machine string `json:"machine"`
+
+	// Timestamp is when the remediation happened.
+	Timestamp metav1.Time `json:"timestamp"`
+
+	// RetryCount is the number of consecutive remediations of the replacements of the first unhealthy machine
+	// of the sequence; it is 0 for the first remediation.
+	RetryCount int `json:"retryCount"`
+}
+
+func remediationDataFromAnnotation(value string) (*remediationData, error) {
+	data := &remediationData{}
+	if err := json.Unmarshal([]byte(value), data); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal value %q of the %s annotation", value, controlplanev1.RemediationForAnnotation)
+	}
+	return data, nil
+}
+
+func (d *remediationData) marshal() (string, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal remediation data")
+	}
+	return string(b), nil
+}
+
+// checkRetryLimits checks if the machine to be remediated can be remediated according to the KCP remediation strategy,
+// and returns the details of the remediation to be tracked.
+//
+// A machine created as a replacement of a remediated machine which becomes unhealthy within MinHealthyPeriod is
+// considered a retry of the same remediation; retries are allowed up to MaxRetry times, each one at least RetryPeriod
+// after the previous one. Otherwise a new remediation sequence starts with a retry count of 0.
+func checkRetryLimits(log logr.Logger, machineToBeRemediated *clusterv1.Machine, controlPlane *internal.ControlPlane, now time.Time) (*remediationData, bool, error) {
+	remediationInProgress := &remediationData{
+		Machine:   machineToBeRemediated.Name,
+		Timestamp: metav1.NewTime(now),
+	}
+
+	// If the machine has not been created by a remediation, this is the first remediation of a new sequence.
+	value, ok := machineToBeRemediated.Annotations[controlplanev1.RemediationForAnnotation]
+	if !ok {
+		return remediationInProgress, true, nil
+	}
+	lastRemediation, err := remediationDataFromAnnotation(value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	strategy := controlPlane.KCP.Spec.RemediationStrategy
+	minHealthyPeriod := defaultRemediationMinHealthyPeriod
+	retryPeriod := time.Duration(0)
+	if strategy != nil {
+		if strategy.MinHealthyPeriod != nil {
+			minHealthyPeriod = strategy.MinHealthyPeriod.Duration
+		}
+		retryPeriod = strategy.RetryPeriod.Duration
+	}
+
+	// If the machine stayed healthy for at least MinHealthyPeriod, the retry count is reset.
+	if !lastRemediation.Timestamp.Add(minHealthyPeriod).After(now) {
+		return remediationInProgress, true, nil
+	}
+	remediationInProgress.RetryCount = lastRemediation.RetryCount + 1
+
+	if lastRemediation.Timestamp.Add(retryPeriod).After(now) {
+		log.Info(fmt.Sprintf("A control plane machine needs remediation, but the remediation of the machine it replaces happened in the latest %s. Skipping remediation", retryPeriod), "UnhealthyMachine", machineToBeRemediated.Name, "RemediatedMachine", lastRemediation.Machine)
+		conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "KCP can't remediate this machine because the machine it replaces has been remediated in the latest %s (RetryPeriod)", retryPeriod)
+		return remediationInProgress, false, nil
+	}
+
+	if strategy != nil && strategy.MaxRetry != nil && remediationInProgress.RetryCount > int(*strategy.MaxRetry) {
+		log.Info(fmt.Sprintf("A control plane machine needs remediation, but the remediation already has been retried %d times. Skipping remediation", *strategy.MaxRetry), "UnhealthyMachine", machineToBeRemediated.Name, "RemediatedMachine", lastRemediation.Machine)
+		conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "KCP can't remediate this machine because the remediation already has been retried %d times (MaxRetry)", *strategy.MaxRetry)
+		return remediationInProgress, false, nil
+	}
+
+	return remediationInProgress, true, nil
+}
+
 // canSafelyRemoveEtcdMember assess if it is possible to remove the member hosted on the machine to be remediated
 // without loosing etcd quorum.
 //
@@ -184,6 +433,12 @@ func (r *KubeadmControlPlaneReconciler) canSafelyRemoveEtcdMember(ctx context.Co
 		return false, errors.Wrapf(err, "failed to get etcdStatus for workload cluster %s", controlPlane.Cluster.Name)
 	}
 
+	return canSafelyRemoveEtcdMemberFrom(log, controlPlane, etcdMembers, machineToBeRemediated), nil
+}
+
+// canSafelyRemoveEtcdMemberFrom assess if it is possible to remove the member hosted on the machine to be remediated
+// from the given list of etcd members without loosing etcd quorum; see canSafelyRemoveEtcdMember.
+func canSafelyRemoveEtcdMemberFrom(log logr.Logger, controlPlane *internal.ControlPlane, etcdMembers []string, machineToBeRemediated *clusterv1.Machine) bool {
 	currentTotalMembers := len(etcdMembers)
 
 	log.Info("etcd cluster before remediation",
@@ -196,7 +451,7 @@ func (r *KubeadmControlPlaneReconciler) canSafelyRemoveEtcdMember(ctx context.Co
 	// given that this could be destructive, this is an additional safeguard.
 	if currentTotalMembers < 3 {
 		log.Info("etcd cluster with less of 3 members can't be safely remediated")
-		return false, nil
+		return false
 	}
 
 	targetTotalMembers := currentTotalMembers - 1
@@ -248,8 +503,5 @@ func (r *KubeadmControlPlaneReconciler) canSafelyRemoveEtcdMember(ctx context.Co
 		"targetQuorum", targetQuorum,
 		"targetUnhealthyMembers", targetUnhealthyMembers,
 		"projectedQuorum", targetTotalMembers-targetUnhealthyMembers)
-	if targetTotalMembers-targetUnhealthyMembers >= targetQuorum {
-		return true, nil
-	}
-	return false, nil
+	return targetTotalMembers-targetUnhealthyMembers >= targetQuorum
 }
@@ -20,6 +20,7 @@ import (
 	"context"
 	"sigs.k8s.io/cluster-api/util/collections"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 
@@ -28,11 +29,13 @@ import (
 	"k8s.io/client-go/tools/record"
 	utilpointer "k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/bootstrap/kubeadm/types/v1beta1"
 	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 func TestReconcileUnhealthyMachines(t *testing.T) {
@@ -74,6 +77,86 @@ func TestReconcileUnhealthyMachines(t *testing.T) {
 		g.Expect(ret.IsZero()).To(BeTrue()) // Remediation skipped
 		g.Expect(err).ToNot(HaveOccurred())
 	})
+	t.Run("reconcileUnhealthyMachines return early if the machine to be remediated is protected from deletion", func(t *testing.T) {
+		g := NewWithT(t)
+
+		m := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "m1-unhealthy-protected",
+				Namespace:   ns.Name,
+				Annotations: map[string]string{clusterv1.MachineDeletionProtectionAnnotation: ""},
+			},
+		}
+		conditions.MarkFalse(m, clusterv1.MachineHealthCheckSuccededCondition, clusterv1.MachineHasFailureReason, clusterv1.ConditionSeverityWarning, "")
+		conditions.MarkFalse(m, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "")
+		controlPlane := &internal.ControlPlane{
+			KCP: &controlplanev1.KubeadmControlPlane{
+				Spec: controlplanev1.KubeadmControlPlaneSpec{
+					Replicas: utilpointer.Int32Ptr(3),
+				},
+			},
+			Cluster:  &clusterv1.Cluster{},
+			Machines: collections.FromMachines(m),
+		}
+		ret, err := r.reconcileUnhealthyMachines(context.TODO(), controlPlane)
+
+		g.Expect(ret.IsZero()).To(BeTrue()) // Remediation skipped
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(controlPlane.KCP.Annotations).ToNot(HaveKey(controlplanev1.RemediationInProgressAnnotation))
+	})
+	t.Run("Remediation does not happen if another remediation is in progress", func(t *testing.T) {
+		g := NewWithT(t)
+
+		m := createMachine(ctx, g, ns.Name, "m1-unhealthy-", withMachineHealthCheckFailed())
+		controlPlane := &internal.ControlPlane{
+			KCP: &controlplanev1.KubeadmControlPlane{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						controlplanev1.RemediationInProgressAnnotation: `{"machine":"m0","timestamp":"2021-01-01T00:00:00Z","retryCount":0}`,
+					},
+				},
+				Spec: controlplanev1.KubeadmControlPlaneSpec{
+					Replicas: utilpointer.Int32Ptr(3),
+				},
+			},
+			Cluster:  &clusterv1.Cluster{},
+			Machines: collections.FromMachines(m),
+		}
+
+		ret, err := r.reconcileUnhealthyMachines(context.TODO(), controlPlane)
+
+		g.Expect(ret.IsZero()).To(BeTrue()) // Remediation skipped
+		g.Expect(err).ToNot(HaveOccurred())
+
+		g.Expect(testEnv.Cleanup(ctx, m)).To(Succeed())
+	})
+	t.Run("Remediation does not happen if the remediation already has been retried MaxRetry times", func(t *testing.T) {
+		g := NewWithT(t)
+
+		m := createMachine(ctx, g, ns.Name, "m1-unhealthy-", withMachineHealthCheckFailed(), withRemediationFor(remediationData{
+			Machine:    "m0",
+			Timestamp:  metav1.NewTime(time.Now().Add(-10 * time.Minute)),
+			RetryCount: 1,
+		}))
+		controlPlane := &internal.ControlPlane{
+			KCP: &controlplanev1.KubeadmControlPlane{Spec: controlplanev1.KubeadmControlPlaneSpec{
+				Replicas: utilpointer.Int32Ptr(3),
+				RemediationStrategy: &controlplanev1.RemediationStrategy{
+					MaxRetry: utilpointer.Int32Ptr(1),
+				},
+			}},
+			Cluster:  &clusterv1.Cluster{},
+			Machines: collections.FromMachines(m),
+		}
+
+		ret, err := r.reconcileUnhealthyMachines(context.TODO(), controlPlane)
+
+		g.Expect(ret.IsZero()).To(BeTrue()) // Remediation skipped
+		g.Expect(err).ToNot(HaveOccurred())
+		assertMachineCondition(ctx, g, m, clusterv1.MachineOwnerRemediatedCondition, corev1.ConditionFalse, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "KCP can't remediate this machine because the remediation already has been retried 1 times (MaxRetry)")
+
+		g.Expect(testEnv.Cleanup(ctx, m)).To(Succeed())
+	})
 	t.Run("Remediation does not happen if desired replicas < 3", func(t *testing.T) {
 		g := NewWithT(t)
 
@@ -237,6 +320,11 @@ func TestReconcileUnhealthyMachines(t *testing.T) {
 		g.Expect(err).ToNot(HaveOccurred())
 
 		assertMachineCondition(ctx, g, m1, clusterv1.MachineOwnerRemediatedCondition, corev1.ConditionFalse, clusterv1.RemediationInProgressReason, clusterv1.ConditionSeverityWarning, "")
+		g.Expect(controlPlane.KCP.Annotations).To(HaveKey(controlplanev1.RemediationInProgressAnnotation))
+		remediation, err := remediationDataFromAnnotation(controlPlane.KCP.Annotations[controlplanev1.RemediationInProgressAnnotation])
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(remediation.Machine).To(Equal(m1.Name))
+		g.Expect(remediation.RetryCount).To(Equal(0))
 
 		err = testEnv.Get(ctx, client.ObjectKey{Namespace: m1.Namespace, Name: m1.Name}, m1)
 		g.Expect(err).ToNot(HaveOccurred())
@@ -253,6 +341,316 @@ func TestReconcileUnhealthyMachines(t *testing.T) {
 	g.Expect(testEnv.Cleanup(ctx, ns)).To(Succeed())
 }
 
+func TestSelectMachineForRemediation(t *testing.T) {
+	machine := func(name string, age time.Duration, fd string, options ...machineOption) *clusterv1.Machine {
+		m := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+			},
+		}
+		if fd != "" {
+			m.Spec.FailureDomain = utilpointer.StringPtr(fd)
+		}
+		for _, opt := range append(options, withNodeRef(name)) {
+			opt(m)
+		}
+		return m
+	}
+	failureDomains := clusterv1.FailureDomains{
+		"one":   clusterv1.FailureDomainSpec{ControlPlane: true},
+		"two":   clusterv1.FailureDomainSpec{ControlPlane: true},
+		"three": clusterv1.FailureDomainSpec{ControlPlane: true},
+	}
+
+	tests := []struct {
+		name              string
+		unhealthyMachines []*clusterv1.Machine
+		healthyMachines   []*clusterv1.Machine
+		failureDomains    clusterv1.FailureDomains
+		etcdLeader        string
+		expectMachine     string
+		expectReason      string
+		externalEtcd      bool
+	}{
+		{
+			name: "a single unhealthy machine is selected",
+			unhealthyMachines: []*clusterv1.Machine{
+				machine("m1", time.Hour, "", withMachineHealthCheckFailed(), withHealthyEtcdMember()),
+			},
+			healthyMachines: []*clusterv1.Machine{
+				machine("m2", time.Hour, "", withHealthyEtcdMember()),
+				machine("m3", time.Hour, "", withHealthyEtcdMember()),
+			},
+			expectMachine: "m1",
+			expectReason:  "",
+		},
+		{
+			name: "machines with an unhealthy etcd member are preferred",
+			unhealthyMachines: []*clusterv1.Machine{
+				machine("m1", 3*time.Hour, "", withMachineHealthCheckFailed(), withHealthyEtcdMember()),
+				machine("m2", time.Hour, "", withMachineHealthCheckFailed(), withUnhealthyEtcdMember()),
+			},
+			healthyMachines: []*clusterv1.Machine{
+				machine("m3", time.Hour, "", withHealthyEtcdMember()),
+				machine("m4", time.Hour, "", withHealthyEtcdMember()),
+				machine("m5", time.Hour, "", withHealthyEtcdMember()),
+			},
+			etcdLeader:    "m3",
+			expectMachine: "m2",
+			expectReason:  "Selected for remediation among 2 unhealthy machines because removing its etcd member preserves etcd quorum, its etcd member is not healthy, it does not host the etcd leader",
+		},
+		{
+			name: "machines whose removal breaks etcd quorum are avoided",
+			unhealthyMachines: []*clusterv1.Machine{
+				machine("m1", 3*time.Hour, "", withMachineHealthCheckFailed(), withHealthyEtcdMember()),
+				machine("m2", time.Hour, "", withMachineHealthCheckFailed(), withUnhealthyEtcdMember()),
+			},
+			healthyMachines: []*clusterv1.Machine{
+				machine("m3", time.Hour, "", withHealthyEtcdMember()),
+			},
+			expectMachine: "m2",
+			expectReason:  "Selected for remediation among 2 unhealthy machines because removing its etcd member preserves etcd quorum, its etcd member is not healthy",
+		},
+		{
+			name: "machines not hosting the etcd leader are preferred",
+			unhealthyMachines: []*clusterv1.Machine{
+				machine("m1", 3*time.Hour, "", withMachineHealthCheckFailed(), withHealthyEtcdMember()),
+				machine("m2", time.Hour, "", withMachineHealthCheckFailed(), withHealthyEtcdMember()),
+			},
+			healthyMachines: []*clusterv1.Machine{
+				machine("m3", time.Hour, "", withHealthyEtcdMember()),
+				machine("m4", time.Hour, "", withHealthyEtcdMember()),
+				machine("m5", time.Hour, "", withHealthyEtcdMember()),
+			},
+			etcdLeader:    "m1",
+			expectMachine: "m2",
+			expectReason:  "Selected for remediation among 2 unhealthy machines because removing its etcd member preserves etcd quorum, it does not host the etcd leader",
+		},
+		{
+			name: "machines in the failure domain with most machines are preferred",
+			unhealthyMachines: []*clusterv1.Machine{
+				machine("m1", 3*time.Hour, "one", withMachineHealthCheckFailed(), withHealthyEtcdMember()),
+				machine("m2", time.Hour, "two", withMachineHealthCheckFailed(), withHealthyEtcdMember()),
+			},
+			healthyMachines: []*clusterv1.Machine{
+				machine("m3", time.Hour, "two", withHealthyEtcdMember()),
+				machine("m4", time.Hour, "three", withHealthyEtcdMember()),
+				machine("m5", time.Hour, "three", withHealthyEtcdMember()),
+			},
+			failureDomains: failureDomains,
+			expectMachine:  "m2",
+			expectReason:   "Selected for remediation among 2 unhealthy machines because removing its etcd member preserves etcd quorum, failure domain two has the most control plane machines",
+		},
+		{
+			name: "the oldest machine is selected among equally preferred machines",
+			unhealthyMachines: []*clusterv1.Machine{
+				machine("m1", time.Hour, "", withMachineHealthCheckFailed(), withHealthyEtcdMember()),
+				machine("m2", 3*time.Hour, "", withMachineHealthCheckFailed(), withHealthyEtcdMember()),
+			},
+			healthyMachines: []*clusterv1.Machine{
+				machine("m3", time.Hour, "", withHealthyEtcdMember()),
+			},
+			expectMachine: "m2",
+			expectReason:  "Selected for remediation among 2 unhealthy machines because it is the oldest unhealthy machine",
+			externalEtcd:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			machines := collections.FromMachines(append(tt.unhealthyMachines, tt.healthyMachines...)...)
+			kcp := &controlplanev1.KubeadmControlPlane{}
+			if tt.externalEtcd {
+				kcp.Spec.KubeadmConfigSpec.ClusterConfiguration = &v1beta1.ClusterConfiguration{
+					Etcd: v1beta1.Etcd{External: &v1beta1.ExternalEtcd{}},
+				}
+			}
+			controlPlane := &internal.ControlPlane{
+				KCP:      kcp,
+				Cluster:  &clusterv1.Cluster{Status: clusterv1.ClusterStatus{FailureDomains: tt.failureDomains}},
+				Machines: machines,
+			}
+			r := &KubeadmControlPlaneReconciler{
+				managementCluster: &fakeManagementCluster{
+					Workload: fakeWorkloadCluster{
+						EtcdMembersResult: machines.Names(),
+						EtcdLeaderResult:  tt.etcdLeader,
+					},
+				},
+			}
+
+			m, reason := r.selectMachineForRemediation(ctx, controlPlane, collections.FromMachines(tt.unhealthyMachines...))
+			g.Expect(m.Name).To(Equal(tt.expectMachine))
+			g.Expect(reason).To(Equal(tt.expectReason))
+		})
+	}
+}
+
+func TestCheckRetryLimits(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name               string
+		lastRemediation    *remediationData
+		strategy           *controlplanev1.RemediationStrategy
+		expectCanRemediate bool
+		expectRetryCount   int
+		expectCondition    bool
+	}{
+		{
+			name:               "a machine not created by a remediation can be remediated",
+			expectCanRemediate: true,
+			expectRetryCount:   0,
+		},
+		{
+			name:               "a machine created by a remediation can be remediated as a retry within the default MinHealthyPeriod",
+			lastRemediation:    &remediationData{Machine: "m0", Timestamp: metav1.NewTime(now.Add(-30 * time.Minute)), RetryCount: 2},
+			expectCanRemediate: true,
+			expectRetryCount:   3,
+		},
+		{
+			name:               "the retry count is reset after the default MinHealthyPeriod",
+			lastRemediation:    &remediationData{Machine: "m0", Timestamp: metav1.NewTime(now.Add(-2 * time.Hour)), RetryCount: 2},
+			strategy:           &controlplanev1.RemediationStrategy{MaxRetry: utilpointer.Int32Ptr(1)},
+			expectCanRemediate: true,
+			expectRetryCount:   0,
+		},
+		{
+			name:               "the retry count is reset after MinHealthyPeriod",
+			lastRemediation:    &remediationData{Machine: "m0", Timestamp: metav1.NewTime(now.Add(-30 * time.Minute)), RetryCount: 2},
+			strategy:           &controlplanev1.RemediationStrategy{MaxRetry: utilpointer.Int32Ptr(1), MinHealthyPeriod: &metav1.Duration{Duration: 10 * time.Minute}},
+			expectCanRemediate: true,
+			expectRetryCount:   0,
+		},
+		{
+			name:               "a retry does not happen within RetryPeriod",
+			lastRemediation:    &remediationData{Machine: "m0", Timestamp: metav1.NewTime(now.Add(-5 * time.Minute)), RetryCount: 0},
+			strategy:           &controlplanev1.RemediationStrategy{RetryPeriod: metav1.Duration{Duration: 10 * time.Minute}},
+			expectCanRemediate: false,
+			expectRetryCount:   1,
+			expectCondition:    true,
+		},
+		{
+			name:               "a retry happens after RetryPeriod",
+			lastRemediation:    &remediationData{Machine: "m0", Timestamp: metav1.NewTime(now.Add(-15 * time.Minute)), RetryCount: 0},
+			strategy:           &controlplanev1.RemediationStrategy{RetryPeriod: metav1.Duration{Duration: 10 * time.Minute}},
+			expectCanRemediate: true,
+			expectRetryCount:   1,
+		},
+		{
+			name:               "a retry happens if MaxRetry is not reached",
+			lastRemediation:    &remediationData{Machine: "m0", Timestamp: metav1.NewTime(now.Add(-5 * time.Minute)), RetryCount: 1},
+			strategy:           &controlplanev1.RemediationStrategy{MaxRetry: utilpointer.Int32Ptr(2)},
+			expectCanRemediate: true,
+			expectRetryCount:   2,
+		},
+		{
+			name:               "a retry does not happen if MaxRetry is reached",
+			lastRemediation:    &remediationData{Machine: "m0", Timestamp: metav1.NewTime(now.Add(-5 * time.Minute)), RetryCount: 2},
+			strategy:           &controlplanev1.RemediationStrategy{MaxRetry: utilpointer.Int32Ptr(2)},
+			expectCanRemediate: false,
+			expectRetryCount:   3,
+			expectCondition:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			m := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "m1"}}
+			if tt.lastRemediation != nil {
+				withRemediationFor(*tt.lastRemediation)(m)
+			}
+			controlPlane := &internal.ControlPlane{
+				KCP: &controlplanev1.KubeadmControlPlane{Spec: controlplanev1.KubeadmControlPlaneSpec{
+					RemediationStrategy: tt.strategy,
+				}},
+			}
+
+			remediation, canRemediate, err := checkRetryLimits(log.Log, m, controlPlane, now)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(canRemediate).To(Equal(tt.expectCanRemediate))
+			g.Expect(remediation.Machine).To(Equal("m1"))
+			g.Expect(remediation.Timestamp.Time).To(BeTemporally("==", now))
+			g.Expect(remediation.RetryCount).To(Equal(tt.expectRetryCount))
+			g.Expect(conditions.Has(m, clusterv1.MachineOwnerRemediatedCondition)).To(Equal(tt.expectCondition))
+		})
+	}
+
+	t.Run("an invalid remediation annotation is reported as an error", func(t *testing.T) {
+		g := NewWithT(t)
+
+		m := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{
+			Name:        "m1",
+			Annotations: map[string]string{controlplanev1.RemediationForAnnotation: "invalid"},
+		}}
+		controlPlane := &internal.ControlPlane{KCP: &controlplanev1.KubeadmControlPlane{}}
+
+		_, canRemediate, err := checkRetryLimits(log.Log, m, controlPlane, now)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(canRemediate).To(BeFalse())
+	})
+}
+
+func TestCompleteRemediationWithoutReplacement(t *testing.T) {
+	remediation := remediationData{Machine: "m0", Timestamp: metav1.Now()}
+	value, err := remediation.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	machine := func(name string) *clusterv1.Machine {
+		return &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+
+	tests := []struct {
+		name             string
+		replicas         int32
+		machines         []*clusterv1.Machine
+		expectAnnotation bool
+	}{
+		{
+			name:             "keeps the remediation in progress while the remediated machine exists",
+			replicas:         3,
+			machines:         []*clusterv1.Machine{machine("m0"), machine("m1"), machine("m2")},
+			expectAnnotation: true,
+		},
+		{
+			name:             "keeps the remediation in progress while the replacement is needed",
+			replicas:         3,
+			machines:         []*clusterv1.Machine{machine("m1"), machine("m2")},
+			expectAnnotation: true,
+		},
+		{
+			name:             "completes the remediation once the remediated machine is gone and no replacement is needed",
+			replicas:         1,
+			machines:         []*clusterv1.Machine{machine("m1")},
+			expectAnnotation: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			controlPlane := &internal.ControlPlane{
+				KCP: &controlplanev1.KubeadmControlPlane{
+					ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{controlplanev1.RemediationInProgressAnnotation: value}},
+					Spec:       controlplanev1.KubeadmControlPlaneSpec{Replicas: utilpointer.Int32Ptr(tt.replicas)},
+				},
+				Machines: collections.FromMachines(tt.machines...),
+			}
+
+			g.Expect(completeRemediationWithoutReplacement(log.Log, controlPlane)).To(Succeed())
+			if tt.expectAnnotation {
+				g.Expect(controlPlane.KCP.Annotations).To(HaveKey(controlplanev1.RemediationInProgressAnnotation))
+			} else {
+				g.Expect(controlPlane.KCP.Annotations).ToNot(HaveKey(controlplanev1.RemediationInProgressAnnotation))
+			}
+		})
+	}
+}
+
 func TestCanSafelyRemoveEtcdMember(t *testing.T) {
 	g := NewWithT(t)
 	ctx := context.TODO()
@@ -529,6 +927,16 @@ func withMachineHealthCheckFailed() machineOption {
 	}
 }
 
+func withRemediationFor(data remediationData) machineOption {
+	return func(machine *clusterv1.Machine) {
+		value, _ := data.marshal()
+		if machine.Annotations == nil {
+			machine.Annotations = map[string]string{}
+		}
+		machine.Annotations[controlplanev1.RemediationForAnnotation] = value
+	}
+}
+
 func withHealthyEtcdMember() machineOption {
 	return func(machine *clusterv1.Machine) {
 		conditions.MarkTrue(machine, controlplanev1.MachineEtcdMemberHealthyCondition)
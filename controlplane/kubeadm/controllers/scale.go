@@ -19,6 +19,7 @@ package controllers
 import (
 	"context"
 	"sigs.k8s.io/cluster-api/util/collections"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -52,7 +53,7 @@ func (r *KubeadmControlPlaneReconciler) initializeControlPlane(ctx context.Conte
 
 	bootstrapSpec := controlPlane.InitialControlPlaneConfig()
 	fd := controlPlane.NextFailureDomainForScaleUp()
-	if err := r.cloneConfigsAndGenerateMachine(ctx, cluster, kcp, bootstrapSpec, fd); err != nil {
+	if err := r.cloneConfigsAndGenerateMachine(ctx, cluster, kcp, bootstrapSpec, fd, nil); err != nil {
 		logger.Error(err, "Failed to create initial control plane Machine")
 		r.recorder.Eventf(kcp, corev1.EventTypeWarning, "FailedInitialization", "Failed to create initial control plane Machine for cluster %s/%s control plane: %v", cluster.Namespace, cluster.Name, err)
 		return ctrl.Result{}, err
@@ -70,15 +71,24 @@ func (r *KubeadmControlPlaneReconciler) scaleUpControlPlane(ctx context.Context,
 		return result, err
 	}
 
+	// If the new machine replaces a remediated machine, link it to the remediation so retries can be tracked.
+	var annotations map[string]string
+	if value, ok := kcp.Annotations[controlplanev1.RemediationInProgressAnnotation]; ok {
+		annotations = map[string]string{controlplanev1.RemediationForAnnotation: value}
+	}
+
 	// Create the bootstrap configuration
 	bootstrapSpec := controlPlane.JoinControlPlaneConfig()
 	fd := controlPlane.NextFailureDomainForScaleUp()
-	if err := r.cloneConfigsAndGenerateMachine(ctx, cluster, kcp, bootstrapSpec, fd); err != nil {
+	if err := r.cloneConfigsAndGenerateMachine(ctx, cluster, kcp, bootstrapSpec, fd, annotations); err != nil {
 		logger.Error(err, "Failed to create additional control plane Machine")
 		r.recorder.Eventf(kcp, corev1.EventTypeWarning, "FailedScaleUp", "Failed to create additional control plane Machine for cluster %s/%s control plane: %v", cluster.Namespace, cluster.Name, err)
 		return ctrl.Result{}, err
 	}
 
+	// The remediation completes once the replacement machine has been created.
+	delete(kcp.Annotations, controlplanev1.RemediationInProgressAnnotation)
+
 	// Requeue the control plane, in case there are other operations to perform
 	return ctrl.Result{Requeue: true}, nil
 }
@@ -92,6 +102,21 @@ func (r *KubeadmControlPlaneReconciler) scaleDownControlPlane(
 ) (ctrl.Result, error) {
 	logger := controlPlane.Logger()
 
+	// Machines protected from deletion are never deleted, so scaling down is stalled if all the machines are protected,
+	// or if all the machines to be rolled out are protected; the latter avoids deleting up-to-date machines instead.
+	deletable := collections.Not(collections.IsDeletionProtected)
+	if controlPlane.Machines.Filter(deletable).Len() == 0 || (outdatedMachines.Len() > 0 && outdatedMachines.Filter(deletable).Len() == 0) {
+		protected := controlPlane.Machines.Filter(collections.IsDeletionProtected).Names()
+		sort.Strings(protected)
+		logger.Info("Scale down stalled by machines protected from deletion", "protected", protected)
+		conditions.MarkFalse(kcp, clusterv1.MachinesDeletableCondition, clusterv1.MachineDeletionProtectedReason, clusterv1.ConditionSeverityWarning,
+			"Scale down stalled, control plane machines to be deleted are protected from deletion (protected machines: %s)", strings.Join(protected, ", "))
+		return ctrl.Result{}, nil
+	}
+	if conditions.Has(kcp, clusterv1.MachinesDeletableCondition) {
+		conditions.MarkTrue(kcp, clusterv1.MachinesDeletableCondition)
+	}
+
 	// Pick the Machine that we should scale down.
 	machineToDelete, err := selectMachineForScaleDown(controlPlane, outdatedMachines)
 	if err != nil {
@@ -226,7 +251,10 @@ func preflightCheckCondition(kind string, obj conditions.Getter, condition clust
 }
 
 func selectMachineForScaleDown(controlPlane *internal.ControlPlane, outdatedMachines collections.Machines) (*clusterv1.Machine, error) {
-	machines := controlPlane.Machines
+	// Machines protected from deletion are never selected.
+	deletable := collections.Not(collections.IsDeletionProtected)
+	machines := controlPlane.Machines.Filter(deletable)
+	outdatedMachines = outdatedMachines.Filter(deletable)
 	switch {
 	case controlPlane.MachineWithDeleteAnnotation(outdatedMachines).Len() > 0:
 		machines = controlPlane.MachineWithDeleteAnnotation(outdatedMachines)
@@ -121,6 +121,56 @@ func TestKubeadmControlPlaneReconciler_scaleUpControlPlane(t *testing.T) {
 		g.Expect(fakeClient.List(ctx, &controlPlaneMachines)).To(Succeed())
 		g.Expect(controlPlaneMachines.Items).To(HaveLen(3))
 	})
+	t.Run("links the new control plane Machine to the remediation in progress", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cluster, kcp, genericMachineTemplate := createClusterWithControlPlane()
+		setKCPHealthy(kcp)
+		remediation := `{"machine":"test-2","timestamp":"2021-01-01T00:00:00Z","retryCount":1}`
+		kcp.Annotations = map[string]string{controlplanev1.RemediationInProgressAnnotation: remediation}
+		initObjs := []client.Object{cluster.DeepCopy(), kcp.DeepCopy(), genericMachineTemplate.DeepCopy()}
+
+		fmc := &fakeManagementCluster{
+			Machines: collections.New(),
+			Workload: fakeWorkloadCluster{},
+		}
+
+		for i := 0; i < 2; i++ {
+			m, _ := createMachineNodePair(fmt.Sprintf("test-%d", i), cluster, kcp, true)
+			setMachineHealthy(m)
+			fmc.Machines.Insert(m)
+			initObjs = append(initObjs, m.DeepCopy())
+		}
+
+		fakeClient := newFakeClient(g, initObjs...)
+
+		r := &KubeadmControlPlaneReconciler{
+			Client:                    fakeClient,
+			managementCluster:         fmc,
+			managementClusterUncached: fmc,
+			recorder:                  record.NewFakeRecorder(32),
+		}
+		controlPlane := &internal.ControlPlane{
+			KCP:      kcp,
+			Cluster:  cluster,
+			Machines: fmc.Machines,
+		}
+
+		result, err := r.scaleUpControlPlane(ctx, cluster, kcp, controlPlane)
+		g.Expect(result).To(Equal(ctrl.Result{Requeue: true}))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(kcp.Annotations).ToNot(HaveKey(controlplanev1.RemediationInProgressAnnotation))
+
+		controlPlaneMachines := clusterv1.MachineList{}
+		g.Expect(fakeClient.List(ctx, &controlPlaneMachines)).To(Succeed())
+		g.Expect(controlPlaneMachines.Items).To(HaveLen(3))
+		for _, m := range controlPlaneMachines.Items {
+			if _, ok := fmc.Machines[m.Name]; ok {
+				continue
+			}
+			g.Expect(m.Annotations).To(HaveKeyWithValue(controlplanev1.RemediationForAnnotation, remediation))
+		}
+	})
 	t.Run("does not create a control plane Machine if preflight checks fail", func(t *testing.T) {
 		cluster, kcp, genericMachineTemplate := createClusterWithControlPlane()
 		initObjs := []client.Object{cluster.DeepCopy(), kcp.DeepCopy(), genericMachineTemplate.DeepCopy()}
@@ -275,6 +325,45 @@ func TestKubeadmControlPlaneReconciler_scaleDownControlPlane_NoError(t *testing.
 		g.Expect(fakeClient.List(context.Background(), &controlPlaneMachines)).To(Succeed())
 		g.Expect(controlPlaneMachines.Items).To(HaveLen(3))
 	})
+
+	t.Run("does not scale down if all the outdated machines are protected from deletion", func(t *testing.T) {
+		g := NewWithT(t)
+
+		machines := map[string]*clusterv1.Machine{
+			"one":   machine("one", withTimestamp(time.Now().Add(-1*time.Minute)), withAnnotation(clusterv1.MachineDeletionProtectionAnnotation)),
+			"two":   machine("two", withTimestamp(time.Now())),
+			"three": machine("three", withTimestamp(time.Now())),
+		}
+		setMachineHealthy(machines["two"])
+		setMachineHealthy(machines["three"])
+		fakeClient := newFakeClient(g, machines["one"], machines["two"], machines["three"])
+
+		r := &KubeadmControlPlaneReconciler{
+			recorder: record.NewFakeRecorder(32),
+			Client:   fakeClient,
+			managementCluster: &fakeManagementCluster{
+				Workload: fakeWorkloadCluster{},
+			},
+		}
+
+		cluster := &clusterv1.Cluster{}
+		kcp := &controlplanev1.KubeadmControlPlane{}
+		controlPlane := &internal.ControlPlane{
+			KCP:      kcp,
+			Cluster:  cluster,
+			Machines: machines,
+		}
+
+		result, err := r.scaleDownControlPlane(context.Background(), cluster, kcp, controlPlane, collections.FromMachines(machines["one"]))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result).To(Equal(ctrl.Result{}))
+		g.Expect(conditions.IsFalse(kcp, clusterv1.MachinesDeletableCondition)).To(BeTrue())
+		g.Expect(conditions.GetReason(kcp, clusterv1.MachinesDeletableCondition)).To(Equal(clusterv1.MachineDeletionProtectedReason))
+
+		controlPlaneMachines := clusterv1.MachineList{}
+		g.Expect(fakeClient.List(context.Background(), &controlPlaneMachines)).To(Succeed())
+		g.Expect(controlPlaneMachines.Items).To(HaveLen(3))
+	})
 }
 
 func TestSelectMachineForScaleDown(t *testing.T) {
@@ -290,6 +379,7 @@ func TestSelectMachineForScaleDown(t *testing.T) {
 	m6 := machine("machine-6", withFailureDomain("two"), withTimestamp(startDate.Add(-7*time.Hour)))
 	m7 := machine("machine-7", withFailureDomain("two"), withTimestamp(startDate.Add(-5*time.Hour)), withAnnotation("cluster.x-k8s.io/delete-machine"))
 	m8 := machine("machine-8", withFailureDomain("two"), withTimestamp(startDate.Add(-6*time.Hour)), withAnnotation("cluster.x-k8s.io/delete-machine"))
+	m9 := machine("machine-9", withFailureDomain("one"), withTimestamp(startDate.Add(-8*time.Hour)), withAnnotation(clusterv1.MachineDeletionProtectionAnnotation))
 
 	mc3 := collections.FromMachines(m1, m2, m3, m4, m5)
 	mc6 := collections.FromMachines(m6, m7, m8)
@@ -315,6 +405,11 @@ func TestSelectMachineForScaleDown(t *testing.T) {
 		Cluster:  &clusterv1.Cluster{Status: clusterv1.ClusterStatus{FailureDomains: fd}},
 		Machines: mc6,
 	}
+	protectedControlPlane := &internal.ControlPlane{
+		KCP:      &kcp,
+		Cluster:  &clusterv1.Cluster{Status: clusterv1.ClusterStatus{FailureDomains: fd}},
+		Machines: collections.FromMachines(m1, m2, m3, m4, m9),
+	}
 
 	testCases := []struct {
 		name             string
@@ -372,6 +467,20 @@ func TestSelectMachineForScaleDown(t *testing.T) {
 			expectErr:        false,
 			expectedMachine:  clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine-8"}},
 		},
+		{
+			name:             "when the oldest outdated machine is protected from deletion, it returns the oldest outdated machine not protected",
+			cp:               protectedControlPlane,
+			outDatedMachines: collections.FromMachines(m9, m3),
+			expectErr:        false,
+			expectedMachine:  clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine-3"}},
+		},
+		{
+			name:             "when there are no outdated machines and the oldest machine is protected from deletion, it returns the oldest machine not protected",
+			cp:               protectedControlPlane,
+			outDatedMachines: collections.New(),
+			expectErr:        false,
+			expectedMachine:  clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine-3"}},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -50,6 +50,7 @@ func (r *KubeadmControlPlaneReconciler) updateStatus(ctx context.Context, kcp *c
 		return err
 	}
 	kcp.Status.UpdatedReplicas = int32(len(controlPlane.UpToDateMachines()))
+	kcp.Status.CertificatesExpiryDate = earliestCertificatesExpiry(ownedMachines)
 
 	replicas := int32(len(ownedMachines))
 	desiredReplicas := *kcp.Spec.Replicas
@@ -99,8 +100,9 @@ func (r *KubeadmControlPlaneReconciler) updateStatus(ctx context.Context, kcp *c
 	kcp.Status.ReadyReplicas = status.ReadyNodes
 	kcp.Status.UnavailableReplicas = replicas - status.ReadyNodes
 
-	// This only gets initialized once and does not change if the kubeadm config map goes away.
-	if status.HasKubeadmConfig {
+	// This only gets initialized once and does not change if the kubeadm config map goes away; while restoring etcd,
+	// it gets initialized again only by the first machine restoring etcd.
+	if status.HasKubeadmConfig && !isReinitializingForEtcdRestore(kcp, ownedMachines) {
 		kcp.Status.Initialized = true
 		conditions.MarkTrue(kcp, controlplanev1.AvailableCondition)
 	}
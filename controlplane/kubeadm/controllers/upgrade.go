@@ -20,11 +20,13 @@ import (
 	"context"
 	"github.com/blang/semver"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
@@ -39,6 +41,17 @@ func (r *KubeadmControlPlaneReconciler) upgradeControlPlane(
 
 	// TODO: handle reconciliation of etcd members and kubeadm config in case they get out of sync with cluster
 
+	// Machines do not carry any etcd health condition when using an external etcd cluster, so the preflight checks
+	// can't detect an unhealthy etcd; instead, rely on the KCP level condition and do not start the upgrade.
+	// NOTE: an Unknown condition, e.g. the external endpoints not being reachable from the management cluster, does not block upgrades.
+	if !controlPlane.IsEtcdManaged() && conditions.IsFalse(kcp, controlplanev1.EtcdClusterHealthyCondition) {
+		message := conditions.GetMessage(kcp, controlplanev1.EtcdClusterHealthyCondition)
+		r.recorder.Eventf(kcp, corev1.EventTypeWarning, "ControlPlaneUnhealthy",
+			"Waiting for the external etcd cluster to be healthy to continue the upgrade: %s", message)
+		logger.Info("Waiting for the external etcd cluster to be healthy to continue the upgrade", "failures", message)
+		return ctrl.Result{RequeueAfter: preflightFailedRequeueAfter}, nil
+	}
+
 	workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, util.ObjectKey(cluster))
 	if err != nil {
 		logger.Error(err, "failed to get remote client for workload cluster", "cluster key", util.ObjectKey(cluster))
@@ -105,9 +118,36 @@ func (r *KubeadmControlPlaneReconciler) upgradeControlPlane(
 		return ctrl.Result{}, err
 	}
 
-	if status.Nodes <= *kcp.Spec.Replicas {
+	maxSurge := rolloutMaxSurge(kcp)
+	if status.Nodes < *kcp.Spec.Replicas+maxSurge {
 		// scaleUp ensures that we don't continue scaling up while waiting for Machines to have NodeRefs
 		return r.scaleUpControlPlane(ctx, cluster, kcp, controlPlane)
 	}
+
+	// When scaling in first, the outdated machine is removed before its replacement is created:
+	// make sure this does not result in etcd losing quorum.
+	if maxSurge == 0 && controlPlane.IsEtcdManaged() {
+		machineToDelete, err := selectMachineForScaleDown(controlPlane, machinesRequireUpgrade)
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to select machine for scale down")
+		}
+		canSafelyRemove, err := r.canSafelyRemoveEtcdMember(ctx, controlPlane, machineToDelete)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !canSafelyRemove {
+			logger.Info("Waiting to scale in the control plane, removing a machine could result in etcd losing quorum", "machine", machineToDelete.Name)
+			return ctrl.Result{RequeueAfter: preflightFailedRequeueAfter}, nil
+		}
+	}
 	return r.scaleDownControlPlane(ctx, cluster, kcp, controlPlane, machinesRequireUpgrade)
 }
+
+// rolloutMaxSurge returns the number of control plane machines that can be created above the desired
+// number of replicas during a rollout; it defaults to 1 when the rollout strategy is not set.
+func rolloutMaxSurge(kcp *controlplanev1.KubeadmControlPlane) int32 {
+	if kcp.Spec.RolloutStrategy == nil || kcp.Spec.RolloutStrategy.RollingUpdate == nil || kcp.Spec.RolloutStrategy.RollingUpdate.MaxSurge == nil {
+		return 1
+	}
+	return int32(kcp.Spec.RolloutStrategy.RollingUpdate.MaxSurge.IntValue())
+}
@@ -18,16 +18,22 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"sigs.k8s.io/cluster-api/util/collections"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/bootstrap/kubeadm/types/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -121,6 +127,164 @@ func TestKubeadmControlPlaneReconciler_upgradeControlPlane(t *testing.T) {
 	g.Expect(finalMachine.Items[0].CreationTimestamp.Time).To(BeTemporally(">", initialMachine.Items[0].CreationTimestamp.Time))
 }
 
+func TestKubeadmControlPlaneReconciler_upgradeControlPlaneScaleIn(t *testing.T) {
+	setup := func(g *WithT, nodes int, unhealthyEtcdMember bool) (*KubeadmControlPlaneReconciler, client.Client, *clusterv1.Cluster, *controlplanev1.KubeadmControlPlane, *internal.ControlPlane) {
+		cluster, kcp, genericMachineTemplate := createClusterWithControlPlane()
+		kcp.Spec.Version = "v1.17.4"
+		kcp.Spec.KubeadmConfigSpec.ClusterConfiguration = nil
+		kcp.Spec.Replicas = pointer.Int32Ptr(3)
+		maxSurge := intstr.FromInt(0)
+		kcp.Spec.RolloutStrategy = &controlplanev1.RolloutStrategy{
+			Type:          controlplanev1.RollingUpdateStrategyType,
+			RollingUpdate: &controlplanev1.RollingUpdate{MaxSurge: &maxSurge},
+		}
+		setKCPHealthy(kcp)
+
+		objs := []client.Object{cluster.DeepCopy(), kcp.DeepCopy(), genericMachineTemplate.DeepCopy()}
+		machines := collections.New()
+		for i := 0; i < nodes; i++ {
+			m, _ := createMachineNodePair(fmt.Sprintf("outdated-%d", i), cluster, kcp, true)
+			m.CreationTimestamp = metav1.NewTime(time.Now().Add(time.Duration(i-nodes) * time.Minute))
+			setMachineHealthy(m)
+			if unhealthyEtcdMember && i == nodes-1 {
+				conditions.MarkFalse(m, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "")
+			}
+			machines.Insert(m)
+			objs = append(objs, m.DeepCopy())
+		}
+
+		fakeClient := newFakeClient(g, objs...)
+		workload := fakeWorkloadCluster{
+			Status:            internal.ClusterStatus{Nodes: int32(nodes)},
+			EtcdMembersResult: machines.Names(),
+		}
+		r := &KubeadmControlPlaneReconciler{
+			Client:   fakeClient,
+			recorder: record.NewFakeRecorder(32),
+			managementCluster: &fakeManagementCluster{
+				Management: &internal.Management{Client: fakeClient},
+				Workload:   workload,
+			},
+			managementClusterUncached: &fakeManagementCluster{
+				Management: &internal.Management{Client: fakeClient},
+				Workload:   workload,
+			},
+		}
+		controlPlane := &internal.ControlPlane{
+			KCP:      kcp,
+			Cluster:  cluster,
+			Machines: machines,
+		}
+		return r, fakeClient, cluster, kcp, controlPlane
+	}
+
+	t.Run("removes an outdated machine before creating its replacement", func(t *testing.T) {
+		g := NewWithT(t)
+		r, fakeClient, cluster, kcp, controlPlane := setup(g, 3, false)
+
+		result, err := r.upgradeControlPlane(ctx, cluster, kcp, controlPlane, controlPlane.Machines)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result).To(Equal(ctrl.Result{Requeue: true}))
+		machines := &clusterv1.MachineList{}
+		g.Expect(fakeClient.List(ctx, machines, client.InNamespace(cluster.Namespace))).To(Succeed())
+		g.Expect(machines.Items).To(HaveLen(2))
+		for _, m := range machines.Items {
+			g.Expect(m.Name).ToNot(Equal("outdated-0"))
+		}
+	})
+
+	t.Run("creates the replacement once the outdated machine has been removed", func(t *testing.T) {
+		g := NewWithT(t)
+		r, fakeClient, cluster, kcp, controlPlane := setup(g, 2, false)
+
+		result, err := r.upgradeControlPlane(ctx, cluster, kcp, controlPlane, controlPlane.Machines)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result).To(Equal(ctrl.Result{Requeue: true}))
+		machines := &clusterv1.MachineList{}
+		g.Expect(fakeClient.List(ctx, machines, client.InNamespace(cluster.Namespace))).To(Succeed())
+		g.Expect(machines.Items).To(HaveLen(3))
+	})
+
+	t.Run("does not remove a machine if etcd could lose quorum", func(t *testing.T) {
+		g := NewWithT(t)
+		r, fakeClient, cluster, kcp, controlPlane := setup(g, 3, true)
+
+		result, err := r.upgradeControlPlane(ctx, cluster, kcp, controlPlane, controlPlane.Machines)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result).To(Equal(ctrl.Result{RequeueAfter: preflightFailedRequeueAfter}))
+		machines := &clusterv1.MachineList{}
+		g.Expect(fakeClient.List(ctx, machines, client.InNamespace(cluster.Namespace))).To(Succeed())
+		g.Expect(machines.Items).To(HaveLen(3))
+	})
+}
+
+func TestKubeadmControlPlaneReconciler_upgradeControlPlaneExternalEtcd(t *testing.T) {
+	setup := func(g *WithT) (*KubeadmControlPlaneReconciler, client.Client, *clusterv1.Cluster, *controlplanev1.KubeadmControlPlane, *internal.ControlPlane) {
+		cluster, kcp, genericMachineTemplate := createClusterWithControlPlane()
+		kcp.Spec.Version = "v1.17.4"
+		kcp.Spec.KubeadmConfigSpec.ClusterConfiguration = &v1beta1.ClusterConfiguration{
+			Etcd: v1beta1.Etcd{
+				External: &v1beta1.ExternalEtcd{Endpoints: []string{"https://etcd-1:2379"}},
+			},
+		}
+		kcp.Spec.Replicas = pointer.Int32Ptr(1)
+		setKCPHealthy(kcp)
+
+		m, _ := createMachineNodePair("outdated", cluster, kcp, true)
+		setMachineHealthy(m)
+
+		fakeClient := newFakeClient(g, cluster.DeepCopy(), kcp.DeepCopy(), genericMachineTemplate.DeepCopy(), m.DeepCopy())
+		workload := fakeWorkloadCluster{
+			Status: internal.ClusterStatus{Nodes: 1},
+		}
+		r := &KubeadmControlPlaneReconciler{
+			Client:   fakeClient,
+			recorder: record.NewFakeRecorder(32),
+			managementCluster: &fakeManagementCluster{
+				Management: &internal.Management{Client: fakeClient},
+				Workload:   workload,
+			},
+			managementClusterUncached: &fakeManagementCluster{
+				Management: &internal.Management{Client: fakeClient},
+				Workload:   workload,
+			},
+		}
+		controlPlane := &internal.ControlPlane{
+			KCP:      kcp,
+			Cluster:  cluster,
+			Machines: collections.FromMachines(m),
+		}
+		return r, fakeClient, cluster, kcp, controlPlane
+	}
+
+	t.Run("upgrade is blocked when the external etcd cluster is unhealthy", func(t *testing.T) {
+		g := NewWithT(t)
+		r, fakeClient, cluster, kcp, controlPlane := setup(g)
+		conditions.MarkFalse(kcp, controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Etcd member %s reports alarms: %s", "etcd-1", "NOSPACE")
+
+		result, err := r.upgradeControlPlane(ctx, cluster, kcp, controlPlane, controlPlane.Machines)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result).To(Equal(ctrl.Result{RequeueAfter: preflightFailedRequeueAfter}))
+
+		machines := &clusterv1.MachineList{}
+		g.Expect(fakeClient.List(ctx, machines, client.InNamespace(cluster.Namespace))).To(Succeed())
+		g.Expect(machines.Items).To(HaveLen(1))
+		g.Expect(r.recorder.(*record.FakeRecorder).Events).To(Receive(ContainSubstring("ControlPlaneUnhealthy")))
+	})
+	t.Run("upgrade proceeds when the external etcd cluster is healthy", func(t *testing.T) {
+		g := NewWithT(t)
+		r, fakeClient, cluster, kcp, controlPlane := setup(g)
+
+		result, err := r.upgradeControlPlane(ctx, cluster, kcp, controlPlane, controlPlane.Machines)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result).To(Equal(ctrl.Result{Requeue: true}))
+
+		machines := &clusterv1.MachineList{}
+		g.Expect(fakeClient.List(ctx, machines, client.InNamespace(cluster.Namespace))).To(Succeed())
+		g.Expect(machines.Items).To(HaveLen(2))
+	})
+}
+
 type machineOpt func(*clusterv1.Machine)
 
 func machine(name string, opts ...machineOpt) *clusterv1.Machine {
@@ -136,11 +136,17 @@ func (m *Management) GetWorkloadCluster(ctx context.Context, clusterKey client.O
 		RootCAs:      caPool,
 		Certificates: []tls.Certificate{clientCert},
 	}
+	// The members of an external etcd cluster are reached directly at the endpoints of the kubeadm configuration,
+	// so their serving certificates are verified.
+	externalTLSConfig := tlsConfig.Clone()
 	tlsConfig.InsecureSkipVerify = true
 	return &Workload{
-		Client:              c,
-		CoreDNSMigrator:     &CoreDNSMigrator{},
-		etcdClientGenerator: NewEtcdClientGenerator(restConfig, tlsConfig),
+		Client:                      c,
+		CoreDNSMigrator:             &CoreDNSMigrator{},
+		etcdClientGenerator:         NewEtcdClientGenerator(restConfig, tlsConfig),
+		externalEtcdClientGenerator: newExternalEtcdClientGenerator(externalTLSConfig),
+		etcdTLSConfig:               tlsConfig,
+		podDialer:                   &proxyPodDialer{restConfig: restConfig},
 	}, nil
 }
 
@@ -18,6 +18,8 @@ package internal
 
 import (
 	"context"
+	"strconv"
+
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -29,6 +31,7 @@ import (
 	"k8s.io/klog/klogr"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1alpha4"
+	kubeadmv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/types/v1beta1"
 	"sigs.k8s.io/cluster-api/controllers/external"
 	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/util/collections"
@@ -37,6 +40,18 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// defaultEtcdQuotaBackendBytes is the size quota of the etcd backend database used by etcd when
+// the quota-backend-bytes argument is not set.
+const defaultEtcdQuotaBackendBytes = 2 * 1024 * 1024 * 1024
+
+const (
+	// coreDNSAddonPhase is the kubeadm init phase installing CoreDNS.
+	coreDNSAddonPhase = "addon/coredns"
+
+	// kubeProxyAddonPhase is the kubeadm init phase installing kube-proxy.
+	kubeProxyAddonPhase = "addon/kube-proxy"
+)
+
 // Log is the global logger for the internal package.
 var Log = klogr.New()
 
@@ -177,9 +192,32 @@ func (c *ControlPlane) NextFailureDomainForScaleUp() *string {
 func (c *ControlPlane) InitialControlPlaneConfig() *bootstrapv1.KubeadmConfigSpec {
 	bootstrapSpec := c.KCP.Spec.KubeadmConfigSpec.DeepCopy()
 	bootstrapSpec.JoinConfiguration = nil
+
+	// Skip installing the addons that are not managed by the control plane.
+	if addons := c.KCP.Spec.Addons; addons != nil {
+		if addons.SkipCoreDNS {
+			addInitSkipPhase(bootstrapSpec, coreDNSAddonPhase)
+		}
+		if addons.SkipKubeProxy {
+			addInitSkipPhase(bootstrapSpec, kubeProxyAddonPhase)
+		}
+	}
 	return bootstrapSpec
 }
 
+// addInitSkipPhase adds a phase to the phases skipped during kubeadm init, unless already there.
+func addInitSkipPhase(bootstrapSpec *bootstrapv1.KubeadmConfigSpec, phase string) {
+	if bootstrapSpec.InitConfiguration == nil {
+		bootstrapSpec.InitConfiguration = &kubeadmv1.InitConfiguration{}
+	}
+	for _, p := range bootstrapSpec.InitConfiguration.SkipPhases {
+		if p == phase {
+			return
+		}
+	}
+	bootstrapSpec.InitConfiguration.SkipPhases = append(bootstrapSpec.InitConfiguration.SkipPhases, phase)
+}
+
 // JoinControlPlaneConfig returns a new KubeadmConfigSpec that is to be used for joining control planes.
 func (c *ControlPlane) JoinControlPlaneConfig() *bootstrapv1.KubeadmConfigSpec {
 	bootstrapSpec := c.KCP.Spec.KubeadmConfigSpec.DeepCopy()
@@ -259,11 +297,25 @@ func (c *ControlPlane) MachinesNeedingRollout() collections.Machines {
 	return machines.AnyFilter(
 		// Machines that are scheduled for rollout (KCP.Spec.UpgradeAfter set, the UpgradeAfter deadline is expired, and the machine was created before the deadline).
 		collections.ShouldRolloutAfter(&c.reconciliationTime, c.KCP.Spec.UpgradeAfter),
+		// Machines whose certificates expire within KCP.Spec.RolloutBefore.CertificatesExpiryDays.
+		ShouldRolloutBefore(&c.reconciliationTime, c.KCP.Spec.RolloutBefore),
+		// Machines created before the current phase of the rotation of the certificate authorities started.
+		collections.ShouldRolloutAfter(&c.reconciliationTime, c.certificateAuthoritiesRotationPhaseStartTime()),
 		// Machines that do not match with KCP config.
 		collections.Not(MatchesKCPConfiguration(c.infraResources, c.kubeadmConfigs, c.KCP)),
 	)
 }
 
+// certificateAuthoritiesRotationPhaseStartTime returns the time the current phase of the rotation
+// of the certificate authorities started, or nil if no rotation is in progress.
+func (c *ControlPlane) certificateAuthoritiesRotationPhaseStartTime() *metav1.Time {
+	rotation := c.KCP.Status.CertificateAuthoritiesRotation
+	if rotation == nil || rotation.Phase == controlplanev1.CertificateAuthoritiesRotationCompletedPhase {
+		return nil
+	}
+	return &rotation.PhaseStartTime
+}
+
 // UpToDateMachines returns the machines that are up to date with the control
 // plane's configuration and therefore do not require rollout.
 func (c *ControlPlane) UpToDateMachines() collections.Machines {
@@ -311,6 +363,20 @@ func (c *ControlPlane) IsEtcdManaged() bool {
 	return c.KCP.Spec.KubeadmConfigSpec.ClusterConfiguration == nil || c.KCP.Spec.KubeadmConfigSpec.ClusterConfiguration.Etcd.External == nil
 }
 
+// EtcdQuotaBackendBytes returns the size quota of the backend database of the managed etcd members, in bytes,
+// as configured with the quota-backend-bytes etcd argument, or the etcd default if not set.
+func (c *ControlPlane) EtcdQuotaBackendBytes() int64 {
+	clusterConfiguration := c.KCP.Spec.KubeadmConfigSpec.ClusterConfiguration
+	if clusterConfiguration == nil || clusterConfiguration.Etcd.Local == nil {
+		return defaultEtcdQuotaBackendBytes
+	}
+	quota, err := strconv.ParseInt(clusterConfiguration.Etcd.Local.ExtraArgs["quota-backend-bytes"], 10, 64)
+	if err != nil || quota <= 0 {
+		return defaultEtcdQuotaBackendBytes
+	}
+	return quota
+}
+
 // UnhealthyMachines returns the list of control plane machines marked as unhealthy by MHC.
 func (c *ControlPlane) UnhealthyMachines() collections.Machines {
 	return c.Machines.Filter(collections.HasUnhealthyCondition)
@@ -19,6 +19,7 @@ package internal
 import (
 	"sigs.k8s.io/cluster-api/util/collections"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 
@@ -28,6 +29,7 @@ import (
 	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1alpha4"
+	kubeadmv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/types/v1beta1"
 	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/util/conditions"
 )
@@ -94,6 +96,112 @@ func TestControlPlane(t *testing.T) {
 	})
 }
 
+func TestInitialControlPlaneConfig(t *testing.T) {
+	tests := []struct {
+		name               string
+		initConfiguration  *kubeadmv1.InitConfiguration
+		addons             *controlplanev1.Addons
+		expectedSkipPhases []string
+	}{
+		{
+			name:               "does not skip any phase when addons are managed by KCP",
+			initConfiguration:  &kubeadmv1.InitConfiguration{SkipPhases: []string{"preflight"}},
+			expectedSkipPhases: []string{"preflight"},
+		},
+		{
+			name:               "skips the CoreDNS and kube-proxy addon phases when not managed by KCP",
+			addons:             &controlplanev1.Addons{SkipCoreDNS: true, SkipKubeProxy: true},
+			expectedSkipPhases: []string{"addon/coredns", "addon/kube-proxy"},
+		},
+		{
+			name:               "preserves the phases skipped in the InitConfiguration",
+			initConfiguration:  &kubeadmv1.InitConfiguration{SkipPhases: []string{"preflight", "addon/kube-proxy"}},
+			addons:             &controlplanev1.Addons{SkipKubeProxy: true},
+			expectedSkipPhases: []string{"preflight", "addon/kube-proxy"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			kcp := &controlplanev1.KubeadmControlPlane{
+				Spec: controlplanev1.KubeadmControlPlaneSpec{
+					KubeadmConfigSpec: bootstrapv1.KubeadmConfigSpec{
+						InitConfiguration: tt.initConfiguration,
+						JoinConfiguration: &kubeadmv1.JoinConfiguration{},
+					},
+					Addons: tt.addons,
+				},
+			}
+			controlPlane := &ControlPlane{KCP: kcp}
+			initConfiguration := tt.initConfiguration.DeepCopy()
+
+			bootstrapSpec := controlPlane.InitialControlPlaneConfig()
+			g.Expect(bootstrapSpec.JoinConfiguration).To(BeNil())
+			g.Expect(bootstrapSpec.InitConfiguration).ToNot(BeNil())
+			g.Expect(bootstrapSpec.InitConfiguration.SkipPhases).To(Equal(tt.expectedSkipPhases))
+			// The KubeadmControlPlane spec must not be changed.
+			g.Expect(kcp.Spec.KubeadmConfigSpec.JoinConfiguration).ToNot(BeNil())
+			g.Expect(kcp.Spec.KubeadmConfigSpec.InitConfiguration).To(Equal(initConfiguration))
+		})
+	}
+}
+
+func TestMachinesNeedingRolloutForCertificateAuthoritiesRotation(t *testing.T) {
+	phaseStartTime := metav1.NewTime(time.Now().Add(-time.Hour))
+	machineCreatedAt := func(name string, creationTimestamp time.Time) *clusterv1.Machine {
+		m := machine(name)
+		m.CreationTimestamp = metav1.NewTime(creationTimestamp)
+		m.Spec.Version = pointer.StringPtr("v1.19.1")
+		return m
+	}
+	oldMachine := machineCreatedAt("old", phaseStartTime.Add(-time.Minute))
+	newMachine := machineCreatedAt("new", phaseStartTime.Add(time.Minute))
+
+	tests := []struct {
+		name     string
+		rotation *controlplanev1.CertificateAuthoritiesRotation
+		expected []string
+	}{
+		{
+			name:     "no rotation",
+			expected: []string{},
+		},
+		{
+			name: "rotation in progress",
+			rotation: &controlplanev1.CertificateAuthoritiesRotation{
+				Phase:          controlplanev1.SigningWithNewCertificateAuthoritiesPhase,
+				PhaseStartTime: phaseStartTime,
+			},
+			expected: []string{"old"},
+		},
+		{
+			name: "rotation completed",
+			rotation: &controlplanev1.CertificateAuthoritiesRotation{
+				Phase:          controlplanev1.CertificateAuthoritiesRotationCompletedPhase,
+				PhaseStartTime: phaseStartTime,
+			},
+			expected: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			controlPlane := &ControlPlane{
+				KCP: &controlplanev1.KubeadmControlPlane{
+					Spec:   controlplanev1.KubeadmControlPlaneSpec{Version: "v1.19.1"},
+					Status: controlplanev1.KubeadmControlPlaneStatus{CertificateAuthoritiesRotation: tt.rotation},
+				},
+				Machines:           collections.FromMachines(oldMachine, newMachine),
+				reconciliationTime: metav1.Now(),
+			}
+			g.Expect(controlPlane.MachinesNeedingRollout().Names()).To(ConsistOf(tt.expected))
+		})
+	}
+}
+
 func TestHasUnhealthyMachine(t *testing.T) {
 	// healthy machine (without MachineHealthCheckSucceded condition)
 	healthyMachine1 := &clusterv1.Machine{}
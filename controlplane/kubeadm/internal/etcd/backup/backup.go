@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package backup provides storage backends for etcd snapshots.
+package backup
+
+import (
+	"context"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrSnapshotNotFound is returned when loading a snapshot that does not exist in a Store.
+var ErrSnapshotNotFound = errors.New("etcd snapshot not found")
+
+// SnapshotInfo describes a snapshot stored in a Store.
+type SnapshotInfo struct {
+	// Name of the snapshot.
+	Name string
+
+	// Size of the snapshot in bytes.
+	Size int64
+
+	// Timestamp is the time the snapshot has been stored.
+	Timestamp time.Time
+}
+
+// Store stores the etcd snapshots of a cluster.
+type Store interface {
+	// Save stores the snapshot read from r under the given name, and returns its size in bytes.
+	Save(ctx context.Context, name string, r io.Reader) (int64, error)
+
+	// Stat returns the description of the snapshot with the given name; it returns ErrSnapshotNotFound
+	// if the snapshot does not exist.
+	Stat(ctx context.Context, name string) (SnapshotInfo, error)
+
+	// List returns the description of all the snapshots in the store, sorted from the oldest to the newest.
+	List(ctx context.Context) ([]SnapshotInfo, error)
+
+	// Load returns a reader for the snapshot with the given name; the caller is responsible for closing it.
+	Load(ctx context.Context, name string) (io.ReadCloser, error)
+
+	// Delete deletes the snapshot with the given name; deleting a snapshot that does not exist is not an error.
+	Delete(ctx context.Context, name string) error
+}
+
+// sortSnapshots sorts snapshots from the oldest to the newest.
+func sortSnapshots(snapshots []SnapshotInfo) {
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		if !snapshots[i].Timestamp.Equal(snapshots[j].Timestamp) {
+			return snapshots[i].Timestamp.Before(snapshots[j].Timestamp)
+		}
+		return snapshots[i].Name < snapshots[j].Name
+	})
+}
+
+// URLStore is a Store whose snapshots can be downloaded without credentials, e.g. by the machines
+// of a workload cluster restoring etcd from a snapshot.
+type URLStore interface {
+	Store
+
+	// DownloadURL returns a URL to download the snapshot with the given name, valid for the given duration;
+	// the URL is generated without checking that the snapshot exists, see Stat.
+	DownloadURL(ctx context.Context, name string, expires time.Duration) (string, error)
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pkg/errors"
+)
+
+// S3Options defines a bucket of an S3-compatible object storage and the credentials to access it.
+type S3Options struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// HTTPClient is the client used to send requests; defaults to the client of the AWS SDK.
+	HTTPClient *http.Client
+}
+
+// s3Store stores snapshots as objects in a bucket of an S3-compatible object storage,
+// using path-style requests.
+type s3Store struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+var _ URLStore = &s3Store{}
+
+// NewS3Store returns a Store keeping the snapshots of a cluster in an S3 bucket, under
+// the <prefix>/<namespace>/<cluster name> key prefix.
+func NewS3Store(opts S3Options, namespace, clusterName string) (Store, error) {
+	if _, err := url.ParseRequestURI(opts.Endpoint); err != nil {
+		return nil, errors.Wrapf(err, "invalid S3 endpoint %q", opts.Endpoint)
+	}
+	s3Opts := s3.Options{
+		Region:           opts.Region,
+		EndpointResolver: s3.EndpointResolverFromURL(opts.Endpoint),
+		UsePathStyle:     true,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: opts.AccessKeyID, SecretAccessKey: opts.SecretAccessKey, Source: "S3Options"}, nil
+		}),
+	}
+	if opts.HTTPClient != nil {
+		s3Opts.HTTPClient = opts.HTTPClient
+	}
+	return &s3Store{
+		client: s3.New(s3Opts),
+		bucket: opts.Bucket,
+		prefix: path.Join(opts.Prefix, namespace, clusterName),
+	}, nil
+}
+
+func (s *s3Store) Save(ctx context.Context, name string, r io.Reader) (int64, error) {
+	// Objects are uploaded with a single request, which requires the length and the hash of the payload upfront;
+	// hence the snapshot is spooled to a temporary file first.
+	f, err := ioutil.TempFile("", "etcd-snapshot-")
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to create temporary file for etcd snapshot %s", name)
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	size, err := io.Copy(f, r)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to read etcd snapshot %s", name)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, errors.Wrapf(err, "failed to read etcd snapshot %s", name)
+	}
+
+	if _, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(s.key(name)),
+		Body:          f,
+		ContentLength: size,
+	}); err != nil {
+		return 0, errors.Wrapf(err, "failed to upload etcd snapshot %s", name)
+	}
+	return size, nil
+}
+
+func (s *s3Store) Load(ctx context.Context, name string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(name)),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, errors.Wrapf(ErrSnapshotNotFound, "failed to download etcd snapshot %s", name)
+		}
+		return nil, errors.Wrapf(err, "failed to download etcd snapshot %s", name)
+	}
+	return out.Body, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, name string) error {
+	if _, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(name)),
+	}); err != nil && !isNotFound(err) {
+		return errors.Wrapf(err, "failed to delete etcd snapshot %s", name)
+	}
+	return nil
+}
+
+func (s *s3Store) Stat(ctx context.Context, name string) (SnapshotInfo, error) {
+	out, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(name)),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return SnapshotInfo{}, errors.Wrapf(ErrSnapshotNotFound, "failed to look up etcd snapshot %s", name)
+		}
+		return SnapshotInfo{}, errors.Wrapf(err, "failed to look up etcd snapshot %s", name)
+	}
+
+	info := SnapshotInfo{Name: name, Size: out.ContentLength}
+	if out.LastModified != nil {
+		info.Timestamp = *out.LastModified
+	}
+	return info, nil
+}
+
+func (s *s3Store) List(ctx context.Context) ([]SnapshotInfo, error) {
+	prefix := s.prefix + "/"
+	snapshots := []SnapshotInfo{}
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list etcd snapshots")
+		}
+		for _, object := range page.Contents {
+			name := strings.TrimPrefix(aws.ToString(object.Key), prefix)
+			// Objects in nested prefixes are not snapshots of this cluster.
+			if strings.Contains(name, "/") || !strings.HasSuffix(name, ".db") {
+				continue
+			}
+			snapshots = append(snapshots, SnapshotInfo{Name: strings.TrimSuffix(name, ".db"), Size: object.Size, Timestamp: aws.ToTime(object.LastModified)})
+		}
+	}
+	sortSnapshots(snapshots)
+	return snapshots, nil
+}
+
+// DownloadURL returns a presigned URL for the snapshot with the given name; the URL is signed
+// with the credentials of the store, and can be used by anyone to download the snapshot until it expires.
+func (s *s3Store) DownloadURL(ctx context.Context, name string, expires time.Duration) (string, error) {
+	req, err := s3.NewPresignClient(s.client, s3.WithPresignExpires(expires)).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(name)),
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to presign the download of etcd snapshot %s", name)
+	}
+	return req.URL, nil
+}
+
+func (s *s3Store) key(name string) string {
+	return path.Join(s.prefix, name+".db")
+}
+
+// isNotFound returns true if err is the response of S3 to a request for an object or a bucket that does not exist.
+func isNotFound(err error) bool {
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound
+}
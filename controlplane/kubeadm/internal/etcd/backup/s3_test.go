@@ -0,0 +1,158 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+// listBucketResult is the response of the ListObjectsV2 S3 API, see
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectsV2.html.
+type listBucketResult struct {
+	XMLName  xml.Name `xml:"ListBucketResult"`
+	Contents []listBucketObject
+}
+
+type listBucketObject struct {
+	Key          string
+	Size         int64
+	LastModified string
+}
+
+func TestS3Store(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access-key/") &&
+			!strings.HasPrefix(r.URL.Query().Get("X-Amz-Credential"), "access-key/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := ioutil.ReadAll(r.Body)
+			objects[r.URL.Path] = data
+		case http.MethodHead:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		case http.MethodGet:
+			if r.URL.Query().Get("list-type") == "2" {
+				result := listBucketResult{}
+				prefix := path.Join(r.URL.Path, r.URL.Query().Get("prefix"))
+				for key, data := range objects {
+					if strings.HasPrefix(key, prefix+"/") {
+						result.Contents = append(result.Contents, listBucketObject{
+							Key:          strings.TrimPrefix(key, r.URL.Path+"/"),
+							Size:         int64(len(data)),
+							LastModified: time.Now().UTC().Format(time.RFC3339),
+						})
+					}
+				}
+				_ = xml.NewEncoder(w).Encode(result)
+				return
+			}
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	store, err := NewS3Store(S3Options{
+		Endpoint:        server.URL,
+		Region:          "us-east-1",
+		Bucket:          "bucket",
+		Prefix:          "backups",
+		AccessKeyID:     "access-key",
+		SecretAccessKey: "secret-key",
+	}, "ns", "cluster")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	size, err := store.Save(ctx, "snapshot", bytes.NewReader([]byte("0123456789")))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(size).To(Equal(int64(10)))
+	g.Expect(objects).To(HaveKey("/bucket/backups/ns/cluster/snapshot.db"))
+
+	objects["/bucket/backups/ns/cluster/nested/other.db"] = []byte("01234")
+	snapshots, err := store.List(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(snapshots).To(HaveLen(1))
+	g.Expect(snapshots[0].Name).To(Equal("snapshot"))
+	g.Expect(snapshots[0].Size).To(Equal(int64(10)))
+	delete(objects, "/bucket/backups/ns/cluster/nested/other.db")
+
+	r, err := store.Load(ctx, "snapshot")
+	g.Expect(err).ToNot(HaveOccurred())
+	data, err := ioutil.ReadAll(r)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(data).To(Equal([]byte("0123456789")))
+	g.Expect(r.Close()).To(Succeed())
+
+	info, err := store.Stat(ctx, "snapshot")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(info.Name).To(Equal("snapshot"))
+	g.Expect(info.Size).To(Equal(int64(10)))
+
+	downloadURL, err := store.(URLStore).DownloadURL(ctx, "snapshot", time.Hour)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(downloadURL).To(ContainSubstring("X-Amz-Signature="))
+	resp, err := http.Get(downloadURL)
+	g.Expect(err).ToNot(HaveOccurred())
+	data, err = ioutil.ReadAll(resp.Body)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(resp.Body.Close()).To(Succeed())
+	g.Expect(data).To(Equal([]byte("0123456789")))
+
+	g.Expect(store.Delete(ctx, "snapshot")).To(Succeed())
+	g.Expect(objects).To(BeEmpty())
+	_, err = store.Load(ctx, "snapshot")
+	g.Expect(errors.Is(err, ErrSnapshotNotFound)).To(BeTrue())
+	_, err = store.Stat(ctx, "snapshot")
+	g.Expect(errors.Is(err, ErrSnapshotNotFound)).To(BeTrue())
+	snapshots, err = store.List(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(snapshots).To(BeEmpty())
+}
@@ -0,0 +1,223 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// snapshotLabelName is the label set on the Secrets holding the chunks of a snapshot.
+	snapshotLabelName = "controlplane.cluster.x-k8s.io/etcd-snapshot"
+
+	// chunksAnnotation is the annotation set on the Secret holding the first chunk of a snapshot
+	// to record the number of chunks of the snapshot.
+	chunksAnnotation = "controlplane.cluster.x-k8s.io/etcd-snapshot-chunks"
+
+	// sizeAnnotation is the annotation set on the Secret holding the first chunk of a snapshot
+	// to record the size of the snapshot in bytes.
+	sizeAnnotation = "controlplane.cluster.x-k8s.io/etcd-snapshot-size"
+
+	// chunkKey is the key of the Secret data holding a chunk.
+	chunkKey = "chunk"
+)
+
+// chunkSize is the maximum size of a chunk, well below the size limit of Secrets.
+var chunkSize = 512 * 1024
+
+// secretStore stores snapshots in chunks of Secrets.
+// The Secret holding the first chunk is written last, so a snapshot can be loaded only once all its chunks are stored.
+type secretStore struct {
+	client      client.Client
+	namespace   string
+	clusterName string
+}
+
+// NewSecretStore returns a Store keeping the snapshots of a cluster in Secrets in the given namespace.
+func NewSecretStore(c client.Client, namespace, clusterName string) Store {
+	return &secretStore{client: c, namespace: namespace, clusterName: clusterName}
+}
+
+func (s *secretStore) Save(ctx context.Context, name string, r io.Reader) (int64, error) {
+	var first []byte
+	var size int64
+	chunks := 0
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			data := append([]byte(nil), buf[:n]...)
+			if chunks == 0 {
+				first = data
+			} else if cerr := s.createChunk(ctx, name, chunks, data, nil); cerr != nil {
+				_ = s.Delete(ctx, name)
+				return 0, cerr
+			}
+			chunks++
+			size += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			_ = s.Delete(ctx, name)
+			return 0, errors.Wrapf(err, "failed to read etcd snapshot %s", name)
+		}
+	}
+	if chunks == 0 {
+		return 0, errors.Errorf("etcd snapshot %s is empty", name)
+	}
+
+	if err := s.createChunk(ctx, name, 0, first, map[string]string{
+		chunksAnnotation: strconv.Itoa(chunks),
+		sizeAnnotation:   strconv.FormatInt(size, 10),
+	}); err != nil {
+		_ = s.Delete(ctx, name)
+		return 0, err
+	}
+	return size, nil
+}
+
+func (s *secretStore) createChunk(ctx context.Context, name string, index int, data []byte, annotations map[string]string) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.chunkName(name, index),
+			Namespace: s.namespace,
+			Labels: map[string]string{
+				clusterv1.ClusterLabelName: s.clusterName,
+				snapshotLabelName:          name,
+			},
+			Annotations: annotations,
+		},
+		Type: clusterv1.ClusterSecretType,
+		Data: map[string][]byte{chunkKey: data},
+	}
+	if err := s.client.Create(ctx, secret); err != nil {
+		return errors.Wrapf(err, "failed to create Secret %s/%s", secret.Namespace, secret.Name)
+	}
+	return nil
+}
+
+func (s *secretStore) getChunk(ctx context.Context, name string, index int) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: s.namespace, Name: s.chunkName(name, index)}
+	if err := s.client.Get(ctx, key, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(ErrSnapshotNotFound, "failed to get chunk %d of etcd snapshot %s", index, name)
+		}
+		return nil, errors.Wrapf(err, "failed to get Secret %s/%s", key.Namespace, key.Name)
+	}
+	return secret, nil
+}
+
+func (s *secretStore) chunkName(name string, index int) string {
+	return fmt.Sprintf("%s-%s-%d", s.clusterName, name, index)
+}
+
+func (s *secretStore) Stat(ctx context.Context, name string) (SnapshotInfo, error) {
+	first, err := s.getChunk(ctx, name, 0)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	size, _ := strconv.ParseInt(first.Annotations[sizeAnnotation], 10, 64)
+	return SnapshotInfo{Name: name, Size: size, Timestamp: first.CreationTimestamp.Time}, nil
+}
+
+func (s *secretStore) List(ctx context.Context) ([]SnapshotInfo, error) {
+	secrets := &corev1.SecretList{}
+	if err := s.client.List(ctx, secrets,
+		client.InNamespace(s.namespace),
+		client.MatchingLabels{clusterv1.ClusterLabelName: s.clusterName},
+		client.HasLabels{snapshotLabelName},
+	); err != nil {
+		return nil, errors.Wrap(err, "failed to list etcd snapshots")
+	}
+	snapshots := []SnapshotInfo{}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		name := secret.Labels[snapshotLabelName]
+		// Only the Secrets holding the first chunk describe a snapshot; they are written last,
+		// so snapshots being written are ignored.
+		if secret.Name != s.chunkName(name, 0) {
+			continue
+		}
+		size, _ := strconv.ParseInt(secret.Annotations[sizeAnnotation], 10, 64)
+		snapshots = append(snapshots, SnapshotInfo{Name: name, Size: size, Timestamp: secret.CreationTimestamp.Time})
+	}
+	sortSnapshots(snapshots)
+	return snapshots, nil
+}
+
+func (s *secretStore) Load(ctx context.Context, name string) (io.ReadCloser, error) {
+	first, err := s.getChunk(ctx, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := strconv.Atoi(first.Annotations[chunksAnnotation])
+	if err != nil || chunks < 1 {
+		return nil, errors.Errorf("invalid number of chunks %q for etcd snapshot %s", first.Annotations[chunksAnnotation], name)
+	}
+	return &chunkReader{ctx: ctx, store: s, name: name, chunks: chunks, next: 1, current: bytes.NewReader(first.Data[chunkKey])}, nil
+}
+
+func (s *secretStore) Delete(ctx context.Context, name string) error {
+	err := s.client.DeleteAllOf(ctx, &corev1.Secret{},
+		client.InNamespace(s.namespace),
+		client.MatchingLabels{clusterv1.ClusterLabelName: s.clusterName, snapshotLabelName: name},
+	)
+	return errors.Wrapf(err, "failed to delete etcd snapshot %s", name)
+}
+
+// chunkReader reads the chunks of a snapshot, getting them one at a time.
+type chunkReader struct {
+	ctx     context.Context
+	store   *secretStore
+	name    string
+	chunks  int
+	next    int
+	current *bytes.Reader
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for r.current.Len() == 0 {
+		if r.next >= r.chunks {
+			return 0, io.EOF
+		}
+		secret, err := r.store.getChunk(r.ctx, r.name, r.next)
+		if err != nil {
+			return 0, err
+		}
+		r.current = bytes.NewReader(secret.Data[chunkKey])
+		r.next++
+	}
+	return r.current.Read(p)
+}
+
+func (r *chunkReader) Close() error {
+	return nil
+}
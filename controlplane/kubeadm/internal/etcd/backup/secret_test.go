@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSecretStore(t *testing.T) {
+	defer func(size int) { chunkSize = size }(chunkSize)
+	chunkSize = 4
+
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	t.Run("saves, loads and deletes a snapshot in chunks", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		store := NewSecretStore(c, "ns", "cluster")
+
+		size, err := store.Save(ctx, "snapshot", bytes.NewReader([]byte("0123456789")))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(size).To(Equal(int64(10)))
+
+		secrets := &corev1.SecretList{}
+		g.Expect(c.List(ctx, secrets, client.InNamespace("ns"))).To(Succeed())
+		g.Expect(secrets.Items).To(HaveLen(3))
+
+		snapshots, err := store.List(ctx)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(snapshots).To(HaveLen(1))
+		g.Expect(snapshots[0].Name).To(Equal("snapshot"))
+		g.Expect(snapshots[0].Size).To(Equal(int64(10)))
+
+		info, err := store.Stat(ctx, "snapshot")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(info.Name).To(Equal("snapshot"))
+		g.Expect(info.Size).To(Equal(int64(10)))
+
+		r, err := store.Load(ctx, "snapshot")
+		g.Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(r)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(data).To(Equal([]byte("0123456789")))
+		g.Expect(r.Close()).To(Succeed())
+
+		g.Expect(store.Delete(ctx, "snapshot")).To(Succeed())
+		g.Expect(c.List(ctx, secrets, client.InNamespace("ns"))).To(Succeed())
+		g.Expect(secrets.Items).To(BeEmpty())
+
+		_, err = store.Load(ctx, "snapshot")
+		g.Expect(errors.Is(err, ErrSnapshotNotFound)).To(BeTrue())
+		_, err = store.Stat(ctx, "snapshot")
+		g.Expect(errors.Is(err, ErrSnapshotNotFound)).To(BeTrue())
+	})
+
+	t.Run("does not delete the snapshots of other clusters", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		store := NewSecretStore(c, "ns", "cluster")
+		other := NewSecretStore(c, "ns", "other")
+
+		_, err := store.Save(ctx, "snapshot", bytes.NewReader([]byte("01234")))
+		g.Expect(err).ToNot(HaveOccurred())
+		_, err = other.Save(ctx, "snapshot", bytes.NewReader([]byte("56789")))
+		g.Expect(err).ToNot(HaveOccurred())
+
+		snapshots, err := store.List(ctx)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(snapshots).To(HaveLen(1))
+
+		g.Expect(store.Delete(ctx, "snapshot")).To(Succeed())
+		snapshots, err = store.List(ctx)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(snapshots).To(BeEmpty())
+		r, err := other.Load(ctx, "snapshot")
+		g.Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(r)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(data).To(Equal([]byte("56789")))
+	})
+
+	t.Run("fails to save an empty snapshot", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		store := NewSecretStore(c, "ns", "cluster")
+
+		_, err := store.Save(ctx, "snapshot", bytes.NewReader(nil))
+		g.Expect(err).To(HaveOccurred())
+	})
+}
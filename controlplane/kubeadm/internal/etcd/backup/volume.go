@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// volumeStore stores snapshots as files in a directory.
+type volumeStore struct {
+	dir string
+}
+
+// NewVolumeStore returns a Store keeping the snapshots of a cluster in a subdirectory of dir.
+func NewVolumeStore(dir, namespace, clusterName string) Store {
+	return &volumeStore{dir: filepath.Join(dir, namespace, clusterName)}
+}
+
+func (s *volumeStore) Save(_ context.Context, name string, r io.Reader) (int64, error) {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return 0, errors.Wrapf(err, "failed to create directory %s", s.dir)
+	}
+
+	// Write to a temporary file first, so a partially written snapshot is never loaded.
+	f, err := ioutil.TempFile(s.dir, name+".*.tmp")
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to create file for etcd snapshot %s", name)
+	}
+	defer os.Remove(f.Name())
+
+	size, err := io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return 0, errors.Wrapf(err, "failed to write etcd snapshot %s", name)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return 0, errors.Wrapf(err, "failed to write etcd snapshot %s", name)
+	}
+	if err := f.Close(); err != nil {
+		return 0, errors.Wrapf(err, "failed to write etcd snapshot %s", name)
+	}
+	if err := os.Rename(f.Name(), s.path(name)); err != nil {
+		return 0, errors.Wrapf(err, "failed to write etcd snapshot %s", name)
+	}
+	return size, nil
+}
+
+func (s *volumeStore) Stat(_ context.Context, name string) (SnapshotInfo, error) {
+	fi, err := os.Stat(s.path(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return SnapshotInfo{}, errors.Wrapf(ErrSnapshotNotFound, "failed to look up etcd snapshot %s", name)
+		}
+		return SnapshotInfo{}, errors.Wrapf(err, "failed to look up etcd snapshot %s", name)
+	}
+	return SnapshotInfo{Name: name, Size: fi.Size(), Timestamp: fi.ModTime()}, nil
+}
+
+func (s *volumeStore) List(_ context.Context) ([]SnapshotInfo, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to list etcd snapshots in directory %s", s.dir)
+	}
+	snapshots := []SnapshotInfo{}
+	for _, fi := range files {
+		// Temporary files of snapshots being written are ignored.
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".db") {
+			continue
+		}
+		snapshots = append(snapshots, SnapshotInfo{Name: strings.TrimSuffix(fi.Name(), ".db"), Size: fi.Size(), Timestamp: fi.ModTime()})
+	}
+	sortSnapshots(snapshots)
+	return snapshots, nil
+}
+
+func (s *volumeStore) Load(_ context.Context, name string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Wrapf(ErrSnapshotNotFound, "failed to open etcd snapshot %s", name)
+		}
+		return nil, errors.Wrapf(err, "failed to open etcd snapshot %s", name)
+	}
+	return f, nil
+}
+
+func (s *volumeStore) Delete(_ context.Context, name string) error {
+	if err := os.Remove(s.path(name)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to delete etcd snapshot %s", name)
+	}
+	return nil
+}
+
+func (s *volumeStore) path(name string) string {
+	return filepath.Join(s.dir, name+".db")
+}
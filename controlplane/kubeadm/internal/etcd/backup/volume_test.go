@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+func TestVolumeStore(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := NewVolumeStore(dir, "ns", "cluster")
+
+	size, err := store.Save(ctx, "snapshot", bytes.NewReader([]byte("0123456789")))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(size).To(Equal(int64(10)))
+	g.Expect(filepath.Join(dir, "ns", "cluster", "snapshot.db")).To(BeARegularFile())
+
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "ns", "cluster", "other.db.1234.tmp"), nil, 0600)).To(Succeed())
+	snapshots, err := store.List(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(snapshots).To(HaveLen(1))
+	g.Expect(snapshots[0].Name).To(Equal("snapshot"))
+	g.Expect(snapshots[0].Size).To(Equal(int64(10)))
+
+	info, err := store.Stat(ctx, "snapshot")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(info.Name).To(Equal("snapshot"))
+	g.Expect(info.Size).To(Equal(int64(10)))
+
+	r, err := store.Load(ctx, "snapshot")
+	g.Expect(err).ToNot(HaveOccurred())
+	data, err := ioutil.ReadAll(r)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(data).To(Equal([]byte("0123456789")))
+	g.Expect(r.Close()).To(Succeed())
+
+	g.Expect(store.Delete(ctx, "snapshot")).To(Succeed())
+	g.Expect(store.Delete(ctx, "snapshot")).To(Succeed())
+	_, err = store.Load(ctx, "snapshot")
+	g.Expect(errors.Is(err, ErrSnapshotNotFound)).To(BeTrue())
+	_, err = store.Stat(ctx, "snapshot")
+	g.Expect(errors.Is(err, ErrSnapshotNotFound)).To(BeTrue())
+	snapshots, err = store.List(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(snapshots).To(BeEmpty())
+
+	// Listing the snapshots of a cluster without snapshots is not an error.
+	snapshots, err = NewVolumeStore(dir, "ns", "other").List(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(snapshots).To(BeEmpty())
+}
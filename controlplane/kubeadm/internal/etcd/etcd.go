@@ -19,6 +19,7 @@ package etcd
 import (
 	"context"
 	"crypto/tls"
+	"io"
 	"net"
 	"time"
 
@@ -38,13 +39,17 @@ type GRPCDial func(ctx context.Context, addr string) (net.Conn, error)
 // etcd wraps the etcd client from etcd's clientv3 package.
 // This interface is implemented by both the clientv3 package and the backoff adapter that adds retries to the client.
 type etcd interface {
+	AlarmDisarm(ctx context.Context, m *clientv3.AlarmMember) (*clientv3.AlarmResponse, error)
 	AlarmList(ctx context.Context) (*clientv3.AlarmResponse, error)
 	Close() error
+	Defragment(ctx context.Context, endpoint string) (*clientv3.DefragmentResponse, error)
 	Endpoints() []string
+	Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
 	MemberList(ctx context.Context) (*clientv3.MemberListResponse, error)
 	MemberRemove(ctx context.Context, id uint64) (*clientv3.MemberRemoveResponse, error)
 	MemberUpdate(ctx context.Context, id uint64, peerURLs []string) (*clientv3.MemberUpdateResponse, error)
 	MoveLeader(ctx context.Context, id uint64) (*clientv3.MoveLeaderResponse, error)
+	Snapshot(ctx context.Context) (io.ReadCloser, error)
 	Status(ctx context.Context, endpoint string) (*clientv3.StatusResponse, error)
 }
 
@@ -52,8 +57,14 @@ type etcd interface {
 type Client struct {
 	EtcdClient etcd
 	Endpoint   string
+	MemberID   uint64
 	LeaderID   uint64
 	Errors     []string
+	// DBSize is the size of the backend database of the member the client is connected to, in bytes.
+	DBSize int64
+	// DBSizeInUse is the size of the backend database logically in use, in bytes; the difference
+	// with DBSize is the space that can be reclaimed by defragmenting the member.
+	DBSizeInUse int64
 }
 
 // MemberAlarm represents an alarm type association with a cluster member.
@@ -147,6 +158,29 @@ func NewClient(ctx context.Context, endpoints []string, p proxy.Proxy, tlsConfig
 	return newEtcdClient(ctx, etcdClient)
 }
 
+// NewExternalClient creates a new etcd client connecting directly to the given endpoints, e.g. the endpoints
+// of an external etcd cluster, with a TLS configuration.
+func NewExternalClient(ctx context.Context, endpoints []string, tlsConfig *tls.Config) (*Client, error) {
+	etcdClient, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: etcdTimeout,
+		DialOptions: []grpc.DialOption{
+			grpc.WithBlock(), // block until the underlying connection is up
+		},
+		TLS: tlsConfig,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create etcd client")
+	}
+
+	client, err := newEtcdClient(ctx, etcdClient)
+	if err != nil {
+		etcdClient.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
 func newEtcdClient(ctx context.Context, etcdClient etcd) (*Client, error) {
 	endpoints := etcdClient.Endpoints()
 	if len(endpoints) == 0 {
@@ -159,10 +193,13 @@ func newEtcdClient(ctx context.Context, etcdClient etcd) (*Client, error) {
 	}
 
 	return &Client{
-		Endpoint:   endpoints[0],
-		EtcdClient: etcdClient,
-		LeaderID:   status.Leader,
-		Errors:     status.Errors,
+		Endpoint:    endpoints[0],
+		EtcdClient:  etcdClient,
+		MemberID:    status.Header.GetMemberId(),
+		LeaderID:    status.Leader,
+		Errors:      status.Errors,
+		DBSize:      status.DbSize,
+		DBSizeInUse: status.DbSizeInUse,
 	}, nil
 }
 
@@ -226,6 +263,35 @@ func (c *Client) UpdateMemberPeerURLs(ctx context.Context, id uint64, peerURLs [
 	return members, nil
 }
 
+// Snapshot streams a snapshot of the backend database of the member the client is connected to.
+// The caller is responsible for closing the returned reader.
+func (c *Client) Snapshot(ctx context.Context) (io.ReadCloser, error) {
+	r, err := c.EtcdClient.Snapshot(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get snapshot of etcd member")
+	}
+	return r, nil
+}
+
+// Defragment defragments the backend database of the member the client is connected to;
+// the member does not serve requests until the defragmentation is complete.
+func (c *Client) Defragment(ctx context.Context) error {
+	_, err := c.EtcdClient.Defragment(ctx, c.Endpoint)
+	return errors.Wrapf(err, "failed to defragment etcd member %s", c.Endpoint)
+}
+
+// Value returns the value of a key, or an empty string if the key does not exist.
+func (c *Client) Value(ctx context.Context, key string) (string, error) {
+	response, err := c.EtcdClient.Get(ctx, key)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get etcd key %q", key)
+	}
+	if len(response.Kvs) == 0 {
+		return "", nil
+	}
+	return string(response.Kvs[0].Value), nil
+}
+
 // Alarms retrieves all alarms on a cluster.
 func (c *Client) Alarms(ctx context.Context) ([]MemberAlarm, error) {
 	alarmResponse, err := c.EtcdClient.AlarmList(ctx)
@@ -243,3 +309,12 @@ func (c *Client) Alarms(ctx context.Context) ([]MemberAlarm, error) {
 
 	return memberAlarms, nil
 }
+
+// DisarmAlarm disarms an alarm raised by a cluster member.
+func (c *Client) DisarmAlarm(ctx context.Context, alarm MemberAlarm) error {
+	_, err := c.EtcdClient.AlarmDisarm(ctx, &clientv3.AlarmMember{
+		MemberID: alarm.MemberID,
+		Alarm:    etcdserverpb.AlarmType(alarm.Type),
+	})
+	return errors.Wrapf(err, "failed to disarm %s alarm of etcd member %v", AlarmTypeName[alarm.Type], alarm.MemberID)
+}
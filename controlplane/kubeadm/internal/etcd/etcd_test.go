@@ -17,6 +17,7 @@ limitations under the License.
 package etcd
 
 import (
+	"io/ioutil"
 	"testing"
 
 	. "github.com/onsi/gomega"
@@ -24,6 +25,7 @@ import (
 	"github.com/pkg/errors"
 	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/mvcc/mvccpb"
 	etcdfake "sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd/fake"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
@@ -62,6 +64,17 @@ func TestEtcdMembers_WithErrors(t *testing.T) {
 	err = client.RemoveMember(ctx, 1234)
 	g.Expect(err).To(HaveOccurred())
 
+	_, err = client.Snapshot(ctx)
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = client.Value(ctx, "key")
+	g.Expect(err).To(HaveOccurred())
+
+	err = client.Defragment(ctx)
+	g.Expect(err).To(HaveOccurred())
+
+	err = client.DisarmAlarm(ctx, MemberAlarm{MemberID: 1234, Type: AlarmNoSpace})
+	g.Expect(err).To(HaveOccurred())
 }
 
 func TestEtcdMembers_WithSuccess(t *testing.T) {
@@ -84,11 +97,25 @@ func TestEtcdMembers_WithSuccess(t *testing.T) {
 		},
 		MemberRemoveResponse: &clientv3.MemberRemoveResponse{},
 		AlarmResponse:        &clientv3.AlarmResponse{},
-		StatusResponse:       &clientv3.StatusResponse{},
+		DefragmentResponse:   &clientv3.DefragmentResponse{},
+		StatusResponse: &clientv3.StatusResponse{
+			Header:      &etcdserverpb.ResponseHeader{MemberId: 1234},
+			Leader:      1234,
+			DbSize:      2048,
+			DbSizeInUse: 1024,
+		},
+		SnapshotData: []byte("snapshot"),
+		GetResponse: &clientv3.GetResponse{
+			Kvs: []*mvccpb.KeyValue{{Key: []byte("key"), Value: []byte("value")}},
+		},
 	}
 
 	client, err := newEtcdClient(ctx, fakeEtcdClient)
 	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(client.MemberID).To(Equal(uint64(1234)))
+	g.Expect(client.LeaderID).To(Equal(uint64(1234)))
+	g.Expect(client.DBSize).To(Equal(int64(2048)))
+	g.Expect(client.DBSizeInUse).To(Equal(int64(1024)))
 
 	members, err := client.Members(ctx)
 	g.Expect(err).NotTo(HaveOccurred())
@@ -104,4 +131,23 @@ func TestEtcdMembers_WithSuccess(t *testing.T) {
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(len(updatedMembers[0].PeerURLs)).To(Equal(2))
 	g.Expect(updatedMembers[0].PeerURLs).To(Equal([]string{"https://1.2.3.4:2000", "https://4.5.6.7:2000"}))
+
+	snapshot, err := client.Snapshot(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	defer snapshot.Close()
+	data, err := ioutil.ReadAll(snapshot)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(data).To(Equal([]byte("snapshot")))
+
+	value, err := client.Value(ctx, "key")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(value).To(Equal("value"))
+
+	err = client.Defragment(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(fakeEtcdClient.DefragmentedEndpoint).To(Equal("https://etcd-instance:2379"))
+
+	err = client.DisarmAlarm(ctx, MemberAlarm{MemberID: 1234, Type: AlarmNoSpace})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(fakeEtcdClient.DisarmedAlarms).To(Equal([]*clientv3.AlarmMember{{MemberID: 1234, Alarm: etcdserverpb.AlarmType_NOSPACE}}))
 }
@@ -17,22 +17,30 @@ limitations under the License.
 package fake
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"io/ioutil"
 
 	"go.etcd.io/etcd/clientv3"
 )
 
 type FakeEtcdClient struct {
 	AlarmResponse        *clientv3.AlarmResponse
+	DefragmentResponse   *clientv3.DefragmentResponse
 	EtcdEndpoints        []string
+	GetResponse          *clientv3.GetResponse
 	MemberListResponse   *clientv3.MemberListResponse
 	MemberRemoveResponse *clientv3.MemberRemoveResponse
 	MemberUpdateResponse *clientv3.MemberUpdateResponse
 	MoveLeaderResponse   *clientv3.MoveLeaderResponse
 	StatusResponse       *clientv3.StatusResponse
+	SnapshotData         []byte
 	ErrorResponse        error
 	MovedLeader          uint64
 	RemovedMember        uint64
+	DefragmentedEndpoint string
+	DisarmedAlarms       []*clientv3.AlarmMember
 }
 
 func (c *FakeEtcdClient) Endpoints() []string {
@@ -48,10 +56,24 @@ func (c *FakeEtcdClient) Close() error {
 	return nil
 }
 
+func (c *FakeEtcdClient) AlarmDisarm(_ context.Context, m *clientv3.AlarmMember) (*clientv3.AlarmResponse, error) {
+	c.DisarmedAlarms = append(c.DisarmedAlarms, m)
+	return c.AlarmResponse, c.ErrorResponse
+}
+
 func (c *FakeEtcdClient) AlarmList(_ context.Context) (*clientv3.AlarmResponse, error) {
 	return c.AlarmResponse, c.ErrorResponse
 }
 
+func (c *FakeEtcdClient) Defragment(_ context.Context, endpoint string) (*clientv3.DefragmentResponse, error) {
+	c.DefragmentedEndpoint = endpoint
+	return c.DefragmentResponse, c.ErrorResponse
+}
+
+func (c *FakeEtcdClient) Get(_ context.Context, _ string, _ ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	return c.GetResponse, c.ErrorResponse
+}
+
 func (c *FakeEtcdClient) MemberList(_ context.Context) (*clientv3.MemberListResponse, error) {
 	return c.MemberListResponse, c.ErrorResponse
 }
@@ -62,6 +84,9 @@ func (c *FakeEtcdClient) MemberRemove(_ context.Context, i uint64) (*clientv3.Me
 func (c *FakeEtcdClient) MemberUpdate(_ context.Context, _ uint64, _ []string) (*clientv3.MemberUpdateResponse, error) {
 	return c.MemberUpdateResponse, c.ErrorResponse
 }
+func (c *FakeEtcdClient) Snapshot(_ context.Context) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(c.SnapshotData)), c.ErrorResponse
+}
 func (c *FakeEtcdClient) Status(_ context.Context, _ string) (*clientv3.StatusResponse, error) {
 	return c.StatusResponse, nil
 }
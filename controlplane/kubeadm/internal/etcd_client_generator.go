@@ -95,3 +95,29 @@ func (c *etcdClientGenerator) forLeader(ctx context.Context, nodeNames []string)
 
 	return nil, errors.Wrap(kerrors.NewAggregate(errs), "could not establish a connection to the etcd leader")
 }
+
+// externalEtcdClientGenerator generates etcd clients that connect to the members of an external etcd cluster.
+type externalEtcdClientGenerator struct {
+	tlsConfig    *tls.Config
+	createClient clientCreator
+}
+
+// newExternalEtcdClientGenerator returns a new externalEtcdClientGenerator instance.
+func newExternalEtcdClientGenerator(tlsConfig *tls.Config) *externalEtcdClientGenerator {
+	ecg := &externalEtcdClientGenerator{tlsConfig: tlsConfig}
+
+	ecg.createClient = func(ctx context.Context, endpoints []string) (*etcd.Client, error) {
+		return etcd.NewExternalClient(ctx, endpoints, ecg.tlsConfig)
+	}
+
+	return ecg
+}
+
+// forEndpoint returns a client for the member of the external etcd cluster serving the given endpoint.
+func (c *externalEtcdClientGenerator) forEndpoint(ctx context.Context, endpoint string) (*etcd.Client, error) {
+	client, err := c.createClient(ctx, []string{endpoint})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not establish a connection to the etcd endpoint %s", endpoint)
+	}
+	return client, nil
+}
@@ -197,3 +197,47 @@ func TestForLeader(t *testing.T) {
 	}
 
 }
+
+func TestForEndpoint(t *testing.T) {
+	g := NewWithT(t)
+
+	tests := []struct {
+		name     string
+		endpoint string
+		cc       clientCreator
+
+		expectedErr    string
+		expectedClient etcd.Client
+	}{
+		{
+			name:     "Returns client successfully",
+			endpoint: "https://etcd-1:2379",
+			cc: func(ctx context.Context, endpoints []string) (*etcd.Client, error) {
+				return &etcd.Client{Endpoint: endpoints[0]}, nil
+			},
+			expectedClient: etcd.Client{Endpoint: "https://etcd-1:2379"},
+		},
+		{
+			name:     "Returns error",
+			endpoint: "https://etcd-1:2379",
+			cc: func(ctx context.Context, endpoints []string) (*etcd.Client, error) {
+				return nil, errors.New("something went wrong")
+			},
+			expectedErr: "could not establish a connection to the etcd endpoint https://etcd-1:2379: something went wrong",
+		},
+	}
+
+	for _, tt := range tests {
+		externalSubject := newExternalEtcdClientGenerator(&tls.Config{})
+		externalSubject.createClient = tt.cc
+
+		client, err := externalSubject.forEndpoint(ctx, tt.endpoint)
+
+		if tt.expectedErr != "" {
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).Should(Equal(tt.expectedErr))
+		} else {
+			g.Expect(*client).Should(Equal(tt.expectedClient))
+		}
+	}
+}
@@ -20,6 +20,9 @@ import (
 	"encoding/json"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"reflect"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1alpha4"
 	kubeadmv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/types/v1beta1"
@@ -37,6 +40,41 @@ func MatchesKCPConfiguration(infraConfigs map[string]*unstructured.Unstructured,
 	)
 }
 
+// ShouldRolloutBefore returns a filter to find all machines whose certificates expire within
+// rolloutBefore.CertificatesExpiryDays of the reconciliation time.
+func ShouldRolloutBefore(reconciliationTime *metav1.Time, rolloutBefore *controlplanev1.RolloutBefore) collections.Func {
+	return func(machine *clusterv1.Machine) bool {
+		if machine == nil || reconciliationTime == nil || rolloutBefore == nil || rolloutBefore.CertificatesExpiryDays == nil {
+			return false
+		}
+		value, ok := machine.Annotations[controlplanev1.CertificatesExpiryAnnotation]
+		if !ok {
+			return false
+		}
+		expiry, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			// An unparsable expiry date is ignored; the certificates are read again by the next certificates check,
+			// see HasCertificatesExpiry.
+			return false
+		}
+		threshold := reconciliationTime.Add(time.Duration(*rolloutBefore.CertificatesExpiryDays) * 24 * time.Hour)
+		return expiry.Before(threshold)
+	}
+}
+
+// HasCertificatesExpiry is a filter to find all machines whose certificates expiry date is recorded, and can be parsed.
+func HasCertificatesExpiry(machine *clusterv1.Machine) bool {
+	if machine == nil {
+		return false
+	}
+	value, ok := machine.Annotations[controlplanev1.CertificatesExpiryAnnotation]
+	if !ok {
+		return false
+	}
+	_, err := time.Parse(time.RFC3339, value)
+	return err == nil
+}
+
 // MatchesTemplateClonedFrom returns a filter to find all machines that match a given KCP infra template.
 func MatchesTemplateClonedFrom(infraConfigs map[string]*unstructured.Unstructured, kcp *controlplanev1.KubeadmControlPlane) collections.Func {
 	return func(machine *clusterv1.Machine) bool {
@@ -203,6 +241,21 @@ func cleanupConfigFields(kcpConfig *bootstrapv1.KubeadmConfigSpec, machineConfig
 		machineConfig.Spec.JoinConfiguration.NodeRegistration = emptyNodeRegistration
 	}
 
+	// If KCP InitConfiguration is not present, set machine InitConfiguration to nil (nothing can trigger rollout here).
+	// NOTE: this is required because KCP applies an InitConfiguration to skip the addons it does not manage in case no one is provided.
+	if kcpConfig.InitConfiguration == nil {
+		machineConfig.Spec.InitConfiguration = nil
+	}
+
+	// Cleanup InitConfiguration.SkipPhases from kcpConfig and machineConfig, because those info are relevant only for
+	// the init process and the initial machine gets additional phases to skip the addons not managed by KCP.
+	if kcpConfig.InitConfiguration != nil {
+		kcpConfig.InitConfiguration.SkipPhases = nil
+	}
+	if machineConfig.Spec.InitConfiguration != nil {
+		machineConfig.Spec.InitConfiguration.SkipPhases = nil
+	}
+
 	// Clear up the TypeMeta information from the comparison.
 	// NOTE: KCP types don't carry this information.
 	if machineConfig.Spec.InitConfiguration != nil && kcpConfig.InitConfiguration != nil {
@@ -19,10 +19,12 @@ package internal
 import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1alpha4"
 	kubeadmv1beta1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/types/v1beta1"
@@ -230,6 +232,40 @@ func TestCleanupConfigFields(t *testing.T) {
 		g.Expect(kcpConfig.JoinConfiguration).ToNot(BeNil())
 		g.Expect(machineConfig.Spec.JoinConfiguration.NodeRegistration).To(Equal(kubeadmv1beta1.NodeRegistrationOptions{}))
 	})
+	t.Run("InitConfiguration gets removed from MachineConfig if it was not derived by KCPConfig", func(t *testing.T) {
+		g := NewWithT(t)
+		kcpConfig := &bootstrapv1.KubeadmConfigSpec{
+			InitConfiguration: nil, // KCP not providing an InitConfiguration
+		}
+		machineConfig := &bootstrapv1.KubeadmConfig{
+			Spec: bootstrapv1.KubeadmConfigSpec{
+				InitConfiguration: &kubeadmv1beta1.InitConfiguration{
+					SkipPhases: []string{"addon/coredns"}, // Machine gets an InitConfiguration skipping the addons not managed by KCP
+				},
+			},
+		}
+		cleanupConfigFields(kcpConfig, machineConfig)
+		g.Expect(kcpConfig.InitConfiguration).To(BeNil())
+		g.Expect(machineConfig.Spec.InitConfiguration).To(BeNil())
+	})
+	t.Run("InitConfiguration.SkipPhases gets removed because it is not relevant for compare", func(t *testing.T) {
+		g := NewWithT(t)
+		kcpConfig := &bootstrapv1.KubeadmConfigSpec{
+			InitConfiguration: &kubeadmv1beta1.InitConfiguration{
+				SkipPhases: []string{"addon/coredns"},
+			},
+		}
+		machineConfig := &bootstrapv1.KubeadmConfig{
+			Spec: bootstrapv1.KubeadmConfigSpec{
+				InitConfiguration: &kubeadmv1beta1.InitConfiguration{
+					SkipPhases: []string{"addon/coredns", "addon/kube-proxy"},
+				},
+			},
+		}
+		cleanupConfigFields(kcpConfig, machineConfig)
+		g.Expect(kcpConfig.InitConfiguration.SkipPhases).To(BeNil())
+		g.Expect(machineConfig.Spec.InitConfiguration.SkipPhases).To(BeNil())
+	})
 	t.Run("InitConfiguration.TypeMeta gets removed from MachineConfig", func(t *testing.T) {
 		g := NewWithT(t)
 		kcpConfig := &bootstrapv1.KubeadmConfigSpec{
@@ -845,6 +881,86 @@ func TestMatchesKubeadmBootstrapConfig(t *testing.T) {
 	})
 }
 
+func TestShouldRolloutBefore(t *testing.T) {
+	reconciliationTime := metav1.Now()
+	rolloutBefore := &controlplanev1.RolloutBefore{CertificatesExpiryDays: pointer.Int32Ptr(14)}
+	machineExpiringIn := func(d time.Duration) *clusterv1.Machine {
+		return &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					controlplanev1.CertificatesExpiryAnnotation: reconciliationTime.Add(d).Format(time.RFC3339),
+				},
+			},
+		}
+	}
+
+	t.Run("nil machine returns false", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(ShouldRolloutBefore(&reconciliationTime, rolloutBefore)(nil)).To(BeFalse())
+	})
+
+	t.Run("returns false if rolloutBefore is not set", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(ShouldRolloutBefore(&reconciliationTime, nil)(machineExpiringIn(time.Hour))).To(BeFalse())
+		g.Expect(ShouldRolloutBefore(&reconciliationTime, &controlplanev1.RolloutBefore{})(machineExpiringIn(time.Hour))).To(BeFalse())
+	})
+
+	t.Run("returns false if the machine has no expiry annotation", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(ShouldRolloutBefore(&reconciliationTime, rolloutBefore)(&clusterv1.Machine{})).To(BeFalse())
+	})
+
+	t.Run("returns false if the expiry annotation cannot be parsed", func(t *testing.T) {
+		g := NewWithT(t)
+		m := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{controlplanev1.CertificatesExpiryAnnotation: "tomorrow"},
+			},
+		}
+		g.Expect(ShouldRolloutBefore(&reconciliationTime, rolloutBefore)(m)).To(BeFalse())
+	})
+
+	t.Run("returns false if the certificates expire after the threshold", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(ShouldRolloutBefore(&reconciliationTime, rolloutBefore)(machineExpiringIn(15 * 24 * time.Hour))).To(BeFalse())
+	})
+
+	t.Run("returns true if the certificates expire before the threshold", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(ShouldRolloutBefore(&reconciliationTime, rolloutBefore)(machineExpiringIn(13 * 24 * time.Hour))).To(BeTrue())
+	})
+}
+
+func TestHasCertificatesExpiry(t *testing.T) {
+	machineWithExpiry := func(value string) *clusterv1.Machine {
+		return &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{controlplanev1.CertificatesExpiryAnnotation: value},
+			},
+		}
+	}
+
+	t.Run("nil machine returns false", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(HasCertificatesExpiry(nil)).To(BeFalse())
+	})
+
+	t.Run("returns false if the machine has no expiry annotation", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(HasCertificatesExpiry(&clusterv1.Machine{})).To(BeFalse())
+	})
+
+	t.Run("returns false if the expiry annotation cannot be parsed", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(HasCertificatesExpiry(machineWithExpiry("tomorrow"))).To(BeFalse())
+	})
+
+	t.Run("returns true if the expiry annotation can be parsed", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(HasCertificatesExpiry(machineWithExpiry("2030-01-01T00:00:00Z"))).To(BeTrue())
+	})
+}
+
 func TestMatchesTemplateClonedFrom(t *testing.T) {
 	t.Run("nil machine returns false", func(t *testing.T) {
 		g := NewWithT(t)
@@ -25,6 +25,7 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"fmt"
+	"io"
 	"math/big"
 	"time"
 
@@ -60,9 +61,11 @@ var (
 type WorkloadCluster interface {
 	// Basic health and status checks.
 	ClusterStatus(ctx context.Context) (ClusterStatus, error)
+	CertificatesExpiry(ctx context.Context, nodeName string, kcp *controlplanev1.KubeadmControlPlane) (time.Time, error)
 	UpdateStaticPodConditions(ctx context.Context, controlPlane *ControlPlane)
 	UpdateEtcdConditions(ctx context.Context, controlPlane *ControlPlane)
 	EtcdMembers(ctx context.Context) ([]string, error)
+	EtcdLeader(ctx context.Context) (string, error)
 
 	// Upgrade related tasks.
 	ReconcileKubeletRBACBinding(ctx context.Context, version semver.Version) error
@@ -81,16 +84,28 @@ type WorkloadCluster interface {
 	RemoveNodeFromKubeadmConfigMap(ctx context.Context, nodeName string) error
 	ForwardEtcdLeadership(ctx context.Context, machine *clusterv1.Machine, leaderCandidate *clusterv1.Machine) error
 	AllowBootstrapTokensToGetNodes(ctx context.Context) error
+	UpdateClusterInfoCertificateAuthorities(ctx context.Context, caData []byte) error
+	NodesCreatedBefore(ctx context.Context, t time.Time) ([]string, error)
 
 	// State recovery tasks.
 	ReconcileEtcdMembers(ctx context.Context, nodeNames []string) ([]string, error)
+	EtcdSnapshot(ctx context.Context) (io.ReadCloser, error)
+	RestoredEtcdSnapshot(ctx context.Context) (string, error)
+	RemoveStaleControlPlaneNodes(ctx context.Context, nodeNames []string) ([]string, error)
+
+	// Etcd maintenance tasks.
+	EtcdMemberStatuses(ctx context.Context, nodeNames []string) ([]EtcdMemberStatus, error)
+	DefragmentEtcdMember(ctx context.Context, nodeName string) error
 }
 
 // Workload defines operations on workload clusters.
 type Workload struct {
-	Client              ctrlclient.Client
-	CoreDNSMigrator     coreDNSMigrator
-	etcdClientGenerator etcdClientFor
+	Client                      ctrlclient.Client
+	CoreDNSMigrator             coreDNSMigrator
+	etcdClientGenerator         etcdClientFor
+	externalEtcdClientGenerator externalEtcdClientFor
+	etcdTLSConfig               *tls.Config
+	podDialer                   podDialer
 }
 
 var _ WorkloadCluster = &Workload{}
@@ -384,6 +399,11 @@ func (w *Workload) UpdateKubeProxyImageInfo(ctx context.Context, kcp *controlpla
 		return nil
 	}
 
+	// Return early if kube-proxy is not managed by the control plane.
+	if kcp.Spec.Addons != nil && kcp.Spec.Addons.SkipKubeProxy {
+		return nil
+	}
+
 	ds := &appsv1.DaemonSet{}
 
 	if err := w.Client.Get(ctx, ctrlclient.ObjectKey{Name: kubeProxyKey, Namespace: metav1.NamespaceSystem}, ds); err != nil {
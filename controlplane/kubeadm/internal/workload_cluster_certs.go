@@ -0,0 +1,181 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	clientcmdv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/proxy"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// defaultAPIServerPort is the port the kube-apiserver listens on when not set in the InitConfiguration.
+	defaultAPIServerPort = 6443
+
+	// etcdClientPort is the port etcd serves clients on.
+	etcdClientPort = 2379
+
+	// clusterInfoConfigMapName is the name of the ConfigMap in the kube-public namespace kubeadm join
+	// discovers the cluster from.
+	clusterInfoConfigMapName = "cluster-info"
+
+	// clusterInfoKubeconfigKey is the key of the kubeconfig in the cluster-info ConfigMap.
+	clusterInfoKubeconfigKey = "kubeconfig"
+)
+
+// podDialer opens connections to the ports of the pods in the kube-system namespace of a workload cluster.
+type podDialer interface {
+	dial(ctx context.Context, podName string, port int) (net.Conn, error)
+}
+
+// proxyPodDialer opens connections to pods through the port-forward subresource of the workload cluster's API server.
+type proxyPodDialer struct {
+	restConfig *rest.Config
+}
+
+func (d *proxyPodDialer) dial(ctx context.Context, podName string, port int) (net.Conn, error) {
+	dialer, err := proxy.NewDialer(proxy.Proxy{
+		Kind:       "pods",
+		Namespace:  metav1.NamespaceSystem,
+		KubeConfig: rest.CopyConfig(d.restConfig),
+		Port:       port,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create dialer for pod %s", podName)
+	}
+	return dialer.DialContextWithAddr(ctx, podName)
+}
+
+// CertificatesExpiry returns the earliest expiry date of the serving certificates of the kube-apiserver and,
+// if etcd is managed by the control plane, of the etcd member running on the given node.
+func (w *Workload) CertificatesExpiry(ctx context.Context, nodeName string, kcp *controlplanev1.KubeadmControlPlane) (time.Time, error) {
+	apiServerPort := defaultAPIServerPort
+	if initConfiguration := kcp.Spec.KubeadmConfigSpec.InitConfiguration; initConfiguration != nil && initConfiguration.LocalAPIEndpoint.BindPort != 0 {
+		apiServerPort = int(initConfiguration.LocalAPIEndpoint.BindPort)
+	}
+	// The kube-apiserver accepts connections without client certificates.
+	expiry, err := w.servingCertificateExpiry(ctx, staticPodName("kube-apiserver", nodeName), apiServerPort, &tls.Config{})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if clusterConfiguration := kcp.Spec.KubeadmConfigSpec.ClusterConfiguration; clusterConfiguration != nil && clusterConfiguration.Etcd.External != nil {
+		return expiry, nil
+	}
+	// etcd requires a client certificate, so the one used by the etcd client is presented.
+	etcdExpiry, err := w.servingCertificateExpiry(ctx, staticPodName("etcd", nodeName), etcdClientPort, w.etcdTLSConfig)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if etcdExpiry.Before(expiry) {
+		expiry = etcdExpiry
+	}
+	return expiry, nil
+}
+
+// servingCertificateExpiry returns the expiry date of the certificate served on a port of a pod.
+func (w *Workload) servingCertificateExpiry(ctx context.Context, podName string, port int, tlsConfig *tls.Config) (time.Time, error) {
+	conn, err := w.podDialer.dial(ctx, podName, port)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "failed to connect to pod %s", podName)
+	}
+	defer conn.Close()
+
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	// The certificate is only inspected, not trusted.
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.InsecureSkipVerify = true
+	tlsConn := tls.Client(conn, tlsConfig)
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = tlsConn.SetDeadline(deadline)
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		return time.Time{}, errors.Wrapf(err, "failed to complete TLS handshake with pod %s", podName)
+	}
+	certificates := tlsConn.ConnectionState().PeerCertificates
+	if len(certificates) == 0 {
+		return time.Time{}, errors.Errorf("pod %s did not present any certificate", podName)
+	}
+	return certificates[0].NotAfter, nil
+}
+
+// UpdateClusterInfoCertificateAuthorities sets the certificate authorities trusted by the nodes joining the cluster,
+// as published in the cluster-info ConfigMap.
+func (w *Workload) UpdateClusterInfoCertificateAuthorities(ctx context.Context, caData []byte) error {
+	configMapKey := ctrlclient.ObjectKey{Name: clusterInfoConfigMapName, Namespace: metav1.NamespacePublic}
+	clusterInfo, err := w.getConfigMap(ctx, configMapKey)
+	if err != nil {
+		return err
+	}
+
+	config := &clientcmdv1.Config{}
+	if err := yaml.Unmarshal([]byte(clusterInfo.Data[clusterInfoKubeconfigKey]), config); err != nil {
+		return errors.Wrap(err, "failed to parse the cluster-info kubeconfig")
+	}
+	changed := false
+	for i := range config.Clusters {
+		if !bytes.Equal(config.Clusters[i].Cluster.CertificateAuthorityData, caData) {
+			config.Clusters[i].Cluster.CertificateAuthorityData = caData
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize the cluster-info kubeconfig")
+	}
+	clusterInfo.Data[clusterInfoKubeconfigKey] = string(out)
+	if err := w.Client.Update(ctx, clusterInfo); err != nil {
+		return errors.Wrap(err, "error updating cluster-info ConfigMap")
+	}
+	return nil
+}
+
+// NodesCreatedBefore returns the sorted names of the nodes created before the given time, e.g. the nodes which may
+// not trust the certificate authorities published since then.
+func (w *Workload) NodesCreatedBefore(ctx context.Context, t time.Time) ([]string, error) {
+	nodes := &corev1.NodeList{}
+	if err := w.Client.List(ctx, nodes); err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+	var names []string
+	for _, node := range nodes.Items {
+		if node.CreationTimestamp.Time.Before(t) {
+			names = append(names, node.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	cabpkv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1alpha4"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/types/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha4"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCertificatesExpiry(t *testing.T) {
+	apiServerExpiry := time.Now().Add(200 * 24 * time.Hour).Truncate(time.Second)
+	etcdExpiry := time.Now().Add(100 * 24 * time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name         string
+		kcp          *controlplanev1.KubeadmControlPlane
+		pods         map[string]time.Time
+		expectErr    bool
+		expectExpiry time.Time
+	}{
+		{
+			name: "returns the earliest expiry of the kube-apiserver and etcd certificates",
+			kcp:  &controlplanev1.KubeadmControlPlane{},
+			pods: map[string]time.Time{
+				"kube-apiserver-node-1:6443": apiServerExpiry,
+				"etcd-node-1:2379":           etcdExpiry,
+			},
+			expectExpiry: etcdExpiry,
+		},
+		{
+			name: "uses the kube-apiserver port of the init configuration",
+			kcp: &controlplanev1.KubeadmControlPlane{
+				Spec: controlplanev1.KubeadmControlPlaneSpec{
+					KubeadmConfigSpec: cabpkv1.KubeadmConfigSpec{
+						InitConfiguration: &kubeadmv1beta1.InitConfiguration{
+							LocalAPIEndpoint: kubeadmv1beta1.APIEndpoint{BindPort: 443},
+						},
+					},
+				},
+			},
+			pods: map[string]time.Time{
+				"kube-apiserver-node-1:443": apiServerExpiry,
+				"etcd-node-1:2379":          etcdExpiry,
+			},
+			expectExpiry: etcdExpiry,
+		},
+		{
+			name: "ignores etcd when it is external",
+			kcp: &controlplanev1.KubeadmControlPlane{
+				Spec: controlplanev1.KubeadmControlPlaneSpec{
+					KubeadmConfigSpec: cabpkv1.KubeadmConfigSpec{
+						ClusterConfiguration: &kubeadmv1beta1.ClusterConfiguration{
+							Etcd: kubeadmv1beta1.Etcd{External: &kubeadmv1beta1.ExternalEtcd{}},
+						},
+					},
+				},
+			},
+			pods: map[string]time.Time{
+				"kube-apiserver-node-1:6443": apiServerExpiry,
+			},
+			expectExpiry: apiServerExpiry,
+		},
+		{
+			name: "returns an error if a pod cannot be reached",
+			kcp:  &controlplanev1.KubeadmControlPlane{},
+			pods: map[string]time.Time{
+				"kube-apiserver-node-1:6443": apiServerExpiry,
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			w := &Workload{podDialer: newFakePodDialer(g, tt.pods)}
+			expiry, err := w.CertificatesExpiry(ctx, "node-1", tt.kcp)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(expiry.Equal(tt.expectExpiry)).To(BeTrue(), "expected %s, got %s", tt.expectExpiry, expiry)
+		})
+	}
+}
+
+func TestUpdateClusterInfoCertificateAuthorities(t *testing.T) {
+	g := NewWithT(t)
+
+	clusterInfo := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: clusterInfoConfigMapName, Namespace: metav1.NamespacePublic},
+		Data: map[string]string{
+			clusterInfoKubeconfigKey: `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: b2xkLWNh
+    server: https://10.0.0.1:6443
+  name: ""
+contexts: null
+current-context: ""
+kind: Config
+preferences: {}
+users: null
+`,
+			"jws-kubeconfig-abcdef": "signature",
+		},
+	}
+	fakeClient := fake.NewClientBuilder().WithObjects(clusterInfo).Build()
+	w := &Workload{Client: fakeClient}
+
+	g.Expect(w.UpdateClusterInfoCertificateAuthorities(ctx, []byte("old-ca\nnew-ca"))).To(Succeed())
+
+	actual := &corev1.ConfigMap{}
+	g.Expect(fakeClient.Get(ctx, ctrlclient.ObjectKeyFromObject(clusterInfo), actual)).To(Succeed())
+	config, err := clientcmd.Load([]byte(actual.Data[clusterInfoKubeconfigKey]))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(config.Clusters).To(HaveLen(1))
+	for _, cluster := range config.Clusters {
+		g.Expect(cluster.Server).To(Equal("https://10.0.0.1:6443"))
+		g.Expect(cluster.CertificateAuthorityData).To(Equal([]byte("old-ca\nnew-ca")))
+	}
+	g.Expect(actual.Data).To(HaveKeyWithValue("jws-kubeconfig-abcdef", "signature"))
+}
+
+// fakePodDialer serves self-signed certificates with the given expiry dates on "<pod name>:<port>" addresses.
+type fakePodDialer struct {
+	certificates map[string]tls.Certificate
+}
+
+func newFakePodDialer(g *WithT, pods map[string]time.Time) *fakePodDialer {
+	d := &fakePodDialer{certificates: map[string]tls.Certificate{}}
+	for addr, notAfter := range pods {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		g.Expect(err).ToNot(HaveOccurred())
+		tmpl := x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: addr},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     notAfter,
+			KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, key.Public(), key)
+		g.Expect(err).ToNot(HaveOccurred())
+		d.certificates[addr] = tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+	return d
+}
+
+func (d *fakePodDialer) dial(_ context.Context, podName string, port int) (net.Conn, error) {
+	certificate, ok := d.certificates[net.JoinHostPort(podName, strconv.Itoa(port))]
+	if !ok {
+		return nil, errors.Errorf("pod %s not found", podName)
+	}
+	client, server := net.Pipe()
+	go func() {
+		tlsServer := tls.Server(server, &tls.Config{Certificates: []tls.Certificate{certificate}})
+		_ = tlsServer.Handshake()
+	}()
+	return client, nil
+}
+
+func TestNodesCreatedBefore(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Now().Truncate(time.Second)
+	node := func(name string, creationTimestamp time.Time) ctrlclient.Object {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(creationTimestamp)}}
+	}
+	fakeClient := fake.NewClientBuilder().WithObjects(
+		node("old-2", now.Add(-time.Minute)),
+		node("new", now.Add(time.Minute)),
+		node("old-1", now.Add(-time.Hour)),
+	).Build()
+	w := &Workload{Client: fakeClient}
+
+	names, err := w.NodesCreatedBefore(ctx, now)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(names).To(Equal([]string{"old-1", "old-2"}))
+}
@@ -45,13 +45,109 @@ func (w *Workload) UpdateEtcdConditions(ctx context.Context, controlPlane *Contr
 	w.updateExternalEtcdConditions(ctx, controlPlane)
 }
 
-func (w *Workload) updateExternalEtcdConditions(ctx context.Context, controlPlane *ControlPlane) { //nolint:unparam
-	// When KCP is not responsible for external etcd, we are reporting only health at KCP level.
+func (w *Workload) updateExternalEtcdConditions(ctx context.Context, controlPlane *ControlPlane) {
+	// NOTE: The members of the external etcd cluster are reached at the endpoints of the kubeadm configuration,
+	// using the apiserver-etcd-client certificate; all the endpoints are expected to report the same list of members.
+	endpoints := controlPlane.KCP.Spec.KubeadmConfigSpec.ClusterConfiguration.Etcd.External.Endpoints
+	if len(endpoints) == 0 {
+		conditions.MarkUnknown(controlPlane.KCP, controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterInspectionFailedReason, "The external etcd cluster does not have any endpoint")
+		return
+	}
+
+	var (
+		// kcpErrors is used to store errors reported by the etcd members.
+		kcpErrors []string
+		// unreachable is used to store the endpoints KCP failed to connect to.
+		unreachable []string
+		// clusterID is used to store and compare the etcd's cluster id.
+		clusterID *uint64
+		// members is used to store the list of etcd members and compare with all the other endpoints.
+		members []*etcd.Member
+	)
+
+	for _, endpoint := range endpoints {
+		etcdClient, err := w.externalEtcdClientGenerator.forEndpoint(ctx, endpoint)
+		if err != nil {
+			unreachable = append(unreachable, endpoint)
+			continue
+		}
+		msg := checkExternalEtcdEndpoint(ctx, etcdClient, endpoint, &members, &clusterID)
+		etcdClient.Close()
+		if msg != "" {
+			kcpErrors = append(kcpErrors, msg)
+		}
+	}
+
+	// If none of the endpoints can be reached, e.g. because the external etcd cluster is not reachable from the
+	// management cluster, the health of etcd is unknown.
+	if len(unreachable) == len(endpoints) {
+		conditions.MarkUnknown(controlPlane.KCP, controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterInspectionFailedReason, "Failed to connect to the external etcd endpoints %s", strings.Join(unreachable, ", "))
+		return
+	}
+	for _, endpoint := range unreachable {
+		kcpErrors = append(kcpErrors, fmt.Sprintf("Failed to connect to the etcd endpoint %s", endpoint))
+	}
+
+	if len(kcpErrors) > 0 {
+		conditions.MarkFalse(controlPlane.KCP, controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "%s", strings.Join(kcpErrors, "; "))
+		return
+	}
 	conditions.MarkTrue(controlPlane.KCP, controlplanev1.EtcdClusterHealthyCondition)
+}
+
+// checkExternalEtcdEndpoint checks the health of the external etcd member serving the given endpoint, comparing the
+// members and the cluster ID it reports with the ones reported by the endpoints checked before, if any; it returns
+// a message describing the problem found, or an empty string if the member is healthy.
+func checkExternalEtcdEndpoint(ctx context.Context, etcdClient *etcd.Client, endpoint string, members *[]*etcd.Member, clusterID **uint64) string {
+	// While creating a new client, the status for the endpoint is retrieved; check if the endpoint has errors.
+	if len(etcdClient.Errors) > 0 {
+		return fmt.Sprintf("Etcd endpoint %s status reports errors: %s", endpoint, strings.Join(etcdClient.Errors, ", "))
+	}
+
+	// Gets the list etcd members known by this member.
+	currentMembers, err := etcdClient.Members(ctx)
+	if err != nil {
+		return fmt.Sprintf("Failed get answer from the etcd endpoint %s", endpoint)
+	}
+
+	// Check if the list of members IDs reported is the same as all other members.
+	// NOTE: the first member reporting this information is the baseline for this information.
+	if *members == nil {
+		*members = currentMembers
+	}
+	if !etcdutil.MemberEqual(*members, currentMembers) {
+		return fmt.Sprintf("Etcd endpoint %s reports the cluster is composed by members %s, but all previously seen etcd members are reporting %s", endpoint, etcdutil.MemberNames(currentMembers), etcdutil.MemberNames(*members))
+	}
+
+	// Retrieve the member serving the endpoint and check for alarms.
+	var member *etcd.Member
+	for _, m := range currentMembers {
+		if m.ID == etcdClient.MemberID {
+			member = m
+		}
+	}
+	if member == nil {
+		return fmt.Sprintf("Etcd endpoint %s is served by member %d, which is not part of the cluster", endpoint, etcdClient.MemberID)
+	}
+	alarmList := []string{}
+	for _, alarm := range member.Alarms {
+		if alarm != etcd.AlarmOk {
+			alarmList = append(alarmList, etcd.AlarmTypeName[alarm])
+		}
+	}
+	if len(alarmList) > 0 {
+		return fmt.Sprintf("Etcd member %s reports alarms: %s", member.Name, strings.Join(alarmList, ", "))
+	}
 
-	// TODO: check external etcd for alarms an possibly also for member errors
-	// this requires implementing an new type of etcd client generator given that it is not possible to use nodes
-	// as a source for the etcd endpoint address; the address of the external etcd should be available on the kubeadm configuration.
+	// Check if the member belongs to the same cluster as all other members.
+	// NOTE: the first member reporting this information is the baseline for this information.
+	if *clusterID == nil {
+		*clusterID = &member.ClusterID
+	}
+	if **clusterID != member.ClusterID {
+		return fmt.Sprintf("Etcd member %s has cluster ID %d, but all previously seen etcd members have cluster ID %d", member.Name, member.ClusterID, **clusterID)
+	}
+	return ""
 }
 
 func (w *Workload) updateManagedEtcdConditions(ctx context.Context, controlPlane *ControlPlane) {
@@ -67,9 +163,12 @@ func (w *Workload) updateManagedEtcdConditions(ctx context.Context, controlPlane
 	}
 
 	// Update conditions for etcd members on the nodes.
+	quota := controlPlane.EtcdQuotaBackendBytes()
 	var (
 		// kcpErrors is used to store errors that can't be reported on any machine.
 		kcpErrors []string
+		// kcpWarnings is used to store warnings about the size of the etcd database of the members.
+		kcpWarnings []string
 		// clusterID is used to store and compare the etcd's cluster id.
 		clusterID *uint64
 		// members is used to store the list of etcd members and compare with all the other nodes in the cluster.
@@ -109,6 +208,12 @@ func (w *Workload) updateManagedEtcdConditions(ctx context.Context, controlPlane
 		}
 		defer etcdClient.Close()
 
+		// Check the size of the member's database against the quota; once the quota is exceeded, etcd raises a NOSPACE alarm
+		// and the cluster only accepts reads and deletes.
+		if usage := etcdClient.DBSize * 100 / quota; usage >= etcdDatabaseQuotaWarningPercent {
+			kcpWarnings = append(kcpWarnings, fmt.Sprintf("Etcd member on node %s is using %d%% of its database quota", node.Name, usage))
+		}
+
 		// While creating a new client, forFirstAvailableNode retrieves the status for the endpoint; check if the endpoint has errors.
 		if len(etcdClient.Errors) > 0 {
 			conditions.MarkFalse(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "Etcd member status reports errors: %s", strings.Join(etcdClient.Errors, ", "))
@@ -174,6 +279,7 @@ func (w *Workload) updateManagedEtcdConditions(ctx context.Context, controlPlane
 		controlPlane:      controlPlane,
 		machineConditions: []clusterv1.ConditionType{controlplanev1.MachineEtcdMemberHealthyCondition},
 		kcpErrors:         kcpErrors,
+		kcpWarnings:       kcpWarnings,
 		condition:         controlplanev1.EtcdClusterHealthyCondition,
 		unhealthyReason:   controlplanev1.EtcdClusterUnhealthyReason,
 		unknownReason:     controlplanev1.EtcdClusterUnknownReason,
@@ -181,6 +287,10 @@ func (w *Workload) updateManagedEtcdConditions(ctx context.Context, controlPlane
 	})
 }
 
+// etcdDatabaseQuotaWarningPercent is the percentage of the database quota used by an etcd member
+// above which a warning is reported.
+const etcdDatabaseQuotaWarningPercent = 80
+
 func compareMachinesAndMembers(controlPlane *ControlPlane, members []*etcd.Member, kcpErrors []string) []string {
 	// NOTE: We run this check only if we actually know the list of members, otherwise the first for loop
 	// could generate a false negative when reporting missing etcd members.
@@ -475,6 +585,7 @@ type aggregateFromMachinesToKCPInput struct {
 	controlPlane      *ControlPlane
 	machineConditions []clusterv1.ConditionType
 	kcpErrors         []string
+	kcpWarnings       []string
 	condition         clusterv1.ConditionType
 	unhealthyReason   string
 	unknownReason     string
@@ -521,13 +632,16 @@ func aggregateFromMachinesToKCP(input aggregateFromMachinesToKCPInput) {
 		input.kcpErrors = append(input.kcpErrors, fmt.Sprintf("Following machines are reporting %s errors: %s", input.note, strings.Join(kcpMachinesWithErrors.List(), ", ")))
 	}
 	if len(input.kcpErrors) > 0 {
-		conditions.MarkFalse(input.controlPlane.KCP, input.condition, input.unhealthyReason, clusterv1.ConditionSeverityError, strings.Join(input.kcpErrors, "; "))
+		conditions.MarkFalse(input.controlPlane.KCP, input.condition, input.unhealthyReason, clusterv1.ConditionSeverityError, "%s", strings.Join(append(input.kcpErrors, input.kcpWarnings...), "; "))
 		return
 	}
 
-	// In case of no errors and at least one machine with warnings, report false, warnings.
+	// In case of no errors and at least one machine with warnings or KCP level warnings, report false, warnings.
 	if len(kcpMachinesWithWarnings) > 0 {
-		conditions.MarkFalse(input.controlPlane.KCP, input.condition, input.unhealthyReason, clusterv1.ConditionSeverityWarning, "Following machines are reporting %s warnings: %s", input.note, strings.Join(kcpMachinesWithWarnings.List(), ", "))
+		input.kcpWarnings = append([]string{fmt.Sprintf("Following machines are reporting %s warnings: %s", input.note, strings.Join(kcpMachinesWithWarnings.List(), ", "))}, input.kcpWarnings...)
+	}
+	if len(input.kcpWarnings) > 0 {
+		conditions.MarkFalse(input.controlPlane.KCP, input.condition, input.unhealthyReason, clusterv1.ConditionSeverityWarning, "%s", strings.Join(input.kcpWarnings, "; "))
 		return
 	}
 
@@ -17,6 +17,7 @@ limitations under the License.
 package internal
 
 import (
+	"context"
 	"sigs.k8s.io/cluster-api/util/collections"
 	"testing"
 
@@ -40,13 +41,14 @@ import (
 
 func TestUpdateEtcdConditions(t *testing.T) {
 	tests := []struct {
-		name                      string
-		kcp                       *controlplanev1.KubeadmControlPlane
-		machines                  []*clusterv1.Machine
-		injectClient              client.Client // This test is injecting a fake client because it is required to create nodes with a controlled Status or to fail with a specific error.
-		injectEtcdClientGenerator etcdClientFor // This test is injecting a fake etcdClientGenerator because it is required to nodes with a controlled Status or to fail with a specific error.
-		expectedKCPCondition      *clusterv1.Condition
-		expectedMachineConditions map[string]clusterv1.Conditions
+		name                              string
+		kcp                               *controlplanev1.KubeadmControlPlane
+		machines                          []*clusterv1.Machine
+		injectClient                      client.Client // This test is injecting a fake client because it is required to create nodes with a controlled Status or to fail with a specific error.
+		injectEtcdClientGenerator         etcdClientFor // This test is injecting a fake etcdClientGenerator because it is required to nodes with a controlled Status or to fail with a specific error.
+		injectExternalEtcdClientGenerator externalEtcdClientFor
+		expectedKCPCondition              *clusterv1.Condition
+		expectedMachineConditions         map[string]clusterv1.Conditions
 	}{
 		{
 			name: "if list nodes return an error should report all the conditions Unknown",
@@ -446,6 +448,69 @@ func TestUpdateEtcdConditions(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "etcd members close to the database quota should report a warning",
+			kcp: &controlplanev1.KubeadmControlPlane{
+				Spec: controlplanev1.KubeadmControlPlaneSpec{
+					KubeadmConfigSpec: bootstrapv1.KubeadmConfigSpec{
+						ClusterConfiguration: &v1beta1.ClusterConfiguration{
+							Etcd: v1beta1.Etcd{
+								Local: &v1beta1.LocalEtcd{
+									ExtraArgs: map[string]string{"quota-backend-bytes": "1000"},
+								},
+							},
+						},
+					},
+				},
+			},
+			machines: []*clusterv1.Machine{
+				fakeMachine("m1", withNodeRef("n1")),
+				fakeMachine("m2", withNodeRef("n2")),
+			},
+			injectClient: &fakeClient{
+				list: &corev1.NodeList{
+					Items: []corev1.Node{
+						*fakeNode("n1"),
+						*fakeNode("n2"),
+					},
+				},
+			},
+			injectEtcdClientGenerator: &fakeEtcdClientGenerator{
+				forNodesClientFunc: func(n []string) (*etcd.Client, error) {
+					dbSize := map[string]int64{"n1": 500, "n2": 900}
+					if _, ok := dbSize[n[0]]; !ok {
+						return nil, errors.New("no client for this node")
+					}
+					return &etcd.Client{
+						EtcdClient: &fake2.FakeEtcdClient{
+							EtcdEndpoints: []string{},
+							MemberListResponse: &clientv3.MemberListResponse{
+								Header: &pb.ResponseHeader{
+									ClusterId: uint64(1),
+								},
+								Members: []*pb.Member{
+									{Name: "n1", ID: uint64(1)},
+									{Name: "n2", ID: uint64(2)},
+								},
+							},
+							AlarmResponse: &clientv3.AlarmResponse{
+								Alarms: []*pb.AlarmMember{},
+							},
+						},
+						DBSize: dbSize[n[0]],
+					}, nil
+				},
+			},
+			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityWarning, "Etcd member on node %s is using %d%% of its database quota", "n2", 90),
+			expectedMachineConditions: map[string]clusterv1.Conditions{
+				"m1": {
+					*conditions.TrueCondition(controlplanev1.MachineEtcdMemberHealthyCondition),
+				},
+				"m2": {
+					*conditions.TrueCondition(controlplanev1.MachineEtcdMemberHealthyCondition),
+				},
+			},
+		},
 		{
 			name: "Eternal etcd should set a condition at KCP level",
 			kcp: &controlplanev1.KubeadmControlPlane{
@@ -453,12 +518,19 @@ func TestUpdateEtcdConditions(t *testing.T) {
 					KubeadmConfigSpec: bootstrapv1.KubeadmConfigSpec{
 						ClusterConfiguration: &v1beta1.ClusterConfiguration{
 							Etcd: v1beta1.Etcd{
-								External: &v1beta1.ExternalEtcd{},
+								External: &v1beta1.ExternalEtcd{
+									Endpoints: []string{"https://etcd-1:2379"},
+								},
 							},
 						},
 					},
 				},
 			},
+			injectExternalEtcdClientGenerator: &fakeExternalEtcdClientGenerator{
+				clients: map[string]*etcd.Client{
+					"https://etcd-1:2379": fakeExternalEtcdClient(1, nil),
+				},
+			},
 			expectedKCPCondition: conditions.TrueCondition(controlplanev1.EtcdClusterHealthyCondition),
 		},
 	}
@@ -470,8 +542,9 @@ func TestUpdateEtcdConditions(t *testing.T) {
 				tt.kcp = &controlplanev1.KubeadmControlPlane{}
 			}
 			w := &Workload{
-				Client:              tt.injectClient,
-				etcdClientGenerator: tt.injectEtcdClientGenerator,
+				Client:                      tt.injectClient,
+				etcdClientGenerator:         tt.injectEtcdClientGenerator,
+				externalEtcdClientGenerator: tt.injectExternalEtcdClientGenerator,
 			}
 			controlPane := &ControlPlane{
 				KCP:      tt.kcp,
@@ -490,6 +563,122 @@ func TestUpdateEtcdConditions(t *testing.T) {
 	}
 }
 
+func TestUpdateExternalEtcdConditions(t *testing.T) {
+	externalEtcdKCP := func(endpoints ...string) *controlplanev1.KubeadmControlPlane {
+		return &controlplanev1.KubeadmControlPlane{
+			Spec: controlplanev1.KubeadmControlPlaneSpec{
+				KubeadmConfigSpec: bootstrapv1.KubeadmConfigSpec{
+					ClusterConfiguration: &v1beta1.ClusterConfiguration{
+						Etcd: v1beta1.Etcd{
+							External: &v1beta1.ExternalEtcd{Endpoints: endpoints},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name                 string
+		endpoints            []string
+		clients              map[string]*etcd.Client
+		expectedKCPCondition *clusterv1.Condition
+	}{
+		{
+			name:                 "external etcd without endpoints should report unknown condition",
+			expectedKCPCondition: conditions.UnknownCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterInspectionFailedReason, "The external etcd cluster does not have any endpoint"),
+		},
+		{
+			name:                 "failure connecting to all the endpoints should report unknown condition",
+			endpoints:            []string{"https://etcd-1:2379", "https://etcd-2:2379"},
+			expectedKCPCondition: conditions.UnknownCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterInspectionFailedReason, "Failed to connect to the external etcd endpoints %s", "https://etcd-1:2379, https://etcd-2:2379"),
+		},
+		{
+			name:      "failure connecting to some endpoints should report false condition",
+			endpoints: []string{"https://etcd-1:2379", "https://etcd-2:2379"},
+			clients: map[string]*etcd.Client{
+				"https://etcd-1:2379": fakeExternalEtcdClient(1, nil),
+			},
+			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Failed to connect to the etcd endpoint %s", "https://etcd-2:2379"),
+		},
+		{
+			name:      "etcd members with alarms should report false condition",
+			endpoints: []string{"https://etcd-1:2379", "https://etcd-2:2379"},
+			clients: map[string]*etcd.Client{
+				"https://etcd-1:2379": fakeExternalEtcdClient(1, []*pb.AlarmMember{{MemberID: uint64(2), Alarm: pb.AlarmType_NOSPACE}}),
+				"https://etcd-2:2379": fakeExternalEtcdClient(2, []*pb.AlarmMember{{MemberID: uint64(2), Alarm: pb.AlarmType_NOSPACE}}),
+			},
+			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Etcd member %s reports alarms: %s", "etcd-2", "NOSPACE"),
+		},
+		{
+			name:      "etcd endpoints reporting status errors should report false condition",
+			endpoints: []string{"https://etcd-1:2379"},
+			clients: map[string]*etcd.Client{
+				"https://etcd-1:2379": func() *etcd.Client {
+					c := fakeExternalEtcdClient(1, nil)
+					c.Errors = []string{"etcdserver: no leader"}
+					return c
+				}(),
+			},
+			expectedKCPCondition: conditions.FalseCondition(controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterUnhealthyReason, clusterv1.ConditionSeverityError, "Etcd endpoint %s status reports errors: %s", "https://etcd-1:2379", "etcdserver: no leader"),
+		},
+		{
+			name:      "healthy etcd members should report true",
+			endpoints: []string{"https://etcd-1:2379", "https://etcd-2:2379"},
+			clients: map[string]*etcd.Client{
+				"https://etcd-1:2379": fakeExternalEtcdClient(1, nil),
+				"https://etcd-2:2379": fakeExternalEtcdClient(2, nil),
+			},
+			expectedKCPCondition: conditions.TrueCondition(controlplanev1.EtcdClusterHealthyCondition),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			kcp := externalEtcdKCP(tt.endpoints...)
+			w := &Workload{
+				externalEtcdClientGenerator: &fakeExternalEtcdClientGenerator{clients: tt.clients},
+			}
+			w.UpdateEtcdConditions(ctx, &ControlPlane{KCP: kcp})
+
+			g.Expect(*conditions.Get(kcp, controlplanev1.EtcdClusterHealthyCondition)).To(conditions.MatchCondition(*tt.expectedKCPCondition))
+		})
+	}
+}
+
+type fakeExternalEtcdClientGenerator struct {
+	clients map[string]*etcd.Client
+}
+
+func (c *fakeExternalEtcdClientGenerator) forEndpoint(_ context.Context, endpoint string) (*etcd.Client, error) {
+	if client, ok := c.clients[endpoint]; ok {
+		return client, nil
+	}
+	return nil, errors.Errorf("no client for endpoint %s", endpoint)
+}
+
+// fakeExternalEtcdClient returns a client for the given member of a two members external etcd cluster.
+func fakeExternalEtcdClient(memberID uint64, alarms []*pb.AlarmMember) *etcd.Client {
+	return &etcd.Client{
+		EtcdClient: &fake2.FakeEtcdClient{
+			MemberListResponse: &clientv3.MemberListResponse{
+				Header: &pb.ResponseHeader{
+					ClusterId: uint64(1),
+				},
+				Members: []*pb.Member{
+					{Name: "etcd-1", ID: uint64(1)},
+					{Name: "etcd-2", ID: uint64(2)},
+				},
+			},
+			AlarmResponse: &clientv3.AlarmResponse{
+				Alarms: alarms,
+			},
+		},
+		MemberID: memberID,
+	}
+}
+
 func TestUpdateStaticPodConditions(t *testing.T) {
 	n1APIServerPodName := staticPodName("kube-apiserver", "n1")
 	n1APIServerPodkey := client.ObjectKey{
@@ -73,6 +73,11 @@ func (w *Workload) UpdateCoreDNS(ctx context.Context, kcp *controlplanev1.Kubead
 		return nil
 	}
 
+	// Return early if CoreDNS is not managed by the control plane.
+	if kcp.Spec.Addons != nil && kcp.Spec.Addons.SkipCoreDNS {
+		return nil
+	}
+
 	// Return early if the configuration is nil.
 	if kcp.Spec.KubeadmConfigSpec.ClusterConfiguration == nil {
 		return nil
@@ -148,6 +148,25 @@ kind: ClusterConfiguration
 			objs:      []client.Object{badCM},
 			expectErr: false,
 		},
+		{
+			name: "returns early without error if core dns is not managed by KCP",
+			kcp: &controlplanev1.KubeadmControlPlane{
+				Spec: controlplanev1.KubeadmControlPlaneSpec{
+					KubeadmConfigSpec: cabpkv1.KubeadmConfigSpec{
+						ClusterConfiguration: &kubeadmv1.ClusterConfiguration{
+							DNS: kubeadmv1.DNS{
+								Type: "",
+							},
+						},
+					},
+					Addons: &controlplanev1.Addons{
+						SkipCoreDNS: true,
+					},
+				},
+			},
+			objs:      []client.Object{badCM},
+			expectErr: false,
+		},
 		{
 			name: "returns early without error if KCP ClusterConfiguration is nil",
 			kcp: &controlplanev1.KubeadmControlPlane{
@@ -18,10 +18,13 @@ package internal
 
 import (
 	"context"
+	"io"
 
 	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd"
 	etcdutil "sigs.k8s.io/cluster-api/controlplane/kubeadm/internal/etcd/util"
@@ -33,6 +36,10 @@ type etcdClientFor interface {
 	forLeader(ctx context.Context, nodeNames []string) (*etcd.Client, error)
 }
 
+type externalEtcdClientFor interface {
+	forEndpoint(ctx context.Context, endpoint string) (*etcd.Client, error)
+}
+
 // ReconcileEtcdMembers iterates over all etcd members and finds members that do not have corresponding nodes.
 // If there are any such members, it deletes them from etcd and removes their nodes from the kubeadm configmap so that kubeadm does not run etcd health checks on them.
 func (w *Workload) ReconcileEtcdMembers(ctx context.Context, nodeNames []string) ([]string, error) {
@@ -199,9 +206,96 @@ func (w *Workload) ForwardEtcdLeadership(ctx context.Context, machine *clusterv1
 	return nil
 }
 
-type EtcdMemberStatus struct {
-	Name       string
-	Responsive bool
+// EtcdSnapshot streams a snapshot of the etcd cluster from the first control plane node
+// with a reachable etcd member; closing the returned reader closes the connection to etcd.
+func (w *Workload) EtcdSnapshot(ctx context.Context) (io.ReadCloser, error) {
+	nodes, err := w.getControlPlaneNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list control plane nodes")
+	}
+	nodeNames := make([]string, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		nodeNames = append(nodeNames, node.Name)
+	}
+	etcdClient, err := w.etcdClientGenerator.forFirstAvailableNode(ctx, nodeNames)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create etcd client")
+	}
+
+	snapshot, err := etcdClient.Snapshot(ctx)
+	if err != nil {
+		etcdClient.Close()
+		return nil, err
+	}
+	return &etcdSnapshotReader{ReadCloser: snapshot, client: etcdClient}, nil
+}
+
+// etcdSnapshotReader closes the etcd client used to stream a snapshot along with the snapshot.
+type etcdSnapshotReader struct {
+	io.ReadCloser
+	client *etcd.Client
+}
+
+func (r *etcdSnapshotReader) Close() error {
+	err := r.ReadCloser.Close()
+	if cerr := r.client.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// EtcdRestoredSnapshotKey is the etcd key where the name of the snapshot etcd has been restored from
+// is stored once the restore is complete.
+const EtcdRestoredSnapshotKey = "/cluster.x-k8s.io/restored-etcd-snapshot"
+
+// RestoredEtcdSnapshot returns the name of the snapshot the etcd cluster has been restored from,
+// or an empty string if the etcd cluster has not been restored from a snapshot.
+func (w *Workload) RestoredEtcdSnapshot(ctx context.Context) (string, error) {
+	nodes, err := w.getControlPlaneNodes(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list control plane nodes")
+	}
+	nodeNames := make([]string, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		nodeNames = append(nodeNames, node.Name)
+	}
+	etcdClient, err := w.etcdClientGenerator.forFirstAvailableNode(ctx, nodeNames)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create etcd client")
+	}
+	defer etcdClient.Close()
+
+	return etcdClient.Value(ctx, EtcdRestoredSnapshotKey)
+}
+
+// RemoveStaleControlPlaneNodes deletes the control plane nodes not included in nodeNames, e.g. the nodes
+// of the machines that existed when a snapshot was taken, once the snapshot has been restored;
+// the nodes are removed from the kubeadm configmap as well. It returns the names of the deleted nodes.
+func (w *Workload) RemoveStaleControlPlaneNodes(ctx context.Context, nodeNames []string) ([]string, error) {
+	nodes, err := w.getControlPlaneNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list control plane nodes")
+	}
+
+	removedNodes := []string{}
+	errs := []error{}
+	keep := sets.NewString(nodeNames...)
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if keep.Has(node.Name) {
+			continue
+		}
+
+		removedNodes = append(removedNodes, node.Name)
+		if err := w.Client.Delete(ctx, node); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, errors.Wrapf(err, "failed to delete node %s", node.Name))
+		}
+		if err := w.RemoveNodeFromKubeadmConfigMap(ctx, node.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return removedNodes, kerrors.NewAggregate(errs)
 }
 
 // EtcdStatus returns the current status of the etcd cluster
@@ -234,3 +328,124 @@ func (w *Workload) EtcdMembers(ctx context.Context) ([]string, error) {
 	}
 	return names, nil
 }
+
+// EtcdLeader returns the name of the etcd member which is the leader of the etcd cluster.
+func (w *Workload) EtcdLeader(ctx context.Context) (string, error) {
+	nodes, err := w.getControlPlaneNodes(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list control plane nodes")
+	}
+	nodeNames := make([]string, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		nodeNames = append(nodeNames, node.Name)
+	}
+	etcdClient, err := w.etcdClientGenerator.forLeader(ctx, nodeNames)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create etcd client")
+	}
+	defer etcdClient.Close()
+
+	members, err := etcdClient.Members(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list etcd members using etcd client")
+	}
+
+	for _, member := range members {
+		if member.ID == etcdClient.LeaderID {
+			return member.Name, nil
+		}
+	}
+	return "", errors.Errorf("failed to find the etcd leader with ID %d among the etcd members", etcdClient.LeaderID)
+}
+
+// EtcdMemberStatus is the status of the etcd member running on a control plane node.
+type EtcdMemberStatus struct {
+	// Name is the name of the member, which is the name of the node the member is running on.
+	Name string
+
+	// IsLeader is true if the member is the leader of the etcd cluster.
+	IsLeader bool
+
+	// DBSize is the size of the backend database of the member, in bytes.
+	DBSize int64
+
+	// DBSizeInUse is the size of the backend database logically in use, in bytes.
+	DBSizeInUse int64
+
+	// Alarms is the list of alarms raised by the member.
+	Alarms []etcd.AlarmType
+}
+
+// EtcdMemberStatuses returns the status of the etcd members running on the given nodes; it fails if any of the
+// members can't be reached, so that maintenance operations are performed only when all the members are available.
+func (w *Workload) EtcdMemberStatuses(ctx context.Context, nodeNames []string) ([]EtcdMemberStatus, error) {
+	statuses := make([]EtcdMemberStatus, 0, len(nodeNames))
+	for _, nodeName := range nodeNames {
+		status, err := w.etcdMemberStatus(ctx, nodeName)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// etcdMemberStatus returns the status of the etcd member running on the given node.
+func (w *Workload) etcdMemberStatus(ctx context.Context, nodeName string) (EtcdMemberStatus, error) {
+	etcdClient, err := w.etcdClientGenerator.forFirstAvailableNode(ctx, []string{nodeName})
+	if err != nil {
+		return EtcdMemberStatus{}, errors.Wrapf(err, "failed to create etcd client for the member on node %s", nodeName)
+	}
+	defer etcdClient.Close()
+
+	members, err := etcdClient.Members(ctx)
+	if err != nil {
+		return EtcdMemberStatus{}, errors.Wrapf(err, "failed to list etcd members using the member on node %s", nodeName)
+	}
+	member := etcdutil.MemberForName(members, nodeName)
+	if member == nil {
+		return EtcdMemberStatus{}, errors.Errorf("failed to get etcd member from node %q", nodeName)
+	}
+
+	status := EtcdMemberStatus{
+		Name:        nodeName,
+		IsLeader:    member.ID == etcdClient.LeaderID,
+		DBSize:      etcdClient.DBSize,
+		DBSizeInUse: etcdClient.DBSizeInUse,
+	}
+	for _, alarm := range member.Alarms {
+		if alarm != etcd.AlarmOk {
+			status.Alarms = append(status.Alarms, alarm)
+		}
+	}
+	return status, nil
+}
+
+// DefragmentEtcdMember defragments the backend database of the etcd member running on the given node,
+// then disarms the NOSPACE alarm raised by the member, if any; etcd raises the alarm again
+// if the database still exceeds the quota after the defragmentation.
+func (w *Workload) DefragmentEtcdMember(ctx context.Context, nodeName string) error {
+	etcdClient, err := w.etcdClientGenerator.forFirstAvailableNode(ctx, []string{nodeName})
+	if err != nil {
+		return errors.Wrap(err, "failed to create etcd client")
+	}
+	defer etcdClient.Close()
+
+	if err := etcdClient.Defragment(ctx); err != nil {
+		return err
+	}
+
+	alarms, err := etcdClient.Alarms(ctx)
+	if err != nil {
+		return err
+	}
+	for _, alarm := range alarms {
+		if alarm.MemberID != etcdClient.MemberID || alarm.Type != etcd.AlarmNoSpace {
+			continue
+		}
+		if err := etcdClient.DisarmAlarm(ctx, alarm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -19,12 +19,14 @@ package internal
 import (
 	"context"
 	"errors"
+	"io/ioutil"
 	"testing"
 
 	. "github.com/onsi/gomega"
 
 	"go.etcd.io/etcd/clientv3"
 	pb "go.etcd.io/etcd/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/mvcc/mvccpb"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -569,6 +571,343 @@ kind: ClusterStatus`,
 
 }
 
+func TestEtcdSnapshot(t *testing.T) {
+	cp1 := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cp1",
+			Labels: map[string]string{
+				labelNodeRoleControlPlane: "",
+			},
+		},
+	}
+
+	tests := []struct {
+		name                string
+		etcdClientGenerator etcdClientFor
+		expectErr           bool
+		expectSnapshot      []byte
+	}{
+		{
+			name:                "returns an error if it fails to create the etcd client",
+			etcdClientGenerator: &fakeEtcdClientGenerator{forNodesErr: errors.New("no client")},
+			expectErr:           true,
+		},
+		{
+			name: "returns an error if the client errors getting the snapshot",
+			etcdClientGenerator: &fakeEtcdClientGenerator{
+				forNodesClient: &etcd.Client{
+					EtcdClient: &fake2.FakeEtcdClient{
+						ErrorResponse: errors.New("cannot get snapshot"),
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "streams the snapshot",
+			etcdClientGenerator: &fakeEtcdClientGenerator{
+				forNodesClient: &etcd.Client{
+					EtcdClient: &fake2.FakeEtcdClient{
+						SnapshotData: []byte("snapshot"),
+					},
+				},
+			},
+			expectSnapshot: []byte("snapshot"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			fakeClient := fake.NewClientBuilder().WithObjects(cp1).Build()
+			w := &Workload{
+				Client:              fakeClient,
+				etcdClientGenerator: tt.etcdClientGenerator,
+			}
+			snapshot, err := w.EtcdSnapshot(ctx)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			data, err := ioutil.ReadAll(snapshot)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(data).To(Equal(tt.expectSnapshot))
+			g.Expect(snapshot.Close()).To(Succeed())
+		})
+	}
+}
+
+func TestRestoredEtcdSnapshot(t *testing.T) {
+	cp1 := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cp1",
+			Labels: map[string]string{
+				labelNodeRoleControlPlane: "",
+			},
+		},
+	}
+
+	tests := []struct {
+		name                string
+		etcdClientGenerator etcdClientFor
+		expectErr           bool
+		expectSnapshot      string
+	}{
+		{
+			name:                "returns an error if it fails to create the etcd client",
+			etcdClientGenerator: &fakeEtcdClientGenerator{forNodesErr: errors.New("no client")},
+			expectErr:           true,
+		},
+		{
+			name: "returns an empty name if etcd has not been restored",
+			etcdClientGenerator: &fakeEtcdClientGenerator{
+				forNodesClient: &etcd.Client{
+					EtcdClient: &fake2.FakeEtcdClient{
+						GetResponse: &clientv3.GetResponse{},
+					},
+				},
+			},
+			expectSnapshot: "",
+		},
+		{
+			name: "returns the name of the restored snapshot",
+			etcdClientGenerator: &fakeEtcdClientGenerator{
+				forNodesClient: &etcd.Client{
+					EtcdClient: &fake2.FakeEtcdClient{
+						GetResponse: &clientv3.GetResponse{
+							Kvs: []*mvccpb.KeyValue{{Key: []byte(EtcdRestoredSnapshotKey), Value: []byte("etcd-snapshot-20210101000000")}},
+						},
+					},
+				},
+			},
+			expectSnapshot: "etcd-snapshot-20210101000000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			fakeClient := fake.NewClientBuilder().WithObjects(cp1).Build()
+			w := &Workload{
+				Client:              fakeClient,
+				etcdClientGenerator: tt.etcdClientGenerator,
+			}
+			snapshot, err := w.RestoredEtcdSnapshot(ctx)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(snapshot).To(Equal(tt.expectSnapshot))
+		})
+	}
+}
+
+func TestRemoveStaleControlPlaneNodes(t *testing.T) {
+	g := NewWithT(t)
+
+	kubeadmConfig := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kubeadmConfigKey,
+			Namespace: metav1.NamespaceSystem,
+		},
+		Data: map[string]string{
+			clusterStatusKey: `apiEndpoints:
+  old-node:
+    advertiseAddress: 10.0.0.1
+    bindPort: 6443
+  restored-node:
+    advertiseAddress: 10.0.0.2
+    bindPort: 6443
+apiVersion: kubeadm.k8s.io/v1beta2
+kind: ClusterStatus`,
+		},
+	}
+	oldNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "old-node",
+			Labels: map[string]string{labelNodeRoleControlPlane: ""},
+		},
+	}
+	restoredNode := oldNode.DeepCopy()
+	restoredNode.Name = "restored-node"
+	workerNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-node"}}
+
+	fakeClient := fake.NewClientBuilder().WithObjects(kubeadmConfig, oldNode, restoredNode, workerNode).Build()
+	w := &Workload{Client: fakeClient}
+	removed, err := w.RemoveStaleControlPlaneNodes(ctx, []string{"restored-node"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(removed).To(ConsistOf("old-node"))
+
+	nodes := &corev1.NodeList{}
+	g.Expect(fakeClient.List(ctx, nodes)).To(Succeed())
+	g.Expect(nodes.Items).To(HaveLen(2))
+	g.Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(kubeadmConfig), kubeadmConfig)).To(Succeed())
+	g.Expect(kubeadmConfig.Data[clusterStatusKey]).ToNot(ContainSubstring("old-node"))
+	g.Expect(kubeadmConfig.Data[clusterStatusKey]).To(ContainSubstring("restored-node"))
+}
+
+func TestEtcdLeader(t *testing.T) {
+	g := NewWithT(t)
+
+	cp1 := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "cp1",
+			Labels: map[string]string{labelNodeRoleControlPlane: ""},
+		},
+	}
+	cp2 := cp1.DeepCopy()
+	cp2.Name = "cp2"
+	fakeClient := fake.NewClientBuilder().WithObjects(cp1, cp2).Build()
+
+	leaderClient := &etcd.Client{
+		EtcdClient: &fake2.FakeEtcdClient{
+			MemberListResponse: &clientv3.MemberListResponse{
+				Members: []*pb.Member{
+					{Name: "cp1", ID: uint64(1)},
+					{Name: "cp2", ID: uint64(2)},
+				},
+			},
+			AlarmResponse: &clientv3.AlarmResponse{},
+		},
+		LeaderID: uint64(2),
+	}
+	w := &Workload{
+		Client:              fakeClient,
+		etcdClientGenerator: &fakeEtcdClientGenerator{forLeaderClient: leaderClient},
+	}
+	leader, err := w.EtcdLeader(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(leader).To(Equal("cp2"))
+
+	leaderClient.LeaderID = uint64(3)
+	_, err = w.EtcdLeader(ctx)
+	g.Expect(err).To(HaveOccurred())
+
+	w.etcdClientGenerator = &fakeEtcdClientGenerator{forLeaderErr: errors.New("no leader")}
+	_, err = w.EtcdLeader(ctx)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestEtcdMemberStatuses(t *testing.T) {
+	g := NewWithT(t)
+
+	memberList := &clientv3.MemberListResponse{
+		Header: &pb.ResponseHeader{},
+		Members: []*pb.Member{
+			{Name: "cp1", ID: uint64(1)},
+			{Name: "cp2", ID: uint64(2)},
+		},
+	}
+	clients := map[string]*etcd.Client{
+		"cp1": {
+			EtcdClient: &fake2.FakeEtcdClient{
+				MemberListResponse: memberList,
+				AlarmResponse: &clientv3.AlarmResponse{
+					Alarms: []*pb.AlarmMember{{MemberID: uint64(2), Alarm: pb.AlarmType_NOSPACE}},
+				},
+			},
+			LeaderID: uint64(1),
+			DBSize:   2048,
+		},
+		"cp2": {
+			EtcdClient: &fake2.FakeEtcdClient{
+				MemberListResponse: memberList,
+				AlarmResponse: &clientv3.AlarmResponse{
+					Alarms: []*pb.AlarmMember{{MemberID: uint64(2), Alarm: pb.AlarmType_NOSPACE}},
+				},
+			},
+			LeaderID:    uint64(1),
+			DBSize:      4096,
+			DBSizeInUse: 1024,
+		},
+	}
+	w := &Workload{
+		etcdClientGenerator: &fakeEtcdClientGenerator{
+			forNodesClientFunc: func(n []string) (*etcd.Client, error) {
+				if c, ok := clients[n[0]]; ok {
+					return c, nil
+				}
+				return nil, errors.New("no client for this node")
+			},
+		},
+	}
+
+	statuses, err := w.EtcdMemberStatuses(ctx, []string{"cp1", "cp2"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(statuses).To(Equal([]EtcdMemberStatus{
+		{Name: "cp1", IsLeader: true, DBSize: 2048},
+		{Name: "cp2", DBSize: 4096, DBSizeInUse: 1024, Alarms: []etcd.AlarmType{etcd.AlarmNoSpace}},
+	}))
+
+	_, err = w.EtcdMemberStatuses(ctx, []string{"cp1", "cp2", "cp3"})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestDefragmentEtcdMember(t *testing.T) {
+	tests := []struct {
+		name                 string
+		fakeEtcdClient       *fake2.FakeEtcdClient
+		expectErr            bool
+		expectDisarmedAlarms []*clientv3.AlarmMember
+	}{
+		{
+			name: "defragments the member",
+			fakeEtcdClient: &fake2.FakeEtcdClient{
+				AlarmResponse: &clientv3.AlarmResponse{},
+			},
+		},
+		{
+			name: "disarms the NOSPACE alarm of the member after the defragmentation",
+			fakeEtcdClient: &fake2.FakeEtcdClient{
+				AlarmResponse: &clientv3.AlarmResponse{
+					Alarms: []*pb.AlarmMember{
+						{MemberID: uint64(1), Alarm: pb.AlarmType_NOSPACE},
+						{MemberID: uint64(1), Alarm: pb.AlarmType_CORRUPT},
+						{MemberID: uint64(2), Alarm: pb.AlarmType_NOSPACE},
+					},
+				},
+			},
+			expectDisarmedAlarms: []*clientv3.AlarmMember{{MemberID: uint64(1), Alarm: pb.AlarmType_NOSPACE}},
+		},
+		{
+			name: "does not disarm alarms if the defragmentation fails",
+			fakeEtcdClient: &fake2.FakeEtcdClient{
+				AlarmResponse: &clientv3.AlarmResponse{
+					Alarms: []*pb.AlarmMember{{MemberID: uint64(1), Alarm: pb.AlarmType_NOSPACE}},
+				},
+				ErrorResponse: errors.New("defragmentation failed"),
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			tt.fakeEtcdClient.EtcdEndpoints = []string{"https://cp1:2379"}
+			w := &Workload{
+				etcdClientGenerator: &fakeEtcdClientGenerator{
+					forNodesClient: &etcd.Client{
+						EtcdClient: tt.fakeEtcdClient,
+						Endpoint:   "https://cp1:2379",
+						MemberID:   uint64(1),
+					},
+				},
+			}
+			err := w.DefragmentEtcdMember(ctx, "cp1")
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+			g.Expect(tt.fakeEtcdClient.DefragmentedEndpoint).To(Equal("https://cp1:2379"))
+			g.Expect(tt.fakeEtcdClient.DisarmedAlarms).To(Equal(tt.expectDisarmedAlarms))
+		})
+	}
+}
+
 type fakeEtcdClientGenerator struct {
 	forNodesClient     *etcd.Client
 	forNodesClientFunc func([]string) (*etcd.Client, error)
@@ -139,6 +139,19 @@ func TestUpdateKubeProxyImageInfo(t *testing.T) {
 					Version: "v1.16.3",
 				}},
 		},
+		{
+			name:        "does not update image repository when kube-proxy is not managed by KCP",
+			ds:          newKubeProxyDSWithImage(""), // Using the same image name that would otherwise lead to an error
+			expectErr:   false,
+			expectImage: "",
+			KCP: &v1alpha4.KubeadmControlPlane{
+				Spec: v1alpha4.KubeadmControlPlaneSpec{
+					Version: "v1.16.3",
+					Addons: &v1alpha4.Addons{
+						SkipKubeProxy: true,
+					},
+				}},
+		},
 	}
 
 	for _, tt := range tests {
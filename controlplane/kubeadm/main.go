@@ -70,6 +70,7 @@ var (
 	syncPeriod                     time.Duration
 	webhookPort                    int
 	webhookCertDir                 string
+	etcdBackupDir                  string
 )
 
 // InitFlags initializes the flags.
@@ -106,6 +107,9 @@ func InitFlags(fs *pflag.FlagSet) {
 
 	fs.StringVar(&webhookCertDir, "webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs/",
 		"Webhook cert dir, only used when webhook-port is specified.")
+
+	fs.StringVar(&etcdBackupDir, "etcd-backup-dir", "",
+		"Directory where etcd snapshots are stored when using the Volume etcd backup storage, usually a mounted PersistentVolumeClaim.")
 }
 func main() {
 	rand.Seed(time.Now().UnixNano())
@@ -180,8 +184,9 @@ func setupReconcilers(ctx context.Context, mgr ctrl.Manager) {
 	}
 
 	if err := (&kubeadmcontrolplanecontrollers.KubeadmControlPlaneReconciler{
-		Client:  mgr.GetClient(),
-		Tracker: tracker,
+		Client:        mgr.GetClient(),
+		Tracker:       tracker,
+		EtcdBackupDir: etcdBackupDir,
 	}).SetupWithManager(ctx, mgr, concurrency(kubeadmControlPlaneConcurrency)); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "KubeadmControlPlane")
 		os.Exit(1)
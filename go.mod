@@ -4,6 +4,8 @@ go 1.15
 
 require (
 	github.com/MakeNowJust/heredoc v1.0.0
+	github.com/aws/aws-sdk-go-v2 v1.16.16
+	github.com/aws/aws-sdk-go-v2/service/s3 v1.27.11
 	github.com/blang/semver v3.5.1+incompatible
 	github.com/coredns/corefile-migration v1.0.11
 	github.com/davecgh/go-spew v1.1.1
@@ -13,7 +15,7 @@ require (
 	github.com/fatih/color v1.7.0
 	github.com/go-logr/logr v0.3.0
 	github.com/gobuffalo/flect v0.2.2
-	github.com/google/go-cmp v0.5.2
+	github.com/google/go-cmp v0.5.8
 	github.com/google/go-github v17.0.0+incompatible
 	github.com/google/go-querystring v1.0.0 // indirect
 	github.com/google/gofuzz v1.2.0
@@ -28,6 +28,7 @@ import (
 	"github.com/spf13/pflag"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog"
@@ -72,6 +73,7 @@ var (
 	webhookPort                   int
 	webhookCertDir                string
 	healthAddr                    string
+	clusterNetworkGroupSelector   string
 )
 
 func init() {
@@ -144,6 +146,9 @@ func InitFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&healthAddr, "health-addr", ":9440",
 		"The address the health endpoint binds to.")
 
+	fs.StringVar(&clusterNetworkGroupSelector, "cluster-network-group-selector", "",
+		"Label selector identifying a group of clusters whose pods and services CIDR blocks must not overlap and whose control plane endpoints must be unique (e.g. network-group=prod). If unspecified, the validation is disabled.")
+
 	feature.MutableGates.AddFlag(fs)
 }
 
@@ -308,6 +313,26 @@ func setupWebhooks(mgr ctrl.Manager) {
 		os.Exit(1)
 	}
 
+	networkGroupSelector, err := labels.Parse(clusterNetworkGroupSelector)
+	if err != nil {
+		setupLog.Error(err, "unable to parse cluster network group selector")
+		os.Exit(1)
+	}
+	if err := (&clusterv1.ClusterNetworkValidator{
+		Client:   mgr.GetClient(),
+		Selector: networkGroupSelector,
+	}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "ClusterNetwork")
+		os.Exit(1)
+	}
+
+	if err := (&clusterv1.MachineDeletionProtectionValidator{
+		Client: mgr.GetClient(),
+	}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "MachineDeletionProtection")
+		os.Exit(1)
+	}
+
 	if err := (&clusterv1.Machine{}).SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "Machine")
 		os.Exit(1)
@@ -323,6 +348,13 @@ func setupWebhooks(mgr ctrl.Manager) {
 		os.Exit(1)
 	}
 
+	if err := (&clusterv1.MachineScaleValidator{
+		Client: mgr.GetClient(),
+	}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "MachineScale")
+		os.Exit(1)
+	}
+
 	if feature.Gates.Enabled(feature.MachinePool) {
 		if err := (&expv1.MachinePool{}).SetupWebhookWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "MachinePool")
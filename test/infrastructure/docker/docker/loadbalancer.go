@@ -21,6 +21,7 @@ import (
 	"fmt"
 
 	"github.com/pkg/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/test/infrastructure/docker/docker/types"
 	"sigs.k8s.io/cluster-api/test/infrastructure/docker/third_party/forked/loadbalancer"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -34,19 +35,25 @@ type lbCreator interface {
 // LoadBalancer manages the load balancer for a specific docker cluster.
 type LoadBalancer struct {
 	name      string
+	ipFamily  clusterv1.ClusterIPFamily
 	container *types.Node
 
 	lbCreator lbCreator
 }
 
-// NewLoadBalancer returns a new helper for managing a docker loadbalancer with a given name.
-func NewLoadBalancer(name string) (*LoadBalancer, error) {
-	if name == "" {
+// NewLoadBalancer returns a new helper for managing a docker loadbalancer for the given cluster.
+func NewLoadBalancer(cluster *clusterv1.Cluster) (*LoadBalancer, error) {
+	if cluster.Name == "" {
 		return nil, errors.New("name is required when creating a docker.LoadBalancer")
 	}
 
+	ipFamily, err := cluster.GetIPFamily()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get ip family for the cluster")
+	}
+
 	container, err := getContainer(
-		withLabel(clusterLabel(name)),
+		withLabel(clusterLabel(cluster.Name)),
 		withLabel(roleLabel(constants.ExternalLoadBalancerNodeRoleValue)),
 	)
 	if err != nil {
@@ -54,7 +61,8 @@ func NewLoadBalancer(name string) (*LoadBalancer, error) {
 	}
 
 	return &LoadBalancer{
-		name:      name,
+		name:      cluster.Name,
+		ipFamily:  ipFamily,
 		container: container,
 		lbCreator: &Manager{},
 	}, nil
@@ -72,12 +80,16 @@ func (s *LoadBalancer) Create(ctx context.Context) error {
 	// Create if not exists.
 	if s.container == nil {
 		var err error
+		listenAddress := "0.0.0.0"
+		if s.ipFamily == clusterv1.IPv6IPFamily {
+			listenAddress = "::"
+		}
 		log.Info("Creating load balancer container")
 		s.container, err = s.lbCreator.CreateExternalLoadBalancerNode(
 			s.containerName(),
 			loadbalancer.Image,
 			clusterLabel(s.name),
-			"0.0.0.0",
+			listenAddress,
 			0,
 		)
 		if err != nil {
@@ -107,17 +119,21 @@ func (s *LoadBalancer) UpdateConfiguration(ctx context.Context) error {
 
 	var backendServers = map[string]string{}
 	for _, n := range controlPlaneNodes {
-		controlPlaneIPv4, _, err := n.IP(ctx)
+		controlPlaneIPv4, controlPlaneIPv6, err := n.IP(ctx)
 		if err != nil {
 			return errors.Wrapf(err, "failed to get IP for container %s", n.String())
 		}
-		backendServers[n.String()] = fmt.Sprintf("%s:%d", controlPlaneIPv4, 6443)
+		if s.ipFamily == clusterv1.IPv6IPFamily {
+			backendServers[n.String()] = fmt.Sprintf("%s:%d", controlPlaneIPv6, 6443)
+		} else {
+			backendServers[n.String()] = fmt.Sprintf("%s:%d", controlPlaneIPv4, 6443)
+		}
 	}
 
 	loadBalancerConfig, err := loadbalancer.Config(&loadbalancer.ConfigData{
 		ControlPlanePort: 6443,
 		BackendServers:   backendServers,
-		IPv6:             false,
+		IPv6:             s.ipFamily == clusterv1.IPv6IPFamily,
 	})
 	if err != nil {
 		return errors.WithStack(err)
@@ -131,12 +147,18 @@ func (s *LoadBalancer) UpdateConfiguration(ctx context.Context) error {
 	return errors.WithStack(s.container.Kill(ctx, "SIGHUP"))
 }
 
-// IP returns the load balancer IP address
+// IP returns the load balancer IP address; for IPv6 clusters the IPv6 address is returned.
 func (s *LoadBalancer) IP(ctx context.Context) (string, error) {
-	lbip4, _, err := s.container.IP(ctx)
+	lbip4, lbip6, err := s.container.IP(ctx)
 	if err != nil {
 		return "", errors.WithStack(err)
 	}
+	if s.ipFamily == clusterv1.IPv6IPFamily {
+		if lbip6 == "" {
+			return "", errors.New("load balancer container does not have an IPv6 address, please check IPv6 is enabled on the docker network")
+		}
+		return lbip6, nil
+	}
 	return lbip4, nil
 }
 
@@ -29,6 +29,7 @@ import (
 
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/util/wait"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	infrav1 "sigs.k8s.io/cluster-api/test/infrastructure/docker/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/test/infrastructure/docker/cloudinit"
 	"sigs.k8s.io/cluster-api/test/infrastructure/docker/docker/types"
@@ -53,6 +54,7 @@ type nodeCreator interface {
 type Machine struct {
 	cluster   string
 	machine   string
+	ipFamily  clusterv1.ClusterIPFamily
 	image     string
 	labels    map[string]string
 	container *types.Node
@@ -61,17 +63,22 @@ type Machine struct {
 }
 
 // NewMachine returns a new Machine service for the given Cluster/DockerCluster pair.
-func NewMachine(cluster, machine, image string, labels map[string]string) (*Machine, error) {
-	if cluster == "" {
+func NewMachine(cluster *clusterv1.Cluster, machine, image string, labels map[string]string) (*Machine, error) {
+	if cluster.Name == "" {
 		return nil, errors.New("cluster is required when creating a docker.Machine")
 	}
 	if machine == "" {
 		return nil, errors.New("machine is required when creating a docker.Machine")
 	}
 
+	ipFamily, err := cluster.GetIPFamily()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get ip family for the cluster")
+	}
+
 	filters := []string{
-		withLabel(clusterLabel(cluster)),
-		withName(machineContainerName(cluster, machine)),
+		withLabel(clusterLabel(cluster.Name)),
+		withName(machineContainerName(cluster.Name, machine)),
 	}
 	for key, val := range labels {
 		filters = append(filters, withLabel(toLabel(key, val)))
@@ -83,8 +90,9 @@ func NewMachine(cluster, machine, image string, labels map[string]string) (*Mach
 	}
 
 	return &Machine{
-		cluster:     cluster,
+		cluster:     cluster.Name,
 		machine:     machine,
+		ipFamily:    ipFamily,
 		image:       image,
 		container:   container,
 		labels:      labels,
@@ -92,13 +100,18 @@ func NewMachine(cluster, machine, image string, labels map[string]string) (*Mach
 	}, nil
 }
 
-func ListMachinesByCluster(cluster string, labels map[string]string) ([]*Machine, error) {
-	if cluster == "" {
+func ListMachinesByCluster(cluster *clusterv1.Cluster, labels map[string]string) ([]*Machine, error) {
+	if cluster.Name == "" {
 		return nil, errors.New("cluster is required when listing machines in the cluster")
 	}
 
+	ipFamily, err := cluster.GetIPFamily()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get ip family for the cluster")
+	}
+
 	filters := []string{
-		withLabel(clusterLabel(cluster)),
+		withLabel(clusterLabel(cluster.Name)),
 	}
 	for key, val := range labels {
 		filters = append(filters, withLabel(toLabel(key, val)))
@@ -112,8 +125,9 @@ func ListMachinesByCluster(cluster string, labels map[string]string) ([]*Machine
 	machines := make([]*Machine, len(containers))
 	for i, container := range containers {
 		machines[i] = &Machine{
-			cluster:     cluster,
-			machine:     machineFromContainerName(cluster, container.Name),
+			cluster:     cluster.Name,
+			machine:     machineFromContainerName(cluster.Name, container.Name),
+			ipFamily:    ipFamily,
 			image:       container.Image,
 			labels:      labels,
 			container:   container,
@@ -163,13 +177,22 @@ func (m *Machine) ProviderID() string {
 	return fmt.Sprintf("docker:////%s", m.ContainerName())
 }
 
-func (m *Machine) Address(ctx context.Context) (string, error) {
-	ipv4, _, err := m.container.IP(ctx)
+// Address returns the addresses of the machine matching the IP family of the cluster;
+// both the IPv4 and IPv6 addresses are returned for dual-stack clusters.
+func (m *Machine) Address(ctx context.Context) ([]string, error) {
+	ipv4, ipv6, err := m.container.IP(ctx)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return ipv4, nil
+	switch m.ipFamily {
+	case clusterv1.IPv6IPFamily:
+		return []string{ipv6}, nil
+	case clusterv1.DualStackIPFamily:
+		return []string{ipv4, ipv6}, nil
+	default:
+		return []string{ipv4}, nil
+	}
 }
 
 // Create creates a docker container hosting a Kubernetes node.
@@ -63,6 +63,11 @@ type Helper struct {
 	// won't drain otherwise
 	SkipWaitForDeleteTimeoutSeconds int
 
+	// AdditionalFilters are applied sequentially after base drain filters to
+	// exclude pods using custom logic.  Any filter that returns PodDeleteStatus
+	// with Delete == false will immediately stop execution of further filters.
+	AdditionalFilters []PodFilter
+
 	Out    io.Writer
 	ErrOut io.Writer
 
@@ -170,17 +175,17 @@ func (d *Helper) GetPodsForDeletion(ctx context.Context, nodeName string) (*podD
 	pods := []podDelete{}
 
 	for _, pod := range podList.Items {
-		var status podDeleteStatus
+		var status PodDeleteStatus
 		for _, filter := range d.makeFilters() {
 			status = filter(pod)
-			if !status.delete {
+			if !status.Delete {
 				// short-circuit as soon as pod is filtered out
 				// at that point, there is no reason to run pod
 				// through any additional filters
 				break
 			}
 		}
-		if status.delete {
+		if status.Delete {
 			pods = append(pods, podDelete{
 				pod:    pod,
 				status: status,
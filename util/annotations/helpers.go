@@ -17,6 +17,7 @@ limitations under the License.
 package annotations
 
 import (
+	"sort"
 	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -25,12 +26,43 @@ import (
 
 // IsPaused returns true if the Cluster is paused or the object has the `paused` annotation.
 func IsPaused(cluster *clusterv1.Cluster, o metav1.Object) bool {
-	if cluster.Spec.Paused {
+	if IsClusterPaused(cluster) {
 		return true
 	}
 	return HasPausedAnnotation(o)
 }
 
+// IsClusterPaused returns true if the Cluster is paused by spec.paused or by at least one pause reason annotation.
+func IsClusterPaused(cluster *clusterv1.Cluster) bool {
+	return cluster.Spec.Paused || HasWithPrefix(clusterv1.PauseReasonAnnotationPrefix+"/", cluster.GetAnnotations())
+}
+
+// PauseReasons returns the reasons why the Cluster is paused, sorted by name; reasons set with pause reason
+// annotations are reported by name, followed by the annotation value if any.
+func PauseReasons(cluster *clusterv1.Cluster) []string {
+	reasons := []string{}
+	if cluster.Spec.Paused {
+		reasons = append(reasons, "spec.paused")
+	}
+	if HasPausedAnnotation(cluster) {
+		reasons = append(reasons, clusterv1.PausedAnnotation)
+	}
+
+	annotationReasons := []string{}
+	for key, value := range cluster.GetAnnotations() {
+		name := strings.TrimPrefix(key, clusterv1.PauseReasonAnnotationPrefix+"/")
+		if name == key {
+			continue
+		}
+		if value != "" {
+			name = name + ": " + value
+		}
+		annotationReasons = append(annotationReasons, name)
+	}
+	sort.Strings(annotationReasons)
+	return append(reasons, annotationReasons...)
+}
+
 // HasPausedAnnotation returns true if the object has the `paused` annotation.
 func HasPausedAnnotation(o metav1.Object) bool {
 	return hasAnnotation(o, clusterv1.PausedAnnotation)
@@ -41,6 +73,12 @@ func HasSkipRemediationAnnotation(o metav1.Object) bool {
 	return hasAnnotation(o, clusterv1.MachineSkipRemediationAnnotation)
 }
 
+// HasDeletionProtectionAnnotation returns true if the object has the `deletion-protection` annotation.
+func HasDeletionProtectionAnnotation(o metav1.Object) bool {
+	return hasAnnotation(o, clusterv1.MachineDeletionProtectionAnnotation)
+}
+
+// HasWithPrefix returns true if at least one of the annotations has the prefix specified.
 func HasWithPrefix(prefix string, annotations map[string]string) bool {
 	for key := range annotations {
 		if strings.HasPrefix(key, prefix) {
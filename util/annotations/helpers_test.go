@@ -20,6 +20,7 @@ import (
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	"testing"
 )
 
@@ -120,3 +121,110 @@ func TestAddAnnotations(t *testing.T) {
 		})
 	}
 }
+
+func TestHasWithPrefix(t *testing.T) {
+	g := NewWithT(t)
+
+	var testcases = []struct {
+		name        string
+		prefix      string
+		annotations map[string]string
+		expected    bool
+	}{
+		{
+			name:        "should return false if there are no annotations",
+			prefix:      "foo",
+			annotations: nil,
+			expected:    false,
+		},
+		{
+			name:   "should return false if no annotation has the prefix",
+			prefix: "foo",
+			annotations: map[string]string{
+				"bar": "baz",
+			},
+			expected: false,
+		},
+		{
+			name:   "should return true if an annotation has the prefix",
+			prefix: "foo",
+			annotations: map[string]string{
+				"bar":     "baz",
+				"foo/bar": "baz",
+			},
+			expected: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g.Expect(HasWithPrefix(tc.prefix, tc.annotations)).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestIsPaused(t *testing.T) {
+	var testcases = []struct {
+		name            string
+		cluster         *clusterv1.Cluster
+		object          metav1.Object
+		expected        bool
+		expectedReasons []string
+	}{
+		{
+			name:            "should return false if the cluster and the object are not paused",
+			cluster:         &clusterv1.Cluster{},
+			object:          &clusterv1.Machine{},
+			expected:        false,
+			expectedReasons: []string{},
+		},
+		{
+			name:            "should return true if the cluster has spec.paused",
+			cluster:         &clusterv1.Cluster{Spec: clusterv1.ClusterSpec{Paused: true}},
+			object:          &clusterv1.Machine{},
+			expected:        true,
+			expectedReasons: []string{"spec.paused"},
+		},
+		{
+			name: "should return true if the cluster has pause reason annotations",
+			cluster: &clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						clusterv1.PauseReasonAnnotationPrefix + "/upgrade":      "",
+						clusterv1.PauseReasonAnnotationPrefix + "/etcd-restore": "restoring from snapshot",
+					},
+				},
+			},
+			object:          &clusterv1.Machine{},
+			expected:        true,
+			expectedReasons: []string{"etcd-restore: restoring from snapshot", "upgrade"},
+		},
+		{
+			name: "should return true if the object has the paused annotation",
+			cluster: &clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						clusterv1.PauseReasonAnnotationPrefix: "",
+					},
+				},
+			},
+			object: &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						clusterv1.PausedAnnotation: "",
+					},
+				},
+			},
+			expected:        true,
+			expectedReasons: []string{},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(IsPaused(tc.cluster, tc.object)).To(Equal(tc.expected))
+			g.Expect(PauseReasons(tc.cluster)).To(Equal(tc.expectedReasons))
+		})
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"k8s.io/apimachinery/pkg/selection"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -133,6 +134,15 @@ func HasDeletionTimestamp(machine *clusterv1.Machine) bool {
 	return !machine.DeletionTimestamp.IsZero()
 }
 
+// IsDeletionProtected returns a filter to find all machines protected from deletion, i.e. with the deletion
+// protection annotation and not being deleted yet.
+func IsDeletionProtected(machine *clusterv1.Machine) bool {
+	if machine == nil {
+		return false
+	}
+	return machine.DeletionTimestamp.IsZero() && annotations.HasDeletionProtectionAnnotation(machine)
+}
+
 // HasUnhealthyCondition returns a filter to find all machines that have a MachineHealthCheckSucceeded condition set to False,
 // indicating a problem was detected on the machine, and the MachineOwnerRemediated condition set, indicating that KCP is
 // responsible of performing remediation as owner of the machine.
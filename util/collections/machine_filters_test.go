@@ -125,6 +125,28 @@ func TestHasDeletionTimestamp(t *testing.T) {
 	})
 }
 
+func TestIsDeletionProtected(t *testing.T) {
+	t.Run("machine with the deletion protection annotation returns true", func(t *testing.T) {
+		g := NewWithT(t)
+		m := &clusterv1.Machine{}
+		m.SetAnnotations(map[string]string{clusterv1.MachineDeletionProtectionAnnotation: ""})
+		g.Expect(collections.IsDeletionProtected(m)).To(BeTrue())
+	})
+	t.Run("machine being deleted with the deletion protection annotation returns false", func(t *testing.T) {
+		g := NewWithT(t)
+		m := &clusterv1.Machine{}
+		m.SetAnnotations(map[string]string{clusterv1.MachineDeletionProtectionAnnotation: ""})
+		now := metav1.Now()
+		m.SetDeletionTimestamp(&now)
+		g.Expect(collections.IsDeletionProtected(m)).To(BeFalse())
+	})
+	t.Run("machine without the deletion protection annotation returns false", func(t *testing.T) {
+		g := NewWithT(t)
+		m := &clusterv1.Machine{}
+		g.Expect(collections.IsDeletionProtected(m)).To(BeFalse())
+	})
+}
+
 func TestShouldRolloutAfter(t *testing.T) {
 	reconciliationTime := metav1.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
 	t.Run("if the machine is nil it returns false", func(t *testing.T) {
@@ -0,0 +1,184 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cron implements parsing of cron schedules in the standard five fields format
+// (minute, hour, day of month, month, day of week).
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxSearchYears bounds the search for the next activation of a schedule,
+// e.g. for schedules like "0 0 30 2 *" that never activate.
+const maxSearchYears = 5
+
+type bounds struct {
+	name     string
+	min, max uint
+}
+
+var (
+	minuteBounds     = bounds{"minute", 0, 59}
+	hourBounds       = bounds{"hour", 0, 23}
+	dayOfMonthBounds = bounds{"day of month", 1, 31}
+	monthBounds      = bounds{"month", 1, 12}
+	// Both 0 and 7 are accepted for Sunday.
+	dayOfWeekBounds = bounds{"day of week", 0, 7}
+)
+
+// Schedule is a parsed cron schedule.
+// All the times are evaluated in UTC.
+type Schedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+
+	// dayOfMonthStar and dayOfWeekStar track whether the corresponding field is unrestricted;
+	// when both the fields are restricted a day matches if either of the fields matches.
+	dayOfMonthStar, dayOfWeekStar bool
+}
+
+// Parse parses a cron schedule in the standard five fields format, e.g. "0 2 * * 6".
+// Each field supports "*", single values, ranges ("1-5"), lists ("1,3,5") and steps ("*/15", "0-30/10").
+func Parse(spec string) (*Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, errors.Errorf("expected 5 fields in schedule %q, found %d", spec, len(fields))
+	}
+
+	s := &Schedule{}
+	var err error
+	if s.minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, err
+	}
+	if s.dayOfMonth, err = parseField(fields[2], dayOfMonthBounds); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, err
+	}
+	if s.dayOfWeek, err = parseField(fields[4], dayOfWeekBounds); err != nil {
+		return nil, err
+	}
+	// Sunday can be specified either as 0 or 7.
+	if s.dayOfWeek&(1<<7) != 0 {
+		s.dayOfWeek |= 1
+	}
+	s.dayOfMonthStar = fields[2] == "*" || strings.HasPrefix(fields[2], "*/")
+	s.dayOfWeekStar = fields[4] == "*" || strings.HasPrefix(fields[4], "*/")
+	return s, nil
+}
+
+// parseField parses a comma separated list of ranges, returning the matching values as a bitset.
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, expr := range strings.Split(field, ",") {
+		rangeExpr, step := expr, uint(1)
+		if i := strings.Index(expr, "/"); i >= 0 {
+			s, err := strconv.ParseUint(expr[i+1:], 10, 32)
+			if err != nil || s == 0 {
+				return 0, errors.Errorf("invalid step %q in %s field %q", expr[i+1:], b.name, field)
+			}
+			rangeExpr, step = expr[:i], uint(s)
+		}
+
+		start, end := b.min, b.max
+		switch {
+		case rangeExpr == "*":
+		case strings.Contains(rangeExpr, "-"):
+			parts := strings.SplitN(rangeExpr, "-", 2)
+			var err error
+			if start, err = parseValue(parts[0], b); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(parts[1], b); err != nil {
+				return 0, err
+			}
+			if end < start {
+				return 0, errors.Errorf("invalid range %q in %s field %q", rangeExpr, b.name, field)
+			}
+		default:
+			v, err := parseValue(rangeExpr, b)
+			if err != nil {
+				return 0, err
+			}
+			start = v
+			// A single value with a step, e.g. "5/10", means from the value to the max.
+			if step == 1 {
+				end = v
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(value string, b bounds) (uint, error) {
+	v, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, errors.Errorf("invalid value %q in %s field", value, b.name)
+	}
+	if uint(v) < b.min || uint(v) > b.max {
+		return 0, errors.Errorf("value %d out of range [%d-%d] in %s field", v, b.min, b.max, b.name)
+	}
+	return uint(v), nil
+}
+
+// Next returns the first activation time of the schedule strictly after the given time,
+// or the zero time if the schedule never activates.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dayOfMonth := s.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := s.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if s.dayOfMonthStar || s.dayOfWeekStar {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
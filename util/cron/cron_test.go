@@ -0,0 +1,133 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cron
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		expectErr bool
+	}{
+		{name: "every minute", spec: "* * * * *"},
+		{name: "weekly", spec: "0 2 * * 6"},
+		{name: "ranges, lists and steps", spec: "*/15 1-5 1,15 1-12/2 1-5"},
+		{name: "sunday as 7", spec: "0 0 * * 7"},
+		{name: "too few fields", spec: "0 2 * *", expectErr: true},
+		{name: "too many fields", spec: "0 0 2 * * 6", expectErr: true},
+		{name: "value out of range", spec: "60 * * * *", expectErr: true},
+		{name: "invalid range", spec: "* 5-1 * * *", expectErr: true},
+		{name: "invalid step", spec: "*/0 * * * *", expectErr: true},
+		{name: "not a number", spec: "* * * JAN *", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, err := Parse(tt.spec)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Friday, 2021-01-01 10:30 UTC.
+	now := time.Date(2021, time.January, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		spec     string
+		from     time.Time
+		expected time.Time
+	}{
+		{
+			name:     "every minute",
+			spec:     "* * * * *",
+			from:     now,
+			expected: time.Date(2021, time.January, 1, 10, 31, 0, 0, time.UTC),
+		},
+		{
+			name:     "every saturday at 02:00",
+			spec:     "0 2 * * 6",
+			from:     now,
+			expected: time.Date(2021, time.January, 2, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "every sunday at midnight using 7",
+			spec:     "0 0 * * 7",
+			from:     now,
+			expected: time.Date(2021, time.January, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "every 15 minutes",
+			spec:     "*/15 * * * *",
+			from:     now,
+			expected: time.Date(2021, time.January, 1, 10, 45, 0, 0, time.UTC),
+		},
+		{
+			name:     "first day of the next month",
+			spec:     "0 0 1 * *",
+			from:     now,
+			expected: time.Date(2021, time.February, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "next year",
+			spec:     "0 0 1 1 *",
+			from:     now,
+			expected: time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "day of month or day of week when both are restricted",
+			spec:     "0 0 15 * 1",
+			from:     now,
+			expected: time.Date(2021, time.January, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "strictly after the given time",
+			spec:     "30 10 * * *",
+			from:     now,
+			expected: time.Date(2021, time.January, 2, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "never",
+			spec:     "0 0 30 2 *",
+			from:     now,
+			expected: time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			s, err := Parse(tt.spec)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(s.Next(tt.from)).To(Equal(tt.expected))
+		})
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package kubeconfig
 
 import (
+	"bytes"
 	"context"
 	"crypto"
 	"crypto/x509"
@@ -174,6 +175,42 @@ func NeedsClientCertRotation(configSecret *corev1.Secret, threshold time.Duratio
 	return false, nil
 }
 
+// NeedsCertificateAuthorityUpdate returns whether the Kubeconfig secret's clusters trust other certificate
+// authorities than the ones in the given CA certificate data, or its client certificates are not issued by the first of them.
+func NeedsCertificateAuthorityUpdate(configSecret *corev1.Secret, caData []byte) (bool, error) {
+	data, err := toKubeconfigBytes(configSecret)
+	if err != nil {
+		return false, err
+	}
+
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to convert kubeconfig Secret into a clientcmdapi.Config")
+	}
+
+	for _, cluster := range config.Clusters {
+		if !bytes.Equal(cluster.CertificateAuthorityData, caData) {
+			return true, nil
+		}
+	}
+
+	caCert, err := certs.DecodeCertPEM(caData)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to decode CA Cert")
+	}
+	for _, authInfo := range config.AuthInfos {
+		cert, err := certs.DecodeCertPEM(authInfo.ClientCertificateData)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to decode kubeconfig client certificate")
+		}
+		if err := cert.CheckSignatureFrom(caCert); err != nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
 // RegenerateSecret creates and stores a new Kubeconfig in the given secret.
 func RegenerateSecret(ctx context.Context, c client.Client, configSecret *corev1.Secret) error {
 	clusterName, _, err := secret.ParseSecretName(configSecret.Name)
@@ -226,6 +263,8 @@ func generateKubeconfig(ctx context.Context, c client.Client, clusterName client
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to generate a kubeconfig")
 	}
+	// Trust all the certificates of the CA, including the ones of a CA being rotated.
+	cfg.Clusters[clusterName.Name].CertificateAuthorityData = clusterCA.Data[secret.TLSCrtDataName]
 
 	out, err := clientcmd.Write(*cfg)
 	if err != nil {
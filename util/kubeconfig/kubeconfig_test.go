@@ -390,6 +390,50 @@ func TestNeedsClientCertRotation(t *testing.T) {
 	g.Expect(NeedsClientCertRotation(kubeconfigSecret, certs.DefaultCertDuration-time.Hour)).To(BeFalse())
 }
 
+func TestNeedsCertificateAuthorityUpdate(t *testing.T) {
+	g := NewWithT(t)
+
+	caKey1, err := certs.NewPrivateKey()
+	g.Expect(err).NotTo(HaveOccurred())
+	caCert1, err := getTestCACert(caKey1)
+	g.Expect(err).NotTo(HaveOccurred())
+	caKey2, err := certs.NewPrivateKey()
+	g.Expect(err).NotTo(HaveOccurred())
+	caCert2, err := getTestCACert(caKey2)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	bundle := append(certs.EncodeCertPEM(caCert1), certs.EncodeCertPEM(caCert2)...)
+	caSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test1-ca",
+			Namespace: "test",
+		},
+		Data: map[string][]byte{
+			secret.TLSKeyDataName: certs.EncodePrivateKeyPEM(caKey1),
+			secret.TLSCrtDataName: bundle,
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(setupScheme()).WithObjects(caSecret).Build()
+
+	clusterKey := client.ObjectKey{Name: "test1", Namespace: "test"}
+	g.Expect(CreateSecretWithOwner(ctx, c, clusterKey, "localhost:6443", metav1.OwnerReference{})).To(Succeed())
+	s := &corev1.Secret{}
+	g.Expect(c.Get(ctx, client.ObjectKey{Name: "test1-kubeconfig", Namespace: "test"}, s)).To(Succeed())
+
+	// The kubeconfig trusts all the certificates of the CA.
+	clientConfig, err := clientcmd.NewClientConfigFromBytes(s.Data[secret.KubeconfigDataName])
+	g.Expect(err).NotTo(HaveOccurred())
+	restClient, err := clientConfig.ClientConfig()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(restClient.CAData).To(Equal(bundle))
+
+	g.Expect(NeedsCertificateAuthorityUpdate(s, bundle)).To(BeFalse())
+	// The trusted certificates changed.
+	g.Expect(NeedsCertificateAuthorityUpdate(s, certs.EncodeCertPEM(caCert1))).To(BeTrue())
+	// The client certificate is not issued by the signing CA.
+	g.Expect(NeedsCertificateAuthorityUpdate(s, append(certs.EncodeCertPEM(caCert2), certs.EncodeCertPEM(caCert1)...))).To(BeTrue())
+}
+
 func TestRegenerateClientCerts(t *testing.T) {
 	g := NewWithT(t)
 	caKey, err := certs.NewPrivateKey()
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package maintenance implements utilities to check a Cluster's maintenance windows
+// before performing disruptive actions on its machines.
+package maintenance
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/cron"
+)
+
+// DisruptionAllowed returns true if disruptive actions on the cluster's machines are allowed at the given time,
+// i.e. if the cluster does not define maintenance windows or one of them is open.
+// If disruptive actions are not allowed, it returns the time at which the next maintenance window opens,
+// or the zero time if none of the windows ever opens.
+func DisruptionAllowed(cluster *clusterv1.Cluster, now time.Time) (bool, time.Time, error) {
+	if cluster == nil || len(cluster.Spec.MaintenanceWindows) == 0 {
+		return true, time.Time{}, nil
+	}
+
+	var next time.Time
+	for _, w := range cluster.Spec.MaintenanceWindows {
+		schedule, err := cron.Parse(w.Schedule)
+		if err != nil {
+			return false, time.Time{}, errors.Wrapf(err, "failed to parse maintenance window schedule %q", w.Schedule)
+		}
+
+		// The window is open if it opened after now - duration and not after now.
+		start := schedule.Next(now.Add(-w.Duration.Duration))
+		if !start.IsZero() && !start.After(now) {
+			return true, time.Time{}, nil
+		}
+
+		if n := schedule.Next(now); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return false, next, nil
+}
+
+// RequeueAfter returns how long to wait before retrying a disruptive action deferred until the given time.
+func RequeueAfter(next, now time.Time) time.Duration {
+	if next.IsZero() {
+		// None of the windows ever opens; check again periodically in case the windows are updated.
+		return 1 * time.Hour
+	}
+	return next.Sub(now) + time.Second
+}
+
+// MarkDeferred sets the DisruptionAllowed condition to False on the given object, documenting that the action
+// has been deferred until the next maintenance window opens.
+func MarkDeferred(to conditions.Setter, action string, next time.Time) {
+	if next.IsZero() {
+		conditions.MarkFalse(to, clusterv1.DisruptionAllowedCondition, clusterv1.OutsideMaintenanceWindowReason, clusterv1.ConditionSeverityInfo,
+			"%s deferred, none of the Cluster's maintenance windows is scheduled to open", action)
+		return
+	}
+	conditions.MarkFalse(to, clusterv1.DisruptionAllowedCondition, clusterv1.OutsideMaintenanceWindowReason, clusterv1.ConditionSeverityInfo,
+		"%s deferred until the next maintenance window opens at %s", action, next.UTC().Format(time.RFC3339))
+}
+
+// MarkAllowed sets the DisruptionAllowed condition to True on the given object if the cluster defines
+// maintenance windows, otherwise it removes the condition.
+func MarkAllowed(to conditions.Setter, cluster *clusterv1.Cluster) {
+	if cluster == nil || len(cluster.Spec.MaintenanceWindows) == 0 {
+		conditions.Delete(to, clusterv1.DisruptionAllowedCondition)
+		return
+	}
+	conditions.MarkTrue(to, clusterv1.DisruptionAllowedCondition)
+}
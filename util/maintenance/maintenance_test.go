@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+func TestDisruptionAllowed(t *testing.T) {
+	// Saturday, 2021-01-02 03:00 UTC.
+	now := time.Date(2021, time.January, 2, 3, 0, 0, 0, time.UTC)
+
+	saturdayNight := clusterv1.MaintenanceWindow{Schedule: "0 2 * * 6", Duration: metav1.Duration{Duration: 4 * time.Hour}}
+	sundayNight := clusterv1.MaintenanceWindow{Schedule: "0 2 * * 0", Duration: metav1.Duration{Duration: 4 * time.Hour}}
+	saturdayShort := clusterv1.MaintenanceWindow{Schedule: "0 2 * * 6", Duration: metav1.Duration{Duration: 30 * time.Minute}}
+
+	tests := []struct {
+		name         string
+		windows      []clusterv1.MaintenanceWindow
+		expectAllow  bool
+		expectedNext time.Time
+		expectErr    bool
+	}{
+		{
+			name:        "no maintenance windows",
+			windows:     nil,
+			expectAllow: true,
+		},
+		{
+			name:        "within a maintenance window",
+			windows:     []clusterv1.MaintenanceWindow{saturdayNight},
+			expectAllow: true,
+		},
+		{
+			name:         "after a maintenance window closed",
+			windows:      []clusterv1.MaintenanceWindow{saturdayShort},
+			expectAllow:  false,
+			expectedNext: time.Date(2021, time.January, 9, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "before a maintenance window opens",
+			windows:      []clusterv1.MaintenanceWindow{sundayNight, saturdayShort},
+			expectAllow:  false,
+			expectedNext: time.Date(2021, time.January, 3, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:        "invalid schedule",
+			windows:     []clusterv1.MaintenanceWindow{{Schedule: "invalid", Duration: metav1.Duration{Duration: time.Hour}}},
+			expectAllow: false,
+			expectErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			cluster := &clusterv1.Cluster{Spec: clusterv1.ClusterSpec{MaintenanceWindows: tt.windows}}
+			allowed, next, err := DisruptionAllowed(cluster, now)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+			g.Expect(allowed).To(Equal(tt.expectAllow))
+			g.Expect(next).To(Equal(tt.expectedNext))
+		})
+	}
+}
+
+func TestMarkDeferredAndAllowed(t *testing.T) {
+	g := NewWithT(t)
+
+	next := time.Date(2021, time.January, 9, 2, 0, 0, 0, time.UTC)
+	cluster := &clusterv1.Cluster{Spec: clusterv1.ClusterSpec{MaintenanceWindows: []clusterv1.MaintenanceWindow{
+		{Schedule: "0 2 * * 6", Duration: metav1.Duration{Duration: time.Hour}},
+	}}}
+	obj := &clusterv1.MachineDeployment{}
+
+	MarkDeferred(obj, "Rollout", next)
+	g.Expect(conditions.IsFalse(obj, clusterv1.DisruptionAllowedCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(obj, clusterv1.DisruptionAllowedCondition)).To(Equal(clusterv1.OutsideMaintenanceWindowReason))
+	g.Expect(conditions.GetMessage(obj, clusterv1.DisruptionAllowedCondition)).To(Equal("Rollout deferred until the next maintenance window opens at 2021-01-09T02:00:00Z"))
+
+	MarkAllowed(obj, cluster)
+	g.Expect(conditions.IsTrue(obj, clusterv1.DisruptionAllowedCondition)).To(BeTrue())
+
+	MarkAllowed(obj, &clusterv1.Cluster{})
+	g.Expect(conditions.Has(obj, clusterv1.DisruptionAllowedCondition)).To(BeFalse())
+}
@@ -19,6 +19,7 @@ package predicates
 import (
 	"github.com/go-logr/logr"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
@@ -54,6 +55,7 @@ func ClusterCreateInfraReady(logger logr.Logger) predicate.Funcs {
 }
 
 // ClusterCreateNotPaused returns a predicate that returns true for a create event when a cluster has Spec.Paused set as false
+// and no pause reason annotations
 // it also returns true if the resource provided is not a Cluster to allow for use with controller-runtime NewControllerManagedBy
 func ClusterCreateNotPaused(logger logr.Logger) predicate.Funcs {
 	return predicate.Funcs{
@@ -67,8 +69,8 @@ func ClusterCreateNotPaused(logger logr.Logger) predicate.Funcs {
 			}
 			log = log.WithValues("namespace", c.Namespace, "cluster", c.Name)
 
-			// Only need to trigger a reconcile if the Cluster.Spec.Paused is false
-			if !c.Spec.Paused {
+			// Only need to trigger a reconcile if the Cluster is not paused
+			if !annotations.IsClusterPaused(c) {
 				log.V(4).Info("Cluster is not paused, allowing further processing")
 				return true
 			}
@@ -113,7 +115,8 @@ func ClusterUpdateInfraReady(logger logr.Logger) predicate.Funcs {
 	}
 }
 
-// ClusterUpdateUnpaused returns a predicate that returns true for an update event when a cluster has Spec.Paused changed from true to false
+// ClusterUpdateUnpaused returns a predicate that returns true for an update event when a cluster is resumed, i.e. Spec.Paused
+// changed from true to false or the last pause reason annotation was removed
 // it also returns true if the resource provided is not a Cluster to allow for use with controller-runtime NewControllerManagedBy
 func ClusterUpdateUnpaused(logger logr.Logger) predicate.Funcs {
 	return predicate.Funcs{
@@ -130,7 +133,7 @@ func ClusterUpdateUnpaused(logger logr.Logger) predicate.Funcs {
 
 			newCluster := e.ObjectNew.(*clusterv1.Cluster)
 
-			if oldCluster.Spec.Paused && !newCluster.Spec.Paused {
+			if annotations.IsClusterPaused(oldCluster) && !annotations.IsClusterPaused(newCluster) {
 				log.V(4).Info("Cluster was unpaused, allowing further processing")
 				return true
 			}
@@ -29,6 +29,10 @@ const (
 	// TLSCrtDataName is the key used to store a TLS certificate in the secret's data field.
 	TLSCrtDataName = "tls.crt"
 
+	// TLSNextKeyDataName is the key used to store the private key replacing the one in TLSKeyDataName while
+	// a certificate authority is being rotated.
+	TLSNextKeyDataName = "tls-next.key"
+
 	// Kubeconfig is the secret name suffix storing the Cluster Kubeconfig.
 	Kubeconfig = Purpose("kubeconfig")
 
@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api/util/certs"
+)
+
+// A certificate authority secret is rotated in three steps, each of them being safe to repeat:
+//
+//   1. AddNextKeyPair adds a new certificate authority to the trusted ones, while the current one keeps signing.
+//   2. PromoteNextKeyPair makes the new certificate authority the signing one, while the previous one is still trusted.
+//   3. RemovePreviousKeyPairs stops trusting the previous certificate authority.
+//
+// The signing certificate is always the first one in TLSCrtDataName, as kubeadm uses it as the issuer of the
+// certificates it generates. For the ServiceAccount purpose, public keys are stored in place of certificates.
+
+// AddNextKeyPair generates a new key pair for a secret of the given purpose, appends its certificate to the trusted
+// ones and stores its private key in TLSNextKeyDataName. It does nothing if the secret already has a next key.
+func AddNextKeyPair(s *corev1.Secret, purpose Purpose) error {
+	if _, ok := s.Data[TLSNextKeyDataName]; ok {
+		return nil
+	}
+	if len(s.Data[TLSKeyDataName]) == 0 {
+		return errors.Errorf("secret %s/%s has no private key and cannot be rotated", s.Namespace, s.Name)
+	}
+
+	generator := generateCACert
+	if purpose == ServiceAccount {
+		generator = generateServiceAccountKeys
+	}
+	kp, err := generator()
+	if err != nil {
+		return err
+	}
+
+	crt := bytes.TrimRight(s.Data[TLSCrtDataName], "\n")
+	s.Data[TLSCrtDataName] = append(append(crt, '\n'), kp.Cert...)
+	s.Data[TLSNextKeyDataName] = kp.Key
+	return nil
+}
+
+// PromoteNextKeyPair makes the key in TLSNextKeyDataName the signing key of a secret, and moves its certificate
+// first in TLSCrtDataName. It does nothing if the secret has no next key.
+func PromoteNextKeyPair(s *corev1.Secret) error {
+	nextKey, ok := s.Data[TLSNextKeyDataName]
+	if !ok {
+		return nil
+	}
+	signer, err := certs.DecodePrivateKeyPEM(nextKey)
+	if err != nil {
+		return errors.Wrapf(err, "failed to decode the next private key of secret %s/%s", s.Namespace, s.Name)
+	}
+	nextPublicKey, ok := signer.Public().(*rsa.PublicKey)
+	if !ok {
+		return errors.Errorf("the next private key of secret %s/%s is not an RSA key", s.Namespace, s.Name)
+	}
+
+	blocks, err := decodePEMBlocks(s.Data[TLSCrtDataName])
+	if err != nil {
+		return errors.Wrapf(err, "failed to decode the certificates of secret %s/%s", s.Namespace, s.Name)
+	}
+	next := -1
+	for i, block := range blocks {
+		publicKey, err := pemBlockPublicKey(block)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode the certificates of secret %s/%s", s.Namespace, s.Name)
+		}
+		if nextPublicKey.Equal(publicKey) {
+			next = i
+			break
+		}
+	}
+	if next == -1 {
+		return errors.Errorf("secret %s/%s has no certificate for its next private key", s.Namespace, s.Name)
+	}
+
+	ordered := append([]*pem.Block{blocks[next]}, append(blocks[:next:next], blocks[next+1:]...)...)
+	s.Data[TLSCrtDataName] = encodePEMBlocks(ordered)
+	s.Data[TLSKeyDataName] = nextKey
+	delete(s.Data, TLSNextKeyDataName)
+	return nil
+}
+
+// RemovePreviousKeyPairs removes all the certificates but the signing one from a secret.
+func RemovePreviousKeyPairs(s *corev1.Secret) error {
+	if _, ok := s.Data[TLSNextKeyDataName]; ok {
+		return errors.Errorf("secret %s/%s has a next private key which is not promoted yet", s.Namespace, s.Name)
+	}
+	blocks, err := decodePEMBlocks(s.Data[TLSCrtDataName])
+	if err != nil {
+		return errors.Wrapf(err, "failed to decode the certificates of secret %s/%s", s.Namespace, s.Name)
+	}
+	if len(blocks) == 0 {
+		return errors.Errorf("secret %s/%s has no certificate", s.Namespace, s.Name)
+	}
+	s.Data[TLSCrtDataName] = encodePEMBlocks(blocks[:1])
+	return nil
+}
+
+func decodePEMBlocks(data []byte) ([]*pem.Block, error) {
+	var blocks []*pem.Block
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		blocks = append(blocks, block)
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		return nil, errors.New("invalid PEM data")
+	}
+	return blocks, nil
+}
+
+func encodePEMBlocks(blocks []*pem.Block) []byte {
+	var out []byte
+	for _, block := range blocks {
+		out = append(out, pem.EncodeToMemory(block)...)
+	}
+	return out
+}
+
+// pemBlockPublicKey returns the public key of a PEM encoded certificate or public key.
+func pemBlockPublicKey(block *pem.Block) (interface{}, error) {
+	switch block.Type {
+	case "CERTIFICATE":
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return certificate.PublicKey, nil
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, errors.Errorf("unexpected PEM block type %q", block.Type)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret_test
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+	"sigs.k8s.io/cluster-api/bootstrap/kubeadm/types/v1beta1"
+	"sigs.k8s.io/cluster-api/util/certs"
+	"sigs.k8s.io/cluster-api/util/secret"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestRotateCertificateAuthority(t *testing.T) {
+	g := NewWithT(t)
+
+	s := generateSecret(g, secret.ClusterCA)
+	original := s.DeepCopy()
+
+	// Adding the next key pair keeps the current one signing.
+	g.Expect(secret.AddNextKeyPair(s, secret.ClusterCA)).To(Succeed())
+	g.Expect(s.Data).To(HaveKey(secret.TLSNextKeyDataName))
+	g.Expect(s.Data[secret.TLSKeyDataName]).To(Equal(original.Data[secret.TLSKeyDataName]))
+	certificates, err := cert.ParseCertsPEM(s.Data[secret.TLSCrtDataName])
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(certificates).To(HaveLen(2))
+	_, err = tls.X509KeyPair(s.Data[secret.TLSCrtDataName], s.Data[secret.TLSKeyDataName])
+	g.Expect(err).ToNot(HaveOccurred())
+	// Joining nodes pin both certificates.
+	hashes, err := (&secret.Certificate{Purpose: secret.ClusterCA, KeyPair: &certs.KeyPair{Cert: s.Data[secret.TLSCrtDataName]}}).Hashes()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(hashes).To(HaveLen(2))
+
+	// Adding the next key pair again does nothing.
+	added := s.DeepCopy()
+	g.Expect(secret.AddNextKeyPair(s, secret.ClusterCA)).To(Succeed())
+	g.Expect(s.Data).To(Equal(added.Data))
+
+	// Promoting the next key pair makes it signing, while the previous certificate is still trusted.
+	nextKey := s.Data[secret.TLSNextKeyDataName]
+	g.Expect(secret.PromoteNextKeyPair(s)).To(Succeed())
+	g.Expect(s.Data).ToNot(HaveKey(secret.TLSNextKeyDataName))
+	g.Expect(s.Data[secret.TLSKeyDataName]).To(Equal(nextKey))
+	promoted, err := cert.ParseCertsPEM(s.Data[secret.TLSCrtDataName])
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(promoted).To(Equal([]*x509.Certificate{certificates[1], certificates[0]}))
+	_, err = tls.X509KeyPair(s.Data[secret.TLSCrtDataName], s.Data[secret.TLSKeyDataName])
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Promoting again does nothing.
+	g.Expect(secret.PromoteNextKeyPair(s)).To(Succeed())
+	g.Expect(s.Data[secret.TLSKeyDataName]).To(Equal(nextKey))
+
+	// Removing the previous key pairs leaves only the signing certificate.
+	g.Expect(secret.RemovePreviousKeyPairs(s)).To(Succeed())
+	rotated, err := cert.ParseCertsPEM(s.Data[secret.TLSCrtDataName])
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(rotated).To(Equal([]*x509.Certificate{certificates[1]}))
+	_, err = tls.X509KeyPair(s.Data[secret.TLSCrtDataName], s.Data[secret.TLSKeyDataName])
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestRotateServiceAccountKey(t *testing.T) {
+	g := NewWithT(t)
+
+	s := generateSecret(g, secret.ServiceAccount)
+
+	g.Expect(secret.AddNextKeyPair(s, secret.ServiceAccount)).To(Succeed())
+	publicKeys, err := keyutil.ParsePublicKeysPEM(s.Data[secret.TLSCrtDataName])
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(publicKeys).To(HaveLen(2))
+
+	g.Expect(secret.PromoteNextKeyPair(s)).To(Succeed())
+	g.Expect(secret.RemovePreviousKeyPairs(s)).To(Succeed())
+
+	publicKeys, err = keyutil.ParsePublicKeysPEM(s.Data[secret.TLSCrtDataName])
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(publicKeys).To(HaveLen(1))
+	key, err := keyutil.ParsePrivateKeyPEM(s.Data[secret.TLSKeyDataName])
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(key.(*rsa.PrivateKey).PublicKey.Equal(publicKeys[0])).To(BeTrue())
+}
+
+func TestRotationErrors(t *testing.T) {
+	t.Run("a secret without private key cannot be rotated", func(t *testing.T) {
+		g := NewWithT(t)
+		s := generateSecret(g, secret.EtcdCA)
+		delete(s.Data, secret.TLSKeyDataName)
+		g.Expect(secret.AddNextKeyPair(s, secret.EtcdCA)).ToNot(Succeed())
+	})
+
+	t.Run("previous key pairs cannot be removed before the next one is promoted", func(t *testing.T) {
+		g := NewWithT(t)
+		s := generateSecret(g, secret.EtcdCA)
+		g.Expect(secret.AddNextKeyPair(s, secret.EtcdCA)).To(Succeed())
+		g.Expect(secret.RemovePreviousKeyPairs(s)).ToNot(Succeed())
+	})
+
+	t.Run("the next key pair cannot be promoted if its certificate is missing", func(t *testing.T) {
+		g := NewWithT(t)
+		s := generateSecret(g, secret.EtcdCA)
+		other := generateSecret(g, secret.EtcdCA)
+		g.Expect(secret.AddNextKeyPair(s, secret.EtcdCA)).To(Succeed())
+		s.Data[secret.TLSCrtDataName] = other.Data[secret.TLSCrtDataName]
+		g.Expect(secret.PromoteNextKeyPair(s)).ToNot(Succeed())
+	})
+}
+
+func generateSecret(g *WithT, purpose secret.Purpose) *corev1.Secret {
+	certificates := secret.NewCertificatesForInitialControlPlane(&v1beta1.ClusterConfiguration{})
+	g.Expect(certificates.Generate()).To(Succeed())
+	return certificates.GetByPurpose(purpose).AsSecret(client.ObjectKey{Namespace: "default", Name: "test"}, metav1.OwnerReference{})
+}